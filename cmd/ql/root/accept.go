@@ -12,9 +12,15 @@ import (
 )
 
 func newAcceptCmd() *cobra.Command {
+	var dryRun bool
+
 	cmd := &cobra.Command{
 		Use:   "accept <blueprint_id>",
 		Short: "Accept a blueprint and instantiate it",
+		Long: `Accept an available blueprint and create its task, habit or project.
+
+Repeatable blueprints can be accepted again once completed; the blueprint bonus
+is only paid for the first run. --dry-run shows what would be created.`,
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				return errors.New("blueprint_id is required")
@@ -30,23 +36,35 @@ func newAcceptCmd() *cobra.Command {
 			}
 			defer cleanup()
 
+			if dryRun && structuredOutput() {
+				return usageError{errors.New("--dry-run prints text only")}
+			}
+			if dryRun {
+				return printAcceptDryRun(ctx, cmd.OutOrStdout(), svc, code)
+			}
 			res, err := svc.AcceptBlueprint(ctx, code)
 			if err != nil {
 				return err
 			}
+			if structuredOutput() {
+				return printView(cmd.OutOrStdout(), engine.NewCreateView(res), nil)
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "%s %s → created #%d\n", ui.Good.Render(ui.IconScroll+" Accepted"), ui.Muted.Render(code), res.TaskID)
 
 			// Show hint for projects without auto-spawned children
-			def := engine.GetBlueprintDef(code)
+			def := svc.BlueprintDef(code)
 			if def != nil && def.Kind == engine.BlueprintKindProject && len(def.Children) == 0 {
 				fmt.Fprintf(cmd.OutOrStdout(), "%s Add subtasks to activate: %s\n",
 					ui.Muted.Render("💡"),
 					ui.Key.Render(fmt.Sprintf("ql add -p %d \"First step\"", res.TaskID)))
 			}
+			printAchievements(cmd.OutOrStdout(), res.Achievements)
 
 			return nil
 		},
 	}
 
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what accepting would create without saving anything")
+
 	return cmd
 }
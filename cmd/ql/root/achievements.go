@@ -0,0 +1,151 @@
+package root
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"questline/internal/engine"
+	"questline/internal/ui"
+)
+
+func newAchievementsCmd() *cobra.Command {
+	var earnedOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "achievements",
+		Short: "List achievements, earned and still to earn",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			if structuredOutput() {
+				all, err := svc.AchievementViews(ctx)
+				if err != nil {
+					return err
+				}
+				v := engine.AchievementListView{Version: engine.ViewVersion, Achievements: []engine.AchievementStatusView{}}
+				for _, a := range all {
+					if a.Earned || !earnedOnly {
+						v.Achievements = append(v.Achievements, a)
+					}
+				}
+				return printView(cmd.OutOrStdout(), v, achievementTable(v.Achievements))
+			}
+
+			all, err := engine.GetAchievementsForPlayer(ctx, svc)
+			if err != nil {
+				return err
+			}
+			earned := 0
+			for _, a := range all {
+				if a.Earned {
+					earned++
+				}
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintln(out, ui.Heading(ui.IconTrophy, fmt.Sprintf("Achievements %d/%d", earned, len(all))))
+			for _, a := range all {
+				if a.Earned {
+					line := fmt.Sprintf("%s %s %s", a.Icon, ui.Good.Render(a.Name), ui.Muted.Render(a.Description))
+					if a.EarnedAt != nil {
+						line += " " + ui.Muted.Render("("+a.EarnedAt.Local().Format("2006-01-02")+")")
+					}
+					fmt.Fprintln(out, line)
+				} else if !earnedOnly {
+					fmt.Fprintf(out, "%s %s %s\n", ui.Muted.Render("·"), a.Name, ui.Muted.Render(a.Description))
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&earnedOnly, "earned", false, "Only list earned achievements")
+	cmd.AddCommand(readOnly(newAchievementsValidateCmd()))
+
+	return cmd
+}
+
+// achievementTable is the CSV of ql achievements: one row per achievement.
+func achievementTable(views []engine.AchievementStatusView) [][]string {
+	rows := [][]string{{"id", "name", "icon", "description", "earned", "earned_at"}}
+	for _, a := range views {
+		earnedAt := ""
+		if a.EarnedAt != nil {
+			earnedAt = a.EarnedAt.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{a.ID, a.Name, a.Icon, a.Description, strconv.FormatBool(a.Earned), earnedAt})
+	}
+	return rows
+}
+
+func newAchievementsValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [path...]",
+		Short: "Lint user achievement files",
+		Long: `Lint user achievement files (YAML or JSON).
+
+Without arguments this checks the achievements directory: $QL_ACHIEVEMENTS_DIR,
+or ~/.config/questline/achievements. Paths may be files or directories. Besides
+the file format and conditions it checks that ids are unique and attributes are
+registered.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			paths := args
+			if len(paths) == 0 {
+				dir, err := engine.ResolveAchievementsDir()
+				if err != nil {
+					return err
+				}
+				paths = []string{dir}
+			}
+			files, err := dataFiles(paths)
+			if err != nil {
+				return fmt.Errorf("achievement path: %w", err)
+			}
+
+			var defs []engine.AchievementDef
+			var problems []error
+			counts := map[string]int{}
+			for _, f := range files {
+				d, errs := engine.LoadAchievementFile(f)
+				defs = append(defs, d...)
+				problems = append(problems, errs...)
+				counts[f] = len(d)
+			}
+
+			db, cleanup, err := openDB(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			more, err := engine.NewService(db).ValidateAchievements(ctx, defs)
+			if err != nil {
+				return err
+			}
+			problems = append(problems, more...)
+
+			out := cmd.OutOrStdout()
+			if len(files) == 0 {
+				fmt.Fprintln(out, ui.Muted.Render("No achievement files in "+strings.Join(paths, ", ")))
+				return nil
+			}
+			printFileProblems(out, files, problems, counts, "achievement(s)")
+			if len(problems) > 0 {
+				return fmt.Errorf("%d problem(s) in achievement files", len(problems))
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
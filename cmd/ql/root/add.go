@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"questline/internal/engine"
@@ -21,12 +22,24 @@ func newAddCmd() *cobra.Command {
 	var habitInterval string
 	var habitDuration string
 	var habitGoal int
+	var due string
+	var after []int64
+	var tagList string
+	var literal bool
 
 	cmd := &cobra.Command{
 		Use:   "add <title>",
 		Short: "Add a task (or project/habit)",
+		Long: `Add a task. The title may carry quick-add tokens instead of flags:
+
+  ql add "Run 5k !3 @str #fitness ^12 due:sat every:mon,thu"
+
+!3 sets the difficulty, @str or @str:50,int:50 the attributes, #fitness a
+tag, ^12 the parent, due:<date> the due date and every:<schedule> makes it a
+habit (use - for spaces, as in every:first-mon). Start a word with \ to keep
+it in the title, or pass --literal to take the title as typed.`,
 		Args: func(cmd *cobra.Command, args []string) error {
-			if len(args) != 1 {
+			if len(args) == 0 {
 				return errors.New("title is required")
 			}
 			return nil
@@ -39,26 +52,67 @@ func newAddCmd() *cobra.Command {
 			}
 			defer cleanup()
 
-			title := args[0]
-			primaryAttr, attrWeights := engine.ParseAttributes(attr)
+			reg, err := svc.Attributes(ctx)
+			if err != nil {
+				return err
+			}
+			q := engine.QuickAdd{Title: strings.Join(args, " ")}
+			if !literal {
+				if q, err = engine.ParseQuickAdd(q.Title, reg, time.Now()); err != nil {
+					return err
+				}
+			}
+			if err := quickAddConflicts(cmd, q); err != nil {
+				return err
+			}
+			title := q.Title
+			primaryAttr, attrWeights := reg.ParseWeights(attr)
+			if q.Attribute != "" {
+				primaryAttr, attrWeights = q.Attribute, q.Attributes
+			}
+			tags, err := engine.ParseTags(tagList + "," + strings.Join(q.Tags, ","))
+			if err != nil {
+				return err
+			}
 
 			var parent *int64
 			if parentID != 0 {
 				v := parentID
 				parent = &v
 			}
+			if q.ParentID != nil {
+				parent = q.ParentID
+			}
+			if q.Difficulty != 0 {
+				diff = int(q.Difficulty)
+			}
+			if q.Interval != "" {
+				isHabit = true
+			}
 
 			if isProject {
+				if q.Difficulty != 0 || q.ParentID != nil || q.DueDate != nil || q.Interval != "" {
+					return errors.New("projects take only @attribute and #tag tokens")
+				}
 				res, err := svc.CreateProject(ctx, engine.CreateProjectInput{
 					Title:      title,
 					Attribute:  primaryAttr,
 					Attributes: attrWeights,
+					After:      after,
+					Tags:       tags,
 				})
 				if err != nil {
 					return err
 				}
+				if structuredOutput() {
+					return printView(cmd.OutOrStdout(), engine.NewCreateView(res), nil)
+				}
 				created, _ := svc.TaskRepo().Get(ctx, res.TaskID)
-				fmt.Fprintln(cmd.OutOrStdout(), ui.Good.Render(ui.IconBox+" Created project")+" "+fmt.Sprintf("#%d %s", res.TaskID, created.Title)+" "+ui.Muted.Render("("+ui.StatusText(created.Status)+")"))
+				blockers, err := svc.OpenBlockers(ctx, res.TaskID)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), ui.Good.Render(ui.IconBox+" Created project")+" "+fmt.Sprintf("#%d %s", res.TaskID, created.Title)+" "+ui.Muted.Render("("+ui.StatusText(created.Status)+")")+tagsSuffix(engine.TaskTags{Own: tags})+blockedSuffix(blockers))
 				return nil
 			}
 
@@ -67,19 +121,31 @@ func newAddCmd() *cobra.Command {
 			}
 			d := engine.Difficulty(diff)
 
+			dueDate := q.DueDate
+			if due != "" {
+				d, err := engine.ParseDueDate(due, time.Now())
+				if err != nil {
+					return err
+				}
+				dueDate = &d
+			}
+
 			var interval engine.HabitInterval
 			var duration *time.Duration
 			var goal *int
 			if isHabit {
-				parsed, err := engine.ParseHabitInterval(habitInterval)
-				if err != nil {
-					return err
+				interval = q.Interval
+				if interval == "" {
+					parsed, err := engine.ParseHabitInterval(habitInterval)
+					if err != nil {
+						return err
+					}
+					interval = parsed
 				}
-				interval = parsed
 
 				// Parse duration (e.g., "7d", "1w", "30d", "1m")
 				if habitDuration != "" {
-					dur, err := parseDuration(habitDuration)
+					dur, err := engine.ParseDuration(habitDuration)
 					if err != nil {
 						return fmt.Errorf("invalid duration: %w", err)
 					}
@@ -98,14 +164,20 @@ func newAddCmd() *cobra.Command {
 				Attribute:     primaryAttr,
 				Attributes:    attrWeights,
 				ParentID:      parent,
+				DueDate:       dueDate,
 				IsHabit:       isHabit,
 				HabitInterval: interval,
 				HabitDuration: duration,
 				HabitGoal:     goal,
+				After:         after,
+				Tags:          tags,
 			})
 			if err != nil {
 				return err
 			}
+			if structuredOutput() {
+				return printView(cmd.OutOrStdout(), engine.NewCreateView(res), nil)
+			}
 			created, err := svc.TaskRepo().Get(ctx, res.TaskID)
 			if err != nil {
 				return err
@@ -122,10 +194,22 @@ func newAddCmd() *cobra.Command {
 			if res.ProjectActivated {
 				line += " " + ui.Gold.Render("⚡ project activated")
 			}
+			if created.HabitInterval != nil {
+				line += " " + ui.Muted.Render("· "+*created.HabitInterval)
+			}
 			if goal != nil {
 				line += " " + ui.Muted.Render(fmt.Sprintf("[0/%d]", *goal))
 			}
+			if created.DueDate != nil {
+				line += " " + ui.DueChip(*created.DueDate, time.Now())
+			}
+			blockers, err := svc.OpenBlockers(ctx, res.TaskID)
+			if err != nil {
+				return err
+			}
+			line += tagsSuffix(engine.TaskTags{Own: tags}) + blockedSuffix(blockers)
 			fmt.Fprintln(cmd.OutOrStdout(), line)
+			printAchievements(cmd.OutOrStdout(), res.Achievements)
 			return nil
 		},
 	}
@@ -135,36 +219,35 @@ func newAddCmd() *cobra.Command {
 	cmd.Flags().Int64VarP(&parentID, "parent", "p", 0, "Parent task ID (subtasks/projects)")
 	cmd.Flags().BoolVar(&isProject, "project", false, "Create a project container (requires unlock)")
 	cmd.Flags().BoolVar(&isHabit, "habit", false, "Create a recurring habit (requires unlock)")
-	cmd.Flags().StringVar(&habitInterval, "interval", "daily", "Habit interval (daily|weekly|monthly)")
+	cmd.Flags().StringVar(&habitInterval, "interval", "daily", "Habit schedule: daily, weekly, monthly, every 2 days, mon/wed/fri, weekdays, 3x/week, first mon of month, day 15 of month")
 	cmd.Flags().StringVar(&habitDuration, "duration", "", "Habit duration (e.g., 7d, 1w, 30d, 1m)")
 	cmd.Flags().IntVar(&habitGoal, "goal", 0, "Target completions to finish the habit")
+	cmd.Flags().StringVar(&due, "due", "", "Due date (today, tomorrow, fri, +3d, 2006-01-02)")
+	cmd.Flags().StringVar(&tagList, "tag", "", "Tag(s), comma-separated (e.g., work,urgent); subtasks inherit them")
+	cmd.Flags().Int64SliceVar(&after, "after", nil, "Task ID(s) that must be done first (repeatable or comma-separated)")
+	cmd.Flags().BoolVar(&literal, "literal", false, "Take the title as typed, without quick-add tokens")
 
 	return cmd
 }
 
-// parseDuration parses a duration string like "7d", "1w", "30d", "1m"
-func parseDuration(s string) (time.Duration, error) {
-	if len(s) < 2 {
-		return 0, fmt.Errorf("duration too short: %s", s)
-	}
-	unit := s[len(s)-1]
-	numStr := s[:len(s)-1]
-	var num int
-	if _, err := fmt.Sscanf(numStr, "%d", &num); err != nil {
-		return 0, fmt.Errorf("invalid number in duration: %s", s)
+// quickAddConflicts rejects a field set both by a quick-add token and by its
+// flag. Tags add up, so they never conflict.
+func quickAddConflicts(cmd *cobra.Command, q engine.QuickAdd) error {
+	pairs := []struct {
+		set   bool
+		token string
+		flag  string
+	}{
+		{q.Difficulty != 0, fmt.Sprintf("!%d", q.Difficulty), "diff"},
+		{q.Attribute != "", "@attribute", "attr"},
+		{q.ParentID != nil, "^parent", "parent"},
+		{q.DueDate != nil, "due:", "due"},
+		{q.Interval != "", "every:", "interval"},
 	}
-	if num <= 0 {
-		return 0, fmt.Errorf("duration must be positive: %s", s)
-	}
-
-	switch unit {
-	case 'd':
-		return time.Duration(num) * 24 * time.Hour, nil
-	case 'w':
-		return time.Duration(num) * 7 * 24 * time.Hour, nil
-	case 'm':
-		return time.Duration(num) * 30 * 24 * time.Hour, nil // Approximate month
-	default:
-		return 0, fmt.Errorf("unknown duration unit: %c (use d/w/m)", unit)
+	for _, p := range pairs {
+		if p.set && cmd.Flags().Changed(p.flag) {
+			return fmt.Errorf("%s and --%s both set the same field; use one", p.token, p.flag)
+		}
 	}
+	return nil
 }
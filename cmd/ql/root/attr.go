@@ -0,0 +1,93 @@
+package root
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"questline/internal/engine"
+	"questline/internal/ui"
+)
+
+func newAttrCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "attr",
+		Short: "List and register attribute tracks",
+	}
+	cmd.AddCommand(readOnly(newAttrListCmd()), newAttrAddCmd())
+	return cmd
+}
+
+func newAttrListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List attribute tracks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			reg, err := svc.Attributes(ctx)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintln(out, ui.H2.Render("📊 Attributes"))
+			for _, a := range reg.All() {
+				line := fmt.Sprintf("- %s %s %s", a.Icon, ui.AttrStyle(a.Color).Render(string(a.Code)), a.Name)
+				if len(a.Aliases) > 0 {
+					line += " " + ui.Muted.Render("(aliases: "+strings.Join(a.Aliases, ", ")+")")
+				}
+				if !a.Builtin {
+					line += " " + ui.Muted.Render("[custom]")
+				}
+				fmt.Fprintln(out, line)
+			}
+			return nil
+		},
+	}
+}
+
+func newAttrAddCmd() *cobra.Command {
+	var name, icon, color string
+	var aliases []string
+
+	cmd := &cobra.Command{
+		Use:   "add <CODE>",
+		Short: "Register a custom attribute track",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			def, err := svc.AddAttribute(ctx, engine.AddAttributeInput{
+				Code:    args[0],
+				Name:    name,
+				Icon:    icon,
+				Aliases: aliases,
+				Color:   color,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), ui.Good.Render(ui.IconPlus+" Added attribute")+" "+strings.TrimSpace(def.Icon+" "+ui.AttrStyle(def.Color).Render(string(def.Code)))+" "+ui.Muted.Render("("+def.Name+")"))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Display name (defaults to the code)")
+	cmd.Flags().StringVar(&icon, "icon", "", "Icon shown next to the attribute")
+	cmd.Flags().StringSliceVar(&aliases, "alias", nil, "Alternative names accepted by --attr (comma-separated)")
+	cmd.Flags().StringVar(&color, "color", "", "Color for the attribute label (ANSI number or #RRGGBB)")
+	return cmd
+}
@@ -0,0 +1,426 @@
+package root
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"questline/internal/engine"
+	"questline/internal/ui"
+)
+
+func newBlueprintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "blueprint",
+		Short: "Browse, preview and lint blueprints",
+	}
+	cmd.AddCommand(withOutput(readOnly(newBlueprintListCmd())), readOnly(newBlueprintShowCmd()), readOnly(newBlueprintPreviewCmd()), readOnly(newBlueprintValidateCmd()))
+	return cmd
+}
+
+var blueprintStatusIcons = map[engine.BlueprintStatus]string{
+	engine.BlueprintLocked:    "🔒",
+	engine.BlueprintAvailable: "🟢",
+	engine.BlueprintActive:    "🟣",
+	engine.BlueprintCompleted: "🏁",
+}
+
+func newBlueprintListCmd() *cobra.Command {
+	var status, kind, attr string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List blueprints, with the requirements of locked ones",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			status = strings.ToLower(strings.TrimSpace(status))
+			switch engine.BlueprintStatus(status) {
+			case "", engine.BlueprintLocked, engine.BlueprintAvailable, engine.BlueprintActive, engine.BlueprintCompleted:
+			default:
+				return fmt.Errorf("invalid --status %q (locked, available, active, completed)", status)
+			}
+			kind = strings.ToLower(strings.TrimSpace(kind))
+			switch engine.BlueprintKind(kind) {
+			case "", engine.BlueprintKindTask, engine.BlueprintKindHabit, engine.BlueprintKindProject:
+			default:
+				return fmt.Errorf("invalid --kind %q (task, habit, project)", kind)
+			}
+			var attrCode engine.Attribute
+			if attr != "" {
+				reg, err := svc.Attributes(ctx)
+				if err != nil {
+					return err
+				}
+				d, ok := reg.Lookup(attr)
+				if !ok {
+					return fmt.Errorf("unknown attribute %q (see ql attr list)", attr)
+				}
+				attrCode = d.Code
+			}
+
+			infos, err := svc.Blueprints(ctx)
+			if err != nil {
+				return err
+			}
+			var shown []*engine.BlueprintInfo
+			for i := range infos {
+				b := &infos[i]
+				if status != "" && string(b.Status) != status {
+					continue
+				}
+				if kind != "" && string(b.Def.Kind) != kind {
+					continue
+				}
+				if attrCode != "" && !blueprintHasAttribute(b, attrCode) {
+					continue
+				}
+				shown = append(shown, b)
+			}
+			if structuredOutput() {
+				v := engine.BlueprintListView{Version: engine.ViewVersion, Blueprints: []engine.BlueprintView{}}
+				for _, b := range shown {
+					v.Blueprints = append(v.Blueprints, engine.NewBlueprintView(b))
+				}
+				return printView(cmd.OutOrStdout(), v, blueprintTable(v.Blueprints))
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintln(out, ui.Heading(ui.IconScroll, "Blueprints"))
+			for _, b := range shown {
+				line := fmt.Sprintf("%s %s %s %s", blueprintStatusIcons[b.Status], ui.Key.Render(b.Def.Code), b.Def.Title,
+					ui.Muted.Render(fmt.Sprintf("(%s, %s)", b.Def.Kind, b.Def.Attribute)))
+				switch b.Status {
+				case engine.BlueprintLocked:
+					var unmet []string
+					for _, r := range b.Unmet() {
+						unmet = append(unmet, r.Text)
+					}
+					if len(unmet) > 0 {
+						line += " " + ui.Warn.Render("needs "+strings.Join(unmet, ", "))
+					}
+				case engine.BlueprintActive, engine.BlueprintCompleted:
+					line += " " + blueprintProgressText(b.Progress)
+					if b.Status == engine.BlueprintCompleted && b.Def.Repeatable {
+						line += " " + ui.Muted.Render("(repeatable)")
+					}
+				}
+				fmt.Fprintln(out, line)
+			}
+			if len(shown) == 0 {
+				fmt.Fprintln(out, ui.Muted.Render("(no blueprints match)"))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&status, "status", "", "Only blueprints with this status: locked, available, active, completed")
+	cmd.Flags().StringVar(&kind, "kind", "", "Only blueprints of this kind: task, habit, project")
+	cmd.Flags().StringVar(&attr, "attr", "", "Only blueprints that train this attribute (code or alias)")
+
+	return cmd
+}
+
+// blueprintTable is the CSV of ql blueprint list: one row per blueprint.
+func blueprintTable(views []engine.BlueprintView) [][]string {
+	rows := [][]string{{"code", "kind", "title", "status", "repeatable", "can_accept", "xp", "bonus", "task_id", "done", "total", "completed_at"}}
+	for _, b := range views {
+		taskID, completed := "", ""
+		if b.TaskID != nil {
+			taskID = strconv.FormatInt(*b.TaskID, 10)
+		}
+		if b.CompletedAt != nil {
+			completed = b.CompletedAt.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{
+			b.Code, b.Kind, b.Title, b.Status, strconv.FormatBool(b.Repeatable), strconv.FormatBool(b.CanAccept),
+			strconv.Itoa(b.XP), strconv.Itoa(b.Bonus), taskID, strconv.Itoa(b.Done), strconv.Itoa(b.Total), completed,
+		})
+	}
+	return rows
+}
+
+// blueprintHasAttribute reports whether the blueprint or any of its children
+// awards XP to attr.
+func blueprintHasAttribute(b *engine.BlueprintInfo, attr engine.Attribute) bool {
+	nodes := append([]engine.BlueprintNode{b.Tree}, b.Tree.Children...)
+	for _, n := range nodes {
+		if n.Attribute == attr {
+			return true
+		}
+		if _, ok := n.Attributes[attr]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func newBlueprintShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show <code>",
+		Short: "Show a blueprint: requirements, what it creates and the XP it is worth",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			b, err := svc.BlueprintInfo(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintln(out, ui.Heading(ui.IconScroll, b.Def.Code))
+			if b.Def.Description != "" {
+				fmt.Fprintln(out, ui.Muted.Render(b.Def.Description))
+			}
+			source := "built-in"
+			if b.Def.Source != "" {
+				source = b.Def.Source
+			}
+			statusText := blueprintStatusIcons[b.Status] + " " + string(b.Status)
+			if b.Def.Repeatable {
+				statusText += " " + ui.Muted.Render("(repeatable)")
+			}
+			fmt.Fprintln(out, ui.LabelValue("Status", statusText))
+			fmt.Fprintln(out, ui.LabelValue("Source", source))
+			if b.Progress != nil {
+				progress := blueprintProgressText(b.Progress)
+				if b.Progress.Runs > 1 {
+					progress += " " + ui.Muted.Render(fmt.Sprintf("(run %d)", b.Progress.Runs))
+				}
+				fmt.Fprintln(out, ui.LabelValue("Progress", progress))
+			}
+			fmt.Fprintln(out, "")
+
+			fmt.Fprintln(out, ui.H2.Render("Requirements"))
+			if len(b.Requirements) == 0 {
+				fmt.Fprintln(out, ui.Muted.Render("(none)"))
+			}
+			for _, r := range b.Requirements {
+				if r.Met {
+					fmt.Fprintf(out, "- %s %s\n", ui.Good.Render("✓"), r.Text)
+				} else {
+					fmt.Fprintf(out, "- %s %s\n", ui.Bad.Render("✗"), r.Text)
+				}
+			}
+			fmt.Fprintln(out, "")
+
+			fmt.Fprintln(out, ui.H2.Render("Creates"))
+			fmt.Fprintln(out, blueprintNodeLine(b.Tree))
+			for i, c := range b.Tree.Children {
+				branch := "├─ "
+				if i == len(b.Tree.Children)-1 {
+					branch = "└─ "
+				}
+				fmt.Fprintln(out, branch+blueprintNodeLine(c))
+			}
+			fmt.Fprintln(out, "")
+
+			xp := fmt.Sprintf("%d XP", b.XP)
+			if b.Bonus > 0 {
+				xp += fmt.Sprintf(" + %d XP blueprint bonus", b.Bonus)
+			}
+			fmt.Fprintln(out, ui.LabelValue("XP preview", ui.Gold.Render(xp)+" "+ui.Muted.Render("(at your current levels; habits count once)")))
+			if b.CanAccept() {
+				fmt.Fprintf(out, "%s %s\n", ui.Muted.Render("💡"), ui.Key.Render("ql accept "+b.Def.Code))
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+var difficultyNames = []string{"trivial", "easy", "medium", "hard", "epic"}
+
+// blueprintNodeLine renders one task, habit or project a blueprint creates.
+func blueprintNodeLine(n engine.BlueprintNode) string {
+	var details []string
+	if n.Kind != engine.BlueprintKindProject && n.Difficulty.IsValid() {
+		details = append(details, difficultyNames[n.Difficulty-1])
+	}
+	details = append(details, string(n.Attribute))
+	if n.HabitEvery != "" {
+		details = append(details, string(n.HabitEvery))
+	}
+	if n.HabitGoal != nil {
+		details = append(details, fmt.Sprintf("goal %d", *n.HabitGoal))
+	}
+	line := fmt.Sprintf("%s %s %s", ui.KindIcon(n.Kind == engine.BlueprintKindProject, n.Kind == engine.BlueprintKindHabit), n.Title, ui.Muted.Render("("+strings.Join(details, ", ")+")"))
+	if n.Kind != engine.BlueprintKindProject {
+		line += " " + ui.Gold.Render(fmt.Sprintf("+%d XP", n.XP))
+	}
+	return line
+}
+
+func newBlueprintPreviewCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "preview <code>",
+		Short: "Dry-run accepting a blueprint",
+		Long: `Dry-run accepting a blueprint: every check ql accept makes runs, and the
+tasks it would create are listed, but nothing is saved.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			return printAcceptDryRun(ctx, cmd.OutOrStdout(), svc, args[0])
+		},
+	}
+}
+
+// printAcceptDryRun lists what accepting code would create.
+func printAcceptDryRun(ctx context.Context, out io.Writer, svc *engine.Service, code string) error {
+	tasks, err := svc.AcceptBlueprintDryRun(ctx, code)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s %s\n", ui.Good.Render(ui.IconScroll+" Would accept"), ui.Muted.Render(code+" (dry run, nothing saved)"))
+	for i, t := range tasks {
+		prefix := ""
+		if i > 0 {
+			prefix = "├─ "
+			if i == len(tasks)-1 {
+				prefix = "└─ "
+			}
+		}
+		line := fmt.Sprintf("%s%s %s %s", prefix, ui.KindIcon(t.IsProject, t.IsHabit), t.Title, ui.Muted.Render("("+ui.StatusText(t.Status)+")"))
+		if !t.IsProject {
+			line += " " + ui.Gold.Render(fmt.Sprintf("+%d XP", t.XPValue))
+		}
+		fmt.Fprintln(out, line)
+	}
+	return nil
+}
+
+func newBlueprintValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [path...]",
+		Short: "Lint user blueprint files",
+		Long: `Lint user blueprint files (YAML or JSON).
+
+Without arguments this checks the blueprints directory: $QL_BLUEPRINTS_DIR, or
+~/.config/questline/blueprints. Paths may be files or directories. Besides the
+file format it checks that attributes are registered, codes are unique, and
+unlock conditions name known attributes and blueprints.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			paths := args
+			if len(paths) == 0 {
+				dir, err := engine.ResolveBlueprintsDir()
+				if err != nil {
+					return err
+				}
+				paths = []string{dir}
+			}
+
+			files, err := dataFiles(paths)
+			if err != nil {
+				return fmt.Errorf("blueprint path: %w", err)
+			}
+
+			var defs []engine.BlueprintDef
+			var problems []error
+			counts := map[string]int{}
+			for _, f := range files {
+				d, errs := engine.LoadBlueprintFile(f)
+				defs = append(defs, d...)
+				problems = append(problems, errs...)
+				counts[f] = len(d)
+			}
+
+			rules, err := loadRules()
+			if err != nil {
+				return err
+			}
+			db, cleanup, err := openDB(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			more, err := engine.NewServiceWithRules(db, rules).ValidateBlueprints(ctx, defs)
+			if err != nil {
+				return err
+			}
+			problems = append(problems, more...)
+
+			out := cmd.OutOrStdout()
+			if len(files) == 0 {
+				fmt.Fprintln(out, ui.Muted.Render("No blueprint files in "+strings.Join(paths, ", ")))
+				return nil
+			}
+			printFileProblems(out, files, problems, counts, "blueprint(s)")
+			if len(problems) > 0 {
+				return fmt.Errorf("%d problem(s) in blueprint files", len(problems))
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// dataFiles expands paths into the .yaml, .yml and .json files they name;
+// directories contribute the data files directly inside them.
+func dataFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		for _, pattern := range []string{"*.yaml", "*.yml", "*.json"} {
+			matches, err := filepath.Glob(filepath.Join(p, pattern))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matches...)
+		}
+	}
+	return files, nil
+}
+
+// printFileProblems prints one line per linted file, followed by the problems
+// reported for it (those prefixed with its path).
+func printFileProblems(out io.Writer, files []string, problems []error, counts map[string]int, noun string) {
+	for _, f := range files {
+		var mine []string
+		for _, p := range problems {
+			if msg := p.Error(); strings.HasPrefix(msg, f+":") {
+				mine = append(mine, strings.TrimPrefix(msg, f+": "))
+			}
+		}
+		if len(mine) == 0 {
+			fmt.Fprintf(out, "%s %s\n", ui.Good.Render(ui.IconDone+" "+f), ui.Muted.Render(fmt.Sprintf("(%d %s)", counts[f], noun)))
+			continue
+		}
+		fmt.Fprintln(out, ui.Bad.Render(ui.IconError+" "+f))
+		for _, m := range mine {
+			fmt.Fprintf(out, "  - %s\n", m)
+		}
+	}
+}
@@ -3,9 +3,15 @@ package root
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
 
 	"questline/internal/engine"
 	"questline/internal/storage"
+	"questline/internal/ui"
 )
 
 func openDB(ctx context.Context) (*sql.DB, func(), error) {
@@ -24,9 +30,217 @@ func openDB(ctx context.Context) (*sql.DB, func(), error) {
 }
 
 func openService(ctx context.Context) (*engine.Service, func(), error) {
+	rules, err := loadRules()
+	if err != nil {
+		return nil, nil, err
+	}
 	db, cleanup, err := openDB(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	return engine.NewService(db), cleanup, nil
+	svc := engine.NewServiceWithRules(db, rules)
+	if err := loadUserBlueprints(svc); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if err := loadUserAchievements(svc); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if readOnlyCommand {
+		return svc, cleanup, nil
+	}
+	rep, err := svc.EvaluateHP(ctx)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	// Scripts read the HP from the views; stderr stays free for the error view.
+	if !structuredOutput() {
+		printHPReport(os.Stderr, rep)
+	}
+	return svc, cleanup, nil
+}
+
+// annotationReadOnly marks the commands that only look at the database.
+const annotationReadOnly = "readonly"
+
+// readOnlyCommand is set while a read-only command runs. openService then
+// leaves the HP damage due for the next command that changes something, so
+// listing or exporting never writes.
+var readOnlyCommand bool
+
+// readOnly marks cmd as only reading the database.
+func readOnly(cmd *cobra.Command) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[annotationReadOnly] = "true"
+	return cmd
+}
+
+// printHPReport tells the player about damage taken since the last command.
+// Rest alone is silent.
+func printHPReport(w io.Writer, rep *engine.HPReport) {
+	if rep.Damage == 0 {
+		return
+	}
+	for _, m := range rep.Missed {
+		fmt.Fprintf(w, "%s %s\n", ui.Bad.Render(fmt.Sprintf("%s -%d HP", ui.IconHeart, m.Damage)), ui.Muted.Render(fmt.Sprintf("missed #%d %s ×%d", m.TaskID, m.Title, m.Misses)))
+	}
+	fmt.Fprintln(w, ui.LabelValue("HP", ui.HPChip(rep.After, rep.Max)))
+	if rep.KnockedOut {
+		fmt.Fprintln(w, ui.Bad.Render(fmt.Sprintf("%s Knocked out: reduced XP until %s", ui.IconError, rep.WeakenedUntil.Local().Format("Mon 2 Jan 15:04"))))
+	}
+}
+
+// loadUserBlueprints registers the blueprints in the blueprints directory.
+// Broken files don't stop the command; they are summarized on stderr.
+func loadUserBlueprints(svc *engine.Service) error {
+	dir, err := engine.ResolveBlueprintsDir()
+	if err != nil {
+		return err
+	}
+	defs, errs := engine.LoadBlueprintDir(dir)
+	errs = append(errs, svc.AddUserBlueprints(defs)...)
+	if len(errs) > 0 && !structuredOutput() {
+		fmt.Fprintln(os.Stderr, ui.Warn.Render(fmt.Sprintf("%s %d blueprint file problem(s); run `ql blueprint validate`", ui.IconWarn, len(errs))))
+	}
+	return nil
+}
+
+// loadUserAchievements registers the achievements in the achievements
+// directory, summarizing broken files on stderr like loadUserBlueprints.
+func loadUserAchievements(svc *engine.Service) error {
+	dir, err := engine.ResolveAchievementsDir()
+	if err != nil {
+		return err
+	}
+	defs, errs := engine.LoadAchievementDir(dir)
+	errs = append(errs, svc.AddUserAchievements(defs)...)
+	if len(errs) > 0 && !structuredOutput() {
+		fmt.Fprintln(os.Stderr, ui.Warn.Render(fmt.Sprintf("%s %d achievement file problem(s); run `ql achievements validate`", ui.IconWarn, len(errs))))
+	}
+	return nil
+}
+
+// loadRules reads the rules file ($QL_RULES_PATH or ~/.config/questline/rules.toml),
+// falling back to the default rules when there is none.
+func loadRules() (*engine.Rules, error) {
+	path, err := engine.ResolveRulesPath()
+	if err != nil {
+		return nil, err
+	}
+	return engine.LoadRulesFile(path)
+}
+
+func newDBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Database maintenance",
+	}
+	cmd.AddCommand(newDBMigrateCmd(), newDBRebuildCmd())
+	return cmd
+}
+
+func newDBMigrateCmd() *cobra.Command {
+	var statusOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending schema migrations",
+		Long: `Apply pending schema migrations to the database.
+
+Every command migrates automatically on startup; use this to inspect the
+schema version or to upgrade a database explicitly.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			path, err := storage.ResolveDBPath()
+			if err != nil {
+				return err
+			}
+			db, err := storage.Connect(ctx, path)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			if !statusOnly {
+				before, err := storage.CurrentSchemaVersion(ctx, db)
+				if err != nil {
+					return err
+				}
+				if err := storage.Migrate(ctx, db); err != nil {
+					return err
+				}
+				after, err := storage.CurrentSchemaVersion(ctx, db)
+				if err != nil {
+					return err
+				}
+				if after == before {
+					fmt.Fprintln(cmd.OutOrStdout(), ui.Muted.Render(fmt.Sprintf("Schema already at version %d", after)))
+				} else {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", ui.Good.Render(ui.IconDone+" Migrated"), ui.Muted.Render(fmt.Sprintf("(version %d → %d)", before, after)))
+				}
+				return nil
+			}
+
+			states, err := storage.MigrationStatus(ctx, db)
+			if err != nil {
+				return err
+			}
+			current, err := storage.CurrentSchemaVersion(ctx, db)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), ui.Heading("🗄️", "Schema Migrations"))
+			fmt.Fprintln(cmd.OutOrStdout(), ui.LabelValue("Database", path))
+			fmt.Fprintln(cmd.OutOrStdout(), ui.LabelValue("Version", fmt.Sprintf("%d (latest %d)", current, storage.LatestSchemaVersion())))
+			if current > storage.LatestSchemaVersion() {
+				fmt.Fprintln(cmd.OutOrStdout(), ui.Bad.Render(ui.IconWarn+" database was migrated by a newer questline"))
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "")
+			for _, st := range states {
+				if st.AppliedAt != nil {
+					fmt.Fprintf(cmd.OutOrStdout(), "- %s %03d %s %s\n", ui.Good.Render("applied"), st.Version, st.Name, ui.Muted.Render(st.AppliedAt.Local().Format("2006-01-02 15:04")))
+				} else {
+					fmt.Fprintf(cmd.OutOrStdout(), "- %s %03d %s\n", ui.Warn.Render("pending"), st.Version, st.Name)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&statusOnly, "status", false, "List applied and pending migrations without applying them")
+
+	return cmd
+}
+
+func newDBRebuildCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rebuild",
+		Short: "Recompute player XP and level from the XP ledger",
+		Long: `Recompute the player row (level, total XP and per-attribute XP) purely
+from the append-only XP ledger. Use this to repair counters that drifted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			res, err := svc.RebuildPlayer(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", ui.Good.Render(ui.IconDone+" Rebuilt player"), ui.Muted.Render(fmt.Sprintf("(%d ledger entries)", res.Entries)))
+			fmt.Fprintln(cmd.OutOrStdout(), ui.LabelValue("Total XP", fmt.Sprintf("%d → %d", res.XPBefore, res.XPAfter)))
+			fmt.Fprintln(cmd.OutOrStdout(), ui.LabelValue("Level", fmt.Sprintf("%d → %d", res.LevelBefore, res.LevelAfter)))
+			return nil
+		},
+	}
+
+	return cmd
 }
@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
 
 	"github.com/spf13/cobra"
 
+	"questline/internal/engine"
 	"questline/internal/ui"
 )
 
@@ -38,6 +40,9 @@ func newDoCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if structuredOutput() {
+				return printView(cmd.OutOrStdout(), engine.NewCompleteView(res), nil)
+			}
 
 			name := fmt.Sprintf("#%d", res.TaskID)
 			if before != nil {
@@ -45,16 +50,62 @@ func newDoCmd() *cobra.Command {
 			}
 			line := fmt.Sprintf("%s %s %s", ui.Good.Render(ui.IconDone+" Completed"), name, ui.Muted.Render(fmt.Sprintf("(+%d XP)", res.XPAwarded)))
 			fmt.Fprintln(cmd.OutOrStdout(), line)
+			if res.DeadlineDelta > 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), ui.Good.Render(fmt.Sprintf("⏰ Early bonus +%d XP", res.DeadlineDelta)))
+			} else if res.DeadlineDelta < 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), ui.Warn.Render(fmt.Sprintf("⏰ Late penalty %d XP", res.DeadlineDelta)))
+			}
+			if res.Tracked > 0 {
+				line := ui.Muted.Render(fmt.Sprintf("%s %s tracked", ui.IconTimer, ui.Duration(res.Tracked)))
+				if res.TimeBonus > 0 {
+					line += " " + ui.Good.Render(fmt.Sprintf("+%d XP", res.TimeBonus))
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), line)
+			}
+			if res.Streak > 1 {
+				streak := ui.Gold.Render(fmt.Sprintf("%s %d-period streak", ui.IconFire, res.Streak))
+				if res.StreakBonus > 0 {
+					streak += " " + ui.Muted.Render(fmt.Sprintf("(+%d XP)", res.StreakBonus))
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), streak)
+			}
+			if res.FreezesUsed > 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), ui.Warn.Render(fmt.Sprintf("%s Used %d freeze token(s) to keep the streak alive", ui.IconFreeze, res.FreezesUsed)))
+			}
+			if res.FreezeEarned {
+				fmt.Fprintln(cmd.OutOrStdout(), ui.Good.Render(ui.IconFreeze+" Earned a freeze token"))
+			}
+			if res.WeakenedLoss > 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), ui.Bad.Render(fmt.Sprintf("%s Knocked out: -%d XP", ui.IconError, res.WeakenedLoss)))
+			}
+			if res.HPHealed > 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), ui.Good.Render(fmt.Sprintf("%s +%d HP", ui.IconHeart, res.HPHealed)))
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "%s\n", ui.LabelValue("Level", fmt.Sprintf("%d → %d", res.LevelBefore, res.LevelAfter)))
 			if res.ProjectBonus {
 				fmt.Fprintln(cmd.OutOrStdout(), ui.Gold.Render(ui.IconTrophy+" Project bonus")+" "+ui.Muted.Render(fmt.Sprintf("(volume=%d)", res.ProjectVolume)))
 			}
+			if res.BlueprintCompleted != "" {
+				line := ui.Gold.Render(fmt.Sprintf("%s Blueprint %s completed", ui.IconScroll, res.BlueprintCompleted))
+				if res.BlueprintBonus > 0 {
+					line += " " + ui.Muted.Render(fmt.Sprintf("(+%d XP bonus)", res.BlueprintBonus))
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), line)
+			}
 			if res.LevelUp {
 				fmt.Fprintln(cmd.OutOrStdout(), ui.Gold.Render(ui.IconBolt+" "+ui.BadgeLevelUp))
 			}
+			printAchievements(cmd.OutOrStdout(), res.Achievements)
 			return nil
 		},
 	}
 
 	return cmd
 }
+
+// printAchievements announces newly earned achievements.
+func printAchievements(w io.Writer, earned []engine.Achievement) {
+	for _, a := range earned {
+		fmt.Fprintf(w, "%s %s\n", ui.Gold.Render(fmt.Sprintf("%s Achievement unlocked: %s %s", ui.IconTrophy, a.Icon, a.Name)), ui.Muted.Render("("+a.Description+")"))
+	}
+}
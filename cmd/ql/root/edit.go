@@ -0,0 +1,232 @@
+package root
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"questline/internal/engine"
+	"questline/internal/ui"
+)
+
+func newEditCmd() *cobra.Command {
+	var title, desc, attr, due, interval, duration string
+	var diff, goal int
+	var parentID int64
+	var useEditor bool
+
+	cmd := &cobra.Command{
+		Use:   "edit <id>",
+		Short: "Change a task's title, attributes, parent, due date or schedule",
+		Long: `Change a task, habit or project.
+
+  ql edit 12 --title "Read two chapters" --diff 3
+  ql edit 12 --attr str:70,out:30
+  ql edit 12 --parent 4        # move under #4 (--parent 0: top level)
+  ql edit 12 --due fri         # --due none clears it
+  ql edit 7 --interval mon/wed/fri --duration 30d --goal 12
+
+Without flags (or with --editor) the task opens in $EDITOR as YAML; save
+to apply the changes, or leave it untouched to cancel. Changing the
+difficulty or attributes recalculates the task's XP.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("id is required")
+			}
+			if _, err := strconv.ParseInt(args[0], 10, 64); err != nil {
+				return errors.New("id must be an integer")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			id, _ := strconv.ParseInt(args[0], 10, 64)
+			task, err := svc.TaskRepo().Get(ctx, id)
+			if err != nil {
+				return err
+			}
+			if task == nil {
+				return fmt.Errorf("task %d %w", id, engine.ErrNotFound)
+			}
+			reg, err := svc.Attributes(ctx)
+			if err != nil {
+				return err
+			}
+
+			now := time.Now()
+			var patch engine.TaskPatch
+			flags := cmd.Flags()
+			if useEditor || flags.NFlag() == 0 {
+				orig := engine.NewTaskDoc(task, now.Location())
+				edited, err := editTaskDoc(cmd, id, orig)
+				if err != nil {
+					return err
+				}
+				if edited == orig {
+					fmt.Fprintln(cmd.OutOrStdout(), ui.Muted.Render("No changes"))
+					return nil
+				}
+				if patch, err = edited.Patch(reg, orig, now); err != nil {
+					return err
+				}
+			} else {
+				if flags.Changed("title") {
+					patch.Title = &title
+				}
+				if flags.Changed("desc") {
+					patch.Description = &desc
+				}
+				if flags.Changed("diff") {
+					d := engine.Difficulty(diff)
+					patch.Difficulty = &d
+				}
+				if flags.Changed("attr") {
+					primary, weights, err := reg.ParseWeightsStrict(attr)
+					if err != nil {
+						return err
+					}
+					patch.Attribute = &primary
+					patch.Attributes = weights
+				}
+				if flags.Changed("parent") {
+					patch.ParentID = &parentID
+				}
+				if flags.Changed("due") {
+					if due == "none" {
+						patch.ClearDue = true
+					} else {
+						d, err := engine.ParseDueDate(due, now)
+						if err != nil {
+							return err
+						}
+						patch.DueDate = &d
+					}
+				}
+				if flags.Changed("interval") {
+					parsed, err := engine.ParseHabitInterval(interval)
+					if err != nil {
+						return err
+					}
+					patch.HabitInterval = &parsed
+				}
+				if flags.Changed("duration") {
+					var dur time.Duration
+					if duration != "none" {
+						if dur, err = engine.ParseDuration(duration); err != nil {
+							return fmt.Errorf("invalid duration: %w", err)
+						}
+					}
+					patch.HabitDuration = &dur
+				}
+				if flags.Changed("goal") {
+					patch.HabitGoal = &goal
+				}
+			}
+
+			res, err := svc.UpdateTask(ctx, id, patch)
+			if err != nil {
+				return err
+			}
+			if len(res.Changed) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), ui.Muted.Render("No changes"))
+				return nil
+			}
+			updated, err := svc.TaskRepo().Get(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			line := ui.Good.Render(ui.IconEdit+" Updated") + " " + fmt.Sprintf("%s #%d %s", ui.KindIcon(updated.IsProject, updated.IsHabit), id, updated.Title)
+			line += " " + ui.Muted.Render("("+strings.Join(res.Changed, ", ")+")")
+			if res.XPAfter != res.XPBefore {
+				line += " " + ui.Muted.Render(fmt.Sprintf("+%d → +%d XP", res.XPBefore, res.XPAfter))
+			}
+			if res.ProjectActivated {
+				line += " " + ui.Gold.Render("⚡ project activated")
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), line)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&title, "title", "", "New title")
+	cmd.Flags().StringVar(&desc, "desc", "", "Description (empty to clear)")
+	cmd.Flags().IntVarP(&diff, "diff", "d", 0, "Difficulty (1-5)")
+	cmd.Flags().StringVarP(&attr, "attr", "a", "", "Attribute(s): single (str) or multi (str:50,int:50)")
+	cmd.Flags().Int64VarP(&parentID, "parent", "p", 0, "Move under this task (0 for the top level)")
+	cmd.Flags().StringVar(&due, "due", "", "Due date (today, tomorrow, fri, +3d, 2006-01-02, or none)")
+	cmd.Flags().StringVar(&interval, "interval", "", "Habit schedule (see ql add --help)")
+	cmd.Flags().StringVar(&duration, "duration", "", "Habit duration from its start (e.g., 30d, 4w, or none)")
+	cmd.Flags().IntVar(&goal, "goal", 0, "Target completions to finish the habit (0 to remove)")
+	cmd.Flags().BoolVarP(&useEditor, "editor", "e", false, "Edit the task as YAML in $EDITOR")
+
+	return cmd
+}
+
+// editTaskDoc opens doc in the user's editor and returns the saved version.
+func editTaskDoc(cmd *cobra.Command, id int64, doc engine.TaskDoc) (engine.TaskDoc, error) {
+	data, err := doc.YAML()
+	if err != nil {
+		return doc, err
+	}
+	f, err := os.CreateTemp("", fmt.Sprintf("ql-task-%d-*.yaml", id))
+	if err != nil {
+		return doc, err
+	}
+	defer os.Remove(f.Name())
+	header := fmt.Sprintf("# Task #%d. Save to apply your changes; remove a line to clear that field.\n", id)
+	if _, err := f.WriteString(header); err != nil {
+		f.Close()
+		return doc, err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return doc, err
+	}
+	if err := f.Close(); err != nil {
+		return doc, err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	argv := append(strings.Fields(editor), f.Name())
+	run := exec.Command(argv[0], argv[1:]...)
+	run.Stdin = os.Stdin
+	run.Stdout = cmd.OutOrStdout()
+	run.Stderr = cmd.ErrOrStderr()
+	if err := run.Run(); err != nil {
+		return doc, fmt.Errorf("editor %s: %w", argv[0], err)
+	}
+
+	edited, err := os.ReadFile(f.Name())
+	if err != nil {
+		return doc, err
+	}
+	if bytes.Equal(bytes.TrimSpace(edited), bytes.TrimSpace(append([]byte(header), data...))) {
+		return doc, nil
+	}
+	out, err := engine.ParseTaskDoc(edited)
+	if err != nil {
+		return doc, fmt.Errorf("task #%d: %w", id, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,65 @@
+package root
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"questline/internal/ui"
+)
+
+func newExportCmd() *cobra.Command {
+	var format string
+	var outPath string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the whole questline (player, tasks, history) as a document",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "json" {
+				return fmt.Errorf("unsupported export format %q (supported: json)", format)
+			}
+
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			doc, err := svc.Export(ctx)
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = cmd.OutOrStdout()
+			if outPath != "" && outPath != "-" {
+				f, err := os.Create(outPath)
+				if err != nil {
+					return fmt.Errorf("create export file: %w", err)
+				}
+				defer f.Close()
+				w = f
+			}
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(doc); err != nil {
+				return fmt.Errorf("write export: %w", err)
+			}
+
+			if w != cmd.OutOrStdout() {
+				fmt.Fprintln(cmd.ErrOrStderr(), ui.Good.Render(ui.IconBox+" Exported")+" "+fmt.Sprintf("%d tasks, %d completions to %s", len(doc.Tasks), len(doc.Completions), outPath))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "json", "Export format (json)")
+	cmd.Flags().StringVarP(&outPath, "out", "o", "", "Write to a file instead of stdout")
+	return cmd
+}
@@ -0,0 +1,76 @@
+package root
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"questline/internal/engine"
+	"questline/internal/ui"
+)
+
+func newImportCmd() *cobra.Command {
+	var merge bool
+
+	cmd := &cobra.Command{
+		Use:   "import <file|->",
+		Short: "Import a questline export (into an empty DB, or --merge into this one)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var r io.Reader = cmd.InOrStdin()
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return fmt.Errorf("open import file: %w", err)
+				}
+				defer f.Close()
+				r = f
+			}
+			var doc engine.ExportDoc
+			if err := json.NewDecoder(r).Decode(&doc); err != nil {
+				return fmt.Errorf("read import: %w", err)
+			}
+
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			res, err := svc.Import(ctx, &doc, merge)
+			if err != nil {
+				return err
+			}
+
+			label := "Imported"
+			if res.Merged {
+				label = "Merged"
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), ui.Good.Render(ui.IconDone+" "+label)+" "+fmt.Sprintf("%d tasks, %d completions, %d ledger entries", res.Tasks, res.Completions, res.LedgerEntries))
+			if res.Achievements > 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), ui.Muted.Render(fmt.Sprintf("Restored %d achievements", res.Achievements)))
+			}
+			if res.Dependencies > 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), ui.Muted.Render(fmt.Sprintf("Restored %d task dependencies", res.Dependencies)))
+			}
+			if res.Reviews > 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), ui.Muted.Render(fmt.Sprintf("Restored %d reviews", res.Reviews)))
+			}
+			if res.Sessions > 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), ui.Muted.Render(fmt.Sprintf("Restored %d work sessions", res.Sessions)))
+			}
+			if res.Attributes > 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), ui.Muted.Render(fmt.Sprintf("Added %d custom attributes", res.Attributes)))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&merge, "merge", false, "Merge into a non-empty database (IDs are remapped)")
+	return cmd
+}
@@ -0,0 +1,82 @@
+package root
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"questline/internal/ui"
+)
+
+func newLinkCmd() *cobra.Command {
+	var blocks, after []int64
+	var remove bool
+
+	cmd := &cobra.Command{
+		Use:   "link <id> (--blocks <id> | --after <id>)",
+		Short: "Order tasks: one must be done before another",
+		Long: `Order tasks with dependencies.
+
+  ql link 14 --blocks 15   # #15 can't be completed until #14 is done
+  ql link 15 --after 14    # the same, seen from #15
+  ql link 14 --blocks 15 --remove
+
+A project waits for its tasks, so a task can't wait for its own project.
+Links that would make tasks wait for each other are refused.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("id is required")
+			}
+			if _, err := strconv.ParseInt(args[0], 10, 64); err != nil {
+				return errors.New("id must be an integer")
+			}
+			if len(blocks) == 0 && len(after) == 0 {
+				return errors.New("--blocks or --after is required")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			id, _ := strconv.ParseInt(args[0], 10, 64)
+			// Each pair is (task, blocker).
+			var pairs [][2]int64
+			for _, other := range blocks {
+				pairs = append(pairs, [2]int64{other, id})
+			}
+			for _, other := range after {
+				pairs = append(pairs, [2]int64{id, other})
+			}
+
+			out := cmd.OutOrStdout()
+			for _, p := range pairs {
+				if remove {
+					if err := svc.RemoveDependency(ctx, p[0], p[1]); err != nil {
+						return err
+					}
+					fmt.Fprintf(out, "%s %s\n", ui.Good.Render(ui.IconUndo+" Unlinked"), fmt.Sprintf("#%d no longer waits for #%d", p[0], p[1]))
+					continue
+				}
+				if err := svc.AddDependency(ctx, p[0], p[1]); err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "%s %s\n", ui.Good.Render(ui.IconBlocked+" Linked"), fmt.Sprintf("#%d waits for #%d", p[0], p[1]))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64SliceVar(&blocks, "blocks", nil, "Task ID(s) that wait for this one")
+	cmd.Flags().Int64SliceVar(&after, "after", nil, "Task ID(s) this one waits for")
+	cmd.Flags().BoolVar(&remove, "remove", false, "Remove the links instead of adding them")
+
+	return cmd
+}
@@ -3,17 +3,34 @@ package root
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"questline/internal/engine"
+	"questline/internal/storage"
 	"questline/internal/ui"
 )
 
 func newListCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "list",
+		Use:   "list [filter...]",
 		Short: "List tasks (tree view)",
+		Long: `List tasks as a tree, optionally filtered:
+
+  ql list status:pending attr:str due<fri
+  ql list kind:habit,project -status:done
+  ql list parent:12 diff>=3 tag:work
+  ql list groceries             # words search titles and descriptions
+
+Keys: status (pending, active, planning, done, open), kind (task, habit,
+project, subtask), attr, diff, parent (a task ID or none), due (a date,
+none, any or overdue) and tag (set or inherited). diff and due also
+compare with <, <=, > and >=. Commas match any of several values and a
+leading - negates a term.
+Matching subtasks whose parent doesn't match are listed at the top level.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
 			svc, cleanup, err := openService(ctx)
@@ -22,28 +39,77 @@ func newListCmd() *cobra.Command {
 			}
 			defer cleanup()
 
-			fmt.Fprintln(cmd.OutOrStdout(), ui.Heading(ui.IconQuest, "Quest Log"))
+			reg, err := svc.Attributes(ctx)
+			if err != nil {
+				return err
+			}
+			filter, err := engine.ParseTaskFilter(strings.Join(args, " "), reg, time.Now())
+			if err != nil {
+				return err
+			}
 
-			tasks, err := svc.TaskRepo().ListAll(ctx)
+			tasks, err := svc.FilterTasks(ctx, filter)
 			if err != nil {
 				return err
 			}
+			if structuredOutput() {
+				tree, err := svc.TaskTree(ctx, tasks, time.Now())
+				if err != nil {
+					return err
+				}
+				return printView(cmd.OutOrStdout(), engine.TaskListView{Version: engine.ViewVersion, Tasks: tree}, taskTable(tree))
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), ui.Heading(ui.IconQuest, "Quest Log"))
 			if len(tasks) == 0 {
+				if !filter.Empty() {
+					fmt.Fprintln(cmd.OutOrStdout(), ui.Muted.Render("(no matching tasks)"))
+					return nil
+				}
 				fmt.Fprintln(cmd.OutOrStdout(), ui.Muted.Render("(empty — add your first quest with: ql add \"My first task\")"))
 				return nil
 			}
+			streaks, err := svc.HabitStreaks(ctx)
+			if err != nil {
+				return err
+			}
+			blocked, err := svc.BlockedTasks(ctx)
+			if err != nil {
+				return err
+			}
+			deps, err := svc.Dependencies(ctx)
+			if err != nil {
+				return err
+			}
+			tags, err := svc.TaskTags(ctx)
+			if err != nil {
+				return err
+			}
+			now := time.Now()
 			children := map[int64][]int64{}
 			roots := []int64{}
 			byID := map[int64]int{}
 			for i := range tasks {
 				byID[tasks[i].ID] = i
+			}
+			for i := range tasks {
 				if tasks[i].ParentID == nil {
 					roots = append(roots, tasks[i].ID)
 					continue
 				}
 				pid := *tasks[i].ParentID
+				// Subtasks of a filtered-out parent are listed at the top level.
+				if _, ok := byID[pid]; !ok {
+					roots = append(roots, tasks[i].ID)
+					continue
+				}
 				children[pid] = append(children[pid], tasks[i].ID)
 			}
+			// Siblings are listed in the order they can be done in.
+			roots = engine.OrderByDependencies(roots, deps)
+			for pid := range children {
+				children[pid] = engine.OrderByDependencies(children[pid], deps)
+			}
 
 			var render func(id int64, prefix string, isLast bool)
 			render = func(id int64, prefix string, isLast bool) {
@@ -56,7 +122,7 @@ func newListCmd() *cobra.Command {
 				}
 
 				icon := ui.KindIcon(t.IsProject, t.IsHabit)
-				line := fmt.Sprintf("%s%s%s #%d %s %s", prefix, branch, icon, t.ID, t.Title, ui.Muted.Render("("+ui.StatusText(t.Status)+")"))
+				line := fmt.Sprintf("%s%s%s #%d %s %s%s", prefix, branch, icon, t.ID, t.Title, ui.Muted.Render("("+ui.StatusText(t.Status)+")"), dueSuffix(&t, now)+habitSuffix(ctx, svc, &t, now)+streakSuffix(streaks[t.ID])+tagsSuffix(tags[t.ID])+blockedSuffix(blocked[t.ID]))
 				fmt.Fprintln(cmd.OutOrStdout(), strings.TrimSpace(line))
 
 				kids := children[id]
@@ -69,7 +135,7 @@ func newListCmd() *cobra.Command {
 				// Render roots without the leading branch so the tree is stable.
 				rootTask := tasks[byID[roots[i]]]
 				icon := ui.KindIcon(rootTask.IsProject, rootTask.IsHabit)
-				fmt.Fprintf(cmd.OutOrStdout(), "%s #%d %s %s\n", icon, rootTask.ID, rootTask.Title, ui.Muted.Render("("+ui.StatusText(rootTask.Status)+")"))
+				fmt.Fprintf(cmd.OutOrStdout(), "%s #%d %s %s%s\n", icon, rootTask.ID, rootTask.Title, ui.Muted.Render("("+ui.StatusText(rootTask.Status)+")"), dueSuffix(&rootTask, now)+habitSuffix(ctx, svc, &rootTask, now)+streakSuffix(streaks[rootTask.ID])+tagsSuffix(tags[rootTask.ID])+blockedSuffix(blocked[rootTask.ID]))
 				kids := children[rootTask.ID]
 				for j := range kids {
 					render(kids[j], "", j == len(kids)-1)
@@ -81,3 +147,95 @@ func newListCmd() *cobra.Command {
 
 	return cmd
 }
+
+// taskTable lays a task tree out as CSV rows, parents before their subtasks.
+func taskTable(tree []engine.TaskView) [][]string {
+	rows := [][]string{{"id", "parent_id", "depth", "kind", "status", "title", "difficulty", "attribute", "xp_value", "due_date", "overdue", "habit_interval", "streak", "tags", "inherited_tags", "blocked_by"}}
+	var walk func(tasks []engine.TaskView, depth int)
+	walk = func(tasks []engine.TaskView, depth int) {
+		for _, t := range tasks {
+			parent, due, interval, streak := "", "", "", ""
+			if t.ParentID != nil {
+				parent = strconv.FormatInt(*t.ParentID, 10)
+			}
+			if t.DueDate != nil {
+				due = t.DueDate.Format(time.RFC3339)
+			}
+			if t.Habit != nil {
+				interval, streak = t.Habit.Interval, strconv.Itoa(t.Habit.Streak)
+			}
+			blockers := make([]string, len(t.BlockedBy))
+			for i, id := range t.BlockedBy {
+				blockers[i] = strconv.FormatInt(id, 10)
+			}
+			rows = append(rows, []string{
+				strconv.FormatInt(t.ID, 10), parent, strconv.Itoa(depth), t.Kind, t.Status, t.Title,
+				strconv.Itoa(t.Difficulty), t.Attribute, strconv.Itoa(t.XPValue), due, strconv.FormatBool(t.Overdue),
+				interval, streak, strings.Join(t.Tags, " "), strings.Join(t.InheritedTags, " "), strings.Join(blockers, " "),
+			})
+			walk(t.Subtasks, depth+1)
+		}
+	}
+	walk(tree, 0)
+	return rows
+}
+
+// dueSuffix renders the due-date chip for an open, non-habit task.
+func dueSuffix(t *storage.Task, now time.Time) string {
+	if t.DueDate == nil || t.IsHabit || t.Status == "done" {
+		return ""
+	}
+	return " " + ui.DueChip(*t.DueDate, now)
+}
+
+// streakSuffix renders a habit's streak chip, if it has a streak.
+func streakSuffix(st engine.HabitStreak) string {
+	if st.Current == 0 {
+		return ""
+	}
+	return " " + ui.StreakChip(st.Current, st.DoneThisPeriod)
+}
+
+// tagsSuffix renders a task's tag chips, inherited ones dimmed.
+func tagsSuffix(tags engine.TaskTags) string {
+	var chips []string
+	for _, tag := range tags.Own {
+		chips = append(chips, ui.TagChip(tag, false))
+	}
+	for _, tag := range tags.Inherited {
+		chips = append(chips, ui.TagChip(tag, true))
+	}
+	if len(chips) == 0 {
+		return ""
+	}
+	return " " + strings.Join(chips, " ")
+}
+
+// blockedSuffix renders the unfinished tasks a task waits for, if any.
+func blockedSuffix(blockers []int64) string {
+	if len(blockers) == 0 {
+		return ""
+	}
+	ids := make([]string, len(blockers))
+	for i, id := range blockers {
+		ids[i] = fmt.Sprintf("#%d", id)
+	}
+	return " " + ui.Warn.Render(ui.IconBlocked+" after "+strings.Join(ids, ", "))
+}
+
+// habitSuffix renders a habit's schedule and, for schedules asking for several
+// completions per period, the progress in the current one.
+func habitSuffix(ctx context.Context, svc *engine.Service, t *storage.Task, now time.Time) string {
+	if !t.IsHabit || t.HabitInterval == nil {
+		return ""
+	}
+	out := " " + ui.Muted.Render("· "+*t.HabitInterval)
+	comps, err := svc.CompletionRepo().ListByTask(ctx, t.ID)
+	if err != nil {
+		return out
+	}
+	if p := engine.GetHabitProgress(t, comps, now); p.PeriodTarget > 1 {
+		out += " " + ui.Muted.Render(fmt.Sprintf("%d/%d", p.PeriodDone, p.PeriodTarget))
+	}
+	return out
+}
@@ -0,0 +1,190 @@
+package root
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"questline/internal/engine"
+)
+
+// output is the format chosen with the global --output flag.
+var output = "text"
+
+// annotationOutput marks the commands that print views with --output.
+const annotationOutput = "output"
+
+// errCodeUsage is the error code of bad arguments and flags, next to the
+// engine's ErrCode* codes.
+const errCodeUsage = "usage"
+
+// exitCodes are the exit statuses of failed commands by error code.
+var exitCodes = map[string]int{
+	engine.ErrCodeError:    1,
+	errCodeUsage:           2,
+	engine.ErrCodeNotFound: 3,
+	engine.ErrCodeLocked:   4,
+	engine.ErrCodeConflict: 5,
+}
+
+// usageError is an error in the command line rather than in what it asked for.
+type usageError struct{ err error }
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+// errorCode returns the error code of a failed command.
+func errorCode(err error) string {
+	var usage usageError
+	// Cobra reports unknown commands with a plain error.
+	if errors.As(err, &usage) || strings.HasPrefix(err.Error(), "unknown command ") {
+		return errCodeUsage
+	}
+	return engine.ErrorCode(err)
+}
+
+// withOutput marks cmd as printing views with --output.
+func withOutput(cmd *cobra.Command) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[annotationOutput] = "true"
+	return cmd
+}
+
+// structuredOutput reports whether --output asks for a view rather than text.
+func structuredOutput() bool {
+	switch output {
+	case "json", "yaml", "csv":
+		return true
+	}
+	return false
+}
+
+// checkOutput validates --output for cmd.
+func checkOutput(cmd *cobra.Command) error {
+	if output == "text" {
+		return nil
+	}
+	if !structuredOutput() {
+		return usageError{fmt.Errorf("unknown output format %q (text, json, yaml or csv)", output)}
+	}
+	if cmd.Annotations[annotationOutput] == "" {
+		return usageError{fmt.Errorf("%s prints text only; --output %s works with list, search, status, time, review, add, do, restore, accept, achievements, blueprint list, tag list and trash list", cmd.CommandPath(), output)}
+	}
+	return nil
+}
+
+// markUsageErrors makes the argument checks of cmd and its subcommands return
+// usage errors.
+func markUsageErrors(cmd *cobra.Command) {
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(cmd *cobra.Command, a []string) error {
+			if err := args(cmd, a); err != nil {
+				return usageError{err}
+			}
+			return nil
+		}
+	}
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error { return usageError{err} })
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
+
+// printView writes v in the --output format. For CSV, table is a header row
+// and the data rows; without one, every field of v is listed as a key,value
+// row, with nested keys joined by dots (player.hp, achievements.0.id).
+func printView(w io.Writer, v any, table [][]string) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	switch output {
+	case "json":
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, data, "", "  "); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		_, err := w.Write(buf.Bytes())
+		return err
+	case "yaml":
+		// Going through JSON keeps the field names and order of the json tags.
+		node, err := viewNode(data)
+		if err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(node); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "csv":
+		if table == nil {
+			node, err := viewNode(data)
+			if err != nil {
+				return err
+			}
+			table = [][]string{{"key", "value"}}
+			flattenNode(node, "", &table)
+		}
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(table); err != nil {
+			return err
+		}
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown output format %q", output)
+}
+
+// viewNode parses a JSON document into a YAML node in block style.
+func viewNode(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var plain func(n *yaml.Node)
+	plain = func(n *yaml.Node) {
+		n.Style = 0
+		for _, c := range n.Content {
+			plain(c)
+		}
+	}
+	plain(&doc)
+	return doc.Content[0], nil
+}
+
+func flattenNode(n *yaml.Node, key string, rows *[][]string) {
+	join := func(k string) string {
+		if key == "" {
+			return k
+		}
+		return key + "." + k
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			flattenNode(n.Content[i+1], join(n.Content[i].Value), rows)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			flattenNode(c, join(strconv.Itoa(i)), rows)
+		}
+	default:
+		value := n.Value
+		if n.Tag == "!!null" {
+			value = ""
+		}
+		*rows = append(*rows, []string{key, value})
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"questline/internal/engine"
 	"questline/internal/ui"
 )
 
@@ -46,6 +47,9 @@ Use this to fix accidental completions or when a task was marked done prematurel
 			if err != nil {
 				return err
 			}
+			if structuredOutput() {
+				return printView(cmd.OutOrStdout(), engine.NewRestoreView(res), nil)
+			}
 
 			name := fmt.Sprintf("#%d", res.TaskID)
 			if before != nil {
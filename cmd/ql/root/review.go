@@ -0,0 +1,219 @@
+package root
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"questline/internal/engine"
+	"questline/internal/ui"
+)
+
+func newReviewCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "review [week|month]",
+		Short: "Look back at the past week or month and tidy up stale tasks",
+		Long: `Walk through the past week (or month): completions per attribute, habits
+that fell behind and blueprints in progress. Each stale task (pending for
+` + strconv.Itoa(engine.ReviewStaleDays) + ` days or overdue) can be kept, rescheduled, dropped into the
+trash or re-rated. Saving the review pays review XP once per calendar week
+or month. With --output, only the report is printed, as with --dry-run.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			period := engine.ReviewWeek
+			if len(args) == 1 {
+				var err error
+				if period, err = engine.ParseReviewPeriod(args[0]); err != nil {
+					return err
+				}
+			}
+
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			now := time.Now()
+			rv, err := svc.BuildReview(ctx, period, now)
+			if err != nil {
+				return err
+			}
+			// Saving asks about stale tasks, so scripts only get the report.
+			if structuredOutput() {
+				return printView(cmd.OutOrStdout(), engine.NewReviewView(rv), nil)
+			}
+			reg, err := svc.Attributes(ctx)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			printReview(out, reg, rv, now)
+			if dryRun {
+				return nil
+			}
+
+			actions, err := askReviewActions(cmd.InOrStdin(), out, rv, now)
+			if err != nil {
+				return err
+			}
+			res, err := svc.SaveReview(ctx, period, actions, now)
+			if err != nil {
+				return err
+			}
+
+			line := ui.Good.Render(ui.IconDone + " Review saved")
+			switch {
+			case res.Review.XPAwarded > 0:
+				line += " " + ui.Muted.Render(fmt.Sprintf("(+%d XP)", res.Review.XPAwarded))
+			default:
+				line += " " + ui.Muted.Render(fmt.Sprintf("(review XP already earned this %s)", period))
+			}
+			fmt.Fprintln(out, line)
+			if n := res.Review.Rescheduled + res.Review.Dropped + res.Review.Rerated; n > 0 {
+				fmt.Fprintln(out, ui.Muted.Render(fmt.Sprintf("%d rescheduled · %d dropped · %d re-rated", res.Review.Rescheduled, res.Review.Dropped, res.Review.Rerated)))
+			}
+			if res.LevelAfter != res.LevelBefore {
+				fmt.Fprintf(out, "%s\n", ui.LabelValue("Level", fmt.Sprintf("%d → %d", res.LevelBefore, res.LevelAfter)))
+			}
+			printAchievements(out, res.Achievements)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the review without asking about tasks or saving it")
+
+	return cmd
+}
+
+// printReview prints the report part of a review.
+func printReview(w io.Writer, reg *engine.AttributeRegistry, rv *engine.Review, now time.Time) {
+	title := "Weekly review"
+	if rv.Period == engine.ReviewMonth {
+		title = "Monthly review"
+	}
+	fmt.Fprintln(w, ui.Heading(ui.IconReview, title)+" "+ui.Muted.Render(fmt.Sprintf("%s – %s", rv.Since.Format("Mon 2 Jan"), rv.Until.Format("Mon 2 Jan"))))
+	if rv.Last != nil {
+		fmt.Fprintln(w, ui.Muted.Render("Last "+string(rv.Period)+"ly review: "+rv.Last.CreatedAt.Local().Format("Mon 2 Jan 15:04")))
+	}
+	fmt.Fprintln(w, "")
+
+	fmt.Fprintln(w, ui.H2.Render(fmt.Sprintf("%s %d completions · %d XP", ui.IconDone, rv.Completions, rv.XPEarned)))
+	for _, a := range rv.Attributes {
+		def := reg.Def(a.Attr)
+		fmt.Fprintf(w, "- %s %s %d XP %s\n", def.Icon, ui.AttrStyle(def.Color).Render(string(def.Code)), a.XP, ui.Muted.Render(fmt.Sprintf("(%d)", a.Completions)))
+	}
+	if len(rv.Attributes) == 0 {
+		fmt.Fprintln(w, ui.Muted.Render("(nothing completed)"))
+	}
+	fmt.Fprintln(w, "")
+
+	if len(rv.Behind) > 0 {
+		fmt.Fprintln(w, ui.H2.Render(fmt.Sprintf("%s Habits behind (%d)", ui.IconLoop, len(rv.Behind))))
+		for _, h := range rv.Behind {
+			fmt.Fprintf(w, "- #%d %s %s\n", h.Task.ID, h.Task.Title, ui.Warn.Render(fmt.Sprintf("%d/%d", h.Done, h.Expected)))
+		}
+		fmt.Fprintln(w, "")
+	}
+
+	if len(rv.Blueprints) > 0 {
+		fmt.Fprintln(w, ui.H2.Render(fmt.Sprintf("%s Blueprints in progress (%d)", ui.IconScroll, len(rv.Blueprints))))
+		for i := range rv.Blueprints {
+			fmt.Fprintf(w, "- %s %s\n", ui.Key.Render(rv.Blueprints[i].Code), blueprintProgressText(&rv.Blueprints[i]))
+		}
+		fmt.Fprintln(w, "")
+	}
+
+	if len(rv.Stale) > 0 {
+		fmt.Fprintln(w, ui.H2.Render(fmt.Sprintf("%s Stale tasks (%d)", ui.IconWarn, len(rv.Stale))))
+		for _, st := range rv.Stale {
+			fmt.Fprintf(w, "- %s\n", staleText(st, now))
+		}
+		fmt.Fprintln(w, "")
+	}
+}
+
+func staleText(st engine.StaleTask, now time.Time) string {
+	line := fmt.Sprintf("#%d %s %s", st.Task.ID, st.Task.Title, ui.Muted.Render(fmt.Sprintf("(open %d days, diff %d)", st.AgeDays, st.Task.Difficulty)))
+	if st.Task.DueDate != nil {
+		line += " " + ui.DueChip(*st.Task.DueDate, now)
+	}
+	return line
+}
+
+// askReviewActions asks what to do with each stale task. Running out of input
+// keeps the remaining ones.
+func askReviewActions(r io.Reader, w io.Writer, rv *engine.Review, now time.Time) ([]engine.ReviewAction, error) {
+	in := bufio.NewScanner(r)
+	ask := func(prompt string) (string, bool) {
+		fmt.Fprint(w, prompt)
+		if !in.Scan() {
+			fmt.Fprintln(w)
+			return "", false
+		}
+		return strings.TrimSpace(in.Text()), true
+	}
+
+	var actions []engine.ReviewAction
+	for _, st := range rv.Stale {
+		fmt.Fprintln(w, staleText(st, now))
+	choose:
+		for {
+			answer, ok := ask(ui.Key.Render("  [k]eep, [r]eschedule, [d]rop, re-[a]te? "))
+			if !ok {
+				return actions, in.Err()
+			}
+			switch strings.ToLower(answer) {
+			case "", "k", "keep":
+				break choose
+			case "d", "drop":
+				actions = append(actions, engine.ReviewAction{TaskID: st.Task.ID, Kind: engine.ReviewDrop})
+				break choose
+			case "r", "reschedule":
+				for {
+					v, ok := ask(ui.Key.Render("  Due (today, fri, +3d, 2026-11-01 or none): "))
+					if !ok {
+						return actions, in.Err()
+					}
+					a := engine.ReviewAction{TaskID: st.Task.ID, Kind: engine.ReviewReschedule}
+					if !strings.EqualFold(v, "none") {
+						due, err := engine.ParseDueDate(v, now)
+						if err != nil {
+							fmt.Fprintln(w, ui.Bad.Render("  "+err.Error()))
+							continue
+						}
+						a.Due = &due
+					}
+					actions = append(actions, a)
+					break choose
+				}
+			case "a", "rate", "rerate", "re-rate":
+				for {
+					v, ok := ask(ui.Key.Render("  Difficulty (1-5): "))
+					if !ok {
+						return actions, in.Err()
+					}
+					n, err := strconv.Atoi(v)
+					if err != nil || !engine.Difficulty(n).IsValid() {
+						fmt.Fprintln(w, ui.Bad.Render("  difficulty must be 1-5"))
+						continue
+					}
+					actions = append(actions, engine.ReviewAction{TaskID: st.Task.ID, Kind: engine.ReviewRerate, Difficulty: engine.Difficulty(n)})
+					break choose
+				}
+			default:
+				fmt.Fprintln(w, ui.Bad.Render("  answer k, r, d or a"))
+			}
+		}
+	}
+	return actions, in.Err()
+}
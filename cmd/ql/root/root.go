@@ -6,6 +6,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"questline/internal/engine"
 	"questline/internal/ui"
 )
 
@@ -17,24 +18,54 @@ var rootCmd = &cobra.Command{
 	Long:          "Questline is a local-first CLI/TUI task manager with RPG progression mechanics.",
 	SilenceUsage:  true,
 	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		readOnlyCommand = cmd.Annotations[annotationReadOnly] != ""
+		return checkOutput(cmd)
+	},
 }
 
 func Execute() {
 	rootCmd.Version = Version
 	rootCmd.SetVersionTemplate("{{.Name}} v{{.Version}}\n")
 
+	rootCmd.PersistentFlags().StringVar(&output, "output", "text", "Output format: text, or json, yaml or csv for scripts (see docs/OUTPUT.md)")
+
 	rootCmd.AddCommand(
-		newAddCmd(),
-		newDoCmd(),
-		newRestoreCmd(),
-		newListCmd(),
-		newStatusCmd(),
-		newAcceptCmd(),
+		withOutput(newAddCmd()),
+		withOutput(newDoCmd()),
+		newStartCmd(),
+		newStopCmd(),
+		newFocusCmd(),
+		withOutput(newRestoreCmd()),
+		newEditCmd(),
+		newDeleteCmd(),
+		newTrashCmd(),
+		newLinkCmd(),
+		newTagCmd(),
+		withOutput(readOnly(newListCmd())),
+		withOutput(readOnly(newSearchCmd())),
+		withOutput(readOnly(newStatusCmd())),
+		withOutput(newReviewCmd()),
+		withOutput(readOnly(newTimeCmd())),
+		withOutput(newAcceptCmd()),
+		newBlueprintCmd(),
+		withOutput(readOnly(newAchievementsCmd())),
 		newBoardCmd(),
+		newDBCmd(),
+		newAttrCmd(),
+		readOnly(newExportCmd()),
+		newImportCmd(),
+		newRulesCmd(),
+		newServeCmd(),
 	)
 
+	markUsageErrors(rootCmd)
+
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, ui.Bad.Render(ui.IconError+" "+err.Error()))
-		os.Exit(1)
+		code := errorCode(err)
+		if !structuredOutput() || printView(os.Stderr, engine.NewErrorView(code, err), nil) != nil {
+			fmt.Fprintln(os.Stderr, ui.Bad.Render(ui.IconError+" "+err.Error()))
+		}
+		os.Exit(exitCodes[code])
 	}
 }
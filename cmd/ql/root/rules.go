@@ -0,0 +1,167 @@
+package root
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"questline/internal/engine"
+	"questline/internal/ui"
+)
+
+func newRulesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rules",
+		Short: "Inspect the rule set (XP curve, gates, habit decay, streaks, deadlines)",
+	}
+	cmd.AddCommand(readOnly(newRulesShowCmd()))
+	return cmd
+}
+
+func newRulesShowCmd() *cobra.Command {
+	var preset string
+	var asTOML bool
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective rule values",
+		Long: `Print the effective rule values.
+
+Rules are read from $QL_RULES_PATH, or ~/.config/questline/rules.toml (or
+rules.json) when it exists. A rules file starts from a preset (` + strings.Join(engine.RulePresets(), ", ") + `)
+and overrides any subset of its values; --toml prints a complete file to start from.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var rules *engine.Rules
+			source := "built-in defaults"
+			if preset != "" {
+				r, err := engine.PresetRules(preset)
+				if err != nil {
+					return err
+				}
+				rules = r
+				source = "preset " + r.Preset
+			} else {
+				path, err := engine.ResolveRulesPath()
+				if err != nil {
+					return err
+				}
+				r, err := engine.LoadRulesFile(path)
+				if err != nil {
+					return err
+				}
+				rules = r
+				if path != "" {
+					source = path
+				}
+			}
+
+			out := cmd.OutOrStdout()
+			if asTOML {
+				text, err := rules.Encode()
+				if err != nil {
+					return err
+				}
+				fmt.Fprint(out, text)
+				return nil
+			}
+
+			fmt.Fprintln(out, ui.Heading("📏", "Rules"))
+			fmt.Fprintln(out, ui.LabelValue("Source", source+" "+ui.Muted.Render("(preset "+rules.Preset+")")))
+			fmt.Fprintln(out, "")
+
+			x := rules.XP
+			fmt.Fprintln(out, ui.H2.Render("XP"))
+			fmt.Fprintf(out, "- %s %g × level^%g %s\n", ui.Key.Render("Level curve:"), x.RequiredCoef, x.RequiredExponent,
+				ui.Muted.Render(fmt.Sprintf("(L1 %d, L5 %d, L10 %d)", rules.XPRequiredForLevel(1), rules.XPRequiredForLevel(5), rules.XPRequiredForLevel(10))))
+			mults := make([]string, 0, len(x.DifficultyMultipliers))
+			for _, m := range x.DifficultyMultipliers {
+				mults = append(mults, fmt.Sprintf("%g", m))
+			}
+			fmt.Fprintf(out, "- %s base %g × [%s] %s\n", ui.Key.Render("Task XP:"), x.TaskBase, strings.Join(mults, " "), ui.Muted.Render("(trivial..epic)"))
+			fmt.Fprintf(out, "- %s +%g%% per attribute level\n", ui.Key.Render("Attribute bonus:"), x.AttributeLevelBonusRate*100)
+			fmt.Fprintf(out, "- %s %g%% of child XP\n", ui.Key.Render("Project bonus:"), x.ProjectBonusRate*100)
+			fmt.Fprintf(out, "- %s %d XP, once per blueprint\n", ui.Key.Render("Blueprint bonus:"), x.BlueprintBonus)
+			fmt.Fprintf(out, "- %s %d XP a week, %d XP a month\n", ui.Key.Render("Review bonus:"), x.ReviewBonus, x.MonthlyReviewBonus)
+			fmt.Fprintln(out, "")
+
+			g := rules.Gates
+			fmt.Fprintln(out, ui.H2.Render("🔓 Gates"))
+			fmt.Fprintf(out, "- %s L%d %s\n", ui.Key.Render("Subtasks:"), g.Subtasks, ui.Muted.Render(fmt.Sprintf("(unlimited depth at L%d)", g.DeepSubtasks)))
+			fmt.Fprintf(out, "- %s L%d\n", ui.Key.Render("Habits:"), g.Habits)
+			fmt.Fprintf(out, "- %s L%d\n", ui.Key.Render("Projects:"), g.Projects)
+			fmt.Fprintf(out, "- %s L%d\n", ui.Key.Render("Reviews:"), g.Reviews)
+			diffNames := []string{"trivial", "easy", "medium", "hard", "epic"}
+			diffs := make([]string, 0, len(diffNames))
+			for i, name := range diffNames {
+				diffs = append(diffs, fmt.Sprintf("%s L%d", name, g.DifficultyUnlock[i]))
+			}
+			fmt.Fprintf(out, "- %s %s\n", ui.Key.Render("Difficulty:"), strings.Join(diffs, ", "))
+			steps := make([]string, 0, len(g.MaxActiveTasks))
+			for _, st := range g.MaxActiveTasks {
+				steps = append(steps, fmt.Sprintf("%d from L%d", st.Tasks, st.Level))
+			}
+			fmt.Fprintf(out, "- %s %s\n", ui.Key.Render("Max active tasks:"), strings.Join(steps, ", "))
+			fmt.Fprintln(out, "")
+
+			h := rules.Habits
+			fmt.Fprintln(out, ui.H2.Render("🔁 Habit decay"))
+			fmt.Fprintf(out, "- %g%% XP after %d completions at the same difficulty within %d days\n", h.DecayFactor*100, h.DecayThreshold, h.DecayWindowDays)
+			fmt.Fprintln(out, "")
+
+			st := rules.Streaks
+			fmt.Fprintln(out, ui.H2.Render(ui.IconFire+" Streaks"))
+			fmt.Fprintf(out, "- %s +%g%% XP per period after the first, at most +%g%%\n", ui.Key.Render("Bonus:"), st.BonusPerPeriod*100, st.MaxBonus*100)
+			if st.FreezeEvery > 0 {
+				fmt.Fprintf(out, "- %s one every %d periods, up to %d held\n", ui.Key.Render("Freeze tokens:"), st.FreezeEvery, st.MaxFreezes)
+			} else {
+				fmt.Fprintf(out, "- %s off\n", ui.Key.Render("Freeze tokens:"))
+			}
+			fmt.Fprintln(out, "")
+
+			d := rules.Deadlines
+			fmt.Fprintln(out, ui.H2.Render("⏰ Deadlines"))
+			if d.LatePenaltyPerDay > 0 {
+				fmt.Fprintf(out, "- %s -%g%% XP per day late, at most -%g%%\n", ui.Key.Render("Late penalty:"), d.LatePenaltyPerDay*100, d.MaxLatePenalty*100)
+			} else {
+				fmt.Fprintf(out, "- %s off\n", ui.Key.Render("Late penalty:"))
+			}
+			if d.EarlyBonus > 0 {
+				fmt.Fprintf(out, "- %s +%g%% XP when done %d+ days early\n", ui.Key.Render("Early bonus:"), d.EarlyBonus*100, d.EarlyBonusDays)
+			} else {
+				fmt.Fprintf(out, "- %s off\n", ui.Key.Render("Early bonus:"))
+			}
+			fmt.Fprintln(out, "")
+
+			tr := rules.Time
+			fmt.Fprintln(out, ui.H2.Render(ui.IconTimer+" Tracked time"))
+			if tr.BonusPerHour > 0 {
+				fmt.Fprintf(out, "- %s +%g%% XP per tracked hour, at most +%g%%\n", ui.Key.Render("Bonus:"), tr.BonusPerHour*100, tr.MaxBonus*100)
+			} else {
+				fmt.Fprintf(out, "- %s off\n", ui.Key.Render("Bonus:"))
+			}
+			fmt.Fprintln(out, "")
+
+			hp := rules.HP
+			fmt.Fprintln(out, ui.H2.Render(ui.IconHeart+" HP"))
+			fmt.Fprintf(out, "- %s %d\n", ui.Key.Render("Max:"), hp.Max)
+			if hp.DamagePerMiss > 0 {
+				fmt.Fprintf(out, "- %s -%d HP per missed habit occurrence\n", ui.Key.Render("Damage:"), hp.DamagePerMiss)
+			} else {
+				fmt.Fprintf(out, "- %s off\n", ui.Key.Render("Damage:"))
+			}
+			fmt.Fprintf(out, "- %s +%d HP per day, +%d per habit, +%d per task\n", ui.Key.Render("Healing:"), hp.RegenPerDay, hp.HealPerHabit, hp.HealPerTask)
+			if hp.KnockoutDays > 0 {
+				fmt.Fprintf(out, "- %s XP ×%g for %d days at 0 HP\n", ui.Key.Render("Knockout:"), hp.KnockoutXPMultiplier, hp.KnockoutDays)
+			} else {
+				fmt.Fprintf(out, "- %s off\n", ui.Key.Render("Knockout:"))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&preset, "preset", "", "Show a built-in preset instead of the effective rules")
+	cmd.Flags().BoolVar(&asTOML, "toml", false, "Print the rules as a TOML file")
+	return cmd
+}
@@ -0,0 +1,107 @@
+package root
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"questline/internal/engine"
+	"questline/internal/storage"
+	"questline/internal/ui"
+)
+
+func newSearchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search task titles and descriptions",
+		Long: `Search task titles and descriptions, best matches first.
+
+Every word has to match, as a word or the start of one:
+
+  ql search milk
+  ql search "oat mi"
+  ql search report status:open   # filters work as in ql list`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("query is required")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			reg, err := svc.Attributes(ctx)
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			filter, err := engine.ParseTaskFilter(strings.Join(args, " "), reg, now)
+			if err != nil {
+				return err
+			}
+			if len(filter.Words) == 0 {
+				return errors.New("query has no words to search for (use ql list to filter)")
+			}
+			matches, err := svc.FilterTasks(ctx, filter)
+			if err != nil {
+				return err
+			}
+			if structuredOutput() {
+				views, err := svc.TaskViews(ctx, matches, now)
+				if err != nil {
+					return err
+				}
+				return printView(cmd.OutOrStdout(), engine.TaskListView{Version: engine.ViewVersion, Tasks: views}, taskTable(views))
+			}
+			all, err := svc.TaskRepo().ListAll(ctx)
+			if err != nil {
+				return err
+			}
+			byID := make(map[int64]*storage.Task, len(all))
+			for i := range all {
+				byID[all[i].ID] = &all[i]
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintln(out, ui.Heading(ui.IconSearch, "Search"))
+			if len(matches) == 0 {
+				fmt.Fprintln(out, ui.Muted.Render("(no matching tasks)"))
+				return nil
+			}
+			for _, t := range matches {
+				line := fmt.Sprintf("%s #%d %s %s%s", ui.KindIcon(t.IsProject, t.IsHabit), t.ID, t.Title, ui.Muted.Render("("+ui.StatusText(t.Status)+")"), dueSuffix(&t, now))
+				if path := taskPath(byID, t.ParentID); path != "" {
+					line += " " + ui.Muted.Render("· in "+path)
+				}
+				fmt.Fprintln(out, line)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// taskPath renders the titles from the top-level task down to parentID,
+// e.g. "Move house › Kitchen".
+func taskPath(byID map[int64]*storage.Task, parentID *int64) string {
+	var titles []string
+	for p := parentID; p != nil && len(titles) < len(byID); {
+		t := byID[*p]
+		if t == nil {
+			break
+		}
+		titles = append([]string{t.Title}, titles...)
+		p = t.ParentID
+	}
+	return strings.Join(titles, " › ")
+}
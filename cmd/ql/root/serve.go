@@ -0,0 +1,100 @@
+package root
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"questline/internal/server"
+	"questline/internal/ui"
+)
+
+func newServeCmd() *cobra.Command {
+	var addr, token string
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve a local HTTP/JSON API for widgets and scripts",
+		Long: `Serve the Questline API until interrupted:
+
+  ql serve --addr 127.0.0.1:7777
+  curl -H "Authorization: Bearer $QL_API_TOKEN" localhost:7777/api/v1/player
+
+Requests need the token from --token or $QL_API_TOKEN; without either, a
+random one is made up and printed. The endpoints are described in
+docs/API.md and at /api/v1/openapi.yaml.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			if token == "" {
+				token = os.Getenv("QL_API_TOKEN")
+			}
+			generated := token == ""
+			if generated {
+				b := make([]byte, 16)
+				if _, err := rand.Read(b); err != nil {
+					return err
+				}
+				token = hex.EncodeToString(b)
+			}
+
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			srv := &http.Server{
+				Handler:           server.New(svc, token),
+				ReadHeaderTimeout: 10 * time.Second,
+				// Ending the context ends open event streams, so shutdown
+				// doesn't wait for them.
+				BaseContext: func(net.Listener) context.Context { return ctx },
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintln(out, ui.Good.Render(ui.IconSparkle+" Serving the API")+" "+fmt.Sprintf("http://%s%s", ln.Addr(), server.Prefix)+" "+ui.Muted.Render("(Ctrl+C to stop)"))
+			if generated {
+				fmt.Fprintln(out, ui.LabelValue("Token", token))
+			}
+			if host, _, _ := net.SplitHostPort(ln.Addr().String()); !net.ParseIP(host).IsLoopback() {
+				fmt.Fprintln(out, ui.Warn.Render(fmt.Sprintf("%s Listening beyond this machine; anyone with the token can change your tasks", ui.IconWarn)))
+			}
+
+			errc := make(chan error, 1)
+			go func() { errc <- srv.Serve(ln) }()
+			select {
+			case err := <-errc:
+				return err
+			case <-ctx.Done():
+			}
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			fmt.Fprintln(out, ui.Muted.Render("Stopped"))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:7777", "Address to listen on")
+	cmd.Flags().StringVar(&token, "token", "", "Token requests must bear (default $QL_API_TOKEN, or a random one)")
+
+	return cmd
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"questline/internal/engine"
+	"questline/internal/storage"
 	"questline/internal/ui"
 )
 
@@ -23,12 +25,21 @@ func newStatusCmd() *cobra.Command {
 			}
 			defer cleanup()
 
+			if structuredOutput() {
+				v, err := svc.StatusView(ctx, time.Now())
+				if err != nil {
+					return err
+				}
+				return printView(cmd.OutOrStdout(), v, nil)
+			}
+
 			p, err := svc.PlayerRepo().GetOrCreateMain(ctx)
 			if err != nil {
 				return err
 			}
-			computedLevel := engine.LevelForTotalXP(p.XPTotal)
-			nextReq := engine.XPRequiredForLevel(computedLevel + 1)
+			rules := svc.Rules()
+			computedLevel := rules.LevelForTotalXP(p.XPTotal)
+			nextReq := rules.XPRequiredForLevel(computedLevel + 1)
 			toNext := nextReq - p.XPTotal
 			if toNext < 0 {
 				toNext = 0
@@ -37,26 +48,95 @@ func newStatusCmd() *cobra.Command {
 			fmt.Fprintln(cmd.OutOrStdout(), ui.Heading(ui.IconSparkle, "Player Status"))
 			fmt.Fprintln(cmd.OutOrStdout(), ui.LabelValue("Level", computedLevel))
 			fmt.Fprintln(cmd.OutOrStdout(), ui.LabelValue("Total XP", fmt.Sprintf("%d (next at %d, %d to go)", p.XPTotal, nextReq, toNext)))
+			hp, err := svc.HP(ctx, 5)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), ui.LabelValue("HP", ui.HPChip(hp.HP, hp.Max)))
+			if hp.WeakenedUntil != nil {
+				fmt.Fprintln(cmd.OutOrStdout(), ui.Bad.Render(fmt.Sprintf("%s Knocked out: XP ×%.2f until %s", ui.IconError, rules.HP.KnockoutXPMultiplier, hp.WeakenedUntil.Local().Format("Mon 2 Jan 15:04"))))
+			}
+			running, err := svc.RunningTimer(ctx)
+			if err != nil {
+				return err
+			}
+			if running != nil {
+				fmt.Fprintln(cmd.OutOrStdout(), runningText(running, time.Now()))
+			}
 			fmt.Fprintln(cmd.OutOrStdout(), "")
 
 			fmt.Fprintln(cmd.OutOrStdout(), ui.H2.Render("📊 Attributes"))
-			// Original 4 attributes
-			fmt.Fprintf(cmd.OutOrStdout(), "- 💪 STR: lvl %d (xp %d)\n", engine.AttributeLevelForXP(p.XPStr), p.XPStr)
-			fmt.Fprintf(cmd.OutOrStdout(), "- 🧠 INT: lvl %d (xp %d)\n", engine.AttributeLevelForXP(p.XPInt), p.XPInt)
-			fmt.Fprintf(cmd.OutOrStdout(), "- 🧘 WIS: lvl %d (xp %d)\n", engine.AttributeLevelForXP(p.XPWis), p.XPWis)
-			fmt.Fprintf(cmd.OutOrStdout(), "- 🎨 ART: lvl %d (xp %d)\n", engine.AttributeLevelForXP(p.XPArt), p.XPArt)
-			// New 5 attributes
-			fmt.Fprintf(cmd.OutOrStdout(), "- 🏠 HOME: lvl %d (xp %d)\n", engine.AttributeLevelForXP(p.XPHome), p.XPHome)
-			fmt.Fprintf(cmd.OutOrStdout(), "- 🌲 OUT: lvl %d (xp %d)\n", engine.AttributeLevelForXP(p.XPOut), p.XPOut)
-			fmt.Fprintf(cmd.OutOrStdout(), "- 📚 READ: lvl %d (xp %d)\n", engine.AttributeLevelForXP(p.XPRead), p.XPRead)
-			fmt.Fprintf(cmd.OutOrStdout(), "- 🎬 CINEMA: lvl %d (xp %d)\n", engine.AttributeLevelForXP(p.XPCinema), p.XPCinema)
-			fmt.Fprintf(cmd.OutOrStdout(), "- 💼 CAREER: lvl %d (xp %d)\n", engine.AttributeLevelForXP(p.XPCareer), p.XPCareer)
+			reg, err := svc.Attributes(ctx)
+			if err != nil {
+				return err
+			}
+			for _, a := range reg.All() {
+				xp := p.AttrXP(string(a.Code))
+				fmt.Fprintf(cmd.OutOrStdout(), "- %s %s: lvl %d (xp %d)\n", a.Icon, ui.AttrStyle(a.Color).Render(string(a.Code)), rules.AttributeLevelForXP(xp), xp)
+			}
 			fmt.Fprintln(cmd.OutOrStdout(), "")
 
 			all, err := svc.TaskRepo().ListAll(ctx)
 			if err != nil {
 				return err
 			}
+
+			now := time.Now()
+			var overdue []storage.Task
+			for i := range all {
+				if engine.IsOverdue(&all[i], now) {
+					overdue = append(overdue, all[i])
+				}
+			}
+			if len(overdue) > 0 {
+				sort.Slice(overdue, func(i, j int) bool { return overdue[i].DueDate.Before(*overdue[j].DueDate) })
+				fmt.Fprintln(cmd.OutOrStdout(), ui.H2.Render(fmt.Sprintf("⏰ Overdue (%d)", len(overdue))))
+				for i := range overdue {
+					fmt.Fprintf(cmd.OutOrStdout(), "- #%d %s %s\n", overdue[i].ID, overdue[i].Title, ui.DueChip(*overdue[i].DueDate, now))
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "")
+			}
+
+			streaks, err := svc.HabitStreaks(ctx)
+			if err != nil {
+				return err
+			}
+			if len(streaks) > 0 {
+				tokens, err := svc.FreezeTokens(ctx)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), ui.H2.Render(ui.IconFire+" Streaks")+" "+ui.Muted.Render(fmt.Sprintf("(%s %d freeze tokens)", ui.IconFreeze, tokens)))
+				for i := range all {
+					st, ok := streaks[all[i].ID]
+					if !ok || all[i].Status == "done" {
+						continue
+					}
+					chip := ui.StreakChip(st.Current, st.DoneThisPeriod)
+					if chip == "" {
+						chip = ui.Muted.Render("no streak")
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "- #%d %s %s %s\n", all[i].ID, all[i].Title, chip, ui.Muted.Render(fmt.Sprintf("(best %d)", st.Longest)))
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "")
+			}
+
+			if len(hp.Recent) > 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), ui.H2.Render(ui.IconHeart+" Recent HP"))
+				for _, e := range hp.Recent {
+					delta := ui.Good.Render(fmt.Sprintf("%+d", e.Delta))
+					if e.Delta < 0 || e.Reason == engine.HPReasonKnockout {
+						delta = ui.Bad.Render(fmt.Sprintf("%+d", e.Delta))
+					}
+					what := e.Reason
+					if e.TaskID != nil {
+						what += fmt.Sprintf(" #%d", *e.TaskID)
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "- %s %s %s\n", delta, what, ui.Muted.Render(e.CreatedAt.Local().Format("Mon 2 Jan 15:04")))
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "")
+			}
+
 			activeLeaf := 0
 			for i := range all {
 				if all[i].IsProject {
@@ -69,8 +149,8 @@ func newStatusCmd() *cobra.Command {
 			}
 
 			fmt.Fprintln(cmd.OutOrStdout(), ui.H2.Render("🔓 Gates"))
-			fmt.Fprintf(cmd.OutOrStdout(), "- %s %d %s\n", ui.Key.Render("Max active tasks:"), engine.MaxActiveTasks(computedLevel), ui.Muted.Render(fmt.Sprintf("(currently %d)", activeLeaf)))
-			maxDepth := engine.MaxSubtaskDepth(computedLevel)
+			fmt.Fprintf(cmd.OutOrStdout(), "- %s %d %s\n", ui.Key.Render("Max active tasks:"), rules.MaxActiveTasks(computedLevel), ui.Muted.Render(fmt.Sprintf("(currently %d)", activeLeaf)))
+			maxDepth := rules.MaxSubtaskDepth(computedLevel)
 			switch {
 			case maxDepth == engine.SubtaskDepthUnlimited:
 				fmt.Fprintln(cmd.OutOrStdout(), "- "+ui.Key.Render("Subtasks:")+" "+ui.Good.Render("enabled")+" "+ui.Muted.Render("(unlimited depth)"))
@@ -79,10 +159,11 @@ func newStatusCmd() *cobra.Command {
 			default:
 				fmt.Fprintf(cmd.OutOrStdout(), "- %s %s %s\n", ui.Key.Render("Subtasks:"), ui.Good.Render("enabled"), ui.Muted.Render(fmt.Sprintf("(max depth %d)", maxDepth)))
 			}
-			fmt.Fprintf(cmd.OutOrStdout(), "- %s %s\n", ui.Key.Render("Habits:"), enabledStr(computedLevel >= engine.LevelHabits))
-			fmt.Fprintf(cmd.OutOrStdout(), "- %s %s\n", ui.Key.Render("Projects:"), enabledStr(computedLevel >= engine.LevelProjects))
+			fmt.Fprintf(cmd.OutOrStdout(), "- %s %s\n", ui.Key.Render("Habits:"), enabledStr(computedLevel >= rules.Gates.Habits))
+			fmt.Fprintf(cmd.OutOrStdout(), "- %s %s\n", ui.Key.Render("Projects:"), enabledStr(computedLevel >= rules.Gates.Projects))
+			fmt.Fprintf(cmd.OutOrStdout(), "- %s %s\n", ui.Key.Render("Reviews:"), enabledStr(computedLevel >= rules.Gates.Reviews))
 			// Show unlocked difficulty levels
-			maxDiff := engine.MaxDifficultyForLevel(computedLevel)
+			maxDiff := rules.MaxDifficultyForLevel(computedLevel)
 			diffNames := []string{"Trivial", "Easy", "Medium", "Hard", "Epic"}
 			diffUnlocked := make([]string, 0)
 			for d := engine.DifficultyTrivial; d <= maxDiff; d++ {
@@ -90,7 +171,7 @@ func newStatusCmd() *cobra.Command {
 			}
 			nextDiffLevel := 0
 			if maxDiff < engine.DifficultyEpic {
-				nextDiffLevel = engine.DifficultyUnlockLevels[maxDiff+1]
+				nextDiffLevel = rules.DifficultyUnlockLevel(maxDiff + 1)
 			}
 			diffInfo := ui.Good.Render(fmt.Sprintf("1-%d", maxDiff))
 			if nextDiffLevel > 0 {
@@ -130,12 +211,19 @@ func newStatusCmd() *cobra.Command {
 				}
 				fmt.Fprintln(cmd.OutOrStdout(), ui.H2.Render(heading+":"))
 				for i := range list {
-					def := engine.GetBlueprintDef(list[i].Code)
+					def := svc.BlueprintDef(list[i].Code)
+					line := "- " + ui.Muted.Render(list[i].Code)
 					if def != nil && def.Description != "" {
-						fmt.Fprintf(cmd.OutOrStdout(), "- %s: %s\n", ui.Key.Render(list[i].Code), ui.Muted.Render(def.Description))
-					} else {
-						fmt.Fprintf(cmd.OutOrStdout(), "- %s\n", ui.Muted.Render(list[i].Code))
+						line = fmt.Sprintf("- %s: %s", ui.Key.Render(list[i].Code), ui.Muted.Render(def.Description))
 					}
+					if st != "available" {
+						prog, err := svc.BlueprintProgress(ctx, list[i].Code)
+						if err != nil {
+							return err
+						}
+						line += " " + blueprintProgressText(prog)
+					}
+					fmt.Fprintln(cmd.OutOrStdout(), line)
 				}
 				fmt.Fprintln(cmd.OutOrStdout(), "")
 			}
@@ -147,6 +235,20 @@ func newStatusCmd() *cobra.Command {
 	return cmd
 }
 
+// blueprintProgressText renders how far a blueprint's instance has come.
+func blueprintProgressText(p *engine.BlueprintProgress) string {
+	if p.Status == engine.BlueprintCompleted && p.CompletedAt != nil {
+		return ui.Good.Render("completed " + p.CompletedAt.Local().Format("Mon 2 Jan"))
+	}
+	if p.TaskID == 0 {
+		return ui.Muted.Render("(no linked task)")
+	}
+	if p.Total == 0 {
+		return ui.Warn.Render(fmt.Sprintf("[%d done]", p.Done)) + " " + ui.Muted.Render(fmt.Sprintf("#%d", p.TaskID))
+	}
+	return ui.Warn.Render(fmt.Sprintf("[%d/%d]", p.Done, p.Total)) + " " + ui.Muted.Render(fmt.Sprintf("#%d", p.TaskID))
+}
+
 func enabledStr(ok bool) string {
 	if ok {
 		return ui.Good.Render("enabled")
@@ -0,0 +1,156 @@
+package root
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"questline/internal/engine"
+	"questline/internal/ui"
+)
+
+func newTagCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tag",
+		Short: "Tag tasks and list tags",
+		Long: `Tags are free-form labels, separate from attributes and XP:
+
+  ql tag add 12 work urgent     # or: ql add "..." --tag work,urgent
+  ql tag rm 12 urgent
+  ql tag list
+  ql list tag:work
+
+Subtasks inherit the tags of the tasks above them.`,
+	}
+	cmd.AddCommand(newTagAddCmd(), newTagRemoveCmd(), withOutput(readOnly(newTagListCmd())))
+	return cmd
+}
+
+// tagArgs validates "<id> <tag>..." and returns the ID and the parsed tags.
+func tagArgs(args []string) (int64, []string, error) {
+	if len(args) < 2 {
+		return 0, nil, errors.New("id and at least one tag are required")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return 0, nil, errors.New("id must be an integer")
+	}
+	tags, err := engine.ParseTags(strings.Join(args[1:], ","))
+	if err != nil {
+		return 0, nil, err
+	}
+	return id, tags, nil
+}
+
+func newTagAddCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "add <id> <tag>...",
+		Short: "Tag a task (its subtasks inherit the tags)",
+		Args: func(cmd *cobra.Command, args []string) error {
+			_, _, err := tagArgs(args)
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			id, tags, _ := tagArgs(args)
+			if _, err := svc.AddTags(ctx, id, tags); err != nil {
+				return err
+			}
+			return printTaskTags(cmd, svc, id, "Tagged")
+		},
+	}
+}
+
+func newTagRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "rm <id> <tag>...",
+		Aliases: []string{"remove"},
+		Short:   "Remove tags from a task",
+		Args: func(cmd *cobra.Command, args []string) error {
+			_, _, err := tagArgs(args)
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			id, tags, _ := tagArgs(args)
+			if err := svc.RemoveTags(ctx, id, tags); err != nil {
+				return err
+			}
+			return printTaskTags(cmd, svc, id, "Untagged")
+		},
+	}
+}
+
+// printTaskTags prints a task with the tags it now has.
+func printTaskTags(cmd *cobra.Command, svc *engine.Service, id int64, label string) error {
+	ctx := context.Background()
+	t, err := svc.TaskRepo().Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	tags, err := svc.TaskTags(ctx)
+	if err != nil {
+		return err
+	}
+	suffix := tagsSuffix(tags[id])
+	if suffix == "" {
+		suffix = " " + ui.Muted.Render("(no tags)")
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s %s #%d %s%s\n", ui.Good.Render(ui.IconTag+" "+label), ui.KindIcon(t.IsProject, t.IsHabit), t.ID, t.Title, suffix)
+	return nil
+}
+
+func newTagListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List tags in use",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			counts, err := svc.TagCounts(ctx)
+			if err != nil {
+				return err
+			}
+			if structuredOutput() {
+				v := engine.NewTagListView(counts)
+				rows := [][]string{{"tag", "tasks", "open"}}
+				for _, t := range v.Tags {
+					rows = append(rows, []string{t.Tag, strconv.Itoa(t.Tasks), strconv.Itoa(t.Open)})
+				}
+				return printView(cmd.OutOrStdout(), v, rows)
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintln(out, ui.Heading(ui.IconTag, "Tags"))
+			if len(counts) == 0 {
+				fmt.Fprintln(out, ui.Muted.Render("(none yet — try: ql tag add <id> work)"))
+				return nil
+			}
+			for _, c := range counts {
+				fmt.Fprintf(out, "%s %s\n", ui.TagChip(c.Tag, false), ui.Muted.Render(fmt.Sprintf("%d tagged · %d open (incl. subtasks)", c.Tasks, c.Open)))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,279 @@
+package root
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"questline/internal/engine"
+	"questline/internal/storage"
+	"questline/internal/ui"
+)
+
+// taskIDArg validates a single task ID argument.
+func taskIDArg(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("id is required")
+	}
+	if _, err := strconv.ParseInt(args[0], 10, 64); err != nil {
+		return errors.New("id must be an integer")
+	}
+	return nil
+}
+
+func newStartCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "start <id>",
+		Short: "Start tracking time on a task",
+		Long: `Start a timer on a task. Only one timer runs at a time: starting another
+stops the running one. Completing the task stops its timer too.`,
+		Args: taskIDArg,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			id, _ := strconv.ParseInt(args[0], 10, 64)
+			res, err := svc.StartTimer(ctx, id, engine.SessionTimer, time.Now())
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if res.Stopped != nil {
+				fmt.Fprintln(out, ui.Muted.Render(fmt.Sprintf("Stopped #%d after %s", res.Stopped.TaskID, ui.Duration(res.Stopped.Duration(time.Now())))))
+			}
+			line := fmt.Sprintf("%s #%d %s", ui.Good.Render(ui.IconTimer+" Started"), res.Task.ID, res.Task.Title)
+			if res.Tracked > 0 {
+				line += " " + ui.Muted.Render(fmt.Sprintf("(%s tracked so far)", ui.Duration(res.Tracked)))
+			}
+			fmt.Fprintln(out, line)
+			return nil
+		},
+	}
+}
+
+func newStopCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the running timer",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			res, err := svc.StopTimer(ctx, time.Now())
+			if err != nil {
+				return err
+			}
+			printStopped(cmd.OutOrStdout(), res)
+			return nil
+		},
+	}
+}
+
+func printStopped(w io.Writer, res *engine.TimerResult) {
+	line := fmt.Sprintf("%s #%d %s %s", ui.Good.Render(ui.IconTimer+" Stopped"), res.Session.TaskID, res.Task.Title,
+		ui.Muted.Render(fmt.Sprintf("after %s (%s in total)", ui.Duration(res.Session.Duration(time.Now())), ui.Duration(res.Tracked))))
+	fmt.Fprintln(w, line)
+	printAchievements(w, res.Achievements)
+}
+
+func newFocusCmd() *cobra.Command {
+	var (
+		pomodoro string
+		rounds   int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "focus <id>",
+		Short: "Work on a task in pomodoro rounds",
+		Long: `Work on a task in pomodoro rounds: a focus interval tracked on the task,
+then a break, until the rounds are done. Ctrl+C stops early; the time spent
+so far is kept.`,
+		Args: taskIDArg,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := engine.ParsePomodoro(pomodoro)
+			if err != nil {
+				return err
+			}
+			if rounds < 1 {
+				return errors.New("--rounds must be at least 1")
+			}
+
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			// The interrupt only ends the countdown; the database calls keep ctx.
+			waitCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+			defer stop()
+
+			id, _ := strconv.ParseInt(args[0], 10, 64)
+			out := cmd.OutOrStdout()
+			for round := 1; round <= rounds; round++ {
+				res, err := svc.StartTimer(ctx, id, engine.SessionFocus, time.Now())
+				if err != nil {
+					return err
+				}
+				if res.Stopped != nil {
+					fmt.Fprintln(out, ui.Muted.Render(fmt.Sprintf("Stopped #%d after %s", res.Stopped.TaskID, ui.Duration(res.Stopped.Duration(time.Now())))))
+				}
+				label := fmt.Sprintf("%s Focus %d/%d #%d %s", ui.IconTomato, round, rounds, res.Task.ID, res.Task.Title)
+				finished := countdown(waitCtx, out, ui.Gold.Render(label), p.Work)
+
+				done, err := stopFocus(ctx, svc, res.Session.ID)
+				if err != nil {
+					return err
+				}
+				if done == nil {
+					fmt.Fprintln(out, ui.Muted.Render("The timer was stopped elsewhere; focus ended."))
+					return nil
+				}
+				printStopped(out, done)
+				if !finished || round == rounds {
+					return nil
+				}
+				fmt.Fprint(out, "\a")
+				if p.Break > 0 && !countdown(waitCtx, out, ui.Muted.Render(fmt.Sprintf("Break %d/%d", round, rounds-1)), p.Break) {
+					return nil
+				}
+				fmt.Fprint(out, "\a")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&pomodoro, "pomodoro", "25/5", "Focus and break minutes, e.g. 25/5 or 50/10")
+	cmd.Flags().IntVar(&rounds, "rounds", 4, "Number of focus intervals")
+	return cmd
+}
+
+// stopFocus stops the focus session id. It returns nil when that session is
+// no longer running (the task was completed or another timer started).
+func stopFocus(ctx context.Context, svc *engine.Service, id int64) (*engine.TimerResult, error) {
+	running, err := svc.RunningTimer(ctx)
+	if err != nil || running == nil || running.ID != id {
+		return nil, err
+	}
+	return svc.StopTimer(ctx, time.Now())
+}
+
+// countdown shows label and the time left every second until d has passed.
+// It returns false when ctx is cancelled first.
+func countdown(ctx context.Context, w io.Writer, label string, d time.Duration) bool {
+	end := time.Now().Add(d)
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	for {
+		left := time.Until(end)
+		fmt.Fprintf(w, "\r%s %s ", label, ui.Clock(left))
+		if left <= 0 {
+			fmt.Fprintln(w)
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(w)
+			return false
+		case <-tick.C:
+		}
+	}
+}
+
+func newTimeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "time [today|week|month|all]",
+		Short: "Show the time tracked per attribute and task",
+		Long: `Sum the time tracked with ql start/stop and ql focus since the start of
+today, the week (Monday), the month or ever. Tasks crediting several
+attributes split their time like their XP.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			span := "week"
+			if len(args) == 1 {
+				span = args[0]
+			}
+			now := time.Now()
+			since, err := engine.TimeReportSince(span, now)
+			if err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			rep, err := svc.TimeReport(ctx, since, now)
+			if err != nil {
+				return err
+			}
+			if structuredOutput() {
+				return printView(cmd.OutOrStdout(), engine.NewTimeReportView(rep, now), nil)
+			}
+			reg, err := svc.Attributes(ctx)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			title := "Time tracked"
+			if !since.IsZero() {
+				title += " " + ui.Muted.Render("since "+since.Format("Mon 2 Jan"))
+			}
+			fmt.Fprintln(out, ui.Heading(ui.IconTimer, title))
+			fmt.Fprintln(out, "")
+			if rep.Total == 0 {
+				fmt.Fprintln(out, ui.Muted.Render("(nothing tracked; start a timer with ql start <id> or ql focus <id>)"))
+				return nil
+			}
+			summary := ui.Duration(rep.Total)
+			if rep.Focus > 0 {
+				summary += fmt.Sprintf(" · %d %s", rep.Focus, ui.IconTomato)
+			}
+			fmt.Fprintf(out, "%s\n\n", ui.LabelValue("Total", summary))
+
+			fmt.Fprintln(out, ui.H2.Render("Attributes"))
+			for _, a := range rep.Attributes {
+				def := reg.Def(a.Attr)
+				fmt.Fprintf(out, "- %s %s %s\n", def.Icon, ui.AttrStyle(def.Color).Render(fmt.Sprintf("%-6s", def.Code)), ui.Duration(a.Time))
+			}
+			fmt.Fprintln(out, "")
+
+			fmt.Fprintln(out, ui.H2.Render("Tasks"))
+			for _, t := range rep.Tasks {
+				fmt.Fprintf(out, "- #%d %s %s %s\n", t.Task.ID, t.Task.Title, ui.Duration(t.Time), ui.Muted.Render(fmt.Sprintf("(%d sessions)", t.Sessions)))
+			}
+			if rep.Running != nil {
+				fmt.Fprintln(out, "")
+				fmt.Fprintln(out, runningText(rep.Running, now))
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+// runningText describes the running timer.
+func runningText(s *storage.TaskSession, now time.Time) string {
+	return ui.Gold.Render(fmt.Sprintf("%s Running on #%d for %s", ui.IconTimer, s.TaskID, ui.Clock(s.Duration(now))))
+}
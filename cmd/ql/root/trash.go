@@ -0,0 +1,255 @@
+package root
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"questline/internal/engine"
+	"questline/internal/ui"
+)
+
+func newDeleteCmd() *cobra.Command {
+	var keepXP, revokeXP bool
+
+	cmd := &cobra.Command{
+		Use:   "delete <id>",
+		Short: "Move a task and its subtasks to the trash",
+		Long: `Move a task, with all its subtasks, to the trash.
+
+If any of them were completed, say what happens to the XP they earned:
+--keep-xp leaves it with you, --revoke-xp takes it back (and ql trash
+restore gives it back again).`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("id is required")
+			}
+			if _, err := strconv.ParseInt(args[0], 10, 64); err != nil {
+				return errors.New("id must be an integer")
+			}
+			if keepXP && revokeXP {
+				return errors.New("--keep-xp and --revoke-xp are mutually exclusive")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			id, _ := strconv.ParseInt(args[0], 10, 64)
+			xp := engine.DeleteXPAsk
+			switch {
+			case keepXP:
+				xp = engine.DeleteXPKeep
+			case revokeXP:
+				xp = engine.DeleteXPRevoke
+			}
+			before, _ := svc.TaskRepo().Get(ctx, id)
+			res, err := svc.DeleteTask(ctx, id, xp)
+			var choice engine.XPChoiceError
+			if errors.As(err, &choice) {
+				return fmt.Errorf("%w (--keep-xp or --revoke-xp)", err)
+			}
+			if err != nil {
+				return err
+			}
+
+			name := fmt.Sprintf("#%d", id)
+			if before != nil {
+				name = fmt.Sprintf("%s #%d %s", ui.KindIcon(before.IsProject, before.IsHabit), id, before.Title)
+			}
+			line := fmt.Sprintf("%s %s", ui.Warn.Render(ui.IconTrash+" Trashed"), name)
+			if n := len(res.Deleted) - 1; n > 0 {
+				line += " " + ui.Muted.Render(fmt.Sprintf("(+%d subtasks)", n))
+			}
+			switch {
+			case res.XPRevoked > 0:
+				line += " " + ui.Muted.Render(fmt.Sprintf("(-%d XP)", res.XPRevoked))
+			case res.XPEarned > 0:
+				line += " " + ui.Muted.Render(fmt.Sprintf("(kept %d XP)", res.XPEarned))
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), line)
+			if res.LevelAfter != res.LevelBefore {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\n", ui.LabelValue("Level", fmt.Sprintf("%d → %d", res.LevelBefore, res.LevelAfter)))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&keepXP, "keep-xp", false, "Keep the XP completed tasks earned")
+	cmd.Flags().BoolVar(&revokeXP, "revoke-xp", false, "Take back the XP completed tasks earned")
+
+	return cmd
+}
+
+func newTrashCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trash",
+		Short: "List, restore and purge deleted tasks",
+	}
+	cmd.AddCommand(withOutput(readOnly(newTrashListCmd())), newTrashRestoreCmd(), newTrashPurgeCmd())
+	return cmd
+}
+
+func newTrashListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List deleted tasks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			items, err := svc.Trash(ctx)
+			if err != nil {
+				return err
+			}
+			if structuredOutput() {
+				v := engine.NewTrashView(items)
+				rows := [][]string{{"id", "kind", "status", "title", "deleted_at", "subtasks", "xp_revoked"}}
+				for _, t := range v.Tasks {
+					subtasks := make([]string, len(t.Subtasks))
+					for i, id := range t.Subtasks {
+						subtasks[i] = strconv.FormatInt(id, 10)
+					}
+					rows = append(rows, []string{
+						strconv.FormatInt(t.ID, 10), t.Kind, t.Status, t.Title, t.DeletedAt.Format(time.RFC3339),
+						strings.Join(subtasks, " "), strconv.Itoa(t.XPRevoked),
+					})
+				}
+				return printView(cmd.OutOrStdout(), v, rows)
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintln(out, ui.H2.Render(ui.IconTrash+" Trash"))
+			if len(items) == 0 {
+				fmt.Fprintln(out, ui.Muted.Render("(empty)"))
+				return nil
+			}
+			for _, it := range items {
+				t := it.Task
+				line := fmt.Sprintf("%s #%d %s %s", ui.KindIcon(t.IsProject, t.IsHabit), t.ID, t.Title, ui.Muted.Render("("+ui.StatusText(t.Status)+")"))
+				if n := len(it.Subtasks); n > 0 {
+					line += " " + ui.Muted.Render(fmt.Sprintf("+%d subtasks", n))
+				}
+				if it.XPRevoked > 0 {
+					line += " " + ui.Muted.Render(fmt.Sprintf("-%d XP", it.XPRevoked))
+				}
+				line += " " + ui.Muted.Render("deleted "+t.DeletedAt.Local().Format("2006-01-02 15:04"))
+				fmt.Fprintln(out, line)
+			}
+			return nil
+		},
+	}
+}
+
+func newTrashRestoreCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <id>",
+		Short: "Bring a deleted task and its subtasks back",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("id is required")
+			}
+			if _, err := strconv.ParseInt(args[0], 10, 64); err != nil {
+				return errors.New("id must be an integer")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			id, _ := strconv.ParseInt(args[0], 10, 64)
+			res, err := svc.UndeleteTask(ctx, id)
+			if err != nil {
+				return err
+			}
+			t, err := svc.TaskRepo().Get(ctx, id)
+			if err != nil {
+				return err
+			}
+			line := fmt.Sprintf("%s %s #%d %s", ui.Good.Render(ui.IconUndo+" Restored"), ui.KindIcon(t.IsProject, t.IsHabit), t.ID, t.Title)
+			if n := len(res.Restored) - 1; n > 0 {
+				line += " " + ui.Muted.Render(fmt.Sprintf("(+%d subtasks)", n))
+			}
+			if res.XPRestored > 0 {
+				line += " " + ui.Muted.Render(fmt.Sprintf("(+%d XP)", res.XPRestored))
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), line)
+			if res.LevelAfter != res.LevelBefore {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\n", ui.LabelValue("Level", fmt.Sprintf("%d → %d", res.LevelBefore, res.LevelAfter)))
+			}
+			return nil
+		},
+	}
+}
+
+func newTrashPurgeCmd() *cobra.Command {
+	var older string
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   "purge (--older <age> | --all)",
+		Short: "Remove deleted tasks for good",
+		Long: `Remove tasks from the trash for good, with their completions.
+
+  ql trash purge --older 30d   # deleted more than 30 days ago
+  ql trash purge --all
+
+Your XP doesn't change: the XP ledger keeps its entries.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return errors.New("purge takes no arguments")
+			}
+			if (older == "") == !all {
+				return errors.New("use either --older or --all")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			svc, cleanup, err := openService(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			cutoff := time.Now()
+			if older != "" {
+				age, err := engine.ParseDuration(older)
+				if err != nil {
+					return fmt.Errorf("invalid --older: %w", err)
+				}
+				cutoff = cutoff.Add(-age)
+			}
+			n, err := svc.PurgeTrash(ctx, cutoff)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), ui.Warn.Render(fmt.Sprintf("%s Purged %d tasks", ui.IconTrash, n)))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&older, "older", "", "Only tasks deleted longer ago than this (e.g., 30d, 2w, 1m)")
+	cmd.Flags().BoolVar(&all, "all", false, "Empty the whole trash")
+
+	return cmd
+}
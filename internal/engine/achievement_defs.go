@@ -0,0 +1,431 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvAchievementsDir overrides the directory user achievements are loaded from.
+const EnvAchievementsDir = "QL_ACHIEVEMENTS_DIR"
+
+// AchievementCondKind is what an achievement condition counts.
+type AchievementCondKind string
+
+const (
+	CondLevel       AchievementCondKind = "level"         // player level >= Min
+	CondAttrLevel   AchievementCondKind = "attr_level"    // level of Attr >= Min
+	CondAttrXP      AchievementCondKind = "attr_xp"       // total XP of Attr >= Min
+	CondTasksDone   AchievementCondKind = "tasks_done"    // tasks currently done
+	CondCompletions AchievementCondKind = "completions"   // completions, optionally within a time window
+	CondStreak      AchievementCondKind = "streak"        // longest habit streak, in periods
+	CondBlueprints  AchievementCondKind = "blueprints"    // completed blueprints
+	CondProjects    AchievementCondKind = "projects"      // finished projects
+	CondHabits      AchievementCondKind = "habits"        // habits created
+	CondTracked     AchievementCondKind = "tracked_hours" // hours of time tracked on tasks
+)
+
+var achievementCondKinds = []AchievementCondKind{
+	CondLevel, CondAttrLevel, CondAttrXP, CondTasksDone, CondCompletions, CondStreak, CondBlueprints, CondProjects, CondHabits,
+	CondTracked,
+}
+
+// AchievementCond is one condition of an achievement. Min defaults to 1. The
+// filters apply to the kinds that count tasks, completions, habits or tracked
+// time:
+//
+//	attr        tasks or habits that give XP to this attribute
+//	tag         tasks or habits with this tag, set or inherited
+//	difficulty  at least this difficulty (trivial..epic or 1-5)
+//	window      completions: that many within any stretch this long (24h, 7d, week, month)
+//	hours       completions: local time of day, e.g. "22-4" (10pm to 4am) or "5-8"
+type AchievementCond struct {
+	Kind       AchievementCondKind `yaml:"kind"`
+	Min        int                 `yaml:"min"`
+	Attr       string              `yaml:"attr"`
+	Difficulty string              `yaml:"difficulty"`
+	Window     string              `yaml:"window"`
+	Hours      string              `yaml:"hours"`
+	Tag        string              `yaml:"tag"`
+
+	// Parsed by validate.
+	attr       Attribute
+	tag        string
+	difficulty Difficulty
+	window     time.Duration
+	fromHour   int
+	toHour     int
+	hasHours   bool
+}
+
+// AchievementDef is a badge as data: it is earned once all conditions hold.
+type AchievementDef struct {
+	ID          string            `yaml:"id"`
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description"`
+	Icon        string            `yaml:"icon"`
+	When        []AchievementCond `yaml:"when"`
+
+	Source string `yaml:"-"` // file the achievement was loaded from ("" for built-in ones)
+}
+
+func levelAchievement(id, name, desc, icon string, level int) AchievementDef {
+	return AchievementDef{ID: id, Name: name, Description: desc, Icon: icon, When: []AchievementCond{{Kind: CondLevel, Min: level}}}
+}
+
+func countAchievement(id, name, desc, icon string, kind AchievementCondKind, n int) AchievementDef {
+	return AchievementDef{ID: id, Name: name, Description: desc, Icon: icon, When: []AchievementCond{{Kind: kind, Min: n}}}
+}
+
+func attrLevelAchievement(id, name, desc, icon, attr string, level int) AchievementDef {
+	return AchievementDef{ID: id, Name: name, Description: desc, Icon: icon, When: []AchievementCond{{Kind: CondAttrLevel, Attr: attr, Min: level}}}
+}
+
+// BuiltinAchievements returns the achievements every player can earn.
+func BuiltinAchievements() []AchievementDef {
+	defs := []AchievementDef{
+		// Level milestones
+		levelAchievement("first_steps", "First Steps", "Reach level 1", "🌱", 1),
+		levelAchievement("getting_started", "Getting Started", "Reach level 3", "🌿", 3),
+		levelAchievement("on_the_path", "On the Path", "Reach level 5", "🌳", 5),
+		levelAchievement("seasoned", "Seasoned Adventurer", "Reach level 10", "⭐", 10),
+		levelAchievement("veteran", "Veteran", "Reach level 15", "🌟", 15),
+		levelAchievement("master", "Master", "Reach level 20", "💫", 20),
+
+		// Task completion milestones
+		countAchievement("first_task", "First Quest", "Complete 1 task", "✓", CondTasksDone, 1),
+		countAchievement("productive", "Productive", "Complete 10 tasks", "📋", CondTasksDone, 10),
+		countAchievement("achiever", "Achiever", "Complete 50 tasks", "🏅", CondTasksDone, 50),
+		countAchievement("powerhouse", "Powerhouse", "Complete 100 tasks", "🏆", CondTasksDone, 100),
+
+		// Attribute level achievements
+		attrLevelAchievement("strong", "Strong", "STR level 3", "💪", "str", 3),
+		attrLevelAchievement("smart", "Smart", "INT level 3", "🧠", "int", 3),
+		attrLevelAchievement("wise", "Wise", "WIS level 3", "🧘", "wis", 3),
+		attrLevelAchievement("creative", "Creative", "ART level 3", "🎨", "art", 3),
+		attrLevelAchievement("homemaker", "Homemaker", "HOME level 3", "🏠", "home", 3),
+		attrLevelAchievement("outdoorsy", "Outdoorsy", "OUT level 3", "🌲", "out", 3),
+		attrLevelAchievement("bookworm", "Bookworm", "READ level 3", "📚", "read", 3),
+		attrLevelAchievement("cinephile", "Cinephile", "CINEMA level 3", "🎬", "cinema", 3),
+		attrLevelAchievement("professional", "Professional", "CAREER level 3", "💼", "career", 3),
+
+		// Blueprint, project and habit firsts
+		countAchievement("first_blueprint", "Quest Accepted", "Complete any blueprint", "📜", CondBlueprints, 1),
+		countAchievement("first_project", "Project Manager", "Complete a project", "📦", CondProjects, 1),
+		countAchievement("habit_former", "Habit Former", "Create a habit", "🔁", CondHabits, 1),
+
+		// Counters, streaks and time windows
+		{ID: "iron_week", Name: "Iron Week", Description: "Complete 10 STR tasks within a week", Icon: "🏋️",
+			When: []AchievementCond{{Kind: CondCompletions, Attr: "str", Min: 10, Window: "7d"}}},
+		{ID: "streak_7", Name: "On Fire", Description: "Reach a 7-period habit streak", Icon: "🔥",
+			When: []AchievementCond{{Kind: CondStreak, Min: 7}}},
+		{ID: "streak_30", Name: "Unbreakable", Description: "Reach a 30-period habit streak", Icon: "⛓️",
+			When: []AchievementCond{{Kind: CondStreak, Min: 30}}},
+		{ID: "epic_slayer", Name: "Epic Slayer", Description: "Complete an epic task", Icon: "🐉",
+			When: []AchievementCond{{Kind: CondCompletions, Difficulty: "epic"}}},
+		{ID: "night_owl", Name: "Night Owl", Description: "Complete 5 tasks between 10pm and 4am", Icon: "🦉",
+			When: []AchievementCond{{Kind: CondCompletions, Min: 5, Hours: "22-4"}}},
+		{ID: "early_bird", Name: "Early Bird", Description: "Complete 5 tasks between 5am and 8am", Icon: "🐦",
+			When: []AchievementCond{{Kind: CondCompletions, Min: 5, Hours: "5-8"}}},
+		{ID: "scholar", Name: "Scholar", Description: "Earn 5000 INT XP", Icon: "🎓",
+			When: []AchievementCond{{Kind: CondAttrXP, Attr: "int", Min: 5000}}},
+		{ID: "deep_focus", Name: "Deep Focus", Description: "Track 25 hours on tasks", Icon: "⏱️",
+			When: []AchievementCond{{Kind: CondTracked, Min: 25}}},
+		{ID: "page_turner", Name: "Page Turner", Description: "Track 10 hours of READ", Icon: "📖",
+			When: []AchievementCond{{Kind: CondTracked, Attr: "read", Min: 10}}},
+		{ID: "renaissance", Name: "Renaissance", Description: "STR, INT and ART level 2", Icon: "🏛️",
+			When: []AchievementCond{
+				{Kind: CondAttrLevel, Attr: "str", Min: 2},
+				{Kind: CondAttrLevel, Attr: "int", Min: 2},
+				{Kind: CondAttrLevel, Attr: "art", Min: 2},
+			}},
+	}
+	for i := range defs {
+		if err := defs[i].validate(); err != nil {
+			panic(fmt.Sprintf("built-in achievement %s: %v", defs[i].ID, err))
+		}
+	}
+	return defs
+}
+
+// validate checks the definition and parses its conditions.
+func (d *AchievementDef) validate() error {
+	d.ID = strings.ToLower(strings.TrimSpace(d.ID))
+	if d.ID == "" {
+		return errors.New("id is required")
+	}
+	if strings.TrimSpace(d.Name) == "" {
+		return errors.New("name is required")
+	}
+	if d.Icon == "" {
+		d.Icon = "🏅"
+	}
+	if len(d.When) == 0 {
+		return errors.New("at least one condition is required under when")
+	}
+	for i := range d.When {
+		if err := d.When[i].validate(); err != nil {
+			return fmt.Errorf("condition %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (c *AchievementCond) validate() error {
+	known := false
+	for _, k := range achievementCondKinds {
+		known = known || c.Kind == k
+	}
+	if !known {
+		names := make([]string, len(achievementCondKinds))
+		for i, k := range achievementCondKinds {
+			names[i] = string(k)
+		}
+		return fmt.Errorf("unknown kind %q (%s)", c.Kind, strings.Join(names, ", "))
+	}
+	if c.Min == 0 {
+		c.Min = 1
+	}
+	if c.Min < 0 {
+		return errors.New("min must be positive")
+	}
+
+	c.attr = Attribute(strings.ToUpper(strings.TrimSpace(c.Attr)))
+	if (c.Kind == CondAttrLevel || c.Kind == CondAttrXP) && c.attr == "" {
+		return fmt.Errorf("%s needs attr", c.Kind)
+	}
+	counts := c.Kind == CondTasksDone || c.Kind == CondCompletions || c.Kind == CondStreak || c.Kind == CondHabits || c.Kind == CondTracked
+	if c.attr != "" && !counts && c.Kind != CondAttrLevel && c.Kind != CondAttrXP {
+		return fmt.Errorf("%s has no attr filter", c.Kind)
+	}
+	if c.Tag != "" {
+		if !counts {
+			return fmt.Errorf("%s has no tag filter", c.Kind)
+		}
+		tag, err := NormalizeTag(c.Tag)
+		if err != nil {
+			return err
+		}
+		c.tag = tag
+	}
+	if c.Difficulty != "" {
+		if c.Kind != CondTasksDone && c.Kind != CondCompletions {
+			return fmt.Errorf("%s has no difficulty filter", c.Kind)
+		}
+		d, err := parseDifficultyName(c.Difficulty)
+		if err != nil {
+			return err
+		}
+		c.difficulty = d
+	}
+	if c.Window != "" || c.Hours != "" {
+		if c.Kind != CondCompletions {
+			return errors.New("window and hours only apply to completions")
+		}
+	}
+	if c.Window != "" {
+		w, err := parseWindow(c.Window)
+		if err != nil {
+			return err
+		}
+		c.window = w
+	}
+	if c.Hours != "" {
+		from, to, ok := strings.Cut(c.Hours, "-")
+		f, err1 := strconv.Atoi(strings.TrimSpace(from))
+		t, err2 := strconv.Atoi(strings.TrimSpace(to))
+		if !ok || err1 != nil || err2 != nil || f < 0 || f > 23 || t < 0 || t > 24 || f == t {
+			return fmt.Errorf("invalid hours %q (e.g. 22-4 or 5-8)", c.Hours)
+		}
+		c.fromHour, c.toHour, c.hasHours = f, t, true
+	}
+	return nil
+}
+
+// parseWindow accepts day, week, month or a count of hours or days (36h, 7d).
+func parseWindow(input string) (time.Duration, error) {
+	s := strings.ToLower(strings.TrimSpace(input))
+	switch s {
+	case "day":
+		return 24 * time.Hour, nil
+	case "week":
+		return 7 * 24 * time.Hour, nil
+	case "month":
+		return 30 * 24 * time.Hour, nil
+	}
+	if n, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil && strings.HasSuffix(s, "d") && n > 0 {
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	if n, err := strconv.Atoi(strings.TrimSuffix(s, "h")); err == nil && strings.HasSuffix(s, "h") && n > 0 {
+		return time.Duration(n) * time.Hour, nil
+	}
+	return 0, fmt.Errorf("invalid window %q (day, week, month, 36h, 7d)", input)
+}
+
+// inHours reports whether t falls in the condition's time of day; ranges may
+// wrap around midnight.
+func (c *AchievementCond) inHours(t time.Time) bool {
+	if !c.hasHours {
+		return true
+	}
+	h := t.In(time.Local).Hour()
+	if c.fromHour < c.toHour {
+		return h >= c.fromHour && h < c.toHour
+	}
+	return h >= c.fromHour || h < c.toHour
+}
+
+// DefaultAchievementsDir returns ~/.config/questline/achievements.
+func DefaultAchievementsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve home dir: %w", err)
+	}
+	return filepath.Join(home, ".config", "questline", "achievements"), nil
+}
+
+// ResolveAchievementsDir returns $QL_ACHIEVEMENTS_DIR if set, otherwise the default directory.
+func ResolveAchievementsDir() (string, error) {
+	if v := os.Getenv(EnvAchievementsDir); v != "" {
+		return v, nil
+	}
+	return DefaultAchievementsDir()
+}
+
+// LoadAchievementDir reads every .yaml, .yml and .json file in dir. Each file
+// holds one achievement or a list of them:
+//
+//	id: gym_rat
+//	name: Gym Rat
+//	description: 12 STR workouts in a month, and a 4-week streak
+//	icon: 🏋️
+//	when:
+//	  - kind: completions
+//	    attr: str
+//	    min: 12
+//	    window: month
+//	  - kind: streak
+//	    min: 4
+//
+// It returns the achievements that parsed and one error per broken file or
+// achievement; a missing dir has none of either.
+func LoadAchievementDir(dir string) ([]AchievementDef, []error) {
+	paths, err := listDataFiles(dir)
+	if err != nil {
+		return nil, []error{fmt.Errorf("read achievements dir: %w", err)}
+	}
+	var defs []AchievementDef
+	var errs []error
+	for _, path := range paths {
+		d, fileErrs := LoadAchievementFile(path)
+		defs = append(defs, d...)
+		errs = append(errs, fileErrs...)
+	}
+	return defs, errs
+}
+
+// LoadAchievementFile reads the achievements in one file.
+func LoadAchievementFile(path string) ([]AchievementDef, []error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, []error{fmt.Errorf("read achievement: %w", err)}
+	}
+	list, err := decodeOneOrList[AchievementDef](data)
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", path, err)}
+	}
+	var defs []AchievementDef
+	var errs []error
+	for i := range list {
+		d := list[i]
+		if err := d.validate(); err != nil {
+			name := d.ID
+			if name == "" {
+				name = "#" + strconv.Itoa(i+1)
+			}
+			errs = append(errs, fmt.Errorf("%s: achievement %s: %w", path, name, err))
+			continue
+		}
+		d.Source = path
+		defs = append(defs, d)
+	}
+	return defs, errs
+}
+
+// ValidateAchievements checks achievements loaded from files against the
+// rest of the setup: ids must be unique and attributes registered. Each
+// problem is prefixed with the achievement's file.
+func (s *Service) ValidateAchievements(ctx context.Context, defs []AchievementDef) ([]error, error) {
+	reg, err := s.Attributes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]string{}
+	for _, d := range BuiltinAchievements() {
+		known[d.ID] = "built-in"
+	}
+	var problems []error
+	for _, d := range defs {
+		if src, ok := known[d.ID]; ok {
+			problems = append(problems, fmt.Errorf("%s: achievement %s: id is already used by %s", d.Source, d.ID, src))
+		} else {
+			known[d.ID] = d.Source
+		}
+		for i, c := range d.When {
+			if _, ok := reg.Lookup(string(c.attr)); c.attr != "" && !ok {
+				problems = append(problems, fmt.Errorf("%s: achievement %s: condition %d: unknown attribute %s", d.Source, d.ID, i+1, c.attr))
+			}
+		}
+	}
+	return problems, nil
+}
+
+// resolveAchievementAttrs returns defs with attribute aliases in their
+// conditions replaced by the registered codes.
+func (s *Service) resolveAchievementAttrs(ctx context.Context, defs []AchievementDef) ([]AchievementDef, error) {
+	reg, err := s.Attributes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]AchievementDef, len(defs))
+	for i, d := range defs {
+		d.When = append([]AchievementCond(nil), d.When...)
+		for j := range d.When {
+			if a, ok := reg.Lookup(string(d.When[j].attr)); d.When[j].attr != "" && ok {
+				d.When[j].attr = a.Code
+			}
+		}
+		out[i] = d
+	}
+	return out, nil
+}
+
+// AddUserAchievements registers achievements loaded from files (see
+// LoadAchievementDir). An achievement whose id is already taken is skipped and
+// reported.
+func (s *Service) AddUserAchievements(defs []AchievementDef) []error {
+	taken := map[string]bool{}
+	for _, d := range s.AchievementDefs() {
+		taken[d.ID] = true
+	}
+	var errs []error
+	for _, d := range defs {
+		if taken[d.ID] {
+			errs = append(errs, fmt.Errorf("%s: achievement id %q is already defined", d.Source, d.ID))
+			continue
+		}
+		taken[d.ID] = true
+		s.userAchievements = append(s.userAchievements, d)
+	}
+	return errs
+}
+
+// AchievementDefs returns the built-in achievements followed by the user's.
+func (s *Service) AchievementDefs() []AchievementDef {
+	return append(BuiltinAchievements(), s.userAchievements...)
+}
@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"questline/internal/storage"
+)
+
+func TestAchievementConditions(t *testing.T) {
+	day := func(d, hour int) time.Time { return time.Date(2026, 3, d, hour, 0, 0, 0, time.Local) }
+	tasks := []storage.Task{
+		{ID: 1, Status: "done", Attribute: "STR", Difficulty: 2},
+		{ID: 2, Status: "done", Attribute: "INT", Attributes: map[string]int{"INT": 50, "STR": 50}, Difficulty: 5},
+		{ID: 3, Status: "todo", Attribute: "WIS", IsHabit: true},
+	}
+	completions := []storage.TaskCompletion{
+		{TaskID: 1, CompletedAt: day(1, 23), Difficulty: 2},
+		{TaskID: 1, CompletedAt: day(3, 1), Difficulty: 2},
+		{TaskID: 2, CompletedAt: day(6, 7), Difficulty: 5},
+		{TaskID: 3, CompletedAt: day(20, 12), Difficulty: 1},
+	}
+	player := &storage.Player{XPTotal: XPRequiredForLevel(4), XP: map[string]int{"STR": XPRequiredForLevel(2), "INT": XPRequiredForLevel(3)}}
+
+	tests := []struct {
+		name string
+		when []AchievementCond
+		want bool
+	}{
+		{"level", []AchievementCond{{Kind: CondLevel, Min: 4}}, true},
+		{"level too low", []AchievementCond{{Kind: CondLevel, Min: 5}}, false},
+		{"attr level", []AchievementCond{{Kind: CondAttrLevel, Attr: "int", Min: 3}}, true},
+		{"attr xp", []AchievementCond{{Kind: CondAttrXP, Attr: "str", Min: XPRequiredForLevel(2) + 1}}, false},
+		{"tasks done", []AchievementCond{{Kind: CondTasksDone, Min: 2}}, true},
+		{"tasks done by weight", []AchievementCond{{Kind: CondTasksDone, Attr: "str", Min: 2}}, true},
+		{"epic completion", []AchievementCond{{Kind: CondCompletions, Difficulty: "epic"}}, true},
+		{"str completions in a week", []AchievementCond{{Kind: CondCompletions, Attr: "str", Min: 3, Window: "week"}}, true},
+		{"str completions in 4 days", []AchievementCond{{Kind: CondCompletions, Attr: "str", Min: 3, Window: "4d"}}, false},
+		{"all completions in a day", []AchievementCond{{Kind: CondCompletions, Min: 2, Window: "day"}}, false},
+		{"all completions in 27h", []AchievementCond{{Kind: CondCompletions, Min: 2, Window: "27h"}}, true},
+		{"night owl", []AchievementCond{{Kind: CondCompletions, Min: 2, Hours: "22-4"}}, true},
+		{"early bird", []AchievementCond{{Kind: CondCompletions, Min: 2, Hours: "5-8"}}, false},
+		{"streak", []AchievementCond{{Kind: CondStreak, Min: 7}}, true},
+		{"streak by attribute", []AchievementCond{{Kind: CondStreak, Attr: "str", Min: 1}}, false},
+		{"habits", []AchievementCond{{Kind: CondHabits, Attr: "wis"}}, true},
+		{"projects", []AchievementCond{{Kind: CondProjects}}, false},
+		{"combo", []AchievementCond{{Kind: CondLevel, Min: 4}, {Kind: CondAttrLevel, Attr: "str", Min: 2}}, true},
+		{"combo with one unmet", []AchievementCond{{Kind: CondLevel, Min: 4}, {Kind: CondBlueprints}}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			def := AchievementDef{ID: "test", Name: "Test", When: tc.when}
+			if err := def.validate(); err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			checker := NewAchievementChecker(player, tasks, nil)
+			checker.completions = completions
+			checker.streaks = map[int64]HabitStreak{3: {Current: 2, Longest: 9}}
+			checker.defs = []AchievementDef{def}
+			if got := checker.GetAchievements()[0].Earned; got != tc.want {
+				t.Fatalf("earned=%v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUserAchievementsFromDir(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"gym.yaml": `
+id: gym_rat
+name: Gym Rat
+description: 2 STR workouts in a week
+icon: 🏋️
+when:
+  - kind: completions
+    attr: strength
+    min: 2
+    window: week
+  - kind: tasks_done
+    difficulty: trivial
+`,
+		"more.json": `[{"id": "first_steps", "name": "Copycat", "when": [{"kind": "level"}]}]`,
+		"broken.yaml": `
+- id: nowhen
+  name: Nothing
+- id: bad_kind
+  name: Bad
+  when:
+    - kind: vibes
+- id: bad_window
+  name: Bad window
+  when:
+    - kind: completions
+      window: fortnight
+- id: bad_filter
+  name: Bad filter
+  when:
+    - kind: level
+      hours: 22-4
+`,
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defs, errs := LoadAchievementDir(dir)
+	if len(defs) != 2 || len(errs) != 4 {
+		t.Fatalf("loaded %d achievements with errors %v; want 2 and 4 errors", len(defs), errs)
+	}
+	for _, want := range []string{"at least one condition", `unknown kind "vibes"`, `invalid window "fortnight"`, "only apply to completions"} {
+		found := false
+		for _, err := range errs {
+			found = found || strings.Contains(err.Error(), want)
+		}
+		if !found {
+			t.Errorf("no error mentions %q in %v", want, errs)
+		}
+	}
+
+	problems, err := svc.ValidateAchievements(ctx, defs)
+	if err != nil {
+		t.Fatalf("ValidateAchievements: %v", err)
+	}
+	if len(problems) != 2 {
+		t.Fatalf("problems=%v, want the id clash and the unknown attribute STRENGTH", problems)
+	}
+	if errs := svc.AddUserAchievements(defs); len(errs) != 1 {
+		t.Fatalf("AddUserAchievements errors=%v, want the id clash", errs)
+	}
+
+	// The alias resolves when the registry knows it.
+	if _, err := svc.db.ExecContext(ctx, `UPDATE attributes SET aliases = 'strength' WHERE code = 'STR'`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		c, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Lift", Difficulty: DifficultyTrivial, Attribute: AttributeSTR})
+		if err != nil {
+			t.Fatalf("CreateTask: %v", err)
+		}
+		res, err := svc.CompleteTask(ctx, c.TaskID)
+		if err != nil {
+			t.Fatalf("CompleteTask: %v", err)
+		}
+		gotGym := false
+		for _, a := range res.Achievements {
+			gotGym = gotGym || a.ID == "gym_rat"
+		}
+		if gotGym != (i == 1) {
+			t.Fatalf("completion %d earned %+v", i+1, res.Achievements)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package engine
 
 import (
 	"context"
+	"sort"
+	"strings"
+	"time"
 
 	"questline/internal/storage"
 )
@@ -13,61 +16,52 @@ type Achievement struct {
 	Description string
 	Icon        string
 	Earned      bool
+	EarnedAt    *time.Time // when it was first earned; nil if earned before achievements were recorded
 }
 
 // AchievementChecker calculates which achievements the player has earned.
 type AchievementChecker struct {
-	player     *storage.Player
-	tasks      []storage.Task
-	blueprints []storage.Blueprint
+	player      *storage.Player
+	tasks       []storage.Task
+	blueprints  []storage.Blueprint
+	completions []storage.TaskCompletion
+	streaks     map[int64]HabitStreak
+	tags        map[int64]TaskTags
+	sessions    []storage.TaskSession
+	defs        []AchievementDef
+	rules       *Rules
 }
 
+// NewAchievementChecker checks the built-in achievements. Conditions on
+// completions and streaks only see what the service loads into the checker, so
+// prefer GetAchievementsForPlayer.
 func NewAchievementChecker(player *storage.Player, tasks []storage.Task, blueprints []storage.Blueprint) *AchievementChecker {
 	return &AchievementChecker{
 		player:     player,
 		tasks:      tasks,
 		blueprints: blueprints,
+		defs:       BuiltinAchievements(),
+		rules:      defaultRules,
 	}
 }
 
 // GetAchievements returns all achievements with their earned status.
 func (c *AchievementChecker) GetAchievements() []Achievement {
-	achievements := []Achievement{
-		// Level milestones
-		c.levelAchievement("first_steps", "First Steps", "Reach level 1", "🌱", 1),
-		c.levelAchievement("getting_started", "Getting Started", "Reach level 3", "🌿", 3),
-		c.levelAchievement("on_the_path", "On the Path", "Reach level 5", "🌳", 5),
-		c.levelAchievement("seasoned", "Seasoned Adventurer", "Reach level 10", "⭐", 10),
-		c.levelAchievement("veteran", "Veteran", "Reach level 15", "🌟", 15),
-		c.levelAchievement("master", "Master", "Reach level 20", "💫", 20),
-
-		// Task completion milestones
-		c.taskCountAchievement("first_task", "First Quest", "Complete 1 task", "✓", 1),
-		c.taskCountAchievement("productive", "Productive", "Complete 10 tasks", "📋", 10),
-		c.taskCountAchievement("achiever", "Achiever", "Complete 50 tasks", "🏅", 50),
-		c.taskCountAchievement("powerhouse", "Powerhouse", "Complete 100 tasks", "🏆", 100),
-
-		// Attribute level achievements
-		c.attrLevelAchievement("strong", "Strong", "STR level 3", "💪", "str", 3),
-		c.attrLevelAchievement("smart", "Smart", "INT level 3", "🧠", "int", 3),
-		c.attrLevelAchievement("wise", "Wise", "WIS level 3", "🧘", "wis", 3),
-		c.attrLevelAchievement("creative", "Creative", "ART level 3", "🎨", "art", 3),
-		c.attrLevelAchievement("homemaker", "Homemaker", "HOME level 3", "🏠", "home", 3),
-		c.attrLevelAchievement("outdoorsy", "Outdoorsy", "OUT level 3", "🌲", "out", 3),
-		c.attrLevelAchievement("bookworm", "Bookworm", "READ level 3", "📚", "read", 3),
-		c.attrLevelAchievement("cinephile", "Cinephile", "CINEMA level 3", "🎬", "cinema", 3),
-		c.attrLevelAchievement("professional", "Professional", "CAREER level 3", "💼", "career", 3),
-
-		// Blueprint completions
-		c.blueprintAchievement("first_blueprint", "Quest Accepted", "Complete any blueprint", "📜"),
-
-		// Project achievements
-		c.projectAchievement("first_project", "Project Manager", "Complete a project", "📦"),
-
-		// Habit achievements
-		c.habitAchievement("habit_former", "Habit Former", "Create a habit", "🔁"),
+	byID := make(map[int64]*storage.Task, len(c.tasks))
+	for i := range c.tasks {
+		byID[c.tasks[i].ID] = &c.tasks[i]
+	}
+	achievements := make([]Achievement, 0, len(c.defs))
+	for _, d := range c.defs {
+		earned := true
+		for i := range d.When {
+			if !c.condMet(&d.When[i], byID) {
+				earned = false
+				break
+			}
+		}
+		achievements = append(achievements, Achievement{ID: d.ID, Name: d.Name, Description: d.Description, Icon: d.Icon, Earned: earned})
 	}
-
 	return achievements
 }
 
@@ -84,98 +78,232 @@ func (c *AchievementChecker) CountEarned() int {
 
 // CountTotal returns total number of achievements.
 func (c *AchievementChecker) CountTotal() int {
-	return len(c.GetAchievements())
+	return len(c.defs)
 }
 
-func (c *AchievementChecker) levelAchievement(id, name, desc, icon string, level int) Achievement {
-	earned := LevelForTotalXP(c.player.XPTotal) >= level
-	return Achievement{ID: id, Name: name, Description: desc, Icon: icon, Earned: earned}
-}
-
-func (c *AchievementChecker) taskCountAchievement(id, name, desc, icon string, count int) Achievement {
-	doneCount := 0
-	for _, t := range c.tasks {
-		if t.Status == "done" && !t.IsProject {
-			doneCount++
+func (c *AchievementChecker) condMet(cond *AchievementCond, byID map[int64]*storage.Task) bool {
+	switch cond.Kind {
+	case CondLevel:
+		return c.rules.LevelForTotalXP(c.player.XPTotal) >= cond.Min
+	case CondAttrLevel:
+		return c.rules.AttributeLevelForXP(c.player.AttrXP(string(cond.attr))) >= cond.Min
+	case CondAttrXP:
+		return c.player.AttrXP(string(cond.attr)) >= cond.Min
+	case CondTasksDone:
+		n := 0
+		for i := range c.tasks {
+			t := &c.tasks[i]
+			if t.Status == "done" && !t.IsProject && c.matches(cond, t) && t.Difficulty >= int(cond.difficulty) {
+				n++
+			}
+		}
+		return n >= cond.Min
+	case CondCompletions:
+		var times []time.Time
+		for _, tc := range c.completions {
+			if cond.attr != "" || cond.tag != "" {
+				t, ok := byID[tc.TaskID]
+				if !ok || !c.matches(cond, t) {
+					continue
+				}
+			}
+			if tc.Difficulty < int(cond.difficulty) || !cond.inHours(tc.CompletedAt) {
+				continue
+			}
+			times = append(times, tc.CompletedAt)
+		}
+		if cond.window == 0 {
+			return len(times) >= cond.Min
 		}
+		return maxInWindow(times, cond.window) >= cond.Min
+	case CondStreak:
+		for id, st := range c.streaks {
+			if t, ok := byID[id]; ok && c.matches(cond, t) && st.Longest >= cond.Min {
+				return true
+			}
+		}
+		return false
+	case CondBlueprints:
+		n := 0
+		for _, b := range c.blueprints {
+			if b.Status == "completed" {
+				n++
+			}
+		}
+		return n >= cond.Min
+	case CondProjects:
+		n := 0
+		for i := range c.tasks {
+			if c.tasks[i].IsProject && c.tasks[i].Status == "done" {
+				n++
+			}
+		}
+		return n >= cond.Min
+	case CondHabits:
+		n := 0
+		for i := range c.tasks {
+			if c.tasks[i].IsHabit && c.matches(cond, &c.tasks[i]) {
+				n++
+			}
+		}
+		return n >= cond.Min
+	case CondTracked:
+		var total time.Duration
+		now := time.Now()
+		for i := range c.sessions {
+			if t, ok := byID[c.sessions[i].TaskID]; ok && c.matches(cond, t) {
+				total += c.sessions[i].Duration(now)
+			}
+		}
+		return total >= time.Duration(cond.Min)*time.Hour
 	}
-	earned := doneCount >= count
-	return Achievement{ID: id, Name: name, Description: desc, Icon: icon, Earned: earned}
+	return false
 }
 
-func (c *AchievementChecker) attrLevelAchievement(id, name, desc, icon, attr string, level int) Achievement {
-	attrXP := 0
-	switch attr {
-	case "str":
-		attrXP = c.player.XPStr
-	case "int":
-		attrXP = c.player.XPInt
-	case "wis":
-		attrXP = c.player.XPWis
-	case "art":
-		attrXP = c.player.XPArt
-	case "home":
-		attrXP = c.player.XPHome
-	case "out":
-		attrXP = c.player.XPOut
-	case "read":
-		attrXP = c.player.XPRead
-	case "cinema":
-		attrXP = c.player.XPCinema
-	case "career":
-		attrXP = c.player.XPCareer
-	}
-	earned := AttributeLevelForXP(attrXP) >= level
-	return Achievement{ID: id, Name: name, Description: desc, Icon: icon, Earned: earned}
+// matches reports whether t passes the condition's attribute and tag filters.
+func (c *AchievementChecker) matches(cond *AchievementCond, t *storage.Task) bool {
+	return cond.matchesTask(t) && (cond.tag == "" || c.tags[t.ID].Has(cond.tag))
 }
 
-func (c *AchievementChecker) blueprintAchievement(id, name, desc, icon string) Achievement {
-	earned := false
-	for _, b := range c.blueprints {
-		if b.Status == "completed" {
-			earned = true
-			break
-		}
+// matchesTask reports whether t gives XP to the condition's attribute (any
+// task when it has none).
+func (c *AchievementCond) matchesTask(t *storage.Task) bool {
+	if c.attr == "" || strings.EqualFold(t.Attribute, string(c.attr)) {
+		return true
 	}
-	return Achievement{ID: id, Name: name, Description: desc, Icon: icon, Earned: earned}
-}
-
-func (c *AchievementChecker) projectAchievement(id, name, desc, icon string) Achievement {
-	earned := false
-	for _, t := range c.tasks {
-		if t.IsProject && t.Status == "done" {
-			earned = true
-			break
+	for a, w := range t.Attributes {
+		if w > 0 && strings.EqualFold(a, string(c.attr)) {
+			return true
 		}
 	}
-	return Achievement{ID: id, Name: name, Description: desc, Icon: icon, Earned: earned}
+	return false
 }
 
-func (c *AchievementChecker) habitAchievement(id, name, desc, icon string) Achievement {
-	earned := false
-	for _, t := range c.tasks {
-		if t.IsHabit {
-			earned = true
-			break
+// maxInWindow returns the most times that fall within any stretch of length w.
+func maxInWindow(times []time.Time, w time.Duration) int {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	best, lo := 0, 0
+	for hi := range times {
+		for times[hi].Sub(times[lo]) >= w {
+			lo++
+		}
+		if n := hi - lo + 1; n > best {
+			best = n
 		}
 	}
-	return Achievement{ID: id, Name: name, Description: desc, Icon: icon, Earned: earned}
+	return best
 }
 
-// GetAchievementsForPlayer is a convenience function.
+// GetAchievementsForPlayer is a convenience function. Achievements that were
+// recorded as earned stay earned, even if the state that earned them is gone.
 func GetAchievementsForPlayer(ctx context.Context, svc *Service) ([]Achievement, error) {
 	player, err := svc.PlayerRepo().GetOrCreateMain(ctx)
 	if err != nil {
 		return nil, err
 	}
-	tasks, err := svc.TaskRepo().ListAll(ctx)
+	achievements, err := svc.computeAchievements(ctx, player)
+	if err != nil {
+		return nil, err
+	}
+	earned, err := svc.achievements.ListByPlayer(ctx, player.Key)
+	if err != nil {
+		return nil, err
+	}
+	at := make(map[string]time.Time, len(earned))
+	for _, e := range earned {
+		at[e.ID] = e.EarnedAt
+	}
+	for i := range achievements {
+		if t, ok := at[achievements[i].ID]; ok {
+			achievements[i].Earned = true
+			achievements[i].EarnedAt = &t
+		}
+	}
+	return achievements, nil
+}
+
+// computeAchievements evaluates every achievement against the current state.
+func (s *Service) computeAchievements(ctx context.Context, p *storage.Player) ([]Achievement, error) {
+	tasks, err := s.tasks.ListAll(ctx)
 	if err != nil {
 		return nil, err
 	}
-	blueprints, err := svc.BlueprintRepo().ListAll(ctx)
+	blueprints, err := s.blueprints.ListAll(ctx)
 	if err != nil {
 		return nil, err
 	}
-	checker := NewAchievementChecker(player, tasks, blueprints)
+	checker := NewAchievementChecker(p, tasks, blueprints)
+	checker.rules = s.rules
+	if checker.defs, err = s.resolveAchievementAttrs(ctx, s.AchievementDefs()); err != nil {
+		return nil, err
+	}
+
+	// Completions, streaks, tags and sessions are only loaded when some
+	// achievement needs them.
+	var needCompletions, needStreaks, needTags, needSessions bool
+	for _, d := range checker.defs {
+		for _, c := range d.When {
+			needCompletions = needCompletions || c.Kind == CondCompletions
+			needStreaks = needStreaks || c.Kind == CondStreak
+			needTags = needTags || c.tag != ""
+			needSessions = needSessions || c.Kind == CondTracked
+		}
+	}
+	if needCompletions {
+		if checker.completions, err = s.completions.ListAll(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if needStreaks {
+		if checker.streaks, err = s.HabitStreaks(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if needTags {
+		if checker.tags, err = s.TaskTags(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if needSessions {
+		if checker.sessions, err = s.sessions.ListAll(ctx); err != nil {
+			return nil, err
+		}
+	}
 	return checker.GetAchievements(), nil
 }
+
+// recordAchievements stores achievements the player has newly earned and
+// returns them. Players from before achievements were recorded get their
+// current badges stored once without announcing them.
+func (s *Service) recordAchievements(ctx context.Context) ([]Achievement, error) {
+	p, err := s.getPlayer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	achievements, err := s.computeAchievements(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	var earned []Achievement
+	for _, a := range achievements {
+		if !a.Earned {
+			continue
+		}
+		added, err := s.achievements.Insert(ctx, storage.EarnedAchievement{PlayerKey: p.Key, ID: a.ID, EarnedAt: now})
+		if err != nil {
+			return nil, err
+		}
+		if added && p.AchievementsSince != nil {
+			a.EarnedAt = &now
+			earned = append(earned, a)
+		}
+	}
+	if p.AchievementsSince == nil {
+		p.AchievementsSince = &now
+		if err := s.players.Update(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+	return earned, nil
+}
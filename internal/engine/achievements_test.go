@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"questline/internal/storage"
+)
+
+func TestAchievementsArePersistedAndAnnouncedOnce(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	earned := func(list []Achievement) map[string]bool {
+		out := map[string]bool{}
+		for _, a := range list {
+			out[a.ID] = true
+		}
+		return out
+	}
+
+	c, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Email", Difficulty: DifficultyTrivial, Attribute: AttributeINT})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if len(c.Achievements) != 0 {
+		t.Fatalf("create earned %v", c.Achievements)
+	}
+	res, err := svc.CompleteTask(ctx, c.TaskID)
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if got := earned(res.Achievements); !got["first_task"] || res.Achievements[0].EarnedAt == nil {
+		t.Fatalf("achievements=%+v, want first_task with a timestamp", res.Achievements)
+	}
+
+	// Restoring the completion does not take the badge away, and it is not announced twice.
+	if _, err := svc.RestoreTask(ctx, c.TaskID); err != nil {
+		t.Fatalf("RestoreTask: %v", err)
+	}
+	all, err := GetAchievementsForPlayer(ctx, svc)
+	if err != nil {
+		t.Fatalf("GetAchievementsForPlayer: %v", err)
+	}
+	for _, a := range all {
+		if a.ID == "first_task" && (!a.Earned || a.EarnedAt == nil) {
+			t.Fatalf("first_task after restore=%+v, want still earned", a)
+		}
+	}
+	if res, err = svc.CompleteTask(ctx, c.TaskID); err != nil || earned(res.Achievements)["first_task"] {
+		t.Fatalf("complete again: %+v, %v; want first_task not announced again", res.Achievements, err)
+	}
+
+	// A player from before achievements were recorded gets them stored silently.
+	if _, err := svc.db.ExecContext(ctx, `DELETE FROM achievements; UPDATE player SET achievements_since = NULL`); err != nil {
+		t.Fatal(err)
+	}
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelHabits))
+	h, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Stretch", Difficulty: DifficultyTrivial, Attribute: AttributeSTR, IsHabit: true, HabitInterval: HabitIntervalDaily})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if len(h.Achievements) != 0 {
+		t.Fatalf("legacy player announced %+v", h.Achievements)
+	}
+	stored, err := svc.achievements.ListByPlayer(ctx, storage.MainPlayerKey)
+	if err != nil {
+		t.Fatalf("ListByPlayer: %v", err)
+	}
+	got := map[string]bool{}
+	for _, e := range stored {
+		got[e.ID] = true
+	}
+	if !got["first_task"] || !got["habit_former"] || !got["on_the_path"] {
+		t.Fatalf("stored=%v, want the legacy player's badges", got)
+	}
+}
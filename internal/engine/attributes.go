@@ -0,0 +1,184 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"questline/internal/storage"
+)
+
+// AttributeDef describes an attribute track: its code, how it is displayed and
+// the aliases accepted on input.
+type AttributeDef struct {
+	Code    Attribute
+	Name    string
+	Icon    string
+	Aliases []string
+	Color   string // lipgloss color (ANSI number or #RRGGBB)
+	Builtin bool
+}
+
+// BuiltinAttributes returns the attribute tracks every database starts with.
+func BuiltinAttributes() []AttributeDef {
+	return []AttributeDef{
+		{Code: AttributeSTR, Name: "Strength", Icon: "💪", Color: "196", Builtin: true},
+		{Code: AttributeINT, Name: "Intelligence", Icon: "🧠", Color: "51", Builtin: true},
+		{Code: AttributeWIS, Name: "Wisdom", Icon: "🧘", Color: "141", Builtin: true},
+		{Code: AttributeART, Name: "Art", Icon: "🎨", Color: "201", Builtin: true},
+		{Code: AttributeHOME, Name: "Home", Icon: "🏠", Color: "214", Builtin: true},
+		{Code: AttributeOUT, Name: "Outdoors", Icon: "🌲", Aliases: []string{"outdoors"}, Color: "34", Builtin: true},
+		{Code: AttributeREAD, Name: "Reading", Icon: "📚", Aliases: []string{"reading"}, Color: "180", Builtin: true},
+		{Code: AttributeCINEMA, Name: "Cinema", Icon: "🎬", Aliases: []string{"movies", "film"}, Color: "135", Builtin: true},
+		{Code: AttributeCAREER, Name: "Career", Icon: "💼", Aliases: []string{"finance", "work"}, Color: "39", Builtin: true},
+	}
+}
+
+// AttributeRegistry resolves attribute codes and aliases to their definitions.
+type AttributeRegistry struct {
+	defs  []AttributeDef
+	index map[string]int // lowercased code or alias -> position in defs
+}
+
+func NewAttributeRegistry(defs []AttributeDef) *AttributeRegistry {
+	r := &AttributeRegistry{defs: defs, index: map[string]int{}}
+	for i, d := range defs {
+		r.index[strings.ToLower(string(d.Code))] = i
+		for _, a := range d.Aliases {
+			a = strings.ToLower(a)
+			if _, taken := r.index[a]; !taken {
+				r.index[a] = i
+			}
+		}
+	}
+	return r
+}
+
+var builtinRegistry = NewAttributeRegistry(BuiltinAttributes())
+
+// All returns every attribute in display order.
+func (r *AttributeRegistry) All() []AttributeDef {
+	return r.defs
+}
+
+// Lookup finds an attribute by code or alias (case-insensitive).
+func (r *AttributeRegistry) Lookup(input string) (AttributeDef, bool) {
+	i, ok := r.index[strings.ToLower(strings.TrimSpace(input))]
+	if !ok {
+		return AttributeDef{}, false
+	}
+	return r.defs[i], true
+}
+
+// Has reports whether a is a registered attribute code.
+func (r *AttributeRegistry) Has(a Attribute) bool {
+	d, ok := r.Lookup(string(a))
+	return ok && d.Code == a
+}
+
+// Def returns the definition for a, or a bare one named after the code if a is
+// not registered.
+func (r *AttributeRegistry) Def(a Attribute) AttributeDef {
+	if d, ok := r.Lookup(string(a)); ok && d.Code == a {
+		return d
+	}
+	return AttributeDef{Code: a, Name: string(a)}
+}
+
+// Attributes loads the attribute registry from the database.
+func (s *Service) Attributes(ctx context.Context) (*AttributeRegistry, error) {
+	rows, err := s.attributes.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defs := make([]AttributeDef, 0, len(rows))
+	for _, a := range rows {
+		defs = append(defs, AttributeDef{
+			Code:    Attribute(a.Code),
+			Name:    a.Name,
+			Icon:    a.Icon,
+			Aliases: a.Aliases,
+			Color:   a.Color,
+			Builtin: a.Builtin,
+		})
+	}
+	return NewAttributeRegistry(defs), nil
+}
+
+type AddAttributeInput struct {
+	Code    string
+	Name    string
+	Icon    string
+	Aliases []string
+	Color   string
+}
+
+var (
+	attributeCodeRe  = regexp.MustCompile(`^[A-Z][A-Z0-9_]{1,11}$`)
+	attributeAliasRe = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+	colorRe          = regexp.MustCompile(`^(#[0-9a-fA-F]{6}|[0-9]{1,3})$`)
+)
+
+// AddAttribute registers a custom attribute track.
+// Codes are 2-12 uppercase letters/digits; codes and aliases must not collide
+// with any existing attribute.
+func (s *Service) AddAttribute(ctx context.Context, in AddAttributeInput) (*AttributeDef, error) {
+	code := strings.ToUpper(strings.TrimSpace(in.Code))
+	if !attributeCodeRe.MatchString(code) {
+		return nil, fmt.Errorf("invalid attribute code %q (use 2-12 letters, digits or _)", in.Code)
+	}
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		name = code[:1] + strings.ToLower(code[1:])
+	}
+	color := strings.TrimSpace(in.Color)
+	if color != "" && !colorRe.MatchString(color) {
+		return nil, fmt.Errorf("invalid color %q (use an ANSI number or #RRGGBB)", in.Color)
+	}
+
+	var aliases []string
+	for _, a := range in.Aliases {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == "" || a == strings.ToLower(code) {
+			continue
+		}
+		if !attributeAliasRe.MatchString(a) {
+			return nil, fmt.Errorf("invalid alias %q", a)
+		}
+		aliases = append(aliases, a)
+	}
+
+	var def *AttributeDef
+	err := s.inTx(ctx, func(tx *Service) error {
+		reg, err := tx.Attributes(ctx)
+		if err != nil {
+			return err
+		}
+		for _, key := range append([]string{code}, aliases...) {
+			if existing, ok := reg.Lookup(key); ok {
+				return fmt.Errorf("%q is already used by attribute %s", strings.ToLower(key), existing.Code)
+			}
+		}
+		order, err := tx.attributes.MaxSortOrder(ctx)
+		if err != nil {
+			return err
+		}
+		if err := tx.attributes.Insert(ctx, storage.Attribute{
+			Code:      code,
+			Name:      name,
+			Icon:      strings.TrimSpace(in.Icon),
+			Aliases:   aliases,
+			Color:     color,
+			SortOrder: order + 10,
+		}); err != nil {
+			return err
+		}
+		def = &AttributeDef{Code: Attribute(code), Name: name, Icon: strings.TrimSpace(in.Icon), Aliases: aliases, Color: color}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return def, nil
+}
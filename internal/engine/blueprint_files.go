@@ -0,0 +1,407 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvBlueprintsDir overrides the directory user blueprints are loaded from.
+const EnvBlueprintsDir = "QL_BLUEPRINTS_DIR"
+
+// DefaultBlueprintsDir returns ~/.config/questline/blueprints.
+func DefaultBlueprintsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve home dir: %w", err)
+	}
+	return filepath.Join(home, ".config", "questline", "blueprints"), nil
+}
+
+// ResolveBlueprintsDir returns $QL_BLUEPRINTS_DIR if set, otherwise the default directory.
+func ResolveBlueprintsDir() (string, error) {
+	if v := os.Getenv(EnvBlueprintsDir); v != "" {
+		return v, nil
+	}
+	return DefaultBlueprintsDir()
+}
+
+// BlueprintFile is the on-disk form of a blueprint, in YAML or JSON:
+//
+//	code: sprint_retro
+//	kind: project                 # task, habit or project
+//	title: Sprint retrospective
+//	description: Look back on the sprint with the team.
+//	attribute: CAREER             # or "career:70,int:30"
+//	difficulty: medium            # trivial..epic or 1..5 (tasks and habits)
+//	schedule: weekly              # habits: any habit interval
+//	goal: 12                      # habits: completions that finish it
+//	repeatable: true              # can be accepted again once completed
+//	sequential: true              # projects: each child task waits for the previous one
+//	unlock:
+//	  - level >= 4
+//	  - CAREER >= 2 and blueprint career_resume completed
+//	children:                     # projects
+//	  - title: Collect feedback
+//	    difficulty: easy
+type BlueprintFile struct {
+	Code        string               `yaml:"code"`
+	Kind        string               `yaml:"kind"`
+	Title       string               `yaml:"title"`
+	Description string               `yaml:"description"`
+	Attribute   string               `yaml:"attribute"`
+	Difficulty  string               `yaml:"difficulty"`
+	Schedule    string               `yaml:"schedule"`
+	Goal        *int                 `yaml:"goal"`
+	Repeatable  bool                 `yaml:"repeatable"`
+	Sequential  bool                 `yaml:"sequential"`
+	Unlock      []string             `yaml:"unlock"`
+	Children    []BlueprintChildFile `yaml:"children"`
+}
+
+// BlueprintChildFile is a child task or habit of a project blueprint file.
+type BlueprintChildFile struct {
+	Title      string `yaml:"title"`
+	Attribute  string `yaml:"attribute"`
+	Difficulty string `yaml:"difficulty"`
+	Schedule   string `yaml:"schedule"` // makes the child a habit
+	Goal       *int   `yaml:"goal"`
+}
+
+// LoadBlueprintDir reads every .yaml, .yml and .json file in dir. Each file
+// holds one blueprint or a list of them. It returns the blueprints that parsed
+// and one error per broken file or blueprint; a missing dir has none of either.
+func LoadBlueprintDir(dir string) ([]BlueprintDef, []error) {
+	paths, err := listDataFiles(dir)
+	if err != nil {
+		return nil, []error{fmt.Errorf("read blueprints dir: %w", err)}
+	}
+	var defs []BlueprintDef
+	var errs []error
+	for _, path := range paths {
+		d, fileErrs := LoadBlueprintFile(path)
+		defs = append(defs, d...)
+		errs = append(errs, fileErrs...)
+	}
+	return defs, errs
+}
+
+// listDataFiles returns the .yaml, .yml and .json files in dir, sorted. A
+// missing dir has none.
+func listDataFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !e.IsDir() {
+				paths = append(paths, filepath.Join(dir, e.Name()))
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// LoadBlueprintFile reads the blueprints in one file.
+func LoadBlueprintFile(path string) ([]BlueprintDef, []error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, []error{fmt.Errorf("read blueprint: %w", err)}
+	}
+	files, err := decodeOneOrList[BlueprintFile](data)
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", path, err)}
+	}
+	var defs []BlueprintDef
+	var errs []error
+	for i, f := range files {
+		def, err := f.Def()
+		if err != nil {
+			name := f.Code
+			if name == "" {
+				name = "#" + strconv.Itoa(i+1)
+			}
+			errs = append(errs, fmt.Errorf("%s: blueprint %s: %w", path, name, err))
+			continue
+		}
+		def.Source = path
+		defs = append(defs, def)
+	}
+	return defs, errs
+}
+
+// decodeOneOrList decodes a YAML (or JSON) document holding one T or a list of
+// them, rejecting unknown keys.
+func decodeOneOrList[T any](data []byte) ([]T, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	if len(node.Content) == 0 {
+		return nil, errors.New("empty file")
+	}
+	strict := func(v any) error {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(v); err != nil && err != io.EOF {
+			return err
+		}
+		return nil
+	}
+	if node.Content[0].Kind == yaml.SequenceNode {
+		var list []T
+		return list, strict(&list)
+	}
+	var one T
+	if err := strict(&one); err != nil {
+		return nil, err
+	}
+	return []T{one}, nil
+}
+
+// Def checks the file form and converts it to a definition. Attribute codes
+// are checked against the registry later, by Service.ValidateBlueprints.
+func (f BlueprintFile) Def() (BlueprintDef, error) {
+	code, err := normalizeBlueprintCode(f.Code)
+	if err != nil {
+		return BlueprintDef{}, err
+	}
+	def := BlueprintDef{
+		Code:        code,
+		Kind:        BlueprintKind(strings.ToLower(strings.TrimSpace(f.Kind))),
+		Title:       strings.TrimSpace(f.Title),
+		Description: strings.TrimSpace(f.Description),
+		HabitGoal:   f.Goal,
+		Repeatable:  f.Repeatable,
+		Sequential:  f.Sequential,
+	}
+	if def.Title == "" {
+		return def, errors.New("title is required")
+	}
+	def.Attribute, def.Attributes = parseFileAttributes(f.Attribute)
+
+	switch def.Kind {
+	case BlueprintKindTask, BlueprintKindHabit:
+		if def.Difficulty, err = parseDifficultyName(f.Difficulty); err != nil {
+			return def, err
+		}
+		if len(f.Children) > 0 || f.Sequential {
+			return def, fmt.Errorf("only projects have children")
+		}
+	case BlueprintKindProject:
+		if f.Difficulty != "" {
+			return def, errors.New("projects have no difficulty; set it on the children")
+		}
+	default:
+		return def, fmt.Errorf("kind must be task, habit or project (got %q)", f.Kind)
+	}
+
+	if def.Kind == BlueprintKindHabit {
+		if f.Schedule == "" {
+			return def, errors.New("habits need a schedule (e.g. daily, mon/wed/fri, 3x/week)")
+		}
+		if def.HabitEvery, err = ParseHabitInterval(f.Schedule); err != nil {
+			return def, err
+		}
+	} else if f.Schedule != "" || f.Goal != nil {
+		return def, errors.New("schedule and goal are only for habits")
+	}
+	if f.Goal != nil && *f.Goal < 1 {
+		return def, errors.New("goal must be at least 1")
+	}
+
+	for i, c := range f.Children {
+		child := BlueprintChild{Title: strings.TrimSpace(c.Title), HabitGoal: c.Goal}
+		if child.Title == "" {
+			return def, fmt.Errorf("child %d: title is required", i+1)
+		}
+		if c.Attribute != "" {
+			child.Attribute, child.Attributes = parseFileAttributes(c.Attribute)
+		}
+		if child.Difficulty, err = parseDifficultyName(c.Difficulty); err != nil {
+			return def, fmt.Errorf("child %q: %w", child.Title, err)
+		}
+		if c.Schedule != "" {
+			child.IsHabit = true
+			if child.HabitEvery, err = ParseHabitInterval(c.Schedule); err != nil {
+				return def, fmt.Errorf("child %q: %w", child.Title, err)
+			}
+		} else if c.Goal != nil {
+			return def, fmt.Errorf("child %q: goal needs a schedule", child.Title)
+		}
+		def.Children = append(def.Children, child)
+	}
+
+	for _, expr := range f.Unlock {
+		conds, err := ParseUnlockExpr(expr)
+		if err != nil {
+			return def, err
+		}
+		def.Requires = append(def.Requires, conds...)
+	}
+	return def, nil
+}
+
+// parseFileAttributes splits "STR" or "str:70,int:30" into upper-cased codes
+// without resolving them; the first one is the primary attribute.
+func parseFileAttributes(input string) (Attribute, map[Attribute]int) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return DefaultAttribute, nil
+	}
+	var primary Attribute
+	weights := map[Attribute]int{}
+	for i, part := range strings.Split(input, ",") {
+		code, weight, hasWeight := strings.Cut(strings.TrimSpace(part), ":")
+		attr := Attribute(strings.ToUpper(strings.TrimSpace(code)))
+		if i == 0 {
+			primary = attr
+		}
+		w := 100
+		if hasWeight {
+			if n := parseWeight(weight); n > 0 {
+				w = n
+			}
+		}
+		weights[attr] = w
+	}
+	if len(weights) == 1 {
+		return primary, nil
+	}
+	return primary, weights
+}
+
+// resolveBlueprintAttributes maps the attribute codes and aliases of a user
+// blueprint to registered attributes.
+func (s *Service) resolveBlueprintAttributes(ctx context.Context, def BlueprintDef) (*BlueprintDef, error) {
+	reg, err := s.Attributes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolve := func(primary Attribute, weights map[Attribute]int) (Attribute, map[Attribute]int, error) {
+		d, ok := reg.Lookup(string(primary))
+		if !ok {
+			return "", nil, fmt.Errorf("blueprint %s: unknown attribute %s", def.Code, primary)
+		}
+		if weights == nil {
+			return d.Code, nil, nil
+		}
+		out := make(map[Attribute]int, len(weights))
+		for a, w := range weights {
+			wd, ok := reg.Lookup(string(a))
+			if !ok {
+				return "", nil, fmt.Errorf("blueprint %s: unknown attribute %s", def.Code, a)
+			}
+			out[wd.Code] = w
+		}
+		return d.Code, out, nil
+	}
+	if def.Attribute, def.Attributes, err = resolve(def.Attribute, def.Attributes); err != nil {
+		return nil, err
+	}
+	children := make([]BlueprintChild, len(def.Children))
+	for i, c := range def.Children {
+		if c.Attribute != "" {
+			if c.Attribute, c.Attributes, err = resolve(c.Attribute, c.Attributes); err != nil {
+				return nil, err
+			}
+		}
+		children[i] = c
+	}
+	def.Children = children
+	return &def, nil
+}
+
+var difficultyNames = map[string]Difficulty{
+	"trivial": DifficultyTrivial, "easy": DifficultyEasy, "medium": DifficultyMedium, "hard": DifficultyHard, "epic": DifficultyEpic,
+}
+
+// parseDifficultyName accepts trivial..epic or 1..5; empty means trivial.
+func parseDifficultyName(input string) (Difficulty, error) {
+	s := strings.ToLower(strings.TrimSpace(input))
+	if s == "" {
+		return DifficultyTrivial, nil
+	}
+	if d, ok := difficultyNames[s]; ok {
+		return d, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil && Difficulty(n).IsValid() {
+		return Difficulty(n), nil
+	}
+	return 0, fmt.Errorf("invalid difficulty %q (trivial, easy, medium, hard, epic or 1-5)", input)
+}
+
+// ValidateBlueprints checks user blueprints against the rest of the game: their
+// attributes must be registered, codes must be unique and not shadow built-in
+// blueprints, and unlock conditions must name known attributes and blueprints.
+func (s *Service) ValidateBlueprints(ctx context.Context, defs []BlueprintDef) ([]error, error) {
+	reg, err := s.Attributes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]string{}
+	for _, d := range builtinBlueprints() {
+		known[d.Code] = "built-in"
+	}
+	for _, d := range defs {
+		if _, ok := known[d.Code]; !ok {
+			known[d.Code] = d.Source
+		}
+	}
+
+	var problems []error
+	report := func(d BlueprintDef, format string, args ...any) {
+		problems = append(problems, fmt.Errorf("%s: blueprint %s: %s", d.Source, d.Code, fmt.Sprintf(format, args...)))
+	}
+	checkAttrs := func(d BlueprintDef, where string, primary Attribute, weights map[Attribute]int) {
+		attrs := []Attribute{primary}
+		for a := range weights {
+			attrs = append(attrs, a)
+		}
+		for _, a := range attrs {
+			if _, ok := reg.Lookup(string(a)); a != "" && !ok {
+				report(d, "%sunknown attribute %s", where, a)
+			}
+		}
+	}
+	for _, d := range defs {
+		if src := known[d.Code]; src != d.Source {
+			report(d, "code is already used by %s", src)
+		}
+		checkAttrs(d, "", d.Attribute, d.Attributes)
+		for _, c := range d.Children {
+			checkAttrs(d, fmt.Sprintf("child %q: ", c.Title), c.Attribute, c.Attributes)
+		}
+		for _, c := range d.Requires {
+			switch c.Kind {
+			case UnlockAttr:
+				if _, ok := reg.Lookup(string(c.Attr)); !ok {
+					report(d, "unlock: unknown attribute %s", c.Attr)
+				}
+			case UnlockBlueprint:
+				if _, ok := known[c.Name]; !ok {
+					report(d, "unlock: unknown blueprint %s", c.Name)
+				} else if c.Name == d.Code {
+					report(d, "unlock: requires itself")
+				}
+			}
+		}
+	}
+	return problems, nil
+}
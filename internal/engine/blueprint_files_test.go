@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseUnlockExpr(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    []UnlockCond
+		wantErr bool
+	}{
+		{expr: "level >= 5", want: []UnlockCond{{Kind: UnlockLevel, Min: 5}}},
+		{expr: "Level ≥ 3", want: []UnlockCond{{Kind: UnlockLevel, Min: 3}}},
+		{expr: "str >= 2", want: []UnlockCond{{Kind: UnlockAttr, Attr: AttributeSTR, Min: 2}}},
+		{expr: "attr CAREER >= 1", want: []UnlockCond{{Kind: UnlockAttr, Attr: AttributeCAREER, Min: 1}}},
+		{expr: `project "Gym Program" completed`, want: []UnlockCond{{Kind: UnlockProject, Name: "Gym Program"}}},
+		{expr: `completed project 'Move Out'`, want: []UnlockCond{{Kind: UnlockProject, Name: "Move Out"}}},
+		{expr: "blueprint str_gym completed", want: []UnlockCond{{Kind: UnlockBlueprint, Name: "str_gym"}}},
+		{expr: "level >= 4 and INT >= 2 && completed blueprint int_puzzle", want: []UnlockCond{
+			{Kind: UnlockLevel, Min: 4},
+			{Kind: UnlockAttr, Attr: AttributeINT, Min: 2},
+			{Kind: UnlockBlueprint, Name: "int_puzzle"},
+		}},
+		{expr: "level > 5", wantErr: true},
+		{expr: "project Gym completed", wantErr: true},
+		{expr: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseUnlockExpr(tt.expr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseUnlockExpr(%q) error=%v, wantErr %v", tt.expr, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseUnlockExpr(%q)=%+v, want %+v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestUserBlueprintsFromDir(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("garden.yaml", `
+code: garden_beds
+kind: project
+title: Raised Garden Beds
+attribute: home
+unlock:
+  - level >= 7 and home >= 2
+children:
+  - title: Buy lumber
+    difficulty: easy
+  - title: Build frames
+    difficulty: medium
+    attribute: str:60,home:40
+  - title: Water beds
+    schedule: mon/thu
+    goal: 8
+`)
+	write("list.json", `[{"code": "tea", "kind": "habit", "title": "Tea ritual", "schedule": "daily", "difficulty": 1}]`)
+	write("broken.yaml", "code: broken\nkind: chore\ntitle: Nope\n")
+	write("typo.yml", "code: typo\nkind: task\ntitle: Typo\ndifficult: hard\n")
+	write("notes.txt", "ignored")
+
+	defs, errs := LoadBlueprintDir(dir)
+	if len(defs) != 2 || len(errs) != 2 {
+		t.Fatalf("loaded %d defs, errors %v; want 2 defs and 2 errors", len(defs), errs)
+	}
+	for _, err := range errs {
+		if !strings.Contains(err.Error(), "broken.yaml") && !strings.Contains(err.Error(), "typo.yml") {
+			t.Fatalf("error %q does not name its file", err)
+		}
+	}
+	problems, err := svc.ValidateBlueprints(ctx, defs)
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("ValidateBlueprints: %v, %v", problems, err)
+	}
+	if errs := svc.AddUserBlueprints(defs); len(errs) != 0 {
+		t.Fatalf("AddUserBlueprints: %v", errs)
+	}
+
+	// The project gate alone is not enough without HOME level 2.
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelProjects))
+	if _, err := svc.EvaluateBlueprintUnlocks(ctx); err != nil {
+		t.Fatalf("EvaluateBlueprintUnlocks: %v", err)
+	}
+	if b, _ := svc.BlueprintRepo().Get(ctx, "garden_beds"); b == nil || b.Status != string(BlueprintLocked) {
+		t.Fatalf("garden_beds=%+v, want locked", b)
+	}
+	p, _ := svc.PlayerRepo().GetOrCreateMain(ctx)
+	addAttributeXP(p, AttributeHOME, XPRequiredForLevel(2))
+	if err := svc.PlayerRepo().Update(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.EvaluateBlueprintUnlocks(ctx); err != nil {
+		t.Fatalf("EvaluateBlueprintUnlocks: %v", err)
+	}
+
+	res, err := svc.AcceptBlueprint(ctx, "garden_beds")
+	if err != nil {
+		t.Fatalf("AcceptBlueprint: %v", err)
+	}
+	children, err := svc.TaskRepo().ListChildren(ctx, res.TaskID)
+	if err != nil || len(children) != 3 {
+		t.Fatalf("children=%d, %v; want 3", len(children), err)
+	}
+	for _, c := range children {
+		switch c.Title {
+		case "Buy lumber":
+			if c.Attribute != string(AttributeHOME) {
+				t.Fatalf("%s attribute=%s, want inherited HOME", c.Title, c.Attribute)
+			}
+		case "Build frames":
+			if c.Attribute != string(AttributeSTR) || c.Attributes == nil {
+				t.Fatalf("%s attribute=%s weights=%v, want STR with weights", c.Title, c.Attribute, c.Attributes)
+			}
+		case "Water beds":
+			if !c.IsHabit || c.HabitInterval == nil || *c.HabitInterval != "mon,thu" || c.HabitGoal == nil || *c.HabitGoal != 8 {
+				t.Fatalf("%s=%+v, want a mon,thu habit with goal 8", c.Title, c)
+			}
+		}
+	}
+
+	// Unknown attributes and blueprint references are reported.
+	write("bad.yaml", `
+code: tea
+kind: task
+title: Clash
+attribute: knitting
+unlock: ["blueprint nowhere completed", "FOO >= 2"]
+`)
+	bad, errs := LoadBlueprintFile(filepath.Join(dir, "bad.yaml"))
+	if len(errs) != 0 {
+		t.Fatalf("LoadBlueprintFile: %v", errs)
+	}
+	problems, err = svc.ValidateBlueprints(ctx, append(defs, bad...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 4 {
+		t.Fatalf("problems=%v, want code clash, unknown attribute, blueprint and unlock attribute", problems)
+	}
+}
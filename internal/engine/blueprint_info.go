@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"questline/internal/storage"
+)
+
+// BlueprintNode is a task, habit or project a blueprint creates, with the XP it
+// would be worth at the player's current attribute levels.
+type BlueprintNode struct {
+	Kind       BlueprintKind
+	Title      string
+	Difficulty Difficulty
+	Attribute  Attribute
+	Attributes map[Attribute]int
+	HabitEvery HabitInterval
+	HabitGoal  *int
+	XP         int
+	Children   []BlueprintNode
+}
+
+// BlueprintInfo describes a blueprint for browsing: its definition, status,
+// unlock requirements and what accepting it would create.
+type BlueprintInfo struct {
+	Def          BlueprintDef
+	Status       BlueprintStatus
+	Requirements []BlueprintRequirement
+	Tree         BlueprintNode
+	XP           int                // XP of one completion of every task (habits count once)
+	Bonus        int                // blueprint bonus still to earn (0 once paid)
+	Progress     *BlueprintProgress // nil until the blueprint is accepted
+}
+
+// CanAccept reports whether AcceptBlueprint would take the blueprint.
+func (b *BlueprintInfo) CanAccept() bool {
+	return b.Status == BlueprintAvailable || (b.Status == BlueprintCompleted && b.Def.Repeatable)
+}
+
+// Unmet returns the requirements the player does not meet yet.
+func (b *BlueprintInfo) Unmet() []BlueprintRequirement {
+	var out []BlueprintRequirement
+	for _, r := range b.Requirements {
+		if !r.Met {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Blueprints describes every built-in and user blueprint, after bringing their
+// unlock state up to date.
+func (s *Service) Blueprints(ctx context.Context) ([]BlueprintInfo, error) {
+	if _, err := s.EvaluateBlueprintUnlocks(ctx); err != nil {
+		return nil, err
+	}
+	p, err := s.getPlayer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defs := s.BlueprintDefs()
+	out := make([]BlueprintInfo, 0, len(defs))
+	for i := range defs {
+		info, err := s.blueprintInfo(ctx, p, defs[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *info)
+	}
+	return out, nil
+}
+
+// BlueprintInfo describes the blueprint with the given code.
+func (s *Service) BlueprintInfo(ctx context.Context, code string) (*BlueprintInfo, error) {
+	c, err := normalizeBlueprintCode(code)
+	if err != nil {
+		return nil, err
+	}
+	def := s.BlueprintDef(c)
+	if def == nil {
+		return nil, fmt.Errorf("blueprint %s %w", c, ErrNotFound)
+	}
+	if _, err := s.EvaluateBlueprintUnlocks(ctx); err != nil {
+		return nil, err
+	}
+	p, err := s.getPlayer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.blueprintInfo(ctx, p, *def)
+}
+
+func (s *Service) blueprintInfo(ctx context.Context, p *storage.Player, def BlueprintDef) (*BlueprintInfo, error) {
+	// User blueprints show their attributes resolved when the registry knows them.
+	if def.Source != "" {
+		if resolved, err := s.resolveBlueprintAttributes(ctx, def); err == nil {
+			def = *resolved
+		}
+	}
+	info := &BlueprintInfo{Def: def, Status: BlueprintLocked}
+	row, err := s.blueprints.Get(ctx, def.Code)
+	if err != nil {
+		return nil, err
+	}
+	if row != nil {
+		info.Status = BlueprintStatus(row.Status)
+	}
+	if info.Requirements, err = s.blueprintRequirements(ctx, p, &def); err != nil {
+		return nil, err
+	}
+
+	node := func(kind BlueprintKind, title string, d Difficulty, attr Attribute, weights map[Attribute]int, every HabitInterval, goal *int) BlueprintNode {
+		n := BlueprintNode{Kind: kind, Title: title, Difficulty: d, Attribute: attr, Attributes: weights, HabitEvery: every, HabitGoal: goal}
+		if kind != BlueprintKindProject {
+			n.XP, _ = s.rules.CalculateXP(d, s.rules.PlayerAttrLevel(p, attr))
+			info.XP += n.XP
+		}
+		return n
+	}
+	info.Tree = node(def.Kind, def.Title, def.Difficulty, def.Attribute, def.Attributes, def.HabitEvery, def.HabitGoal)
+	for _, c := range def.Children {
+		kind := BlueprintKindTask
+		if c.IsHabit {
+			kind = BlueprintKindHabit
+		}
+		attr := c.Attribute
+		if attr == "" {
+			attr = def.Attribute
+		}
+		info.Tree.Children = append(info.Tree.Children, node(kind, c.Title, c.Difficulty, attr, c.Attributes, c.HabitEvery, c.HabitGoal))
+	}
+
+	if row == nil || row.RewardCompletionID == nil {
+		info.Bonus = s.rules.XP.BlueprintBonus
+	}
+	if info.Status == BlueprintActive || info.Status == BlueprintCompleted {
+		if info.Progress, err = s.BlueprintProgress(ctx, def.Code); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// errDryRun rolls back the transaction of a dry run.
+var errDryRun = errors.New("dry run")
+
+// AcceptBlueprintDryRun accepts the blueprint in a transaction that is rolled
+// back, and returns the tasks it would have created: the instance first, then
+// its children. It fails exactly when AcceptBlueprint would.
+func (s *Service) AcceptBlueprintDryRun(ctx context.Context, code string) ([]storage.Task, error) {
+	var created []storage.Task
+	err := s.inTx(ctx, func(tx *Service) error {
+		res, err := tx.acceptBlueprint(ctx, code)
+		if err != nil {
+			return err
+		}
+		root, err := tx.tasks.Get(ctx, res.TaskID)
+		if err != nil {
+			return err
+		}
+		children, err := tx.tasks.ListChildren(ctx, res.TaskID)
+		if err != nil {
+			return err
+		}
+		created = append([]storage.Task{*root}, children...)
+		return errDryRun
+	})
+	if !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return created, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"questline/internal/storage"
 )
@@ -27,28 +28,7 @@ const (
 
 // PlayerAttrLevel returns the attribute level for a given attribute from a player.
 func PlayerAttrLevel(p *storage.Player, attr Attribute) int {
-	switch attr {
-	case AttributeSTR:
-		return AttributeLevelForXP(p.XPStr)
-	case AttributeINT:
-		return AttributeLevelForXP(p.XPInt)
-	case AttributeWIS:
-		return AttributeLevelForXP(p.XPWis)
-	case AttributeART:
-		return AttributeLevelForXP(p.XPArt)
-	case AttributeHOME:
-		return AttributeLevelForXP(p.XPHome)
-	case AttributeOUT:
-		return AttributeLevelForXP(p.XPOut)
-	case AttributeREAD:
-		return AttributeLevelForXP(p.XPRead)
-	case AttributeCINEMA:
-		return AttributeLevelForXP(p.XPCinema)
-	case AttributeCAREER:
-		return AttributeLevelForXP(p.XPCareer)
-	default:
-		return 0
-	}
+	return AttributeLevelForXP(p.AttrXP(string(attr)))
 }
 
 // UnlockReq represents a single attribute level requirement.
@@ -57,10 +37,16 @@ type UnlockReq struct {
 	MinLevel int
 }
 
-// CheckUnlockReqs checks if all attribute requirements are met for a player.
+// CheckUnlockReqs checks if all attribute requirements are met for a player
+// under the default rules.
 func CheckUnlockReqs(p *storage.Player, reqs ...UnlockReq) bool {
+	return defaultRules.CheckUnlockReqs(p, reqs...)
+}
+
+// CheckUnlockReqs checks if all attribute requirements are met for a player.
+func (r *Rules) CheckUnlockReqs(p *storage.Player, reqs ...UnlockReq) bool {
 	for _, req := range reqs {
-		if PlayerAttrLevel(p, req.Attr) < req.MinLevel {
+		if r.PlayerAttrLevel(p, req.Attr) < req.MinLevel {
 			return false
 		}
 	}
@@ -72,8 +58,10 @@ type BlueprintChild struct {
 	Title      string
 	Difficulty Difficulty
 	Attribute  Attribute
+	Attributes map[Attribute]int // Optional multi-attribute weights
 	IsHabit    bool
 	HabitEvery HabitInterval
+	HabitGoal  *int
 }
 
 type BlueprintDef struct {
@@ -84,10 +72,20 @@ type BlueprintDef struct {
 	Title      string
 	Difficulty Difficulty
 	Attribute  Attribute
+	Attributes map[Attribute]int // Optional multi-attribute weights
 	HabitEvery HabitInterval
+	HabitGoal  *int             // Habits: completions that finish the blueprint (nil = ongoing)
 	Children   []BlueprintChild // Children to auto-create on accept (for projects)
+	Sequential bool             // Each child task waits for the previous one (see AddDependency)
+
+	// Unlock requirements, all of which must hold. Habits and projects also
+	// wait for their feature gate.
+	MinLevel int          // player level (0 = only the feature gate)
+	AttrReqs []UnlockReq  // attribute levels
+	Requires []UnlockCond // further conditions, e.g. from blueprint files
 
-	Unlock func(ctx context.Context, svc *Service, p *storage.Player) (bool, error)
+	Repeatable bool   // can be accepted again once completed (no second bonus)
+	Source     string // file the blueprint was loaded from ("" for built-in ones)
 }
 
 func builtinBlueprints() []BlueprintDef {
@@ -101,9 +99,6 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyEasy,
 			Attribute:   AttributeSTR,
 			HabitEvery:  HabitIntervalDaily,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelHabits, nil
-			},
 		},
 		{
 			Code:        "str_walk",
@@ -113,9 +108,6 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyTrivial,
 			Attribute:   AttributeSTR,
 			HabitEvery:  HabitIntervalDaily,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelHabits, nil
-			},
 		},
 		{
 			Code:        "str_run",
@@ -125,9 +117,8 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyMedium,
 			Attribute:   AttributeSTR,
 			HabitEvery:  HabitIntervalWeekly,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= 5 && PlayerAttrLevel(p, AttributeSTR) >= 2, nil
-			},
+			MinLevel:    5,
+			AttrReqs:    []UnlockReq{{Attr: AttributeSTR, MinLevel: 2}},
 		},
 		{
 			Code:        "str_gym",
@@ -141,9 +132,9 @@ func builtinBlueprints() []BlueprintDef {
 				{Title: "Week 3: Intensity", Difficulty: DifficultyMedium},
 				{Title: "Week 4: Peak", Difficulty: DifficultyHard},
 			},
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= 8 && PlayerAttrLevel(p, AttributeSTR) >= 3, nil
-			},
+			Sequential: true,
+			MinLevel:   8,
+			AttrReqs:   []UnlockReq{{Attr: AttributeSTR, MinLevel: 3}},
 		},
 
 		// ========== INT (Intelligence/Learning) ==========
@@ -155,9 +146,6 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyEasy,
 			Attribute:   AttributeINT,
 			HabitEvery:  HabitIntervalDaily,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelHabits, nil
-			},
 		},
 		{
 			Code:        "int_course",
@@ -171,9 +159,7 @@ func builtinBlueprints() []BlueprintDef {
 				{Title: "Module 3", Difficulty: DifficultyMedium},
 				{Title: "Final Project", Difficulty: DifficultyHard},
 			},
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelProjects && PlayerAttrLevel(p, AttributeINT) >= 2, nil
-			},
+			AttrReqs: []UnlockReq{{Attr: AttributeINT, MinLevel: 2}},
 		},
 		{
 			Code:        "int_lang",
@@ -183,9 +169,8 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyMedium,
 			Attribute:   AttributeINT,
 			HabitEvery:  HabitIntervalDaily,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= 6 && PlayerAttrLevel(p, AttributeINT) >= 2, nil
-			},
+			MinLevel:    6,
+			AttrReqs:    []UnlockReq{{Attr: AttributeINT, MinLevel: 2}},
 		},
 
 		// ========== WIS (Wisdom/Mindfulness) ==========
@@ -197,9 +182,6 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyEasy,
 			Attribute:   AttributeWIS,
 			HabitEvery:  HabitIntervalDaily,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelHabits, nil
-			},
 		},
 		{
 			Code:        "wis_journal",
@@ -209,9 +191,7 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyEasy,
 			Attribute:   AttributeWIS,
 			HabitEvery:  HabitIntervalDaily,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelHabits && PlayerAttrLevel(p, AttributeWIS) >= 1, nil
-			},
+			AttrReqs:    []UnlockReq{{Attr: AttributeWIS, MinLevel: 1}},
 		},
 		{
 			Code:        "wis_digital_detox",
@@ -220,9 +200,9 @@ func builtinBlueprints() []BlueprintDef {
 			Title:       "Digital Detox Day",
 			Difficulty:  DifficultyHard,
 			Attribute:   AttributeWIS,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= 8 && PlayerAttrLevel(p, AttributeWIS) >= 3, nil
-			},
+			Repeatable:  true,
+			MinLevel:    8,
+			AttrReqs:    []UnlockReq{{Attr: AttributeWIS, MinLevel: 3}},
 		},
 
 		// ========== ART (Creativity) ==========
@@ -232,10 +212,8 @@ func builtinBlueprints() []BlueprintDef {
 			Description: "Choose a book and read it cover to cover. Track chapters as subtasks.",
 			Title:       "Read a Book",
 			Attribute:   AttributeART,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				artLevel := AttributeLevelForXP(p.XPArt)
-				return p.Level >= LevelProjects && artLevel >= 1, nil
-			},
+			Repeatable:  true,
+			AttrReqs:    []UnlockReq{{Attr: AttributeART, MinLevel: 1}},
 		},
 		{
 			Code:        "art_critic",
@@ -244,17 +222,9 @@ func builtinBlueprints() []BlueprintDef {
 			Title:       "Write a short review",
 			Difficulty:  DifficultyMedium,
 			Attribute:   AttributeART,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				artLevel := AttributeLevelForXP(p.XPArt)
-				if p.Level < LevelProjects || artLevel < 2 {
-					return false, nil
-				}
-				has, err := svc.tasks.HasCompletedProjectTitle(ctx, "Read a Book")
-				if err != nil {
-					return false, err
-				}
-				return has, nil
-			},
+			Repeatable:  true,
+			AttrReqs:    []UnlockReq{{Attr: AttributeART, MinLevel: 2}},
+			Requires:    []UnlockCond{{Kind: UnlockProject, Name: "Read a Book"}},
 		},
 		{
 			Code:        "art_sketch",
@@ -264,9 +234,7 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyEasy,
 			Attribute:   AttributeART,
 			HabitEvery:  HabitIntervalDaily,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelHabits && PlayerAttrLevel(p, AttributeART) >= 1, nil
-			},
+			AttrReqs:    []UnlockReq{{Attr: AttributeART, MinLevel: 1}},
 		},
 		{
 			Code:        "art_music",
@@ -276,9 +244,8 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyMedium,
 			Attribute:   AttributeART,
 			HabitEvery:  HabitIntervalWeekly,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= 5 && PlayerAttrLevel(p, AttributeART) >= 2, nil
-			},
+			MinLevel:    5,
+			AttrReqs:    []UnlockReq{{Attr: AttributeART, MinLevel: 2}},
 		},
 
 		// ========== HOME (Household) ==========
@@ -290,9 +257,6 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyTrivial,
 			Attribute:   AttributeHOME,
 			HabitEvery:  HabitIntervalDaily,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelHabits, nil
-			},
 		},
 		{
 			Code:        "home_declutter",
@@ -306,9 +270,7 @@ func builtinBlueprints() []BlueprintDef {
 				{Title: "Living Room", Difficulty: DifficultyMedium},
 				{Title: "Storage Areas", Difficulty: DifficultyHard},
 			},
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelProjects && PlayerAttrLevel(p, AttributeHOME) >= 2, nil
-			},
+			AttrReqs: []UnlockReq{{Attr: AttributeHOME, MinLevel: 2}},
 		},
 		{
 			Code:        "home_cook",
@@ -318,9 +280,7 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyMedium,
 			Attribute:   AttributeHOME,
 			HabitEvery:  HabitIntervalWeekly,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelHabits && PlayerAttrLevel(p, AttributeHOME) >= 1, nil
-			},
+			AttrReqs:    []UnlockReq{{Attr: AttributeHOME, MinLevel: 1}},
 		},
 
 		// ========== OUT (Outdoors/Social) ==========
@@ -332,9 +292,6 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyEasy,
 			Attribute:   AttributeOUT,
 			HabitEvery:  HabitIntervalWeekly,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelHabits, nil
-			},
 		},
 		{
 			Code:        "out_social",
@@ -344,9 +301,7 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyEasy,
 			Attribute:   AttributeOUT,
 			HabitEvery:  HabitIntervalWeekly,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelHabits && PlayerAttrLevel(p, AttributeOUT) >= 1, nil
-			},
+			AttrReqs:    []UnlockReq{{Attr: AttributeOUT, MinLevel: 1}},
 		},
 		{
 			Code:        "out_explore",
@@ -355,9 +310,9 @@ func builtinBlueprints() []BlueprintDef {
 			Title:       "Explore New Place",
 			Difficulty:  DifficultyMedium,
 			Attribute:   AttributeOUT,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= 4 && PlayerAttrLevel(p, AttributeOUT) >= 1, nil
-			},
+			Repeatable:  true,
+			MinLevel:    4,
+			AttrReqs:    []UnlockReq{{Attr: AttributeOUT, MinLevel: 1}},
 		},
 
 		// ========== READ (Reading) ==========
@@ -369,9 +324,6 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyEasy,
 			Attribute:   AttributeREAD,
 			HabitEvery:  HabitIntervalDaily,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelHabits, nil
-			},
 		},
 		{
 			Code:        "read_classic",
@@ -379,9 +331,8 @@ func builtinBlueprints() []BlueprintDef {
 			Description: "Read a literary classic that has stood the test of time.",
 			Title:       "Read a Classic",
 			Attribute:   AttributeREAD,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelProjects && PlayerAttrLevel(p, AttributeREAD) >= 2, nil
-			},
+			Repeatable:  true,
+			AttrReqs:    []UnlockReq{{Attr: AttributeREAD, MinLevel: 2}},
 		},
 		{
 			Code:        "read_nonfiction",
@@ -389,9 +340,8 @@ func builtinBlueprints() []BlueprintDef {
 			Description: "Read a non-fiction book to expand your knowledge.",
 			Title:       "Non-Fiction Deep Dive",
 			Attribute:   AttributeREAD,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelProjects && PlayerAttrLevel(p, AttributeREAD) >= 1, nil
-			},
+			Repeatable:  true,
+			AttrReqs:    []UnlockReq{{Attr: AttributeREAD, MinLevel: 1}},
 		},
 
 		// ========== CINEMA (Film/Culture) ==========
@@ -403,9 +353,6 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyEasy,
 			Attribute:   AttributeCINEMA,
 			HabitEvery:  HabitIntervalWeekly,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelHabits, nil
-			},
 		},
 		{
 			Code:        "cinema_director",
@@ -413,15 +360,14 @@ func builtinBlueprints() []BlueprintDef {
 			Description: "Watch the filmography of a famous director to understand their vision.",
 			Title:       "Director Study",
 			Attribute:   AttributeCINEMA,
+			Repeatable:  true,
 			Children: []BlueprintChild{
 				{Title: "Early Work", Difficulty: DifficultyEasy},
 				{Title: "Breakthrough Films", Difficulty: DifficultyMedium},
 				{Title: "Masterpieces", Difficulty: DifficultyMedium},
 				{Title: "Recent Work", Difficulty: DifficultyEasy},
 			},
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelProjects && PlayerAttrLevel(p, AttributeCINEMA) >= 2, nil
-			},
+			AttrReqs: []UnlockReq{{Attr: AttributeCINEMA, MinLevel: 2}},
 		},
 		{
 			Code:        "cinema_theater",
@@ -430,9 +376,9 @@ func builtinBlueprints() []BlueprintDef {
 			Title:       "Theater Visit",
 			Difficulty:  DifficultyMedium,
 			Attribute:   AttributeCINEMA,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= 3 && PlayerAttrLevel(p, AttributeCINEMA) >= 1, nil
-			},
+			Repeatable:  true,
+			MinLevel:    3,
+			AttrReqs:    []UnlockReq{{Attr: AttributeCINEMA, MinLevel: 1}},
 		},
 
 		// ========== CAREER (Professional) ==========
@@ -444,9 +390,7 @@ func builtinBlueprints() []BlueprintDef {
 			Difficulty:  DifficultyMedium,
 			Attribute:   AttributeCAREER,
 			HabitEvery:  HabitIntervalMonthly,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelHabits && PlayerAttrLevel(p, AttributeCAREER) >= 1, nil
-			},
+			AttrReqs:    []UnlockReq{{Attr: AttributeCAREER, MinLevel: 1}},
 		},
 		{
 			Code:        "career_skill",
@@ -460,9 +404,7 @@ func builtinBlueprints() []BlueprintDef {
 				{Title: "Intermediate Practice", Difficulty: DifficultyMedium},
 				{Title: "Apply in Real Project", Difficulty: DifficultyHard},
 			},
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= LevelProjects && PlayerAttrLevel(p, AttributeCAREER) >= 2, nil
-			},
+			AttrReqs: []UnlockReq{{Attr: AttributeCAREER, MinLevel: 2}},
 		},
 		{
 			Code:        "career_resume",
@@ -471,9 +413,8 @@ func builtinBlueprints() []BlueprintDef {
 			Title:       "Update Resume",
 			Difficulty:  DifficultyMedium,
 			Attribute:   AttributeCAREER,
-			Unlock: func(ctx context.Context, svc *Service, p *storage.Player) (bool, error) {
-				return p.Level >= 4, nil
-			},
+			Repeatable:  true,
+			MinLevel:    4,
 		},
 	}
 }
@@ -486,7 +427,8 @@ func normalizeBlueprintCode(code string) (string, error) {
 	return c, nil
 }
 
-// GetBlueprintDef returns the blueprint definition by code, or nil if not found.
+// GetBlueprintDef returns the built-in blueprint definition by code, or nil if
+// not found. Service.BlueprintDef also knows the user's blueprints.
 func GetBlueprintDef(code string) *BlueprintDef {
 	defs := builtinBlueprints()
 	for i := range defs {
@@ -497,6 +439,37 @@ func GetBlueprintDef(code string) *BlueprintDef {
 	return nil
 }
 
+// AddUserBlueprints registers blueprints loaded from files (see
+// LoadBlueprintDir). A blueprint whose code is already taken is skipped and
+// reported.
+func (s *Service) AddUserBlueprints(defs []BlueprintDef) []error {
+	var errs []error
+	for _, def := range defs {
+		if s.BlueprintDef(def.Code) != nil {
+			errs = append(errs, fmt.Errorf("%s: blueprint code %q is already defined", def.Source, def.Code))
+			continue
+		}
+		s.userBlueprints = append(s.userBlueprints, def)
+	}
+	return errs
+}
+
+// BlueprintDefs returns the built-in blueprints followed by the user's.
+func (s *Service) BlueprintDefs() []BlueprintDef {
+	return append(builtinBlueprints(), s.userBlueprints...)
+}
+
+// BlueprintDef returns the blueprint definition by code, or nil if not found.
+func (s *Service) BlueprintDef(code string) *BlueprintDef {
+	defs := s.BlueprintDefs()
+	for i := range defs {
+		if defs[i].Code == code {
+			return &defs[i]
+		}
+	}
+	return nil
+}
+
 // EvaluateBlueprintUnlocks ensures built-in blueprint rows exist and transitions any
 // newly unlocked ones from locked -> available.
 func (s *Service) EvaluateBlueprintUnlocks(ctx context.Context) ([]storage.Blueprint, error) {
@@ -505,7 +478,7 @@ func (s *Service) EvaluateBlueprintUnlocks(ctx context.Context) ([]storage.Bluep
 		return nil, err
 	}
 
-	defs := builtinBlueprints()
+	defs := s.BlueprintDefs()
 	var newlyAvailable []storage.Blueprint
 
 	for _, def := range defs {
@@ -523,11 +496,11 @@ func (s *Service) EvaluateBlueprintUnlocks(ctx context.Context) ([]storage.Bluep
 			continue
 		}
 
-		ok, err := def.Unlock(ctx, s, p)
+		reqs, err := s.blueprintRequirements(ctx, p, &def)
 		if err != nil {
 			return nil, err
 		}
-		if !ok {
+		if !requirementsMet(reqs) {
 			continue
 		}
 
@@ -542,8 +515,25 @@ func (s *Service) EvaluateBlueprintUnlocks(ctx context.Context) ([]storage.Bluep
 }
 
 // AcceptBlueprint marks an available blueprint as active and instantiates its
-// corresponding task/project/habit.
+// corresponding task/project/habit. The instance, its children and the status
+// change are created in one transaction.
 func (s *Service) AcceptBlueprint(ctx context.Context, code string) (*CreateResult, error) {
+	var res *CreateResult
+	err := s.inTx(ctx, func(tx *Service) error {
+		var err error
+		if res, err = tx.acceptBlueprint(ctx, code); err != nil {
+			return err
+		}
+		res.Achievements, err = tx.recordAchievements(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Service) acceptBlueprint(ctx context.Context, code string) (*CreateResult, error) {
 	c, err := normalizeBlueprintCode(code)
 	if err != nil {
 		return nil, err
@@ -559,32 +549,30 @@ func (s *Service) AcceptBlueprint(ctx context.Context, code string) (*CreateResu
 		return nil, err
 	}
 	if b == nil {
-		return nil, fmt.Errorf("unknown blueprint: %s", c)
+		return nil, fmt.Errorf("blueprint %s %w", c, ErrNotFound)
+	}
+	def := s.BlueprintDef(c)
+	if def == nil {
+		return nil, fmt.Errorf("blueprint %s %w", c, ErrNotFound)
 	}
-	if b.Status != string(BlueprintAvailable) {
+	again := b.Status == string(BlueprintCompleted) && def.Repeatable
+	if b.Status != string(BlueprintAvailable) && !again {
 		return nil, fmt.Errorf("blueprint %s is not available (status=%s)", c, b.Status)
 	}
-
-	defs := builtinBlueprints()
-	var def *BlueprintDef
-	for i := range defs {
-		if defs[i].Code == c {
-			def = &defs[i]
-			break
+	if def.Source != "" {
+		if def, err = s.resolveBlueprintAttributes(ctx, *def); err != nil {
+			return nil, err
 		}
 	}
-	if def == nil {
-		return nil, fmt.Errorf("unknown blueprint: %s", c)
-	}
 
 	var res *CreateResult
 	switch def.Kind {
 	case BlueprintKindProject:
-		res, err = s.CreateProject(ctx, CreateProjectInput{Title: def.Title, Attribute: def.Attribute})
+		res, err = s.createProject(ctx, CreateProjectInput{Title: def.Title, Attribute: def.Attribute, Attributes: def.Attributes})
 	case BlueprintKindHabit:
-		res, err = s.CreateTask(ctx, CreateTaskInput{Title: def.Title, Difficulty: def.Difficulty, Attribute: def.Attribute, IsHabit: true, HabitInterval: def.HabitEvery})
+		res, err = s.createTask(ctx, CreateTaskInput{Title: def.Title, Difficulty: def.Difficulty, Attribute: def.Attribute, Attributes: def.Attributes, IsHabit: true, HabitInterval: def.HabitEvery, HabitGoal: def.HabitGoal})
 	case BlueprintKindTask:
-		res, err = s.CreateTask(ctx, CreateTaskInput{Title: def.Title, Difficulty: def.Difficulty, Attribute: def.Attribute, IsHabit: false})
+		res, err = s.createTask(ctx, CreateTaskInput{Title: def.Title, Difficulty: def.Difficulty, Attribute: def.Attribute, Attributes: def.Attributes, IsHabit: false})
 	default:
 		return nil, fmt.Errorf("invalid blueprint kind: %s", def.Kind)
 	}
@@ -594,28 +582,201 @@ func (s *Service) AcceptBlueprint(ctx context.Context, code string) (*CreateResu
 
 	// Auto-create children (for project blueprints)
 	if def.Kind == BlueprintKindProject && len(def.Children) > 0 {
+		var prev int64
 		for _, child := range def.Children {
 			attr := child.Attribute
 			if attr == "" {
 				attr = def.Attribute // inherit from parent
 			}
-			_, err := s.CreateTask(ctx, CreateTaskInput{
+			in := CreateTaskInput{
 				Title:         child.Title,
 				Difficulty:    child.Difficulty,
 				Attribute:     attr,
+				Attributes:    child.Attributes,
 				IsHabit:       child.IsHabit,
 				HabitInterval: child.HabitEvery,
+				HabitGoal:     child.HabitGoal,
 				ParentID:      &res.TaskID,
-			})
+			}
+			if def.Sequential && !child.IsHabit && prev != 0 {
+				in.After = []int64{prev}
+			}
+			created, err := s.createTask(ctx, in)
 			if err != nil {
 				return nil, fmt.Errorf("creating child %q: %w", child.Title, err)
 			}
+			if !child.IsHabit {
+				prev = created.TaskID
+			}
 		}
 	}
 
+	if err := s.tasks.SetBlueprintCode(ctx, res.TaskID, c); err != nil {
+		return nil, err
+	}
 	if err := s.blueprints.Upsert(ctx, storage.Blueprint{Code: c, Status: string(BlueprintActive)}); err != nil {
 		return nil, err
 	}
 
 	return res, nil
 }
+
+// completeBlueprint moves the blueprint task was created from to completed
+// once the task is done, and pays the blueprint bonus the first time. The bonus
+// is booked on completion compID, so restoring that completion takes it back.
+// It returns the blueprint code (empty when nothing changed) and the bonus paid;
+// the caller persists the player.
+func (s *Service) completeBlueprint(ctx context.Context, p *storage.Player, task *storage.Task, compID int64, at time.Time) (string, int, error) {
+	if task.BlueprintCode == nil {
+		return "", 0, nil
+	}
+	b, err := s.blueprints.Get(ctx, *task.BlueprintCode)
+	if err != nil {
+		return "", 0, err
+	}
+	if b == nil || b.Status != string(BlueprintActive) {
+		return "", 0, nil
+	}
+	// Only the latest run of a repeatable blueprint completes it.
+	runs, err := s.tasks.ListByBlueprint(ctx, b.Code)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(runs) > 0 && runs[len(runs)-1].ID != task.ID {
+		return "", 0, nil
+	}
+	b.Status = string(BlueprintCompleted)
+	b.CompletedAt = &at
+
+	bonus := 0
+	if b.RewardCompletionID == nil && s.rules.XP.BlueprintBonus > 0 {
+		bonus = s.rules.XP.BlueprintBonus
+		if weakened(p, at) {
+			bonus, _ = s.rules.WeakenedXP(bonus)
+		}
+		shares := splitXP(bonus, parseStoredAttribute(task.Attribute), task.Attributes)
+		if err := s.recordXP(ctx, p, XPEventBonus, &task.ID, &compID, shares, at); err != nil {
+			return "", 0, err
+		}
+		b.RewardCompletionID = &compID
+	}
+	if err := s.blueprints.SetCompletion(ctx, *b); err != nil {
+		return "", 0, err
+	}
+	return b.Code, bonus, nil
+}
+
+// reopenBlueprint undoes completeBlueprint when completion compID of task is
+// restored: the blueprint is active again and its bonus can be earned again if
+// that completion paid it.
+func (s *Service) reopenBlueprint(ctx context.Context, task *storage.Task, compID int64) error {
+	if task.BlueprintCode == nil {
+		return nil
+	}
+	b, err := s.blueprints.Get(ctx, *task.BlueprintCode)
+	if err != nil {
+		return err
+	}
+	if b == nil || b.Status != string(BlueprintCompleted) {
+		return nil
+	}
+	b.Status = string(BlueprintActive)
+	b.CompletedAt = nil
+	if b.RewardCompletionID != nil && *b.RewardCompletionID == compID {
+		b.RewardCompletionID = nil
+	}
+	return s.blueprints.SetCompletion(ctx, *b)
+}
+
+// BlueprintProgress is how far the latest instance of a blueprint has come.
+// Projects count their finished tasks, tasks are 0/1 and habits count
+// completions towards their goal; Total is 0 for habits without a goal.
+type BlueprintProgress struct {
+	Code        string
+	Status      BlueprintStatus
+	TaskID      int64 // 0 when no task is linked (accepted before instances were tracked)
+	Title       string
+	Runs        int // times the blueprint was accepted
+	Done        int
+	Total       int
+	CompletedAt *time.Time
+}
+
+// BlueprintProgress reports the progress of the blueprint with the given code.
+func (s *Service) BlueprintProgress(ctx context.Context, code string) (*BlueprintProgress, error) {
+	b, err := s.blueprints.Get(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, fmt.Errorf("blueprint %s %w", code, ErrNotFound)
+	}
+	prog := &BlueprintProgress{Code: b.Code, Status: BlueprintStatus(b.Status), CompletedAt: b.CompletedAt}
+
+	tasks, err := s.tasks.ListByBlueprint(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return prog, nil
+	}
+	t := tasks[len(tasks)-1]
+	prog.TaskID, prog.Title, prog.Runs = t.ID, t.Title, len(tasks)
+
+	switch {
+	case t.IsProject:
+		prog.Done, prog.Total, err = s.projectTaskCounts(ctx, t.ID)
+		if err != nil {
+			return nil, err
+		}
+	case t.IsHabit:
+		comps, err := s.completions.ListByTask(ctx, t.ID)
+		if err != nil {
+			return nil, err
+		}
+		prog.Done = len(comps)
+		if t.HabitGoal != nil {
+			prog.Total = *t.HabitGoal
+		}
+	default:
+		prog.Total = 1
+		if t.Status == "done" {
+			prog.Done = 1
+		}
+	}
+	return prog, nil
+}
+
+// projectTaskCounts counts the finished and total non-habit tasks under a project.
+func (s *Service) projectTaskCounts(ctx context.Context, projectID int64) (done, total int, err error) {
+	stack := []int64{projectID}
+	seen := map[int64]bool{}
+
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[cur] {
+			continue
+		}
+		seen[cur] = true
+
+		children, err := s.tasks.ListChildren(ctx, cur)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, c := range children {
+			if c.IsProject {
+				stack = append(stack, c.ID)
+				continue
+			}
+			if c.IsHabit {
+				continue
+			}
+			total++
+			if c.Status == "done" {
+				done++
+			}
+		}
+	}
+	return done, total, nil
+}
@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"questline/internal/storage"
+)
+
+func TestBlueprintCompletionPaysOneTimeBonus(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	bonus := svc.Rules().XP.BlueprintBonus
+
+	setPlayerXP(t, svc, XPRequiredForLevel(5))
+	created, err := svc.AcceptBlueprint(ctx, "career_resume")
+	if err != nil {
+		t.Fatalf("AcceptBlueprint: %v", err)
+	}
+	task, _ := svc.TaskRepo().Get(ctx, created.TaskID)
+	if task.BlueprintCode == nil || *task.BlueprintCode != "career_resume" {
+		t.Fatalf("blueprint_code=%v, want career_resume", task.BlueprintCode)
+	}
+	prog, err := svc.BlueprintProgress(ctx, "career_resume")
+	if err != nil {
+		t.Fatalf("BlueprintProgress: %v", err)
+	}
+	if prog.Status != BlueprintActive || prog.TaskID != task.ID || prog.Done != 0 || prog.Total != 1 {
+		t.Fatalf("progress=%+v, want active 0/1", prog)
+	}
+
+	before, _ := svc.PlayerRepo().GetOrCreateMain(ctx)
+	res, err := svc.CompleteTask(ctx, task.ID)
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if res.BlueprintCompleted != "career_resume" || res.BlueprintBonus != bonus {
+		t.Fatalf("result=%+v, want blueprint completed with %d bonus", res, bonus)
+	}
+	after, _ := svc.PlayerRepo().GetOrCreateMain(ctx)
+	if got := after.XPTotal - before.XPTotal; got != res.XPAwarded+bonus {
+		t.Fatalf("xp gained=%d, want %d", got, res.XPAwarded+bonus)
+	}
+	b, _ := svc.BlueprintRepo().Get(ctx, "career_resume")
+	if b.Status != string(BlueprintCompleted) || b.CompletedAt == nil || b.RewardCompletionID == nil {
+		t.Fatalf("blueprint=%+v, want completed and rewarded", b)
+	}
+	achievements, err := GetAchievementsForPlayer(ctx, svc)
+	if err != nil {
+		t.Fatalf("GetAchievementsForPlayer: %v", err)
+	}
+	for _, a := range achievements {
+		if a.ID == "first_blueprint" && !a.Earned {
+			t.Fatalf("first_blueprint not earned")
+		}
+	}
+
+	// Restoring the completion takes the bonus back and reopens the blueprint.
+	rr, err := svc.RestoreTask(ctx, task.ID)
+	if err != nil {
+		t.Fatalf("RestoreTask: %v", err)
+	}
+	if rr.XPDeducted != res.XPAwarded+bonus {
+		t.Fatalf("deducted=%d, want %d", rr.XPDeducted, res.XPAwarded+bonus)
+	}
+	b, _ = svc.BlueprintRepo().Get(ctx, "career_resume")
+	if b.Status != string(BlueprintActive) || b.CompletedAt != nil || b.RewardCompletionID != nil {
+		t.Fatalf("blueprint after restore=%+v, want active without reward", b)
+	}
+	if res, err = svc.CompleteTask(ctx, task.ID); err != nil || res.BlueprintBonus != bonus {
+		t.Fatalf("complete again: %+v, %v; want the bonus again", res, err)
+	}
+
+	// A second run of the same blueprint completes it without another bonus.
+	if err := svc.BlueprintRepo().Upsert(ctx, storage.Blueprint{Code: "career_resume", Status: string(BlueprintActive)}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	again, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Update Resume", Difficulty: DifficultyMedium, Attribute: AttributeCAREER})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if err := svc.TaskRepo().SetBlueprintCode(ctx, again.TaskID, "career_resume"); err != nil {
+		t.Fatalf("SetBlueprintCode: %v", err)
+	}
+	res, err = svc.CompleteTask(ctx, again.TaskID)
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if res.BlueprintCompleted != "career_resume" || res.BlueprintBonus != 0 {
+		t.Fatalf("second run=%+v, want completed without bonus", res)
+	}
+}
+
+func TestBlueprintInfoDryRunAndRepeat(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelProjects))
+	info, err := svc.BlueprintInfo(ctx, "str_gym")
+	if err != nil {
+		t.Fatalf("BlueprintInfo: %v", err)
+	}
+	var unmet []string
+	for _, r := range info.Unmet() {
+		unmet = append(unmet, r.Text)
+	}
+	if info.Status != BlueprintLocked || len(info.Requirements) != 3 || !reflect.DeepEqual(unmet, []string{"level >= 8", "STR >= 3"}) {
+		t.Fatalf("info=%+v unmet=%v, want locked on level 8 and STR 3", info, unmet)
+	}
+	if len(info.Tree.Children) != 4 || info.XP == 0 || info.Bonus != svc.Rules().XP.BlueprintBonus {
+		t.Fatalf("tree=%+v xp=%d bonus=%d", info.Tree, info.XP, info.Bonus)
+	}
+	if _, err := svc.AcceptBlueprintDryRun(ctx, "str_gym"); err == nil {
+		t.Fatalf("dry run of a locked blueprint succeeded")
+	}
+
+	// A dry run reports what accepting creates and saves nothing.
+	created, err := svc.AcceptBlueprintDryRun(ctx, "career_resume")
+	if err != nil {
+		t.Fatalf("AcceptBlueprintDryRun: %v", err)
+	}
+	if len(created) != 1 || created[0].Title != "Update Resume" || created[0].XPValue == 0 {
+		t.Fatalf("created=%+v", created)
+	}
+	if tasks, _ := svc.TaskRepo().ListAll(ctx); len(tasks) != 0 {
+		t.Fatalf("dry run saved %d tasks", len(tasks))
+	}
+	if b, _ := svc.BlueprintRepo().Get(ctx, "career_resume"); b.Status != string(BlueprintAvailable) {
+		t.Fatalf("status after dry run=%s", b.Status)
+	}
+
+	// Repeatable blueprints can be accepted again once completed, without a second bonus.
+	first, err := svc.AcceptBlueprint(ctx, "career_resume")
+	if err != nil {
+		t.Fatalf("AcceptBlueprint: %v", err)
+	}
+	if _, err := svc.AcceptBlueprint(ctx, "career_resume"); err == nil {
+		t.Fatalf("accepted an active blueprint again")
+	}
+	if _, err := svc.CompleteTask(ctx, first.TaskID); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	second, err := svc.AcceptBlueprint(ctx, "career_resume")
+	if err != nil {
+		t.Fatalf("accept again: %v", err)
+	}
+	info, _ = svc.BlueprintInfo(ctx, "career_resume")
+	if info.Status != BlueprintActive || info.Bonus != 0 || info.Progress.Runs != 2 || info.Progress.TaskID != second.TaskID {
+		t.Fatalf("info=%+v progress=%+v, want second run active without bonus", info, info.Progress)
+	}
+	res, err := svc.CompleteTask(ctx, second.TaskID)
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if res.BlueprintCompleted != "career_resume" || res.BlueprintBonus != 0 {
+		t.Fatalf("result=%+v, want completed without bonus", res)
+	}
+}
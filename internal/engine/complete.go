@@ -3,7 +3,6 @@ package engine
 import (
 	"context"
 	"fmt"
-	"math"
 	"strings"
 	"time"
 
@@ -18,82 +17,61 @@ type CompleteResult struct {
 	LevelUp        bool
 	ProjectBonus   bool
 	ProjectVolume  int
-	HabitCompleted bool // True when a goal-based habit reached its completion target
+	HabitCompleted bool          // True when a goal-based habit reached its completion target
+	DeadlineDelta  int           // XP added (early) or removed (late) by the deadline rules
+	Streak         int           // Habit streak after this completion, in periods
+	StreakBonus    int           // XP added by the streak multiplier
+	FreezesUsed    int           // Freeze tokens spent to keep the streak alive
+	FreezeEarned   bool          // A freeze token was earned by this completion
+	HPHealed       int           // HP restored by this completion
+	WeakenedLoss   int           // XP lost to the knockout penalty
+	Tracked        time.Duration // Time tracked towards this completion
+	TimeBonus      int           // XP added for the tracked time
+
+	BlueprintCompleted string // Code of the blueprint this completion finished
+	BlueprintBonus     int    // One-time XP bonus for finishing the blueprint
+
+	Achievements []Achievement // Achievements earned by this completion
 }
 
+// parseStoredAttribute normalizes an attribute code read from the database.
+// Codes were validated against the registry when written, so any non-empty code
+// is kept as-is.
 func parseStoredAttribute(s string) Attribute {
 	s = strings.TrimSpace(strings.ToUpper(s))
-	a := Attribute(s)
-	if a.IsValid() {
-		return a
+	if s == "" {
+		return DefaultAttribute
 	}
-	return DefaultAttribute
+	return Attribute(s)
 }
 
-// addAttributeXP adds XP to the appropriate attribute on the player.
+// addAttributeXP adds XP to the given attribute on the player.
 func addAttributeXP(p *storage.Player, attr Attribute, xp int) {
-	switch attr {
-	case AttributeSTR:
-		p.XPStr += xp
-	case AttributeINT:
-		p.XPInt += xp
-	case AttributeART:
-		p.XPArt += xp
-	case AttributeHOME:
-		p.XPHome += xp
-	case AttributeOUT:
-		p.XPOut += xp
-	case AttributeREAD:
-		p.XPRead += xp
-	case AttributeCINEMA:
-		p.XPCinema += xp
-	case AttributeCAREER:
-		p.XPCareer += xp
-	case AttributeWIS:
-		fallthrough
-	default:
-		p.XPWis += xp
+	if p.XP == nil {
+		p.XP = map[string]int{}
 	}
+	p.XP[string(attr)] += xp
 }
 
-// distributeXP distributes total XP across attributes based on weights.
-// If no weights provided (or empty), uses the primary attribute only.
-// Weights are percentages (e.g., {STR: 50, INT: 50} means 50% each).
-func distributeXP(p *storage.Player, totalXP int, primaryAttr Attribute, weights map[string]int) {
-	if len(weights) == 0 {
-		// Single attribute mode - all XP goes to primary
-		addAttributeXP(p, primaryAttr, totalXP)
-		return
-	}
-
-	// Calculate total weight
-	totalWeight := 0
-	for _, w := range weights {
-		totalWeight += w
-	}
-	if totalWeight == 0 {
-		addAttributeXP(p, primaryAttr, totalXP)
-		return
-	}
-
-	// Distribute XP proportionally
-	distributed := 0
-	var lastAttr Attribute
-	for attrStr, weight := range weights {
-		attr := parseStoredAttribute(attrStr)
-		share := (totalXP * weight) / totalWeight
-		addAttributeXP(p, attr, share)
-		distributed += share
-		lastAttr = attr
-	}
-
-	// Give any remainder (from integer division) to the last attribute
-	if remainder := totalXP - distributed; remainder > 0 {
-		addAttributeXP(p, lastAttr, remainder)
+// CompleteTask completes a task, habit or project and awards its XP.
+// The status change, XP award and completion record are written in one transaction.
+func (s *Service) CompleteTask(ctx context.Context, id int64) (*CompleteResult, error) {
+	var res *CompleteResult
+	err := s.inTx(ctx, func(tx *Service) error {
+		var err error
+		if res, err = tx.completeTask(ctx, id); err != nil {
+			return err
+		}
+		res.Achievements, err = tx.recordAchievements(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return res, nil
 }
 
-func (s *Service) CompleteTask(ctx context.Context, id int64) (*CompleteResult, error) {
+func (s *Service) completeTask(ctx context.Context, id int64) (*CompleteResult, error) {
 	p, err := s.getPlayer(ctx)
 	if err != nil {
 		return nil, err
@@ -105,14 +83,27 @@ func (s *Service) CompleteTask(ctx context.Context, id int64) (*CompleteResult,
 		return nil, err
 	}
 	if task == nil {
-		return nil, fmt.Errorf("task %d not found", id)
+		return nil, fmt.Errorf("task %d %w", id, ErrNotFound)
 	}
 	if task.Status == "done" {
 		return nil, fmt.Errorf("task %d is already done", id)
 	}
+	blockers, err := s.OpenBlockers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(blockers) > 0 {
+		return nil, BlockedError{TaskID: id, BlockedBy: blockers}
+	}
 
 	now := time.Now().UTC()
 
+	// Settle missed habits first so a late completion cannot dodge the damage.
+	if _, err := s.evaluateHP(ctx, p, now); err != nil {
+		return nil, err
+	}
+	weak := weakened(p, now)
+
 	if task.IsHabit {
 		children, err := s.tasks.ListChildren(ctx, id)
 		if err != nil {
@@ -121,49 +112,53 @@ func (s *Service) CompleteTask(ctx context.Context, id int64) (*CompleteResult,
 		if len(children) > 0 {
 			return nil, fmt.Errorf("habit %d must be a leaf", id)
 		}
-		if task.HabitInterval == nil {
-			return nil, fmt.Errorf("habit %d is missing interval", id)
-		}
-
-		interval, err := ParseHabitInterval(*task.HabitInterval)
+		since := now.AddDate(0, 0, -s.rules.Habits.DecayWindowDays)
+		recentSameDifficulty, err := s.completions.CountSinceWithDifficulty(ctx, id, since, task.Difficulty)
 		if err != nil {
 			return nil, err
 		}
+		xp := s.rules.HabitXP(task.XPValue, recentSameDifficulty)
 
-		since := now.Add(-7 * 24 * time.Hour)
-		recentSameDifficulty, err := s.completions.CountSinceWithDifficulty(ctx, id, since, task.Difficulty)
+		local := now.In(time.Local)
+		plan, err := s.planStreak(ctx, task, local)
 		if err != nil {
 			return nil, err
 		}
-		xp := task.XPValue
-		if recentSameDifficulty >= 5 {
-			xp = int(math.Round(float64(xp) * 0.50))
+		streakBonus := 0
+		if plan.completes {
+			xp, streakBonus = s.rules.StreakXP(xp, plan.streak)
 		}
-		if xp < 1 {
-			xp = 1
-		}
-
-		attr := parseStoredAttribute(task.Attribute)
-		nextDue, err := NextDueDate(now, interval)
+		tracked, err := s.settleTimer(ctx, task, now)
 		if err != nil {
 			return nil, err
 		}
+		xp, timeBonus := s.rules.TimeXP(xp, tracked)
+		weakenedLoss := 0
+		if weak {
+			xp, weakenedLoss = s.rules.WeakenedXP(xp)
+		}
+
+		nextDue := plan.sched.NextDue(local, plan.doneInPeriod).UTC()
 		if err := s.tasks.UpdateHabitAfterCompletion(ctx, id, now, nextDue); err != nil {
 			return nil, err
 		}
 
-		p.XPTotal += xp
-		distributeXP(p, xp, attr, task.Attributes)
-		p.Level = LevelForTotalXP(p.XPTotal)
-		if err := s.players.Update(ctx, p); err != nil {
+		healed, err := s.healHP(ctx, p, task, s.rules.HP.HealPerHabit, now)
+		if err != nil {
 			return nil, err
 		}
-		if _, err := s.completions.Insert(ctx, id, now, task.Difficulty, xp); err != nil {
+		compID, err := s.awardCompletion(ctx, p, task, now, xp, XPEventComplete)
+		if err != nil {
+			return nil, err
+		}
+		freezeEarned, err := s.applyStreak(ctx, task, compID, plan, now)
+		if err != nil {
 			return nil, err
 		}
 
 		// Check if habit goal is reached
 		habitCompleted := false
+		bpCode, bpBonus := "", 0
 		if task.HabitGoal != nil {
 			allComps, err := s.completions.ListByTask(ctx, id)
 			if err != nil {
@@ -176,6 +171,9 @@ func (s *Service) CompleteTask(ctx context.Context, id int64) (*CompleteResult,
 					return nil, err
 				}
 				habitCompleted = true
+				if bpCode, bpBonus, err = s.finishBlueprint(ctx, p, task, compID, now); err != nil {
+					return nil, err
+				}
 			}
 		}
 
@@ -191,6 +189,17 @@ func (s *Service) CompleteTask(ctx context.Context, id int64) (*CompleteResult,
 			LevelAfter:     p.Level,
 			LevelUp:        levelUp,
 			HabitCompleted: habitCompleted,
+			Streak:         plan.streak,
+			StreakBonus:    streakBonus,
+			FreezesUsed:    len(plan.freezeOn),
+			FreezeEarned:   freezeEarned,
+			HPHealed:       healed,
+			WeakenedLoss:   weakenedLoss,
+			Tracked:        tracked,
+			TimeBonus:      timeBonus,
+
+			BlueprintCompleted: bpCode,
+			BlueprintBonus:     bpBonus,
 		}, nil
 	}
 
@@ -207,20 +216,22 @@ func (s *Service) CompleteTask(ctx context.Context, id int64) (*CompleteResult,
 			return nil, fmt.Errorf("project %d has unfinished tasks", id)
 		}
 
-		bonus := int(math.Round(float64(volume) * 0.10))
-		attr := parseStoredAttribute(task.Attribute)
+		bonus := s.rules.ProjectBonus(volume)
+		weakenedLoss := 0
+		if weak {
+			bonus, weakenedLoss = s.rules.WeakenedXP(bonus)
+		}
 
 		if err := s.tasks.MarkDone(ctx, id, now); err != nil {
 			return nil, err
 		}
 
-		p.XPTotal += bonus
-		distributeXP(p, bonus, attr, task.Attributes)
-		p.Level = LevelForTotalXP(p.XPTotal)
-		if err := s.players.Update(ctx, p); err != nil {
+		compID, err := s.awardCompletion(ctx, p, task, now, bonus, XPEventBonus)
+		if err != nil {
 			return nil, err
 		}
-		if _, err := s.completions.Insert(ctx, id, now, task.Difficulty, bonus); err != nil {
+		bpCode, bpBonus, err := s.finishBlueprint(ctx, p, task, compID, now)
+		if err != nil {
 			return nil, err
 		}
 
@@ -237,6 +248,10 @@ func (s *Service) CompleteTask(ctx context.Context, id int64) (*CompleteResult,
 			LevelUp:       levelUp,
 			ProjectBonus:  true,
 			ProjectVolume: volume,
+			WeakenedLoss:  weakenedLoss,
+
+			BlueprintCompleted: bpCode,
+			BlueprintBonus:     bpBonus,
 		}, nil
 	}
 
@@ -248,20 +263,31 @@ func (s *Service) CompleteTask(ctx context.Context, id int64) (*CompleteResult,
 		return nil, fmt.Errorf("task %d is not a leaf task", id)
 	}
 
-	xp := task.XPValue
-	attr := parseStoredAttribute(task.Attribute)
+	xp, deadlineDelta := s.rules.DeadlineXP(task.XPValue, task.DueDate, now)
+	tracked, err := s.settleTimer(ctx, task, now)
+	if err != nil {
+		return nil, err
+	}
+	xp, timeBonus := s.rules.TimeXP(xp, tracked)
+	weakenedLoss := 0
+	if weak {
+		xp, weakenedLoss = s.rules.WeakenedXP(xp)
+	}
 
 	if err := s.tasks.MarkDone(ctx, id, now); err != nil {
 		return nil, err
 	}
 
-	p.XPTotal += xp
-	distributeXP(p, xp, attr, task.Attributes)
-	p.Level = LevelForTotalXP(p.XPTotal)
-	if err := s.players.Update(ctx, p); err != nil {
+	healed, err := s.healHP(ctx, p, task, s.rules.HP.HealPerTask, now)
+	if err != nil {
 		return nil, err
 	}
-	if _, err := s.completions.Insert(ctx, id, now, task.Difficulty, xp); err != nil {
+	compID, err := s.awardCompletion(ctx, p, task, now, xp, XPEventComplete)
+	if err != nil {
+		return nil, err
+	}
+	bpCode, bpBonus, err := s.finishBlueprint(ctx, p, task, compID, now)
+	if err != nil {
 		return nil, err
 	}
 
@@ -271,14 +297,46 @@ func (s *Service) CompleteTask(ctx context.Context, id int64) (*CompleteResult,
 	}
 
 	return &CompleteResult{
-		TaskID:      id,
-		XPAwarded:   xp,
-		LevelBefore: levelBefore,
-		LevelAfter:  p.Level,
-		LevelUp:     levelUp,
+		TaskID:        id,
+		XPAwarded:     xp,
+		LevelBefore:   levelBefore,
+		LevelAfter:    p.Level,
+		LevelUp:       levelUp,
+		DeadlineDelta: deadlineDelta,
+		HPHealed:      healed,
+		WeakenedLoss:  weakenedLoss,
+		Tracked:       tracked,
+		TimeBonus:     timeBonus,
+
+		BlueprintCompleted: bpCode,
+		BlueprintBonus:     bpBonus,
 	}, nil
 }
 
+// awardCompletion records a completion of task worth xp, credits the XP to the
+// player through the ledger and persists the player. It returns the completion ID.
+func (s *Service) awardCompletion(ctx context.Context, p *storage.Player, task *storage.Task, at time.Time, xp int, event string) (int64, error) {
+	compID, err := s.completions.Insert(ctx, task.ID, at, task.Difficulty, xp)
+	if err != nil {
+		return 0, err
+	}
+	shares := splitXP(xp, parseStoredAttribute(task.Attribute), task.Attributes)
+	if err := s.recordXP(ctx, p, event, &task.ID, &compID, shares, at); err != nil {
+		return 0, err
+	}
+	return compID, s.players.Update(ctx, p)
+}
+
+// finishBlueprint completes the blueprint task belongs to, if any, and
+// persists the player when a bonus was paid.
+func (s *Service) finishBlueprint(ctx context.Context, p *storage.Player, task *storage.Task, compID int64, at time.Time) (string, int, error) {
+	code, bonus, err := s.completeBlueprint(ctx, p, task, compID, at)
+	if err != nil || bonus == 0 {
+		return code, bonus, err
+	}
+	return code, bonus, s.players.Update(ctx, p)
+}
+
 func (s *Service) projectVolumeAndUndone(ctx context.Context, projectID int64) (volume int, hasUndone bool, err error) {
 	stack := []int64{projectID}
 	seen := map[int64]bool{}
@@ -366,7 +424,22 @@ type RestoreResult struct {
 // 1. Finds and deletes the last completion record
 // 2. Deducts the XP from the player (total and attribute-specific)
 // 3. Resets the task status to "pending"
+//
+// All three steps run in one transaction.
 func (s *Service) RestoreTask(ctx context.Context, id int64) (*RestoreResult, error) {
+	var res *RestoreResult
+	err := s.inTx(ctx, func(tx *Service) error {
+		var err error
+		res, err = tx.restoreTask(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Service) restoreTask(ctx context.Context, id int64) (*RestoreResult, error) {
 	p, err := s.getPlayer(ctx)
 	if err != nil {
 		return nil, err
@@ -378,7 +451,7 @@ func (s *Service) RestoreTask(ctx context.Context, id int64) (*RestoreResult, er
 		return nil, err
 	}
 	if task == nil {
-		return nil, fmt.Errorf("task %d not found", id)
+		return nil, fmt.Errorf("task %d %w", id, ErrNotFound)
 	}
 
 	// Get the last completion for this task
@@ -392,40 +465,51 @@ func (s *Service) RestoreTask(ctx context.Context, id int64) (*RestoreResult, er
 
 	xp := lastComp.XPAwarded
 
-	// Deduct XP from total
-	p.XPTotal -= xp
-	if p.XPTotal < 0 {
-		p.XPTotal = 0
-	}
-
-	// Deduct XP from the appropriate attribute
-	attr := parseStoredAttribute(task.Attribute)
-	if len(task.Attributes) > 0 {
-		// If task had multiple attributes, deduct proportionally
-		totalWeight := 0
-		for _, w := range task.Attributes {
-			totalWeight += w
-		}
-		if totalWeight > 0 {
-			for attrStr, weight := range task.Attributes {
-				a := parseStoredAttribute(attrStr)
-				share := (xp * weight) / totalWeight
-				deductAttributeXP(p, a, share)
-			}
+	// Reverse exactly what the ledger recorded for this completion. Completions
+	// from before the ledger existed fall back to re-splitting the awarded XP.
+	entries, err := s.ledger.ListByCompletion(ctx, lastComp.ID)
+	if err != nil {
+		return nil, err
+	}
+	var reversal []xpShare
+	for _, e := range entries {
+		if e.Event == XPEventRestore {
+			continue
+		}
+		reversal = append(reversal, xpShare{Attr: Attribute(e.Attribute), Amount: -e.Amount})
+	}
+	if len(reversal) == 0 {
+		for _, sh := range splitXP(xp, parseStoredAttribute(task.Attribute), task.Attributes) {
+			reversal = append(reversal, xpShare{Attr: sh.Attr, Amount: -sh.Amount})
 		}
 	} else {
-		// Single attribute mode
-		deductAttributeXP(p, attr, xp)
+		// The ledger also holds bonuses booked on the completion.
+		xp = 0
+		for _, sh := range reversal {
+			xp -= sh.Amount
+		}
+	}
+	if err := s.recordXP(ctx, p, XPEventRestore, &task.ID, &lastComp.ID, reversal, time.Now().UTC()); err != nil {
+		return nil, err
 	}
-
-	// Recalculate level
-	p.Level = LevelForTotalXP(p.XPTotal)
 
 	// Update player
 	if err := s.players.Update(ctx, p); err != nil {
 		return nil, err
 	}
 
+	// Give back the freeze tokens the completion spent and drop the ones it earned
+	if task.IsHabit {
+		if _, _, err := s.freezes.RevertCompletion(ctx, lastComp.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// A blueprint finished by this completion becomes active again
+	if err := s.reopenBlueprint(ctx, task, lastComp.ID); err != nil {
+		return nil, err
+	}
+
 	// Delete the completion record
 	if err := s.completions.Delete(ctx, lastComp.ID); err != nil {
 		return nil, err
@@ -444,56 +528,3 @@ func (s *Service) RestoreTask(ctx context.Context, id int64) (*RestoreResult, er
 		LevelDown:   p.Level < levelBefore,
 	}, nil
 }
-
-// deductAttributeXP removes XP from the appropriate attribute on the player.
-func deductAttributeXP(p *storage.Player, attr Attribute, xp int) {
-	switch attr {
-	case AttributeSTR:
-		p.XPStr -= xp
-		if p.XPStr < 0 {
-			p.XPStr = 0
-		}
-	case AttributeINT:
-		p.XPInt -= xp
-		if p.XPInt < 0 {
-			p.XPInt = 0
-		}
-	case AttributeART:
-		p.XPArt -= xp
-		if p.XPArt < 0 {
-			p.XPArt = 0
-		}
-	case AttributeHOME:
-		p.XPHome -= xp
-		if p.XPHome < 0 {
-			p.XPHome = 0
-		}
-	case AttributeOUT:
-		p.XPOut -= xp
-		if p.XPOut < 0 {
-			p.XPOut = 0
-		}
-	case AttributeREAD:
-		p.XPRead -= xp
-		if p.XPRead < 0 {
-			p.XPRead = 0
-		}
-	case AttributeCINEMA:
-		p.XPCinema -= xp
-		if p.XPCinema < 0 {
-			p.XPCinema = 0
-		}
-	case AttributeCAREER:
-		p.XPCareer -= xp
-		if p.XPCareer < 0 {
-			p.XPCareer = 0
-		}
-	case AttributeWIS:
-		fallthrough
-	default:
-		p.XPWis -= xp
-		if p.XPWis < 0 {
-			p.XPWis = 0
-		}
-	}
-}
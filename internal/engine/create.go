@@ -14,22 +14,28 @@ type CreateTaskInput struct {
 	Attribute     Attribute         // Primary attribute (backward compat)
 	Attributes    map[Attribute]int // Multi-attribute weights (e.g., {STR: 50, INT: 50})
 	ParentID      *int64
+	DueDate       *time.Time // Optional deadline (see ParseDueDate)
 	IsHabit       bool
 	HabitInterval HabitInterval
 	// Habit duration fields
 	HabitDuration *time.Duration // How long the habit challenge lasts (nil = forever)
 	HabitGoal     *int           // Target completions to complete the habit (nil = ongoing)
+	After         []int64        // Tasks that must be done before this one (see AddDependency)
+	Tags          []string       // Subtasks inherit them (see AddTags)
 }
 
 type CreateProjectInput struct {
 	Title      string
 	Attribute  Attribute
 	Attributes map[Attribute]int
+	After      []int64  // Tasks that must be done before the project (see AddDependency)
+	Tags       []string // Its tasks inherit them (see AddTags)
 }
 
 type CreateResult struct {
 	TaskID           int64
 	ProjectActivated bool
+	Achievements     []Achievement // Achievements earned by creating it
 }
 
 type CapacityError struct {
@@ -40,7 +46,21 @@ func (e CapacityError) Error() string {
 	return fmt.Sprintf("too many active tasks (limit %d)", e.Limit)
 }
 
+// CreateProject creates a project container in the planning state.
 func (s *Service) CreateProject(ctx context.Context, in CreateProjectInput) (*CreateResult, error) {
+	var res *CreateResult
+	err := s.inTx(ctx, func(tx *Service) error {
+		var err error
+		res, err = tx.createProject(ctx, in)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Service) createProject(ctx context.Context, in CreateProjectInput) (*CreateResult, error) {
 	title, err := normalizeTitle(in.Title)
 	if err != nil {
 		return nil, err
@@ -50,12 +70,16 @@ func (s *Service) CreateProject(ctx context.Context, in CreateProjectInput) (*Cr
 	if err != nil {
 		return nil, err
 	}
-	if err := CanCreateProject(p.Level); err != nil {
+	if err := s.rules.CanCreateProject(p.Level); err != nil {
 		return nil, err
 	}
 
+	reg, err := s.Attributes(ctx)
+	if err != nil {
+		return nil, err
+	}
 	attr := in.Attribute
-	if !attr.IsValid() {
+	if !reg.Has(attr) {
 		attr = DefaultAttribute
 	}
 
@@ -85,11 +109,37 @@ func (s *Service) CreateProject(ctx context.Context, in CreateProjectInput) (*Cr
 	if err != nil {
 		return nil, err
 	}
+	for _, after := range in.After {
+		if err := s.addDependency(ctx, id, after); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := s.addTags(ctx, id, in.Tags); err != nil {
+		return nil, err
+	}
 
 	return &CreateResult{TaskID: id}, nil
 }
 
+// CreateTask creates a task or habit. Activating a planning parent project
+// happens in the same transaction as the insert.
 func (s *Service) CreateTask(ctx context.Context, in CreateTaskInput) (*CreateResult, error) {
+	var res *CreateResult
+	err := s.inTx(ctx, func(tx *Service) error {
+		var err error
+		if res, err = tx.createTask(ctx, in); err != nil {
+			return err
+		}
+		res.Achievements, err = tx.recordAchievements(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Service) createTask(ctx context.Context, in CreateTaskInput) (*CreateResult, error) {
 	title, err := normalizeTitle(in.Title)
 	if err != nil {
 		return nil, err
@@ -104,16 +154,27 @@ func (s *Service) CreateTask(ctx context.Context, in CreateTaskInput) (*CreateRe
 	}
 
 	// Check difficulty gate
-	if err := CanUseDifficulty(p.Level, in.Difficulty); err != nil {
+	if err := s.rules.CanUseDifficulty(p.Level, in.Difficulty); err != nil {
 		return nil, err
 	}
 
 	if in.IsHabit {
-		if err := CanCreateHabit(p.Level); err != nil {
+		if err := s.rules.CanCreateHabit(p.Level); err != nil {
+			return nil, err
+		}
+		if in.HabitInterval == "" {
+			return nil, fmt.Errorf("habit interval is required (e.g. daily, mon/wed/fri, 3x/week)")
+		}
+		sched, err := ParseHabitSchedule(string(in.HabitInterval))
+		if err != nil {
 			return nil, err
 		}
-		if !in.HabitInterval.IsValid() {
-			return nil, fmt.Errorf("habit interval is required (daily/weekly)")
+		in.HabitInterval = HabitInterval(sched.String())
+		if in.DueDate == nil {
+			// Habits are due on their first scheduled day.
+			sched.Anchor = time.Now()
+			first := sched.FirstDue(sched.Anchor)
+			in.DueDate = &first
 		}
 	}
 
@@ -126,7 +187,10 @@ func (s *Service) CreateTask(ctx context.Context, in CreateTaskInput) (*CreateRe
 			return nil, err
 		}
 		if parent == nil {
-			return nil, fmt.Errorf("parent task %d not found", *parentID)
+			return nil, fmt.Errorf("parent task %d %w", *parentID, ErrNotFound)
+		}
+		if err := checkParentAccepts(parent); err != nil {
+			return nil, err
 		}
 
 		depth, err := s.taskDepthFromRoot(ctx, *parentID)
@@ -134,7 +198,7 @@ func (s *Service) CreateTask(ctx context.Context, in CreateTaskInput) (*CreateRe
 			return nil, err
 		}
 		// New task will be one deeper than parent.
-		if err := CanAttachToParent(p.Level, depth+1); err != nil {
+		if err := s.rules.CanAttachToParent(p.Level, depth+1); err != nil {
 			return nil, err
 		}
 	}
@@ -143,20 +207,24 @@ func (s *Service) CreateTask(ctx context.Context, in CreateTaskInput) (*CreateRe
 	if err != nil {
 		return nil, err
 	}
-	limit := MaxActiveTasks(p.Level)
+	limit := s.rules.MaxActiveTasks(p.Level)
 	if activeCount >= limit {
 		return nil, CapacityError{Limit: limit}
 	}
 
+	reg, err := s.Attributes(ctx)
+	if err != nil {
+		return nil, err
+	}
 	attr := in.Attribute
-	if !attr.IsValid() {
+	if !reg.Has(attr) {
 		attr = DefaultAttribute
 	}
 
 	// For XP calculation, use the primary attribute's level
 	attrXP := playerXPForAttribute(p, attr)
-	attrLevel := AttributeLevelForXP(attrXP)
-	xpValue, err := CalculateXP(in.Difficulty, attrLevel)
+	attrLevel := s.rules.AttributeLevelForXP(attrXP)
+	xpValue, err := s.rules.CalculateXP(in.Difficulty, attrLevel)
 	if err != nil {
 		return nil, err
 	}
@@ -181,6 +249,12 @@ func (s *Service) CreateTask(ctx context.Context, in CreateTaskInput) (*CreateRe
 		}
 	}
 
+	var dueDate *time.Time
+	if in.DueDate != nil {
+		v := in.DueDate.UTC()
+		dueDate = &v
+	}
+
 	// Calculate habit duration dates
 	var habitStartDate, habitEndDate *time.Time
 	var habitGoal *int
@@ -199,7 +273,7 @@ func (s *Service) CreateTask(ctx context.Context, in CreateTaskInput) (*CreateRe
 		Title:          title,
 		Description:    nil,
 		Status:         status,
-		DueDate:        nil,
+		DueDate:        dueDate,
 		Difficulty:     int(in.Difficulty),
 		Attribute:      string(attr),
 		Attributes:     attrs,
@@ -215,6 +289,15 @@ func (s *Service) CreateTask(ctx context.Context, in CreateTaskInput) (*CreateRe
 		return nil, err
 	}
 
+	for _, after := range in.After {
+		if err := s.addDependency(ctx, id, after); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := s.addTags(ctx, id, in.Tags); err != nil {
+		return nil, err
+	}
+
 	if parentID != nil {
 		parent, err := s.tasks.Get(ctx, *parentID)
 		if err != nil {
@@ -0,0 +1,238 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"questline/internal/storage"
+)
+
+// BlockedError is returned when completing a task that waits for unfinished ones.
+type BlockedError struct {
+	TaskID    int64
+	BlockedBy []int64
+}
+
+func (e BlockedError) Error() string {
+	return fmt.Sprintf("task %d is blocked by %s", e.TaskID, formatTaskIDs(e.BlockedBy))
+}
+
+// CycleError is returned when a dependency would make tasks wait for each
+// other. Path starts and ends with the task that was to wait, e.g. 14 → 15 → 14
+// when 15 already waits for 14. Projects wait for their tasks.
+type CycleError struct {
+	Path []int64
+}
+
+func (e CycleError) Error() string {
+	parts := make([]string, len(e.Path))
+	for i, id := range e.Path {
+		parts[i] = fmt.Sprintf("#%d", id)
+	}
+	return "dependency cycle: " + strings.Join(parts, " → ")
+}
+
+func formatTaskIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("#%d", id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// AddDependency makes taskID wait for blockedBy: taskID cannot be completed
+// until blockedBy is done. Habits repeat and cannot take part, and a
+// dependency that closes a cycle is refused with a CycleError. Adding an
+// existing dependency is a no-op.
+func (s *Service) AddDependency(ctx context.Context, taskID, blockedBy int64) error {
+	return s.inTx(ctx, func(tx *Service) error {
+		return tx.addDependency(ctx, taskID, blockedBy)
+	})
+}
+
+func (s *Service) addDependency(ctx context.Context, taskID, blockedBy int64) error {
+	if taskID == blockedBy {
+		return fmt.Errorf("task %d cannot wait for itself", taskID)
+	}
+	for _, id := range []int64{taskID, blockedBy} {
+		t, err := s.tasks.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if t == nil {
+			return fmt.Errorf("task %d %w", id, ErrNotFound)
+		}
+		if t.IsHabit {
+			return fmt.Errorf("task %d is a habit; habits repeat and cannot have dependencies", id)
+		}
+	}
+
+	waits, err := s.waitGraph(ctx)
+	if err != nil {
+		return err
+	}
+	if path := findPath(waits, blockedBy, taskID); path != nil {
+		return CycleError{Path: append([]int64{taskID}, path...)}
+	}
+	_, err = s.dependencies.Insert(ctx, storage.TaskDependency{TaskID: taskID, BlockedBy: blockedBy, CreatedAt: time.Now().UTC()})
+	return err
+}
+
+// RemoveDependency stops taskID from waiting for blockedBy.
+func (s *Service) RemoveDependency(ctx context.Context, taskID, blockedBy int64) error {
+	removed, err := s.dependencies.Delete(ctx, taskID, blockedBy)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("task %d does not wait for task %d", taskID, blockedBy)
+	}
+	return nil
+}
+
+// Dependencies returns every dependency between tasks.
+func (s *Service) Dependencies(ctx context.Context) ([]storage.TaskDependency, error) {
+	return s.dependencies.ListAll(ctx)
+}
+
+// OpenBlockers returns the unfinished tasks id waits for, in ID order.
+func (s *Service) OpenBlockers(ctx context.Context, id int64) ([]int64, error) {
+	deps, err := s.dependencies.ListBlockers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var open []int64
+	for _, d := range deps {
+		t, err := s.tasks.Get(ctx, d.BlockedBy)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil && t.Status != "done" {
+			open = append(open, d.BlockedBy)
+		}
+	}
+	return open, nil
+}
+
+// BlockedTasks maps every unfinished task that waits for unfinished tasks to
+// those blockers.
+func (s *Service) BlockedTasks(ctx context.Context) (map[int64][]int64, error) {
+	deps, err := s.dependencies.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := map[int64][]int64{}
+	if len(deps) == 0 {
+		return out, nil
+	}
+	tasks, err := s.tasks.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Trashed tasks are not listed: they neither wait nor block.
+	open := make(map[int64]bool, len(tasks))
+	for _, t := range tasks {
+		open[t.ID] = t.Status != "done"
+	}
+	for _, d := range deps {
+		if open[d.TaskID] && open[d.BlockedBy] {
+			out[d.TaskID] = append(out[d.TaskID], d.BlockedBy)
+		}
+	}
+	return out, nil
+}
+
+// waitGraph maps each task to the tasks it waits for: its explicit
+// dependencies and, for projects, their children.
+func (s *Service) waitGraph(ctx context.Context) (map[int64][]int64, error) {
+	deps, err := s.dependencies.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.tasks.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	waits := map[int64][]int64{}
+	for _, d := range deps {
+		waits[d.TaskID] = append(waits[d.TaskID], d.BlockedBy)
+	}
+	for _, t := range tasks {
+		if t.ParentID != nil {
+			waits[*t.ParentID] = append(waits[*t.ParentID], t.ID)
+		}
+	}
+	return waits, nil
+}
+
+// findPath returns a path from one task to another following edges, or nil.
+func findPath(edges map[int64][]int64, from, to int64) []int64 {
+	seen := map[int64]bool{}
+	var walk func(id int64) []int64
+	walk = func(id int64) []int64 {
+		if id == to {
+			return []int64{id}
+		}
+		if seen[id] {
+			return nil
+		}
+		seen[id] = true
+		for _, next := range edges[id] {
+			if rest := walk(next); rest != nil {
+				return append([]int64{id}, rest...)
+			}
+		}
+		return nil
+	}
+	return walk(from)
+}
+
+// OrderByDependencies sorts ids (e.g. the children of a project) so that every
+// task comes after the ones it waits for; otherwise the order is kept.
+// Dependencies on tasks outside ids are ignored.
+func OrderByDependencies(ids []int64, deps []storage.TaskDependency) []int64 {
+	pos := make(map[int64]int, len(ids))
+	for i, id := range ids {
+		pos[id] = i
+	}
+	waiting := map[int64]int{}
+	unblocks := map[int64][]int64{}
+	for _, d := range deps {
+		_, a := pos[d.TaskID]
+		_, b := pos[d.BlockedBy]
+		if a && b {
+			waiting[d.TaskID]++
+			unblocks[d.BlockedBy] = append(unblocks[d.BlockedBy], d.TaskID)
+		}
+	}
+	if len(unblocks) == 0 {
+		return ids
+	}
+
+	out := make([]int64, 0, len(ids))
+	var ready []int64
+	for _, id := range ids {
+		if waiting[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool { return pos[ready[i]] < pos[ready[j]] })
+		id := ready[0]
+		ready = ready[1:]
+		out = append(out, id)
+		for _, next := range unblocks[id] {
+			if waiting[next]--; waiting[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+	if len(out) < len(ids) {
+		// A cycle can't be stored, but keep every task rather than drop one.
+		return ids
+	}
+	return out
+}
@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"questline/internal/storage"
+)
+
+func TestDependenciesGateCompletionAndRefuseCycles(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelProjects))
+
+	create := func(title string, parent *int64, after ...int64) int64 {
+		t.Helper()
+		res, err := svc.CreateTask(ctx, CreateTaskInput{Title: title, Difficulty: DifficultyTrivial, Attribute: AttributeSTR, ParentID: parent, After: after})
+		if err != nil {
+			t.Fatalf("CreateTask %s: %v", title, err)
+		}
+		return res.TaskID
+	}
+	proj, err := svc.CreateProject(ctx, CreateProjectInput{Title: "Move house", Attribute: AttributeHOME})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	pid := proj.TaskID
+	pack := create("Pack", &pid)
+	move := create("Move boxes", &pid, pack)
+	unpack := create("Unpack", &pid)
+	if err := svc.AddDependency(ctx, unpack, move); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+
+	// Completing a blocked task is refused until its blockers are done.
+	var blocked BlockedError
+	if _, err := svc.CompleteTask(ctx, unpack); !errors.As(err, &blocked) || !reflect.DeepEqual(blocked.BlockedBy, []int64{move}) {
+		t.Fatalf("complete unpack err=%v, want blocked by #%d", err, move)
+	}
+	got, err := svc.BlockedTasks(ctx)
+	if err != nil {
+		t.Fatalf("BlockedTasks: %v", err)
+	}
+	if want := map[int64][]int64{move: {pack}, unpack: {move}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("BlockedTasks=%v, want %v", got, want)
+	}
+
+	// Cycles, including through a project waiting for its tasks, are refused.
+	var cycle CycleError
+	if err := svc.AddDependency(ctx, pack, unpack); !errors.As(err, &cycle) || !reflect.DeepEqual(cycle.Path, []int64{pack, unpack, move, pack}) {
+		t.Fatalf("pack after unpack err=%v, want cycle #%d → #%d → #%d → #%d", err, pack, unpack, move, pack)
+	}
+	if err := svc.AddDependency(ctx, pack, pid); !errors.As(err, &cycle) {
+		t.Fatalf("task after its own project err=%v, want a cycle", err)
+	}
+	if err := svc.AddDependency(ctx, pack, pack); err == nil {
+		t.Fatal("a task waiting for itself was accepted")
+	}
+
+	// Children are listed in the order they can be done in.
+	if order := OrderByDependencies([]int64{unpack, move, pack}, mustDeps(t, svc)); !reflect.DeepEqual(order, []int64{pack, move, unpack}) {
+		t.Fatalf("order=%v, want pack, move, unpack", order)
+	}
+
+	for _, id := range []int64{pack, move, unpack} {
+		if _, err := svc.CompleteTask(ctx, id); err != nil {
+			t.Fatalf("CompleteTask %d: %v", id, err)
+		}
+	}
+	if got, _ := svc.BlockedTasks(ctx); len(got) != 0 {
+		t.Fatalf("BlockedTasks after finishing=%v, want none", got)
+	}
+	if err := svc.RemoveDependency(ctx, unpack, move); err != nil {
+		t.Fatalf("RemoveDependency: %v", err)
+	}
+	if err := svc.RemoveDependency(ctx, unpack, move); err == nil {
+		t.Fatal("removing a missing dependency succeeded")
+	}
+}
+
+func TestSequentialBlueprintChainsItsTasks(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	setPlayerXP(t, svc, XPRequiredForLevel(12))
+	p, err := svc.PlayerRepo().GetOrCreateMain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addAttributeXP(p, AttributeSTR, XPRequiredForLevel(3))
+	if err := svc.PlayerRepo().Update(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := svc.AcceptBlueprint(ctx, "str_gym")
+	if err != nil {
+		t.Fatalf("AcceptBlueprint: %v", err)
+	}
+	weeks, err := svc.TaskRepo().ListChildren(ctx, res.TaskID)
+	if err != nil || len(weeks) != 4 {
+		t.Fatalf("children=%v, %v; want 4 weeks", weeks, err)
+	}
+	for i := 1; i < len(weeks); i++ {
+		blockers, err := svc.OpenBlockers(ctx, weeks[i].ID)
+		if err != nil || !reflect.DeepEqual(blockers, []int64{weeks[i-1].ID}) {
+			t.Fatalf("%s blockers=%v, %v; want #%d", weeks[i].Title, blockers, err, weeks[i-1].ID)
+		}
+	}
+	if _, err := svc.CompleteTask(ctx, weeks[1].ID); !errors.As(err, new(BlockedError)) {
+		t.Fatalf("week 2 before week 1 err=%v, want blocked", err)
+	}
+}
+
+func mustDeps(t *testing.T, svc *Service) []storage.TaskDependency {
+	t.Helper()
+	deps, err := svc.Dependencies(context.Background())
+	if err != nil {
+		t.Fatalf("Dependencies: %v", err)
+	}
+	return deps
+}
@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"questline/internal/storage"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// ParseDueDate parses a due date relative to now, in now's location.
+// Supported: "today", "tomorrow", weekday names ("fri", "friday": the next one,
+// never today), offsets ("+3d", "+2w", "+1m", "+4h") and absolute dates
+// ("2006-01-02", "2006-01-02 15:04", RFC 3339). Inputs without a time of day
+// mean the end of that day.
+func ParseDueDate(input string, now time.Time) (time.Time, error) {
+	s := strings.ToLower(strings.TrimSpace(input))
+	if s == "" {
+		return time.Time{}, fmt.Errorf("due date is empty")
+	}
+
+	switch s {
+	case "today":
+		return endOfDay(now), nil
+	case "tomorrow", "tmr":
+		return endOfDay(now.AddDate(0, 0, 1)), nil
+	}
+	if wd, ok := weekdays[s]; ok {
+		days := (int(wd) - int(now.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return endOfDay(now.AddDate(0, 0, days)), nil
+	}
+
+	if strings.HasPrefix(s, "+") && len(s) >= 3 {
+		n, err := strconv.Atoi(s[1 : len(s)-1])
+		if err != nil || n <= 0 {
+			return time.Time{}, fmt.Errorf("invalid due offset %q", input)
+		}
+		switch s[len(s)-1] {
+		case 'h':
+			return now.Add(time.Duration(n) * time.Hour), nil
+		case 'd':
+			return endOfDay(now.AddDate(0, 0, n)), nil
+		case 'w':
+			return endOfDay(now.AddDate(0, 0, 7*n)), nil
+		case 'm':
+			return endOfDay(now.AddDate(0, n, 0)), nil
+		default:
+			return time.Time{}, fmt.Errorf("invalid due offset %q (use h, d, w or m)", input)
+		}
+	}
+
+	if t, err := time.Parse(time.RFC3339, strings.TrimSpace(input)); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return endOfDay(t), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid due date %q (try tomorrow, fri, +3d or 2006-01-02)", input)
+}
+
+func endOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 23, 59, 59, 0, t.Location())
+}
+
+// IsOverdue reports whether an open task is past its due date.
+// Habits are excluded: their due date is the next scheduled repetition.
+func IsOverdue(t *storage.Task, now time.Time) bool {
+	if t.DueDate == nil || t.IsHabit || t.Status == "done" {
+		return false
+	}
+	return now.After(*t.DueDate)
+}
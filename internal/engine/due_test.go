@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseDueDate(t *testing.T) {
+	// Friday afternoon.
+	now := time.Date(2026, 10, 16, 14, 30, 0, 0, time.Local)
+	eod := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 23, 59, 59, 0, time.Local) }
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"today", eod(2026, 10, 16)},
+		{"Tomorrow", eod(2026, 10, 17)},
+		{"mon", eod(2026, 10, 19)},
+		{"friday", eod(2026, 10, 23)},
+		{"+4h", now.Add(4 * time.Hour)},
+		{"+3d", eod(2026, 10, 19)},
+		{"+2w", eod(2026, 10, 30)},
+		{"+1m", eod(2026, 11, 16)},
+		{"2026-12-24", eod(2026, 12, 24)},
+		{"2026-12-24 09:00", time.Date(2026, 12, 24, 9, 0, 0, 0, time.Local)},
+		{"2026-12-24T09:00:00Z", time.Date(2026, 12, 24, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseDueDate(tt.in, now)
+		if err != nil {
+			t.Fatalf("ParseDueDate(%q): %v", tt.in, err)
+		}
+		if !got.Equal(tt.want) {
+			t.Fatalf("ParseDueDate(%q)=%v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "someday", "+0d", "+3x", "2026-13-01"} {
+		if _, err := ParseDueDate(in, now); err == nil {
+			t.Fatalf("ParseDueDate(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestDeadlineRulesAdjustXP(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	late := now.Add(-60 * time.Hour) // counts as three days late
+	early := now.Add(72 * time.Hour)
+
+	tests := []struct {
+		preset string
+		due    *time.Time
+		rate   float64
+	}{
+		{"default", &late, 0},
+		{"hardcore", &late, -0.30},
+		{"hardcore", &early, 0},
+		{"casual", &early, 0.10},
+		{"casual", &late, 0},
+		{"hardcore", nil, 0},
+	}
+	for _, tt := range tests {
+		rules, err := PresetRules(tt.preset)
+		if err != nil {
+			t.Fatalf("PresetRules: %v", err)
+		}
+		svc, cleanup := newTestServiceWithRules(t, rules)
+
+		created, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Report", Difficulty: DifficultyTrivial, Attribute: AttributeINT, DueDate: tt.due})
+		if err != nil {
+			t.Fatalf("%s: CreateTask: %v", tt.preset, err)
+		}
+		task, _ := svc.TaskRepo().Get(ctx, created.TaskID)
+		if tt.due != nil && (task.DueDate == nil || !task.DueDate.Equal(*tt.due)) {
+			t.Fatalf("%s: stored due=%v, want %v", tt.preset, task.DueDate, tt.due)
+		}
+		if got := IsOverdue(task, now); got != (tt.due == &late) {
+			t.Fatalf("%s: IsOverdue=%v", tt.preset, got)
+		}
+
+		res, err := svc.CompleteTask(ctx, created.TaskID)
+		if err != nil {
+			t.Fatalf("%s: CompleteTask: %v", tt.preset, err)
+		}
+		want := int(float64(task.XPValue)*(1+tt.rate) + 0.5)
+		if res.XPAwarded != want || res.DeadlineDelta != want-task.XPValue {
+			t.Fatalf("%s: xp=%d delta=%d, want %d delta %d", tt.preset, res.XPAwarded, res.DeadlineDelta, want, want-task.XPValue)
+		}
+		cleanup()
+	}
+}
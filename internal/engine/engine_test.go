@@ -3,12 +3,18 @@ package engine
 import (
 	"context"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"questline/internal/storage"
 )
 
 func newTestService(t *testing.T) (*Service, func()) {
+	t.Helper()
+	return newTestServiceWithRules(t, DefaultRules())
+}
+
+func newTestServiceWithRules(t *testing.T, rules *Rules) (*Service, func()) {
 	t.Helper()
 	ctx := context.Background()
 
@@ -19,7 +25,7 @@ func newTestService(t *testing.T) (*Service, func()) {
 		t.Fatalf("open db: %v", err)
 	}
 
-	svc := NewService(db)
+	svc := NewServiceWithRules(db, rules)
 	cleanup := func() {
 		_ = db.Close()
 	}
@@ -208,3 +214,96 @@ func TestHabitDecayAndDifficultyReset(t *testing.T) {
 		t.Fatalf("expected habit due_date to be set")
 	}
 }
+
+func TestLedgerRestoreReversesExactSplitAndRebuilds(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	res, err := svc.CreateTask(ctx, CreateTaskInput{
+		Title:      "Cook and read",
+		Difficulty: DifficultyTrivial,
+		Attribute:  AttributeHOME,
+		Attributes: map[Attribute]int{AttributeHOME: 1, AttributeREAD: 1, AttributeWIS: 1},
+	})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if _, err := svc.CompleteTask(ctx, res.TaskID); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if _, err := svc.RestoreTask(ctx, res.TaskID); err != nil {
+		t.Fatalf("RestoreTask: %v", err)
+	}
+
+	p, _ := svc.PlayerRepo().GetOrCreateMain(ctx)
+	if p.XPTotal != 0 || p.AttrXP("HOME") != 0 || p.AttrXP("READ") != 0 || p.AttrXP("WIS") != 0 {
+		t.Fatalf("player after restore=%+v, want all zero", p)
+	}
+
+	done, err := svc.CompleteTask(ctx, res.TaskID)
+	if err != nil {
+		t.Fatalf("CompleteTask again: %v", err)
+	}
+	want, _ := svc.PlayerRepo().GetOrCreateMain(ctx)
+	if got := want.AttrXP("HOME") + want.AttrXP("READ") + want.AttrXP("WIS"); got != done.XPAwarded {
+		t.Fatalf("attribute sum=%d, want %d", got, done.XPAwarded)
+	}
+
+	// Corrupt the counters, then rebuild them from the ledger.
+	broken := *want
+	broken.XPTotal = 9999
+	broken.XP = map[string]int{"HOME": 1, "READ": 2, "WIS": want.AttrXP("WIS")}
+	if err := svc.PlayerRepo().Update(ctx, &broken); err != nil {
+		t.Fatalf("update player: %v", err)
+	}
+	rb, err := svc.RebuildPlayer(ctx)
+	if err != nil {
+		t.Fatalf("RebuildPlayer: %v", err)
+	}
+	if rb.XPAfter != done.XPAwarded {
+		t.Fatalf("rebuilt xp=%d, want %d", rb.XPAfter, done.XPAwarded)
+	}
+	got, _ := svc.PlayerRepo().GetOrCreateMain(ctx)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rebuilt player=%+v, want %+v", got, want)
+	}
+}
+
+func TestCustomAttributeEarnsXP(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := svc.AddAttribute(ctx, AddAttributeInput{Code: "music", Icon: "🎸", Aliases: []string{"guitar"}}); err != nil {
+		t.Fatalf("AddAttribute: %v", err)
+	}
+	if _, err := svc.AddAttribute(ctx, AddAttributeInput{Code: "JAM", Aliases: []string{"Guitar"}}); err == nil {
+		t.Fatalf("expected alias clash error")
+	}
+
+	reg, err := svc.Attributes(ctx)
+	if err != nil {
+		t.Fatalf("Attributes: %v", err)
+	}
+	attr := reg.Parse("guitar")
+	if attr != "MUSIC" {
+		t.Fatalf("Parse(guitar)=%q, want MUSIC", attr)
+	}
+
+	res, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Practice scales", Difficulty: DifficultyTrivial, Attribute: attr})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	done, err := svc.CompleteTask(ctx, res.TaskID)
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	p, _ := svc.PlayerRepo().GetOrCreateMain(ctx)
+	if got := p.AttrXP("MUSIC"); got != done.XPAwarded {
+		t.Fatalf("MUSIC xp=%d, want %d", got, done.XPAwarded)
+	}
+	if got := p.AttrXP("WIS"); got != 0 {
+		t.Fatalf("WIS xp=%d, want 0", got)
+	}
+}
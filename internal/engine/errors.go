@@ -1,6 +1,13 @@
 package engine
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is wrapped by errors about a task or blueprint that doesn't
+// exist.
+var ErrNotFound = errors.New("not found")
 
 // GateError indicates a feature is locked behind a required global level.
 // This is returned by gate checks and should be shown to the user.
@@ -15,3 +22,32 @@ func (e GateError) Error() string {
 	}
 	return fmt.Sprintf("feature '%s' unlocks at level %d", e.Feature, e.RequiredLevel)
 }
+
+// Error codes sort errors for scripts and API clients; see ErrorCode.
+const (
+	ErrCodeError    = "error"     // Anything else: bad input, storage failures
+	ErrCodeNotFound = "not_found" // ErrNotFound
+	ErrCodeLocked   = "locked"    // GateError, DifficultyGateError, CapacityError
+	ErrCodeConflict = "conflict"  // BlockedError, CycleError, XPChoiceError, ErrNoTimer
+)
+
+// ErrorCode returns the error code for err.
+func ErrorCode(err error) string {
+	var (
+		gate     GateError
+		diff     DifficultyGateError
+		capacity CapacityError
+		blocked  BlockedError
+		cycle    CycleError
+		choice   XPChoiceError
+	)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return ErrCodeNotFound
+	case errors.As(err, &gate), errors.As(err, &diff), errors.As(err, &capacity):
+		return ErrCodeLocked
+	case errors.As(err, &blocked), errors.As(err, &cycle), errors.As(err, &choice), errors.Is(err, ErrNoTimer):
+		return ErrCodeConflict
+	}
+	return ErrCodeError
+}
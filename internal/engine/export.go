@@ -0,0 +1,821 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"questline/internal/storage"
+)
+
+// Export document identity. ExportVersion is bumped whenever the document gains
+// or changes fields, so older importers refuse it rather than drop what they
+// don't know; see docs/EXPORT.md for what each version added.
+const (
+	ExportFormat  = "questline-export"
+	ExportVersion = 2
+)
+
+// ExportDoc is a complete, self-contained snapshot of a questline database.
+// IDs are the source database IDs; references between sections (parent_id,
+// task_id, completion_id) use them and are remapped on merge import.
+type ExportDoc struct {
+	Format        string              `json:"format"`
+	Version       int                 `json:"version"`
+	SchemaVersion int                 `json:"schema_version"`
+	ExportedAt    time.Time           `json:"exported_at"`
+	Player        ExportPlayer        `json:"player"`
+	Attributes    []ExportAttribute   `json:"attributes"`
+	Tasks         []ExportTask        `json:"tasks"`
+	Completions   []ExportCompletion  `json:"completions"`
+	XPLedger      []ExportXPEntry     `json:"xp_ledger"`
+	StreakFreezes []ExportFreeze      `json:"streak_freezes,omitempty"`
+	Dependencies  []ExportDependency  `json:"dependencies,omitempty"`
+	Blueprints    []ExportBlueprint   `json:"blueprints"`
+	Achievements  []ExportAchievement `json:"achievements"`
+	Reviews       []ExportReview      `json:"reviews,omitempty"`
+	Sessions      []ExportSession     `json:"sessions,omitempty"`
+}
+
+type ExportPlayer struct {
+	Level         int            `json:"level"`
+	XPTotal       int            `json:"xp_total"`
+	XP            map[string]int `json:"xp"`
+	HP            *int           `json:"hp,omitempty"`
+	WeakenedUntil *time.Time     `json:"weakened_until,omitempty"`
+}
+
+type ExportAttribute struct {
+	Code    string   `json:"code"`
+	Name    string   `json:"name"`
+	Icon    string   `json:"icon,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+	Color   string   `json:"color,omitempty"`
+	Builtin bool     `json:"builtin"`
+}
+
+type ExportTask struct {
+	ID             int64          `json:"id"`
+	ParentID       *int64         `json:"parent_id,omitempty"`
+	Title          string         `json:"title"`
+	Description    *string        `json:"description,omitempty"`
+	Status         string         `json:"status"`
+	CreatedAt      time.Time      `json:"created_at"`
+	CompletedAt    *time.Time     `json:"completed_at,omitempty"`
+	DueDate        *time.Time     `json:"due_date,omitempty"`
+	Difficulty     int            `json:"difficulty"`
+	Attribute      string         `json:"attribute"`
+	Attributes     map[string]int `json:"attributes,omitempty"`
+	XPValue        int            `json:"xp_value"`
+	IsProject      bool           `json:"is_project"`
+	IsHabit        bool           `json:"is_habit"`
+	HabitInterval  *string        `json:"habit_interval,omitempty"`
+	HabitStartDate *time.Time     `json:"habit_start_date,omitempty"`
+	HabitEndDate   *time.Time     `json:"habit_end_date,omitempty"`
+	HabitGoal      *int           `json:"habit_goal,omitempty"`
+	BlueprintCode  *string        `json:"blueprint_code,omitempty"`
+	DeletedAt      *time.Time     `json:"deleted_at,omitempty"`
+	Tags           []string       `json:"tags,omitempty"`
+}
+
+type ExportCompletion struct {
+	ID          int64     `json:"id"`
+	TaskID      int64     `json:"task_id"`
+	CompletedAt time.Time `json:"completed_at"`
+	Difficulty  int       `json:"difficulty"`
+	XPAwarded   int       `json:"xp_awarded"`
+}
+
+type ExportXPEntry struct {
+	ID           int64     `json:"id"`
+	Event        string    `json:"event"`
+	TaskID       *int64    `json:"task_id,omitempty"`
+	CompletionID *int64    `json:"completion_id,omitempty"`
+	Attribute    string    `json:"attribute,omitempty"`
+	Amount       int       `json:"amount"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ExportFreeze is a streak freeze token; the used_* fields are set once it
+// covered a missed period of a habit.
+type ExportFreeze struct {
+	ID                 int64      `json:"id"`
+	EarnedTaskID       *int64     `json:"earned_task_id,omitempty"`
+	EarnedCompletionID *int64     `json:"earned_completion_id,omitempty"`
+	EarnedAt           time.Time  `json:"earned_at"`
+	UsedTaskID         *int64     `json:"used_task_id,omitempty"`
+	UsedCompletionID   *int64     `json:"used_completion_id,omitempty"`
+	CoveredPeriod      *time.Time `json:"covered_period,omitempty"`
+	UsedAt             *time.Time `json:"used_at,omitempty"`
+}
+
+// ExportDependency says task_id cannot be completed before blocked_by is done.
+type ExportDependency struct {
+	TaskID    int64     `json:"task_id"`
+	BlockedBy int64     `json:"blocked_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportBlueprint struct {
+	Code               string     `json:"code"`
+	Status             string     `json:"status"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+	RewardCompletionID *int64     `json:"reward_completion_id,omitempty"`
+}
+
+// ExportAchievement lists an earned achievement. Import restores the ones with
+// an earned_at; the others are derived from the rest of the document again.
+type ExportAchievement struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	EarnedAt *time.Time `json:"earned_at,omitempty"`
+}
+
+// ExportReview is a saved weekly or monthly review. Its XP is in the ledger.
+type ExportReview struct {
+	ID          int64     `json:"id"`
+	Period      string    `json:"period"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Completions int       `json:"completions"`
+	XPEarned    int       `json:"xp_earned"`
+	Rescheduled int       `json:"rescheduled,omitempty"`
+	Dropped     int       `json:"dropped,omitempty"`
+	Rerated     int       `json:"rerated,omitempty"`
+	XPAwarded   int       `json:"xp_awarded"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ExportSession is time tracked on a task; ended_at is missing while the timer
+// runs.
+type ExportSession struct {
+	ID        int64      `json:"id"`
+	TaskID    int64      `json:"task_id"`
+	Kind      string     `json:"kind"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+// Export reads the whole database into an ExportDoc.
+func (s *Service) Export(ctx context.Context) (*ExportDoc, error) {
+	schema, err := storage.CurrentSchemaVersion(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &ExportDoc{
+		Format:        ExportFormat,
+		Version:       ExportVersion,
+		SchemaVersion: schema,
+		ExportedAt:    time.Now().UTC(),
+	}
+	err = s.inTx(ctx, func(tx *Service) error {
+		p, err := tx.players.GetOrCreateMain(ctx)
+		if err != nil {
+			return err
+		}
+		doc.Player = ExportPlayer{Level: p.Level, XPTotal: p.XPTotal, XP: map[string]int{}, HP: p.HP, WeakenedUntil: p.WeakenedUntil}
+		for code, xp := range p.XP {
+			if xp != 0 {
+				doc.Player.XP[code] = xp
+			}
+		}
+
+		attrs, err := tx.attributes.ListAll(ctx)
+		if err != nil {
+			return err
+		}
+		for _, a := range attrs {
+			doc.Attributes = append(doc.Attributes, ExportAttribute{Code: a.Code, Name: a.Name, Icon: a.Icon, Aliases: a.Aliases, Color: a.Color, Builtin: a.Builtin})
+		}
+
+		tasks, err := tx.tasks.ListAllIncludingDeleted(ctx)
+		if err != nil {
+			return err
+		}
+		tags, err := tx.tags.ListAll(ctx)
+		if err != nil {
+			return err
+		}
+		ids := make(map[int64]bool, len(tasks))
+		for _, t := range tasks {
+			ids[t.ID] = true
+		}
+		for _, t := range tasks {
+			// Older deletes could leave subtasks whose parent is gone; they
+			// are exported at the top level.
+			parentID := t.ParentID
+			if parentID != nil && !ids[*parentID] {
+				parentID = nil
+			}
+			doc.Tasks = append(doc.Tasks, ExportTask{
+				ID:             t.ID,
+				ParentID:       parentID,
+				Title:          t.Title,
+				Description:    t.Description,
+				Status:         t.Status,
+				CreatedAt:      t.CreatedAt.UTC(),
+				CompletedAt:    utcPtr(t.CompletedAt),
+				DueDate:        utcPtr(t.DueDate),
+				Difficulty:     t.Difficulty,
+				Attribute:      t.Attribute,
+				Attributes:     t.Attributes,
+				XPValue:        t.XPValue,
+				IsProject:      t.IsProject,
+				IsHabit:        t.IsHabit,
+				HabitInterval:  t.HabitInterval,
+				HabitStartDate: utcPtr(t.HabitStartDate),
+				HabitEndDate:   utcPtr(t.HabitEndDate),
+				HabitGoal:      t.HabitGoal,
+				BlueprintCode:  t.BlueprintCode,
+				DeletedAt:      utcPtr(t.DeletedAt),
+				Tags:           tags[t.ID],
+			})
+		}
+
+		comps, err := tx.completions.ListAll(ctx)
+		if err != nil {
+			return err
+		}
+		for _, c := range comps {
+			doc.Completions = append(doc.Completions, ExportCompletion{ID: c.ID, TaskID: c.TaskID, CompletedAt: c.CompletedAt.UTC(), Difficulty: c.Difficulty, XPAwarded: c.XPAwarded})
+		}
+
+		entries, err := tx.ledger.ListAll(ctx)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.PlayerKey != p.Key {
+				continue
+			}
+			doc.XPLedger = append(doc.XPLedger, ExportXPEntry{ID: e.ID, Event: e.Event, TaskID: e.TaskID, CompletionID: e.CompletionID, Attribute: e.Attribute, Amount: e.Amount, CreatedAt: e.CreatedAt.UTC()})
+		}
+
+		freezes, err := tx.freezes.ListAll(ctx)
+		if err != nil {
+			return err
+		}
+		for _, f := range freezes {
+			doc.StreakFreezes = append(doc.StreakFreezes, ExportFreeze{
+				ID:                 f.ID,
+				EarnedTaskID:       f.EarnedTaskID,
+				EarnedCompletionID: f.EarnedCompletionID,
+				EarnedAt:           f.EarnedAt.UTC(),
+				UsedTaskID:         f.UsedTaskID,
+				UsedCompletionID:   f.UsedCompletionID,
+				CoveredPeriod:      utcPtr(f.CoveredPeriod),
+				UsedAt:             utcPtr(f.UsedAt),
+			})
+		}
+
+		deps, err := tx.dependencies.ListAll(ctx)
+		if err != nil {
+			return err
+		}
+		for _, d := range deps {
+			doc.Dependencies = append(doc.Dependencies, ExportDependency{TaskID: d.TaskID, BlockedBy: d.BlockedBy, CreatedAt: d.CreatedAt.UTC()})
+		}
+
+		bps, err := tx.blueprints.ListAll(ctx)
+		if err != nil {
+			return err
+		}
+		sort.Slice(bps, func(i, j int) bool { return bps[i].Code < bps[j].Code })
+		for _, b := range bps {
+			doc.Blueprints = append(doc.Blueprints, ExportBlueprint{Code: b.Code, Status: b.Status, CompletedAt: utcPtr(b.CompletedAt), RewardCompletionID: b.RewardCompletionID})
+		}
+
+		reviews, err := tx.reviews.ListAll(ctx)
+		if err != nil {
+			return err
+		}
+		for _, r := range reviews {
+			doc.Reviews = append(doc.Reviews, ExportReview{
+				ID:          r.ID,
+				Period:      r.Period,
+				PeriodStart: r.PeriodStart.UTC(),
+				PeriodEnd:   r.PeriodEnd.UTC(),
+				Completions: r.Completions,
+				XPEarned:    r.XPEarned,
+				Rescheduled: r.Rescheduled,
+				Dropped:     r.Dropped,
+				Rerated:     r.Rerated,
+				XPAwarded:   r.XPAwarded,
+				CreatedAt:   r.CreatedAt.UTC(),
+			})
+		}
+
+		sessions, err := tx.sessions.ListAll(ctx)
+		if err != nil {
+			return err
+		}
+		for _, ss := range sessions {
+			doc.Sessions = append(doc.Sessions, ExportSession{ID: ss.ID, TaskID: ss.TaskID, Kind: ss.Kind, StartedAt: ss.StartedAt.UTC(), EndedAt: utcPtr(ss.EndedAt)})
+		}
+
+		achievements, err := GetAchievementsForPlayer(ctx, tx)
+		if err != nil {
+			return err
+		}
+		for _, a := range achievements {
+			if a.Earned {
+				doc.Achievements = append(doc.Achievements, ExportAchievement{ID: a.ID, Name: a.Name, EarnedAt: utcPtr(a.EarnedAt)})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := t.UTC()
+	return &v
+}
+
+// ImportResult reports what an import wrote.
+type ImportResult struct {
+	Merged        bool
+	Attributes    int
+	Tasks         int
+	Completions   int
+	LedgerEntries int
+	StreakFreezes int
+	Dependencies  int
+	Blueprints    int
+	Achievements  int
+	Reviews       int
+	Sessions      int
+	TaskIDs       map[int64]int64 // export ID -> database ID
+}
+
+// ErrImportNotEmpty is returned when a plain import targets a database that
+// already holds data.
+var ErrImportNotEmpty = errors.New("database is not empty (use merge to import into it)")
+
+// Import loads an export document.
+//
+// Without merge the database must be empty; rows keep their exported IDs and the
+// player counters are restored verbatim, so exporting again yields the same
+// document. With merge, rows get new IDs (references are remapped), player XP
+// is added on top of the current totals, custom attributes are added when their
+// code is unknown, and blueprint statuses only ever move forward.
+func (s *Service) Import(ctx context.Context, doc *ExportDoc, merge bool) (*ImportResult, error) {
+	doc = withKnownParents(doc)
+	if err := validateExportDoc(doc); err != nil {
+		return nil, err
+	}
+
+	var res *ImportResult
+	err := s.inTx(ctx, func(tx *Service) error {
+		p, err := tx.players.GetOrCreateMain(ctx)
+		if err != nil {
+			return err
+		}
+		if !merge {
+			empty, err := tx.isEmpty(ctx, p)
+			if err != nil {
+				return err
+			}
+			if !empty {
+				return ErrImportNotEmpty
+			}
+		}
+		res = &ImportResult{Merged: merge, TaskIDs: map[int64]int64{}}
+
+		if err := tx.importAttributes(ctx, doc.Attributes, res); err != nil {
+			return err
+		}
+
+		// keep returns the ID to insert with: the exported one for a fresh
+		// database, 0 (assign a new one) when merging.
+		keep := func(id int64) int64 {
+			if merge {
+				return 0
+			}
+			return id
+		}
+
+		tasks, err := parentsFirst(doc.Tasks)
+		if err != nil {
+			return err
+		}
+		for _, t := range tasks {
+			var parent *int64
+			if t.ParentID != nil {
+				v := res.TaskIDs[*t.ParentID]
+				parent = &v
+			}
+			id, err := tx.tasks.InsertFull(ctx, storage.Task{
+				ID:             keep(t.ID),
+				ParentID:       parent,
+				Title:          t.Title,
+				Description:    t.Description,
+				Status:         t.Status,
+				CreatedAt:      t.CreatedAt,
+				CompletedAt:    t.CompletedAt,
+				DueDate:        t.DueDate,
+				Difficulty:     t.Difficulty,
+				Attribute:      t.Attribute,
+				Attributes:     t.Attributes,
+				XPValue:        t.XPValue,
+				IsProject:      t.IsProject,
+				IsHabit:        t.IsHabit,
+				HabitInterval:  t.HabitInterval,
+				HabitStartDate: t.HabitStartDate,
+				HabitEndDate:   t.HabitEndDate,
+				HabitGoal:      t.HabitGoal,
+				BlueprintCode:  t.BlueprintCode,
+				DeletedAt:      t.DeletedAt,
+			})
+			if err != nil {
+				return err
+			}
+			for _, tag := range t.Tags {
+				if _, err := tx.tags.Add(ctx, id, tag); err != nil {
+					return err
+				}
+			}
+			res.TaskIDs[t.ID] = id
+			res.Tasks++
+		}
+
+		compIDs := map[int64]int64{}
+		for _, c := range doc.Completions {
+			id, err := tx.completions.InsertFull(ctx, storage.TaskCompletion{
+				ID:          keep(c.ID),
+				TaskID:      res.TaskIDs[c.TaskID],
+				CompletedAt: c.CompletedAt,
+				Difficulty:  c.Difficulty,
+				XPAwarded:   c.XPAwarded,
+			})
+			if err != nil {
+				return err
+			}
+			compIDs[c.ID] = id
+			res.Completions++
+		}
+
+		for _, e := range doc.XPLedger {
+			entry := storage.XPEntry{
+				ID:        keep(e.ID),
+				PlayerKey: p.Key,
+				Event:     e.Event,
+				Attribute: e.Attribute,
+				Amount:    e.Amount,
+				CreatedAt: e.CreatedAt,
+			}
+			if e.TaskID != nil {
+				v := res.TaskIDs[*e.TaskID]
+				entry.TaskID = &v
+			}
+			if e.CompletionID != nil {
+				v := compIDs[*e.CompletionID]
+				entry.CompletionID = &v
+			}
+			if _, err := tx.ledger.Insert(ctx, entry); err != nil {
+				return err
+			}
+			res.LedgerEntries++
+		}
+
+		remap := func(ids map[int64]int64, id *int64) *int64 {
+			if id == nil {
+				return nil
+			}
+			v := ids[*id]
+			return &v
+		}
+		for _, f := range doc.StreakFreezes {
+			if _, err := tx.freezes.Insert(ctx, storage.StreakFreeze{
+				ID:                 keep(f.ID),
+				EarnedTaskID:       remap(res.TaskIDs, f.EarnedTaskID),
+				EarnedCompletionID: remap(compIDs, f.EarnedCompletionID),
+				EarnedAt:           f.EarnedAt,
+				UsedTaskID:         remap(res.TaskIDs, f.UsedTaskID),
+				UsedCompletionID:   remap(compIDs, f.UsedCompletionID),
+				CoveredPeriod:      f.CoveredPeriod,
+				UsedAt:             f.UsedAt,
+			}); err != nil {
+				return err
+			}
+			res.StreakFreezes++
+		}
+
+		for _, d := range doc.Dependencies {
+			if _, err := tx.dependencies.Insert(ctx, storage.TaskDependency{TaskID: res.TaskIDs[d.TaskID], BlockedBy: res.TaskIDs[d.BlockedBy], CreatedAt: d.CreatedAt}); err != nil {
+				return err
+			}
+			res.Dependencies++
+		}
+
+		for _, b := range doc.Blueprints {
+			row := storage.Blueprint{Code: b.Code, Status: b.Status, CompletedAt: b.CompletedAt, RewardCompletionID: remap(compIDs, b.RewardCompletionID)}
+			if merge {
+				cur, err := tx.blueprints.Get(ctx, b.Code)
+				if err != nil {
+					return err
+				}
+				if cur != nil && blueprintStatusRank(cur.Status) >= blueprintStatusRank(b.Status) {
+					continue
+				}
+				// A bonus already paid here stays the one that counts.
+				if cur != nil && cur.RewardCompletionID != nil {
+					row.RewardCompletionID = cur.RewardCompletionID
+				}
+			}
+			if err := tx.blueprints.SetCompletion(ctx, row); err != nil {
+				return err
+			}
+			res.Blueprints++
+		}
+
+		for _, r := range doc.Reviews {
+			if _, err := tx.reviews.Insert(ctx, storage.Review{
+				ID:          keep(r.ID),
+				Period:      r.Period,
+				PeriodStart: r.PeriodStart,
+				PeriodEnd:   r.PeriodEnd,
+				Completions: r.Completions,
+				XPEarned:    r.XPEarned,
+				Rescheduled: r.Rescheduled,
+				Dropped:     r.Dropped,
+				Rerated:     r.Rerated,
+				XPAwarded:   r.XPAwarded,
+				CreatedAt:   r.CreatedAt,
+			}); err != nil {
+				return err
+			}
+			res.Reviews++
+		}
+
+		// A merged export's running timer is stopped when one already runs here.
+		running, err := tx.sessions.Running(ctx)
+		if err != nil {
+			return err
+		}
+		for _, ss := range doc.Sessions {
+			row := storage.TaskSession{ID: keep(ss.ID), TaskID: res.TaskIDs[ss.TaskID], Kind: ss.Kind, StartedAt: ss.StartedAt, EndedAt: ss.EndedAt}
+			if row.EndedAt == nil && running != nil {
+				end := doc.ExportedAt
+				if end.Before(row.StartedAt) {
+					end = row.StartedAt
+				}
+				row.EndedAt = &end
+			}
+			if _, err := tx.sessions.Insert(ctx, row); err != nil {
+				return err
+			}
+			res.Sessions++
+		}
+
+		// An achievement already recorded here keeps its timestamp. Exports from
+		// before achievements were recorded have none; like an upgraded database,
+		// their badges are then recorded without being announced.
+		untimed := false
+		for _, a := range doc.Achievements {
+			if a.EarnedAt == nil {
+				untimed = true
+				continue
+			}
+			added, err := tx.achievements.Insert(ctx, storage.EarnedAchievement{PlayerKey: p.Key, ID: a.ID, EarnedAt: a.EarnedAt.UTC()})
+			if err != nil {
+				return err
+			}
+			if added {
+				res.Achievements++
+			}
+		}
+
+		if merge {
+			p.XPTotal += doc.Player.XPTotal
+			for code, xp := range doc.Player.XP {
+				addAttributeXP(p, Attribute(code), xp)
+			}
+			p.Level = tx.rules.LevelForTotalXP(p.XPTotal)
+		} else {
+			resetAttributeXP(p)
+			for code, xp := range doc.Player.XP {
+				addAttributeXP(p, Attribute(code), xp)
+			}
+			p.XPTotal = doc.Player.XPTotal
+			p.Level = doc.Player.Level
+			p.HP = doc.Player.HP
+			p.WeakenedUntil = doc.Player.WeakenedUntil
+			if untimed {
+				p.AchievementsSince = nil
+			}
+		}
+		return tx.players.Update(ctx, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func validateExportDoc(doc *ExportDoc) error {
+	if doc.Format != ExportFormat {
+		return fmt.Errorf("not a questline export (format %q)", doc.Format)
+	}
+	if doc.Version < 1 || doc.Version > ExportVersion {
+		return fmt.Errorf("unsupported export version %d (this binary reads up to %d)", doc.Version, ExportVersion)
+	}
+
+	tasks := map[int64]bool{}
+	for _, t := range doc.Tasks {
+		if tasks[t.ID] {
+			return fmt.Errorf("invalid export: duplicate task id %d", t.ID)
+		}
+		tasks[t.ID] = true
+	}
+	for _, t := range doc.Tasks {
+		if t.ParentID != nil && !tasks[*t.ParentID] {
+			return fmt.Errorf("invalid export: task %d has unknown parent %d", t.ID, *t.ParentID)
+		}
+		for _, tag := range t.Tags {
+			if norm, err := NormalizeTag(tag); err != nil || norm != tag {
+				return fmt.Errorf("invalid export: task %d has invalid tag %q", t.ID, tag)
+			}
+		}
+	}
+	comps := map[int64]bool{}
+	for _, c := range doc.Completions {
+		if !tasks[c.TaskID] {
+			return fmt.Errorf("invalid export: completion %d references unknown task %d", c.ID, c.TaskID)
+		}
+		comps[c.ID] = true
+	}
+	for _, e := range doc.XPLedger {
+		if e.TaskID != nil && !tasks[*e.TaskID] {
+			return fmt.Errorf("invalid export: ledger entry %d references unknown task %d", e.ID, *e.TaskID)
+		}
+		if e.CompletionID != nil && !comps[*e.CompletionID] {
+			return fmt.Errorf("invalid export: ledger entry %d references unknown completion %d", e.ID, *e.CompletionID)
+		}
+	}
+	for _, f := range doc.StreakFreezes {
+		for _, id := range []*int64{f.EarnedTaskID, f.UsedTaskID} {
+			if id != nil && !tasks[*id] {
+				return fmt.Errorf("invalid export: streak freeze %d references unknown task %d", f.ID, *id)
+			}
+		}
+		for _, id := range []*int64{f.EarnedCompletionID, f.UsedCompletionID} {
+			if id != nil && !comps[*id] {
+				return fmt.Errorf("invalid export: streak freeze %d references unknown completion %d", f.ID, *id)
+			}
+		}
+	}
+	for _, d := range doc.Dependencies {
+		for _, id := range []int64{d.TaskID, d.BlockedBy} {
+			if !tasks[id] {
+				return fmt.Errorf("invalid export: dependency %d→%d references unknown task %d", d.TaskID, d.BlockedBy, id)
+			}
+		}
+		if d.TaskID == d.BlockedBy {
+			return fmt.Errorf("invalid export: task %d depends on itself", d.TaskID)
+		}
+	}
+	for _, r := range doc.Reviews {
+		if r.Period != string(ReviewWeek) && r.Period != string(ReviewMonth) {
+			return fmt.Errorf("invalid export: review %d has unknown period %q", r.ID, r.Period)
+		}
+	}
+	running := 0
+	for _, ss := range doc.Sessions {
+		if !tasks[ss.TaskID] {
+			return fmt.Errorf("invalid export: session %d references unknown task %d", ss.ID, ss.TaskID)
+		}
+		if ss.Kind != string(SessionTimer) && ss.Kind != string(SessionFocus) {
+			return fmt.Errorf("invalid export: session %d has unknown kind %q", ss.ID, ss.Kind)
+		}
+		if ss.EndedAt == nil {
+			running++
+		} else if ss.EndedAt.Before(ss.StartedAt) {
+			return fmt.Errorf("invalid export: session %d ends before it starts", ss.ID)
+		}
+	}
+	if running > 1 {
+		return fmt.Errorf("invalid export: %d timers running, at most one can", running)
+	}
+	for _, b := range doc.Blueprints {
+		if b.RewardCompletionID != nil && !comps[*b.RewardCompletionID] {
+			return fmt.Errorf("invalid export: blueprint %s references unknown completion %d", b.Code, *b.RewardCompletionID)
+		}
+	}
+	return nil
+}
+
+// withKnownParents returns doc with tasks whose parent isn't in the document
+// moved to the top level, as exports of databases with subtasks orphaned by
+// older deletes have them.
+func withKnownParents(doc *ExportDoc) *ExportDoc {
+	ids := make(map[int64]bool, len(doc.Tasks))
+	for _, t := range doc.Tasks {
+		ids[t.ID] = true
+	}
+	out := *doc
+	out.Tasks = make([]ExportTask, len(doc.Tasks))
+	for i, t := range doc.Tasks {
+		if t.ParentID != nil && !ids[*t.ParentID] {
+			t.ParentID = nil
+		}
+		out.Tasks[i] = t
+	}
+	return &out
+}
+
+// parentsFirst orders tasks so every parent precedes its children.
+func parentsFirst(tasks []ExportTask) ([]ExportTask, error) {
+	out := make([]ExportTask, 0, len(tasks))
+	placed := map[int64]bool{}
+	pending := tasks
+	for len(pending) > 0 {
+		var next []ExportTask
+		for _, t := range pending {
+			if t.ParentID == nil || placed[*t.ParentID] {
+				out = append(out, t)
+				placed[t.ID] = true
+			} else {
+				next = append(next, t)
+			}
+		}
+		if len(next) == len(pending) {
+			return nil, fmt.Errorf("invalid export: task %d is part of a parent cycle", next[0].ID)
+		}
+		pending = next
+	}
+	return out, nil
+}
+
+func (s *Service) isEmpty(ctx context.Context, p *storage.Player) (bool, error) {
+	if p.XPTotal != 0 {
+		return false, nil
+	}
+	tasks, err := s.tasks.ListAllIncludingDeleted(ctx)
+	if err != nil {
+		return false, err
+	}
+	entries, err := s.ledger.ListAll(ctx)
+	if err != nil {
+		return false, err
+	}
+	return len(tasks) == 0 && len(entries) == 0, nil
+}
+
+// importAttributes registers exported custom attributes whose code is unknown.
+// Aliases already used by another attribute are dropped.
+func (s *Service) importAttributes(ctx context.Context, attrs []ExportAttribute, res *ImportResult) error {
+	reg, err := s.Attributes(ctx)
+	if err != nil {
+		return err
+	}
+	order, err := s.attributes.MaxSortOrder(ctx)
+	if err != nil {
+		return err
+	}
+	for _, a := range attrs {
+		if a.Builtin {
+			continue
+		}
+		if _, ok := reg.Lookup(a.Code); ok {
+			continue
+		}
+		var aliases []string
+		for _, alias := range a.Aliases {
+			if _, taken := reg.Lookup(alias); !taken {
+				aliases = append(aliases, alias)
+			}
+		}
+		order += 10
+		if err := s.attributes.Insert(ctx, storage.Attribute{Code: a.Code, Name: a.Name, Icon: a.Icon, Aliases: aliases, Color: a.Color, SortOrder: order}); err != nil {
+			return err
+		}
+		res.Attributes++
+		if reg, err = s.Attributes(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func blueprintStatusRank(status string) int {
+	switch BlueprintStatus(status) {
+	case BlueprintAvailable:
+		return 1
+	case BlueprintActive:
+		return 2
+	case BlueprintCompleted:
+		return 3
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// exportJSON exports svc with the export timestamp cleared, so documents from
+// different runs compare equal.
+func exportJSON(t *testing.T, svc *Service) []byte {
+	t.Helper()
+	doc, err := svc.Export(context.Background())
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	doc.ExportedAt = time.Time{}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return data
+}
+
+func TestExportImportRoundTripAndMerge(t *testing.T) {
+	src, cleanupSrc := newTestService(t)
+	defer cleanupSrc()
+	ctx := context.Background()
+
+	setPlayerXP(t, src, XPRequiredForLevel(LevelProjects))
+	if _, err := src.AddAttribute(ctx, AddAttributeInput{Code: "MUSIC", Aliases: []string{"guitar"}}); err != nil {
+		t.Fatalf("AddAttribute: %v", err)
+	}
+	proj, err := src.CreateProject(ctx, CreateProjectInput{Title: "Learn a song", Attribute: "MUSIC"})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	pid := proj.TaskID
+	child, err := src.CreateTask(ctx, CreateTaskInput{Title: "Chords", Difficulty: DifficultyTrivial, Attribute: "MUSIC", ParentID: &pid})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	walk, err := src.CreateTask(ctx, CreateTaskInput{Title: "Walk", Difficulty: DifficultyTrivial, Attribute: AttributeSTR, After: []int64{child.TaskID}})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if _, err := src.CompleteTask(ctx, child.TaskID); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+
+	doc, err := src.Export(ctx)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	want := exportJSON(t, src)
+
+	dst, cleanupDst := newTestService(t)
+	defer cleanupDst()
+	if _, err := dst.Import(ctx, doc, false); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if got := exportJSON(t, dst); string(got) != string(want) {
+		t.Fatalf("round trip mismatch:\n got %s\nwant %s", got, want)
+	}
+
+	if _, err := dst.Import(ctx, doc, false); !errors.Is(err, ErrImportNotEmpty) {
+		t.Fatalf("second Import err=%v, want ErrImportNotEmpty", err)
+	}
+	res, err := dst.Import(ctx, doc, true)
+	if err != nil {
+		t.Fatalf("merge Import: %v", err)
+	}
+	newChild, err := dst.TaskRepo().Get(ctx, res.TaskIDs[child.TaskID])
+	if err != nil || newChild == nil {
+		t.Fatalf("get merged child: %v", err)
+	}
+	if newChild.ID == child.TaskID || newChild.ParentID == nil || *newChild.ParentID != res.TaskIDs[pid] {
+		t.Fatalf("merged child id=%d parent=%v, want remapped parent %d", newChild.ID, newChild.ParentID, res.TaskIDs[pid])
+	}
+	if blockers, err := dst.dependencies.ListBlockers(ctx, res.TaskIDs[walk.TaskID]); err != nil || len(blockers) != 1 || blockers[0].BlockedBy != newChild.ID {
+		t.Fatalf("merged dependencies=%+v, %v; want walk blocked by the merged child", blockers, err)
+	}
+	p, _ := dst.PlayerRepo().GetOrCreateMain(ctx)
+	if p.XPTotal != 2*doc.Player.XPTotal || p.AttrXP("MUSIC") != 2*doc.Player.XP["MUSIC"] {
+		t.Fatalf("merged player=%+v, want doubled %+v", p, doc.Player)
+	}
+}
+
+func TestImportReadsOlderVersionsAndRefusesNewer(t *testing.T) {
+	src, cleanupSrc := newTestService(t)
+	defer cleanupSrc()
+	ctx := context.Background()
+
+	res, err := src.CreateTask(ctx, CreateTaskInput{Title: "Walk", Difficulty: DifficultyTrivial, Attribute: AttributeSTR})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if _, err := src.CompleteTask(ctx, res.TaskID); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	doc, err := src.Export(ctx)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if doc.Version != ExportVersion {
+		t.Fatalf("version=%d, want %d", doc.Version, ExportVersion)
+	}
+
+	newer := *doc
+	newer.Version = ExportVersion + 1
+	dst, cleanupDst := newTestService(t)
+	defer cleanupDst()
+	if _, err := dst.Import(ctx, &newer, false); err == nil {
+		t.Fatal("imported a document newer than this binary")
+	}
+
+	// A version 1 document has none of the later sections.
+	v1 := *doc
+	v1.Version = 1
+	v1.Player.HP, v1.StreakFreezes, v1.Dependencies, v1.Reviews, v1.Sessions = nil, nil, nil, nil, nil
+	if _, err := dst.Import(ctx, &v1, false); err != nil {
+		t.Fatalf("Import v1: %v", err)
+	}
+	p, err := dst.PlayerRepo().GetOrCreateMain(ctx)
+	if err != nil {
+		t.Fatalf("GetOrCreateMain: %v", err)
+	}
+	if p.XPTotal != doc.Player.XPTotal {
+		t.Fatalf("xp_total=%d, want %d", p.XPTotal, doc.Player.XPTotal)
+	}
+}
+
+func TestExportImportWithOrphanedSubtask(t *testing.T) {
+	src, cleanupSrc := newTestService(t)
+	defer cleanupSrc()
+	ctx := context.Background()
+
+	res, err := src.CreateTask(ctx, CreateTaskInput{Title: "Kept", Difficulty: DifficultyTrivial, Attribute: AttributeSTR})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	// Older deletes could leave a subtask pointing at a task that is gone.
+	conn, err := src.db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	for _, q := range []string{
+		`PRAGMA foreign_keys = OFF`,
+		`INSERT INTO tasks (id, parent_id, title, attribute, xp_value) VALUES (2, 99, 'Orphan', 'STR', 50)`,
+		`PRAGMA foreign_keys = ON`,
+	} {
+		if _, err := conn.ExecContext(ctx, q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	_ = conn.Close()
+
+	doc, err := src.Export(ctx)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	dst, cleanupDst := newTestService(t)
+	defer cleanupDst()
+	if _, err := dst.Import(ctx, doc, false); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	for _, id := range []int64{res.TaskID, 2} {
+		got, err := dst.TaskRepo().Get(ctx, id)
+		if err != nil || got == nil || got.ParentID != nil {
+			t.Fatalf("task %d=%+v, %v; want it at the top level", id, got, err)
+		}
+	}
+
+	// Files written before exports dropped unknown parents import too.
+	unknown := int64(99)
+	doc.Tasks[1].ParentID = &unknown
+	again, cleanupAgain := newTestService(t)
+	defer cleanupAgain()
+	if _, err := again.Import(ctx, doc, false); err != nil {
+		t.Fatalf("Import with an unknown parent: %v", err)
+	}
+	if doc.Tasks[1].ParentID != &unknown {
+		t.Fatal("Import changed the caller's document")
+	}
+}
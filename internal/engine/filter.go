@@ -0,0 +1,340 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"questline/internal/storage"
+)
+
+// TaskFilter selects tasks with a small query language, e.g.
+//
+//	status:pending attr:str due<fri
+//	kind:habit,project -status:done
+//	diff>=3 parent:12 tag:work groceries
+//
+// Terms are key:value pairs (diff and due also take <, <=, > and >=).
+// Comma-separated values match any of them, a leading "-" negates a term, and
+// all terms must match. Words without a key are searched for in titles and
+// descriptions.
+type TaskFilter struct {
+	Query string   // As parsed
+	Words []string // Searched for in titles and descriptions
+
+	terms []filterTerm
+}
+
+type filterTerm struct {
+	negate bool
+	match  func(t *storage.Task, env *filterEnv) bool
+}
+
+// filterEnv is what terms need beyond the task itself.
+type filterEnv struct {
+	parents map[int64]*int64 // Task ID -> parent ID
+	tags    map[int64]TaskTags
+}
+
+var filterTermRE = regexp.MustCompile(`^(-?)([a-z]+)(:|<=|>=|<|>|=)(.+)$`)
+
+// FilterKeys lists the keys TaskFilter understands.
+var FilterKeys = []string{"status", "kind", "attr", "diff", "parent", "due", "tag"}
+
+// ParseTaskFilter parses a filter query. Attribute names resolve through reg
+// and relative due dates are taken from now.
+func ParseTaskFilter(query string, reg *AttributeRegistry, now time.Time) (*TaskFilter, error) {
+	f := &TaskFilter{Query: strings.TrimSpace(query)}
+	for _, field := range strings.Fields(query) {
+		m := filterTermRE.FindStringSubmatch(strings.ToLower(field))
+		if m == nil {
+			// Punctuation alone can't be searched for.
+			if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+				f.Words = append(f.Words, field)
+			}
+			continue
+		}
+		negate, key, op, value := m[1] == "-", m[2], m[3], m[4]
+		if op == "=" {
+			op = ":"
+		}
+		match, err := parseFilterTerm(key, op, value, reg, now)
+		if err != nil {
+			return nil, fmt.Errorf("filter %q: %w", field, err)
+		}
+		f.terms = append(f.terms, filterTerm{negate: negate, match: match})
+	}
+	return f, nil
+}
+
+// Empty reports whether the filter lets every task through.
+func (f *TaskFilter) Empty() bool {
+	return f == nil || (len(f.terms) == 0 && len(f.Words) == 0)
+}
+
+func parseFilterTerm(key, op, value string, reg *AttributeRegistry, now time.Time) (func(*storage.Task, *filterEnv) bool, error) {
+	values := strings.Split(value, ",")
+	if op != ":" && key != "diff" && key != "due" {
+		return nil, fmt.Errorf("%s only takes %s:value", key, key)
+	}
+	if op != ":" && len(values) > 1 {
+		return nil, fmt.Errorf("%s can't compare against a list", key)
+	}
+
+	switch key {
+	case "status":
+		for _, v := range values {
+			switch v {
+			case "pending", "active", "planning", "done", "open":
+			default:
+				return nil, fmt.Errorf("unknown status %q (pending, active, planning, done or open)", v)
+			}
+		}
+		return func(t *storage.Task, _ *filterEnv) bool {
+			for _, v := range values {
+				if v == t.Status || (v == "open" && t.Status != "done") {
+					return true
+				}
+			}
+			return false
+		}, nil
+
+	case "kind":
+		for _, v := range values {
+			switch v {
+			case "task", "habit", "project", "subtask":
+			default:
+				return nil, fmt.Errorf("unknown kind %q (task, habit, project or subtask)", v)
+			}
+		}
+		return func(t *storage.Task, _ *filterEnv) bool {
+			for _, v := range values {
+				switch {
+				case v == "task" && !t.IsHabit && !t.IsProject,
+					v == "habit" && t.IsHabit,
+					v == "project" && t.IsProject,
+					v == "subtask" && t.ParentID != nil:
+					return true
+				}
+			}
+			return false
+		}, nil
+
+	case "attr":
+		codes := make([]string, len(values))
+		for i, v := range values {
+			def, ok := reg.Lookup(v)
+			if !ok {
+				return nil, fmt.Errorf("unknown attribute %q", v)
+			}
+			codes[i] = string(def.Code)
+		}
+		return func(t *storage.Task, _ *filterEnv) bool {
+			for _, code := range codes {
+				if t.Attribute == code || t.Attributes[code] > 0 {
+					return true
+				}
+			}
+			return false
+		}, nil
+
+	case "diff":
+		levels := make([]int, len(values))
+		for i, v := range values {
+			n, err := strconv.Atoi(v)
+			if err != nil || !Difficulty(n).IsValid() {
+				return nil, fmt.Errorf("difficulty must be 1-5, got %q", v)
+			}
+			levels[i] = n
+		}
+		return func(t *storage.Task, _ *filterEnv) bool {
+			for _, n := range levels {
+				if compareInts(t.Difficulty, op, n) {
+					return true
+				}
+			}
+			return false
+		}, nil
+
+	case "parent":
+		ids := make([]int64, len(values))
+		for i, v := range values {
+			if v == "none" {
+				continue
+			}
+			id, err := strconv.ParseInt(strings.TrimPrefix(v, "#"), 10, 64)
+			if err != nil || id <= 0 {
+				return nil, fmt.Errorf("parent must be a task ID or none, got %q", v)
+			}
+			ids[i] = id
+		}
+		return func(t *storage.Task, env *filterEnv) bool {
+			for _, id := range ids {
+				if id == 0 && t.ParentID == nil {
+					return true
+				}
+				if id != 0 && isDescendant(env.parents, t.ID, id) {
+					return true
+				}
+			}
+			return false
+		}, nil
+
+	case "due":
+		return parseDueTerm(op, values, now)
+
+	case "tag":
+		tags := make([]string, len(values))
+		for i, v := range values {
+			tag, err := NormalizeTag(v)
+			if err != nil {
+				return nil, err
+			}
+			tags[i] = tag
+		}
+		return func(t *storage.Task, env *filterEnv) bool {
+			for _, tag := range tags {
+				if env.tags[t.ID].Has(tag) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown key %q (%s)", key, strings.Join(FilterKeys, ", "))
+}
+
+func parseDueTerm(op string, values []string, now time.Time) (func(*storage.Task, *filterEnv) bool, error) {
+	type dueMatch func(t *storage.Task) bool
+	matches := make([]dueMatch, len(values))
+	for i, v := range values {
+		switch v {
+		case "none", "any", "overdue":
+			if op != ":" {
+				return nil, fmt.Errorf("due%s%s: compare against a date", op, v)
+			}
+		}
+		switch v {
+		case "none":
+			matches[i] = func(t *storage.Task) bool { return t.DueDate == nil }
+		case "any":
+			matches[i] = func(t *storage.Task) bool { return t.DueDate != nil }
+		case "overdue":
+			matches[i] = func(t *storage.Task) bool { return IsOverdue(t, now) }
+		default:
+			d, err := ParseDueDate(v, now)
+			if err != nil {
+				return nil, err
+			}
+			day := dayNumber(d, now.Location())
+			matches[i] = func(t *storage.Task) bool {
+				return t.DueDate != nil && compareInts(dayNumber(*t.DueDate, now.Location()), op, day)
+			}
+		}
+	}
+	return func(t *storage.Task, _ *filterEnv) bool {
+		for _, m := range matches {
+			if m(t) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// dayNumber turns a time into a sortable calendar day in loc, e.g. 20261101.
+func dayNumber(t time.Time, loc *time.Location) int {
+	y, m, d := t.In(loc).Date()
+	return y*10000 + int(m)*100 + d
+}
+
+func compareInts(a int, op string, b int) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return a == b
+}
+
+// isDescendant reports whether id sits somewhere below ancestor.
+func isDescendant(parents map[int64]*int64, id, ancestor int64) bool {
+	seen := map[int64]bool{}
+	for p := parents[id]; p != nil && !seen[*p]; p = parents[*p] {
+		if *p == ancestor {
+			return true
+		}
+		seen[*p] = true
+	}
+	return false
+}
+
+// match reports whether t passes every term of the filter, leaving the search
+// words aside.
+func (f *TaskFilter) match(t *storage.Task, env *filterEnv) bool {
+	for _, term := range f.terms {
+		if term.match(t, env) == term.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// searchQuery turns search words into an FTS5 query matching tasks that
+// contain every word, each as a prefix.
+func searchQuery(words []string) string {
+	parts := make([]string, len(words))
+	for i, w := range words {
+		parts[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"*`
+	}
+	return strings.Join(parts, " ")
+}
+
+// FilterTasks returns the tasks (not in the trash) that match f. When f has
+// search words the best matches come first; otherwise tasks are in ID order.
+func (s *Service) FilterTasks(ctx context.Context, f *TaskFilter) ([]storage.Task, error) {
+	tasks, err := s.tasks.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if f.Empty() {
+		return tasks, nil
+	}
+	tags, err := s.TaskTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	env := &filterEnv{parents: make(map[int64]*int64, len(tasks)), tags: tags}
+	byID := make(map[int64]*storage.Task, len(tasks))
+	for i := range tasks {
+		env.parents[tasks[i].ID] = tasks[i].ParentID
+		byID[tasks[i].ID] = &tasks[i]
+	}
+
+	order := make([]int64, len(tasks))
+	for i := range tasks {
+		order[i] = tasks[i].ID
+	}
+	if len(f.Words) > 0 {
+		if order, err = s.tasks.Search(ctx, searchQuery(f.Words)); err != nil {
+			return nil, err
+		}
+	}
+
+	var out []storage.Task
+	for _, id := range order {
+		if t := byID[id]; t != nil && f.match(t, env) {
+			out = append(out, *t)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFilterTasks(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelHabits))
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC) // a Wednesday
+
+	create := func(in CreateTaskInput) int64 {
+		t.Helper()
+		res, err := svc.CreateTask(ctx, in)
+		if err != nil {
+			t.Fatalf("CreateTask %s: %v", in.Title, err)
+		}
+		return res.TaskID
+	}
+	fri := time.Date(2026, 10, 16, 23, 59, 59, 0, time.UTC)
+	next := time.Date(2026, 10, 20, 23, 59, 59, 0, time.UTC)
+	desc := "oat milk, bread and coffee"
+	shop := create(CreateTaskInput{Title: "Groceries", Difficulty: DifficultyTrivial, Attribute: AttributeHOME, DueDate: &fri})
+	milk := create(CreateTaskInput{Title: "Compare milk prices", Difficulty: DifficultyEasy, Attribute: AttributeINT, Attributes: map[Attribute]int{AttributeINT: 50, AttributeHOME: 50}, ParentID: &shop})
+	run := create(CreateTaskInput{Title: "Run 5k", Difficulty: DifficultyMedium, Attribute: AttributeSTR, DueDate: &next})
+	gym := create(CreateTaskInput{Title: "Gym", Difficulty: DifficultyTrivial, Attribute: AttributeSTR, IsHabit: true, HabitInterval: HabitIntervalDaily})
+	if _, err := svc.UpdateTask(ctx, shop, TaskPatch{Description: &desc}); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if _, err := svc.CompleteTask(ctx, run); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+
+	reg, err := svc.Attributes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := func(query string) []int64 {
+		t.Helper()
+		f, err := ParseTaskFilter(query, reg, now)
+		if err != nil {
+			t.Fatalf("ParseTaskFilter(%q): %v", query, err)
+		}
+		tasks, err := svc.FilterTasks(ctx, f)
+		if err != nil {
+			t.Fatalf("FilterTasks(%q): %v", query, err)
+		}
+		out := []int64{}
+		for _, t := range tasks {
+			out = append(out, t.ID)
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		query string
+		want  []int64
+	}{
+		{"", []int64{shop, milk, run, gym}},
+		{"status:pending", []int64{shop, milk}},
+		{"-status:done kind:task", []int64{shop, milk}},
+		{"kind:habit,subtask", []int64{milk, gym}},
+		{"attr:home", []int64{shop, milk}},
+		{"attr:str diff>=3", []int64{run}},
+		{"diff:1", []int64{shop, gym}},
+		{fmt.Sprintf("parent:%d", shop), []int64{milk}},
+		{"parent:none -kind:habit", []int64{shop, run}},
+		{"due<=fri -kind:habit", []int64{shop}},
+		{"due>fri", []int64{run}},
+		{"due:none kind:task", []int64{milk}},
+		{"milk", []int64{milk, shop}}, // the title counts more than the description
+		{"COFF", []int64{shop}},
+		{"milk kind:subtask", []int64{milk}},
+		{"milk bread", []int64{shop}},
+		{"yoga", []int64{}},
+	} {
+		if got := ids(tc.query); !equalIDs(got, tc.want) {
+			t.Errorf("%q = %v, want %v", tc.query, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"status:later", "kind:chore", "attr:nope", "diff:9", "parent:x", "due<none", "status>done", "prio:1"} {
+		if _, err := ParseTaskFilter(bad, reg, now); err == nil {
+			t.Errorf("%q parsed, want an error", bad)
+		}
+	}
+
+	// The index follows edits and leaves trashed tasks out.
+	title := "Buy oat drink"
+	if _, err := svc.UpdateTask(ctx, milk, TaskPatch{Title: &title}); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if got := ids("drink"); !equalIDs(got, []int64{milk}) {
+		t.Fatalf("after rename = %v, want [%d]", got, milk)
+	}
+	if _, err := svc.DeleteTask(ctx, shop, DeleteXPAsk); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if got := ids("oat"); len(got) != 0 {
+		t.Fatalf("trashed tasks found: %v", got)
+	}
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -2,6 +2,7 @@ package engine
 
 import "fmt"
 
+// Feature gate levels of the default rules; see GateRules.
 const (
 	LevelSubtasks   = 3
 	LevelHabits     = 5
@@ -22,29 +23,12 @@ var DifficultyUnlockLevels = map[Difficulty]int{
 
 // MaxDifficultyForLevel returns the highest difficulty available at the given player level.
 func MaxDifficultyForLevel(level int) Difficulty {
-	max := DifficultyTrivial
-	for diff, req := range DifficultyUnlockLevels {
-		if level >= req && diff > max {
-			max = diff
-		}
-	}
-	return max
+	return defaultRules.MaxDifficultyForLevel(level)
 }
 
 // CanUseDifficulty returns an error if the player level is too low for the requested difficulty.
 func CanUseDifficulty(level int, difficulty Difficulty) error {
-	reqLevel, ok := DifficultyUnlockLevels[difficulty]
-	if !ok {
-		return fmt.Errorf("invalid difficulty: %d", difficulty)
-	}
-	if level < reqLevel {
-		return DifficultyGateError{
-			Difficulty:    difficulty,
-			RequiredLevel: reqLevel,
-			CurrentLevel:  level,
-		}
-	}
-	return nil
+	return defaultRules.CanUseDifficulty(level, difficulty)
 }
 
 // DifficultyGateError is returned when a player tries to use a locked difficulty.
@@ -63,10 +47,7 @@ func (e DifficultyGateError) Error() string {
 // - Level 0 (Drifter): 3
 // - Level 2 (Apprentice): 5
 func MaxActiveTasks(level int) int {
-	if level >= 2 {
-		return 5
-	}
-	return 3
+	return defaultRules.MaxActiveTasks(level)
 }
 
 // MaxSubtaskDepth returns the maximum allowed depth for subtasks.
@@ -79,42 +60,17 @@ func MaxActiveTasks(level int) int {
 const SubtaskDepthUnlimited = -1
 
 func MaxSubtaskDepth(level int) int {
-	if level < LevelSubtasks {
-		return 0
-	}
-	if level < LevelDeepRecurs {
-		return 1
-	}
-	return SubtaskDepthUnlimited
+	return defaultRules.MaxSubtaskDepth(level)
 }
 
 func CanCreateHabit(level int) error {
-	if level < LevelHabits {
-		return GateError{Feature: "habits", RequiredLevel: LevelHabits}
-	}
-	return nil
+	return defaultRules.CanCreateHabit(level)
 }
 
 func CanCreateProject(level int) error {
-	if level < LevelProjects {
-		return GateError{Feature: "projects", RequiredLevel: LevelProjects}
-	}
-	return nil
+	return defaultRules.CanCreateProject(level)
 }
 
 func CanAttachToParent(level int, requestedDepth int) error {
-	maxDepth := MaxSubtaskDepth(level)
-	if maxDepth == SubtaskDepthUnlimited {
-		return nil
-	}
-	if requestedDepth <= 0 {
-		return nil
-	}
-	if maxDepth == 0 {
-		return GateError{Feature: "subtasks", RequiredLevel: LevelSubtasks}
-	}
-	if requestedDepth > maxDepth {
-		return fmt.Errorf("subtask depth %d exceeds max depth %d at level %d", requestedDepth, maxDepth, level)
-	}
-	return nil
+	return defaultRules.CanAttachToParent(level, requestedDepth)
 }
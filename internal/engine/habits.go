@@ -2,12 +2,13 @@ package engine
 
 import (
 	"fmt"
-	"strings"
 	"time"
 
 	"questline/internal/storage"
 )
 
+// HabitInterval is a habit schedule in its stored form: daily, weekly and
+// monthly, or any form ParseHabitSchedule accepts in canonical spelling.
 type HabitInterval string
 
 const (
@@ -17,44 +18,58 @@ const (
 )
 
 func (h HabitInterval) IsValid() bool {
-	switch h {
-	case HabitIntervalDaily, HabitIntervalWeekly, HabitIntervalMonthly:
-		return true
-	default:
-		return false
-	}
+	_, err := ParseHabitSchedule(string(h))
+	return err == nil
 }
 
+// ParseHabitInterval parses a schedule and returns its canonical form.
 func ParseHabitInterval(input string) (HabitInterval, error) {
-	s := strings.TrimSpace(strings.ToLower(input))
-	h := HabitInterval(s)
-	if !h.IsValid() {
-		return "", fmt.Errorf("invalid habit interval: %q", input)
+	sched, err := ParseHabitSchedule(input)
+	if err != nil {
+		return "", err
 	}
-	return h, nil
+	return HabitInterval(sched.String()), nil
 }
 
-func NextDueDate(now time.Time, interval HabitInterval) (time.Time, error) {
-	switch interval {
-	case HabitIntervalDaily:
-		return now.Add(24 * time.Hour), nil
-	case HabitIntervalWeekly:
-		return now.Add(7 * 24 * time.Hour), nil
-	case HabitIntervalMonthly:
-		return now.AddDate(0, 1, 0), nil
+// ParseDuration parses a length of time in days, weeks or months, like "7d",
+// "1w", "30d" or "1m" (30 days): a habit challenge or the age of trashed tasks.
+func ParseDuration(s string) (time.Duration, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("duration too short: %s", s)
+	}
+	unit := s[len(s)-1]
+	numStr := s[:len(s)-1]
+	var num int
+	if _, err := fmt.Sscanf(numStr, "%d", &num); err != nil {
+		return 0, fmt.Errorf("invalid number in duration: %s", s)
+	}
+	if num <= 0 {
+		return 0, fmt.Errorf("duration must be positive: %s", s)
+	}
+
+	switch unit {
+	case 'd':
+		return time.Duration(num) * 24 * time.Hour, nil
+	case 'w':
+		return time.Duration(num) * 7 * 24 * time.Hour, nil
+	case 'm':
+		return time.Duration(num) * 30 * 24 * time.Hour, nil // Approximate month
 	default:
-		return time.Time{}, fmt.Errorf("invalid habit interval: %q", interval)
+		return 0, fmt.Errorf("unknown duration unit: %c (use d/w/m)", unit)
 	}
 }
 
 // HabitProgress represents the current progress of a timed/goal habit.
 type HabitProgress struct {
-	Completions int        // Number of completions so far
-	Goal        *int       // Target completions (nil = ongoing)
-	StartDate   *time.Time // When habit started
-	EndDate     *time.Time // When habit ends (nil = forever)
-	Completed   bool       // Whether habit has reached its goal
-	Expired     bool       // Whether habit has expired without reaching goal
+	Completions  int        // Number of completions so far
+	Goal         *int       // Target completions (nil = ongoing)
+	StartDate    *time.Time // When habit started
+	EndDate      *time.Time // When habit ends (nil = forever)
+	Completed    bool       // Whether habit has reached its goal
+	Expired      bool       // Whether habit has expired without reaching goal
+	Scheduled    int        // Completions the schedule asked for so far, current period included
+	PeriodDone   int        // Completions in the current period
+	PeriodTarget int        // Completions the current period asks for
 }
 
 // GetHabitProgress calculates the current progress of a habit.
@@ -83,6 +98,24 @@ func GetHabitProgress(task *storage.Task, completions []storage.TaskCompletion,
 		progress.Completions++
 	}
 
+	if sched, err := ScheduleForTask(task); err == nil {
+		loc := now.Location()
+		cur := sched.periodStart(now)
+		last := now
+		if task.HabitEndDate != nil && task.HabitEndDate.Before(now) {
+			last = task.HabitEndDate.In(loc)
+		}
+		for p := sched.periodStart(startDate.In(loc)); !p.After(sched.periodStart(last)); p = sched.nextPeriod(p) {
+			progress.Scheduled += sched.target(p)
+		}
+		progress.PeriodTarget = sched.target(cur)
+		for _, c := range completions {
+			if sched.periodStart(c.CompletedAt.In(loc)).Equal(cur) {
+				progress.PeriodDone++
+			}
+		}
+	}
+
 	// Check if goal is reached
 	if task.HabitGoal != nil && progress.Completions >= *task.HabitGoal {
 		progress.Completed = true
@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"questline/internal/storage"
+)
+
+// HP log reasons.
+const (
+	HPReasonMissed   = "missed"
+	HPReasonRest     = "rest"
+	HPReasonHeal     = "heal"
+	HPReasonKnockout = "knockout"
+)
+
+// HPMiss is the damage one habit dealt for occurrences that passed without a completion.
+type HPMiss struct {
+	TaskID int64
+	Title  string
+	Misses int
+	Damage int
+}
+
+// HPReport describes what an HP evaluation changed.
+type HPReport struct {
+	Before        int
+	After         int
+	Max           int
+	Missed        []HPMiss
+	Damage        int
+	Regen         int
+	KnockedOut    bool       // HP dropped to zero during this evaluation
+	WeakenedUntil *time.Time // set while the knockout penalty applies
+}
+
+// HPStatus is the player's current health.
+type HPStatus struct {
+	HP            int
+	Max           int
+	WeakenedUntil *time.Time // nil unless the player is knocked out right now
+	Recent        []storage.HPEntry
+}
+
+// EvaluateHP applies everything that happened to the player's HP since it was
+// last evaluated: damage for habit occurrences that passed without a
+// completion, and rest for every full day. Days the CLI was not run are caught
+// up. Missed habits move on to their next occurrence.
+func (s *Service) EvaluateHP(ctx context.Context) (*HPReport, error) {
+	var rep *HPReport
+	err := s.inTx(ctx, func(tx *Service) error {
+		p, err := tx.getPlayer(ctx)
+		if err != nil {
+			return err
+		}
+		rep, err = tx.evaluateHP(ctx, p, time.Now())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+// HP returns the player's current health and its latest changes.
+func (s *Service) HP(ctx context.Context, recent int) (*HPStatus, error) {
+	p, err := s.getPlayer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	st := &HPStatus{HP: s.rules.HP.Max, Max: s.rules.HP.Max}
+	if p.HP != nil {
+		st.HP = min(*p.HP, st.Max)
+	}
+	if weakened(p, time.Now()) {
+		st.WeakenedUntil = p.WeakenedUntil
+	}
+	if recent > 0 {
+		st.Recent, err = s.hp.Recent(ctx, p.Key, recent)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+// weakened reports whether the knockout penalty applies at now.
+func weakened(p *storage.Player, now time.Time) bool {
+	return p.WeakenedUntil != nil && now.Before(*p.WeakenedUntil)
+}
+
+// evaluateHP updates p's HP up to now and persists it. The first evaluation
+// only starts the clock: habits that were already overdue do no damage.
+func (s *Service) evaluateHP(ctx context.Context, p *storage.Player, now time.Time) (*HPReport, error) {
+	rules := s.rules.HP
+	hp := rules.Max
+	if p.HP != nil {
+		hp = min(*p.HP, rules.Max)
+	}
+	rep := &HPReport{Before: hp, Max: rules.Max}
+	utc := now.UTC()
+	local := now.In(time.Local)
+
+	if rules.DamagePerMiss > 0 {
+		tasks, err := s.tasks.ListAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range tasks {
+			t := &tasks[i]
+			if !t.IsHabit || t.Status == "done" || t.DueDate == nil || !t.DueDate.Before(now) {
+				continue
+			}
+			sched, err := ScheduleForTask(t)
+			if err != nil {
+				return nil, err
+			}
+			if p.HPCheckedAt != nil {
+				until := local
+				if t.HabitEndDate != nil && t.HabitEndDate.Before(now) {
+					until = t.HabitEndDate.In(time.Local)
+				}
+				if misses := sched.missed(*t.DueDate, *p.HPCheckedAt, until); misses > 0 {
+					dmg := misses * rules.DamagePerMiss
+					rep.Missed = append(rep.Missed, HPMiss{TaskID: t.ID, Title: t.Title, Misses: misses, Damage: dmg})
+					rep.Damage += dmg
+					if _, err := s.hp.Insert(ctx, storage.HPEntry{PlayerKey: p.Key, TaskID: &t.ID, Reason: HPReasonMissed, Delta: -dmg, CreatedAt: utc}); err != nil {
+						return nil, err
+					}
+				}
+			}
+			next := sched.FirstDue(local).UTC()
+			if err := s.tasks.UpdateDueDate(ctx, t.ID, &next); err != nil {
+				return nil, err
+			}
+		}
+	}
+	hp = max(hp-rep.Damage, 0)
+
+	if hp == 0 && rep.Damage > 0 && rules.KnockoutDays > 0 && !weakened(p, now) {
+		until := utc.AddDate(0, 0, rules.KnockoutDays)
+		p.WeakenedUntil = &until
+		rep.KnockedOut = true
+		if _, err := s.hp.Insert(ctx, storage.HPEntry{PlayerKey: p.Key, Reason: HPReasonKnockout, CreatedAt: utc}); err != nil {
+			return nil, err
+		}
+	}
+	if weakened(p, now) {
+		rep.WeakenedUntil = p.WeakenedUntil
+	}
+
+	// Rest: every full day since the last evaluation heals RegenPerDay.
+	checked := utc
+	if p.HPCheckedAt != nil {
+		days := int(utc.Sub(*p.HPCheckedAt) / (24 * time.Hour))
+		checked = p.HPCheckedAt.UTC().AddDate(0, 0, days)
+		if regen := min(days*rules.RegenPerDay, rules.Max-hp); regen > 0 {
+			hp += regen
+			rep.Regen = regen
+			if _, err := s.hp.Insert(ctx, storage.HPEntry{PlayerKey: p.Key, Reason: HPReasonRest, Delta: regen, CreatedAt: utc}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	rep.After = hp
+	p.HP = &hp
+	p.HPCheckedAt = &checked
+	if err := s.players.Update(ctx, p); err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+// healHP restores amount HP for completing task, up to the maximum. It returns
+// the HP actually healed; the caller persists the player.
+func (s *Service) healHP(ctx context.Context, p *storage.Player, task *storage.Task, amount int, at time.Time) (int, error) {
+	hp := s.rules.HP.Max
+	if p.HP != nil {
+		hp = *p.HP
+	}
+	healed := min(amount, s.rules.HP.Max-hp)
+	if healed <= 0 {
+		return 0, nil
+	}
+	hp += healed
+	p.HP = &hp
+	if _, err := s.hp.Insert(ctx, storage.HPEntry{PlayerKey: p.Key, TaskID: &task.ID, Reason: HPReasonHeal, Delta: healed, CreatedAt: at}); err != nil {
+		return 0, err
+	}
+	return healed, nil
+}
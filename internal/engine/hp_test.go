@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMissedHabitsDamageHP(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	hp := svc.Rules().HP
+
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelHabits))
+	h, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Stretch", Difficulty: DifficultyTrivial, Attribute: AttributeSTR, IsHabit: true, HabitInterval: HabitIntervalDaily})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	// The first evaluation only starts the clock.
+	rep, err := svc.EvaluateHP(ctx)
+	if err != nil {
+		t.Fatalf("EvaluateHP: %v", err)
+	}
+	if rep.After != hp.Max || rep.Damage != 0 {
+		t.Fatalf("first report=%+v, want full HP", rep)
+	}
+
+	// Last evaluated four days ago; the habit was due three days ago.
+	now := time.Now()
+	setHPClock := func(checked, due time.Time) {
+		t.Helper()
+		if _, err := svc.db.ExecContext(ctx, `UPDATE player SET hp_checked_at = ?`, checked.UTC()); err != nil {
+			t.Fatalf("set hp_checked_at: %v", err)
+		}
+		if _, err := svc.db.ExecContext(ctx, `UPDATE tasks SET due_date = ? WHERE id = ?`, due.UTC(), h.TaskID); err != nil {
+			t.Fatalf("set due_date: %v", err)
+		}
+	}
+	setHPClock(now.AddDate(0, 0, -4), endOfDay(now.AddDate(0, 0, -3)))
+
+	rep, err = svc.EvaluateHP(ctx)
+	if err != nil {
+		t.Fatalf("EvaluateHP: %v", err)
+	}
+	wantDamage := 3 * hp.DamagePerMiss
+	wantRegen := 4 * hp.RegenPerDay
+	if len(rep.Missed) != 1 || rep.Missed[0].Misses != 3 || rep.Damage != wantDamage || rep.Regen != wantRegen || rep.After != hp.Max-wantDamage+wantRegen {
+		t.Fatalf("report=%+v, want 3 misses (%d damage) and %d regen", rep, wantDamage, wantRegen)
+	}
+	task, _ := svc.TaskRepo().Get(ctx, h.TaskID)
+	if task.DueDate == nil || !task.DueDate.Equal(endOfDay(now)) {
+		t.Fatalf("due=%v, want rolled to today", task.DueDate)
+	}
+
+	// Running again right away changes nothing.
+	rep, err = svc.EvaluateHP(ctx)
+	if err != nil {
+		t.Fatalf("EvaluateHP: %v", err)
+	}
+	if rep.Damage != 0 || rep.Regen != 0 {
+		t.Fatalf("repeat report=%+v, want no change", rep)
+	}
+
+	// Low on HP, two more misses knock the player out before the rest heals.
+	p, _ := svc.PlayerRepo().GetOrCreateMain(ctx)
+	low := hp.DamagePerMiss
+	p.HP = &low
+	if err := svc.PlayerRepo().Update(ctx, p); err != nil {
+		t.Fatalf("update player: %v", err)
+	}
+	setHPClock(now.AddDate(0, 0, -3), endOfDay(now.AddDate(0, 0, -2)))
+	rep, err = svc.EvaluateHP(ctx)
+	if err != nil {
+		t.Fatalf("EvaluateHP: %v", err)
+	}
+	rested := 3 * hp.RegenPerDay
+	if rep.Damage != 2*hp.DamagePerMiss || rep.After != rested || !rep.KnockedOut || rep.WeakenedUntil == nil {
+		t.Fatalf("report=%+v, want knockout", rep)
+	}
+
+	// Knocked out: XP is reduced, and completions still heal.
+	c, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Email", Difficulty: DifficultyMedium, Attribute: AttributeINT})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	created, _ := svc.TaskRepo().Get(ctx, c.TaskID)
+	res, err := svc.CompleteTask(ctx, c.TaskID)
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	wantXP, wantLoss := svc.Rules().WeakenedXP(created.XPValue)
+	if res.XPAwarded != wantXP || res.WeakenedLoss != wantLoss || res.HPHealed != hp.HealPerTask {
+		t.Fatalf("result=%+v, want xp %d (lost %d) and %d HP healed", res, wantXP, wantLoss, hp.HealPerTask)
+	}
+
+	st, err := svc.HP(ctx, 10)
+	if err != nil {
+		t.Fatalf("HP: %v", err)
+	}
+	if st.HP != rested+hp.HealPerTask || st.WeakenedUntil == nil {
+		t.Fatalf("status=%+v, want %d HP while weakened", st, rested+hp.HealPerTask)
+	}
+	reasons := map[string]int{}
+	for _, e := range st.Recent {
+		reasons[e.Reason]++
+	}
+	if reasons[HPReasonMissed] != 2 || reasons[HPReasonRest] != 2 || reasons[HPReasonKnockout] != 1 || reasons[HPReasonHeal] != 1 {
+		t.Fatalf("log reasons=%v", reasons)
+	}
+}
@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"questline/internal/storage"
+)
+
+// XP ledger event types.
+const (
+	XPEventComplete   = "complete"   // task or habit completion
+	XPEventRestore    = "restore"    // reversal of an earlier award
+	XPEventBonus      = "bonus"      // project completion bonus
+	XPEventAdjustment = "adjustment" // manual or migration correction
+	XPEventTrash      = "trash"      // XP revoked when its task was deleted
+	XPEventUntrash    = "untrash"    // revoked XP given back when the task left the trash
+)
+
+// xpShare is the part of an XP award credited to a single attribute.
+type xpShare struct {
+	Attr   Attribute
+	Amount int
+}
+
+// splitXP distributes total XP across attributes based on weights.
+// If no weights provided (or empty), uses the primary attribute only.
+// Weights are percentages (e.g., {STR: 50, INT: 50} means 50% each).
+// Attributes are visited in code order and the remainder of the integer division
+// goes to the last one, so the same inputs always produce the same split.
+func splitXP(totalXP int, primaryAttr Attribute, weights map[string]int) []xpShare {
+	totalWeight := 0
+	for _, w := range weights {
+		totalWeight += w
+	}
+	if len(weights) == 0 || totalWeight == 0 {
+		return []xpShare{{Attr: primaryAttr, Amount: totalXP}}
+	}
+
+	codes := make([]string, 0, len(weights))
+	for c := range weights {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+
+	byAttr := map[Attribute]int{}
+	var order []Attribute
+	distributed := 0
+	for _, c := range codes {
+		attr := parseStoredAttribute(c)
+		share := (totalXP * weights[c]) / totalWeight
+		if _, ok := byAttr[attr]; !ok {
+			order = append(order, attr)
+		}
+		byAttr[attr] += share
+		distributed += share
+	}
+	byAttr[order[len(order)-1]] += totalXP - distributed
+
+	out := make([]xpShare, 0, len(order))
+	for _, a := range order {
+		out = append(out, xpShare{Attr: a, Amount: byAttr[a]})
+	}
+	return out
+}
+
+// recordXP credits each share to the player and appends one ledger row per share.
+// The caller is responsible for persisting the player.
+func (s *Service) recordXP(ctx context.Context, p *storage.Player, event string, taskID, completionID *int64, shares []xpShare, at time.Time) error {
+	for _, sh := range shares {
+		if sh.Amount == 0 {
+			continue
+		}
+		if _, err := s.ledger.Insert(ctx, storage.XPEntry{
+			PlayerKey:    p.Key,
+			Event:        event,
+			TaskID:       taskID,
+			CompletionID: completionID,
+			Attribute:    string(sh.Attr),
+			Amount:       sh.Amount,
+			CreatedAt:    at,
+		}); err != nil {
+			return err
+		}
+		p.XPTotal += sh.Amount
+		if sh.Attr != "" {
+			addAttributeXP(p, sh.Attr, sh.Amount)
+		}
+	}
+	p.Level = s.rules.LevelForTotalXP(p.XPTotal)
+	return nil
+}
+
+// RebuildResult describes the player state before and after a ledger rebuild.
+type RebuildResult struct {
+	Entries     int
+	XPBefore    int
+	XPAfter     int
+	LevelBefore int
+	LevelAfter  int
+}
+
+// RebuildPlayer recomputes the player's level, total XP and attribute XP purely
+// from the XP ledger, discarding whatever the counters currently hold.
+func (s *Service) RebuildPlayer(ctx context.Context) (*RebuildResult, error) {
+	var res *RebuildResult
+	err := s.inTx(ctx, func(tx *Service) error {
+		p, err := tx.players.GetOrCreateMain(ctx)
+		if err != nil {
+			return err
+		}
+		total, byAttr, entries, err := tx.ledger.Totals(ctx, p.Key)
+		if err != nil {
+			return err
+		}
+
+		res = &RebuildResult{Entries: entries, XPBefore: p.XPTotal, LevelBefore: p.Level}
+		resetAttributeXP(p)
+		for code, xp := range byAttr {
+			addAttributeXP(p, parseStoredAttribute(code), xp)
+		}
+		p.XPTotal = total
+		p.Level = tx.rules.LevelForTotalXP(total)
+		if err := tx.players.Update(ctx, p); err != nil {
+			return err
+		}
+		res.XPAfter = p.XPTotal
+		res.LevelAfter = p.Level
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// resetAttributeXP zeroes every attribute the player has XP in. Keys are kept so
+// the zeroes are persisted on update.
+func resetAttributeXP(p *storage.Player) {
+	for code := range p.XP {
+		p.XP[code] = 0
+	}
+}
@@ -1,44 +1,39 @@
 package engine
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
-// ParseAttribute parses user input to an Attribute.
-// Supported: str, int, wis, art, home, out, read, cinema, career
+// ParseAttribute parses user input to a built-in Attribute.
+// Supported: str, int, wis, art, home, out, read, cinema, career (plus aliases).
 // If input is empty or unrecognized, returns DefaultAttribute.
+// Custom attributes need the database registry; see AttributeRegistry.Parse.
 func ParseAttribute(input string) Attribute {
-	s := strings.TrimSpace(strings.ToLower(input))
-	switch s {
-	case "":
-		return DefaultAttribute
-	// Original 4
-	case "str":
-		return AttributeSTR
-	case "int":
-		return AttributeINT
-	case "wis":
-		return AttributeWIS
-	case "art":
-		return AttributeART
-	// New 5
-	case "home":
-		return AttributeHOME
-	case "out", "outdoors":
-		return AttributeOUT
-	case "read", "reading":
-		return AttributeREAD
-	case "cinema", "movies", "film":
-		return AttributeCINEMA
-	case "career", "finance", "work":
-		return AttributeCAREER
-	default:
-		return DefaultAttribute
+	return builtinRegistry.Parse(input)
+}
+
+// ParseAttributes is ParseWeights against the built-in attributes.
+func ParseAttributes(input string) (primary Attribute, weights map[Attribute]int) {
+	return builtinRegistry.ParseWeights(input)
+}
+
+// Parse resolves a code or alias to an Attribute.
+// If input is empty or unrecognized, returns DefaultAttribute.
+func (r *AttributeRegistry) Parse(input string) Attribute {
+	if d, ok := r.Lookup(input); ok {
+		return d.Code
 	}
+	return DefaultAttribute
 }
 
-// ParseAttributes parses multi-attribute input like "str:50,int:50" or "str" (100%).
+// ParseWeights parses multi-attribute input like "str:50,int:50" or "str" (100%).
 // Returns primary attribute and weight map.
 // Format: "attr1:weight1,attr2:weight2,..." or just "attr" for single attribute.
-func ParseAttributes(input string) (primary Attribute, weights map[Attribute]int) {
+func (r *AttributeRegistry) ParseWeights(input string) (primary Attribute, weights map[Attribute]int) {
 	input = strings.TrimSpace(input)
 	if input == "" {
 		return DefaultAttribute, nil
@@ -46,7 +41,7 @@ func ParseAttributes(input string) (primary Attribute, weights map[Attribute]int
 
 	// Check if it's a single attribute (no colon, no comma)
 	if !strings.Contains(input, ":") && !strings.Contains(input, ",") {
-		return ParseAttribute(input), nil
+		return r.Parse(input), nil
 	}
 
 	weights = make(map[Attribute]int)
@@ -73,7 +68,7 @@ func ParseAttributes(input string) (primary Attribute, weights map[Attribute]int
 			attrStr = part
 		}
 
-		attr := ParseAttribute(attrStr)
+		attr := r.Parse(attrStr)
 		if i == 0 {
 			firstAttr = attr
 		}
@@ -88,6 +83,27 @@ func ParseAttributes(input string) (primary Attribute, weights map[Attribute]int
 	return firstAttr, weights
 }
 
+// ParseWeightsStrict is ParseWeights for input typed by the user: it refuses
+// unknown attributes and weights that aren't positive numbers instead of
+// falling back to defaults.
+func (r *AttributeRegistry) ParseWeightsStrict(input string) (primary Attribute, weights map[Attribute]int, err error) {
+	if strings.TrimSpace(input) == "" {
+		return "", nil, errors.New("attributes are required")
+	}
+	for _, part := range strings.Split(input, ",") {
+		code, weight, hasWeight := strings.Cut(strings.TrimSpace(part), ":")
+		code = strings.TrimSpace(code)
+		if _, ok := r.Lookup(code); !ok {
+			return "", nil, fmt.Errorf("unknown attribute %q", code)
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(weight)); hasWeight && (err != nil || n <= 0) {
+			return "", nil, fmt.Errorf("weight of %s must be a positive number", code)
+		}
+	}
+	primary, weights = r.ParseWeights(input)
+	return primary, weights, nil
+}
+
 func parseWeight(s string) int {
 	var w int
 	for _, c := range s {
@@ -97,3 +113,134 @@ func parseWeight(s string) int {
 	}
 	return w
 }
+
+// QuickAdd is a task typed in one line (see ParseQuickAdd). Fields the text
+// doesn't set are left zero.
+type QuickAdd struct {
+	Title      string
+	Difficulty Difficulty        // !3
+	Attribute  Attribute         // @str, @str:50,int:50 or @str @int
+	Attributes map[Attribute]int // Set with several attributes
+	Tags       []string          // #fitness
+	ParentID   *int64            // ^12
+	DueDate    *time.Time        // due:sat
+	Interval   HabitInterval     // every:mon,thu; makes it a habit
+}
+
+// ParseQuickAdd splits a one-line task such as
+//
+//	Run 5k !3 @str #fitness ^12 due:sat every:mon,thu
+//
+// into its title and the fields set by inline tokens: !difficulty, @attribute
+// (with optional weights), #tag, ^parent, due:<date> (see ParseDueDate) and
+// every:<schedule> (see ParseHabitSchedule; "-" stands for a space, as in
+// every:first-mon). Every other word is part of the title; a leading
+// backslash keeps a word that looks like a token, as in \#1.
+func ParseQuickAdd(input string, reg *AttributeRegistry, now time.Time) (QuickAdd, error) {
+	var q QuickAdd
+	var title, attrs []string
+	seenTag := map[string]bool{}
+	for _, word := range strings.Fields(input) {
+		lower := strings.ToLower(word)
+		switch {
+		case strings.HasPrefix(word, `\`) && len(word) > 1:
+			title = append(title, word[1:])
+		case strings.HasPrefix(word, "!"):
+			if q.Difficulty != 0 {
+				return QuickAdd{}, fmt.Errorf("%s: difficulty is already !%d", word, q.Difficulty)
+			}
+			n, err := strconv.Atoi(word[1:])
+			if err != nil || !Difficulty(n).IsValid() {
+				return QuickAdd{}, fmt.Errorf("%s: difficulty must be !1 to !5", word)
+			}
+			q.Difficulty = Difficulty(n)
+		case strings.HasPrefix(word, "@"):
+			if _, _, err := reg.ParseWeightsStrict(word[1:]); err != nil {
+				return QuickAdd{}, fmt.Errorf("%s: %w", word, err)
+			}
+			attrs = append(attrs, word[1:])
+		case strings.HasPrefix(word, "#"):
+			tag, err := NormalizeTag(word)
+			if err != nil {
+				return QuickAdd{}, err
+			}
+			if !seenTag[tag] {
+				seenTag[tag] = true
+				q.Tags = append(q.Tags, tag)
+			}
+		case strings.HasPrefix(word, "^"):
+			if q.ParentID != nil {
+				return QuickAdd{}, fmt.Errorf("%s: parent is already ^%d", word, *q.ParentID)
+			}
+			id, err := strconv.ParseInt(word[1:], 10, 64)
+			if err != nil || id <= 0 {
+				return QuickAdd{}, fmt.Errorf("%s: parent must be a task ID, e.g. ^12", word)
+			}
+			q.ParentID = &id
+		case strings.HasPrefix(lower, "due:"):
+			if q.DueDate != nil {
+				return QuickAdd{}, fmt.Errorf("%s: due date given twice", word)
+			}
+			d, err := ParseDueDate(word[len("due:"):], now)
+			if err != nil {
+				return QuickAdd{}, fmt.Errorf("%s: %w", word, err)
+			}
+			q.DueDate = &d
+		case strings.HasPrefix(lower, "every:"):
+			if q.Interval != "" {
+				return QuickAdd{}, fmt.Errorf("%s: schedule is already %s", word, q.Interval)
+			}
+			interval, err := parseQuickInterval(strings.ReplaceAll(word[len("every:"):], "-", " "))
+			if err != nil {
+				return QuickAdd{}, fmt.Errorf("%s: %w", word, err)
+			}
+			q.Interval = interval
+		default:
+			title = append(title, word)
+		}
+	}
+	q.Title = strings.Join(title, " ")
+	if q.Title == "" {
+		return QuickAdd{}, fmt.Errorf("title is empty")
+	}
+	if len(attrs) > 0 {
+		q.Attribute, q.Attributes = reg.ParseWeights(strings.Join(attrs, ","))
+	}
+	return q, nil
+}
+
+// parseQuickInterval reads the schedule of an every: token. Besides the
+// habit schedules it takes the bare forms every:day and every:2-days.
+func parseQuickInterval(s string) (HabitInterval, error) {
+	interval, err := ParseHabitInterval(s)
+	if err == nil {
+		return interval, nil
+	}
+	if again, err2 := ParseHabitInterval("every " + s); err2 == nil {
+		return again, nil
+	}
+	return "", err
+}
+
+// TaskInput returns the CreateTaskInput for q, with difficulty trivial and
+// the default attribute where q leaves them unset.
+func (q QuickAdd) TaskInput() CreateTaskInput {
+	in := CreateTaskInput{
+		Title:         q.Title,
+		Difficulty:    q.Difficulty,
+		Attribute:     q.Attribute,
+		Attributes:    q.Attributes,
+		ParentID:      q.ParentID,
+		DueDate:       q.DueDate,
+		IsHabit:       q.Interval != "",
+		HabitInterval: q.Interval,
+		Tags:          q.Tags,
+	}
+	if in.Difficulty == 0 {
+		in.Difficulty = DifficultyTrivial
+	}
+	if in.Attribute == "" {
+		in.Attribute = DefaultAttribute
+	}
+	return in
+}
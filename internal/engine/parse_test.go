@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseQuickAdd(t *testing.T) {
+	// Friday afternoon.
+	now := time.Date(2026, 10, 16, 14, 30, 0, 0, time.Local)
+	sat := time.Date(2026, 10, 17, 23, 59, 59, 0, time.Local)
+	id := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		in   string
+		want QuickAdd
+	}{
+		{"Buy milk", QuickAdd{Title: "Buy milk"}},
+		{"Run 5k !3 @str #fitness ^12 due:sat every:mon,thu", QuickAdd{
+			Title: "Run 5k", Difficulty: DifficultyMedium, Attribute: AttributeSTR, Tags: []string{"fitness"},
+			ParentID: id(12), DueDate: &sat, Interval: "mon,thu",
+		}},
+		{"Study @str:30,int:70 notes", QuickAdd{Title: "Study notes", Attribute: AttributeSTR,
+			Attributes: map[Attribute]int{AttributeSTR: 30, AttributeINT: 70}}},
+		{"Pair @str @int", QuickAdd{Title: "Pair", Attribute: AttributeSTR,
+			Attributes: map[Attribute]int{AttributeSTR: 100, AttributeINT: 100}}},
+		{"#Work Report #urgent #work", QuickAdd{Title: "Report", Tags: []string{"work", "urgent"}}},
+		{`Watch \#1 \!3 film DUE:tomorrow`, QuickAdd{Title: "Watch #1 !3 film", DueDate: &sat}},
+		{"Stretch every:day", QuickAdd{Title: "Stretch", Interval: "daily"}},
+		{"Water plants every:2-days", QuickAdd{Title: "Water plants", Interval: "every 2 days"}},
+		{"Book club every:first-mon", QuickAdd{Title: "Book club", Interval: "1st mon of month"}},
+		{"Note: it's 5@ or user@host", QuickAdd{Title: "Note: it's 5@ or user@host"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseQuickAdd(tt.in, builtinRegistry, now)
+		if err != nil {
+			t.Fatalf("ParseQuickAdd(%q): %v", tt.in, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("ParseQuickAdd(%q)=%+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+
+	errs := []struct {
+		in, want string
+	}{
+		{"!3 #fitness", "title is empty"},
+		{"Run !6", "!1 to !5"},
+		{"Run !3 !4", "already !3"},
+		{"Run @strength", "unknown attribute"},
+		{"Run @foo", `unknown attribute "foo"`},
+		{"Run @str:lots", "positive number"},
+		{"Run #to-do!", "letters, digits"},
+		{"Run ^x", "task ID"},
+		{"Run ^1 ^2", "already ^1"},
+		{"Run due:someday", "due:someday"},
+		{"Run every:sometimes", "invalid habit interval"},
+	}
+	for _, tt := range errs {
+		_, err := ParseQuickAdd(tt.in, builtinRegistry, now)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("ParseQuickAdd(%q) err=%v, want %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestQuickAddTaskInputDefaults(t *testing.T) {
+	in := QuickAdd{Title: "Stretch", Interval: "daily"}.TaskInput()
+	if in.Difficulty != DifficultyTrivial || in.Attribute != DefaultAttribute || !in.IsHabit || in.HabitInterval != "daily" {
+		t.Fatalf("TaskInput()=%+v", in)
+	}
+}
+
+func TestParseWeightsStrict(t *testing.T) {
+	primary, weights, err := builtinRegistry.ParseWeightsStrict("str:30, int:70")
+	if err != nil || primary != AttributeSTR || weights[AttributeSTR] != 30 || weights[AttributeINT] != 70 {
+		t.Fatalf("ParseWeightsStrict = %s, %v, %v", primary, weights, err)
+	}
+	if primary, _, err := builtinRegistry.ParseWeightsStrict("int"); err != nil || primary != AttributeINT {
+		t.Fatalf("ParseWeightsStrict(int) = %s, %v", primary, err)
+	}
+	// ParseWeights would quietly make these WIS or drop the weight.
+	for _, in := range []string{"", "strr", "str:-5,int:0", "str:x", "str,"} {
+		if _, _, err := builtinRegistry.ParseWeightsStrict(in); err == nil {
+			t.Errorf("ParseWeightsStrict(%q) succeeded", in)
+		}
+	}
+}
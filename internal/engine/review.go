@@ -0,0 +1,368 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"questline/internal/storage"
+)
+
+// XPEventReview is the ledger event of review XP.
+const XPEventReview = "review"
+
+// ReviewStaleDays is how long a task can stay pending before a review brings
+// it up.
+const ReviewStaleDays = 14
+
+// ReviewPeriod is how far back a review looks.
+type ReviewPeriod string
+
+const (
+	ReviewWeek  ReviewPeriod = "week"  // the past 7 days
+	ReviewMonth ReviewPeriod = "month" // the past month
+)
+
+// ParseReviewPeriod parses "week" or "month" (also "weekly", "monthly", "w", "m").
+func ParseReviewPeriod(input string) (ReviewPeriod, error) {
+	switch input {
+	case "week", "weekly", "w", "":
+		return ReviewWeek, nil
+	case "month", "monthly", "m":
+		return ReviewMonth, nil
+	}
+	return "", fmt.Errorf("unknown review period %q (week or month)", input)
+}
+
+// window returns the span a review at now covers, starting at midnight.
+func (p ReviewPeriod) window(now time.Time) (time.Time, time.Time) {
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if p == ReviewMonth {
+		return start.AddDate(0, -1, 0), now
+	}
+	return start.AddDate(0, 0, -7), now
+}
+
+// calendarStart returns the start of the calendar week (Monday) or month
+// containing t. Review XP is paid once per calendar period.
+func (p ReviewPeriod) calendarStart(t time.Time) time.Time {
+	if p == ReviewMonth {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return day.AddDate(0, 0, -mondayFirst(t.Weekday()))
+}
+
+// ReviewAttr is what one attribute gained during a review period.
+type ReviewAttr struct {
+	Attr        Attribute
+	Completions int // Completions that credited the attribute
+	XP          int
+}
+
+// StaleTask is a pending task a review asks about.
+type StaleTask struct {
+	Task    storage.Task
+	AgeDays int  // Days since it was created
+	Overdue bool // Past its due date
+}
+
+// BehindHabit is a habit that fell short of its schedule during a review period.
+type BehindHabit struct {
+	Task     storage.Task
+	Done     int // Completions counted towards the schedule
+	Expected int // Completions the schedule asked for
+}
+
+// Review is the report a weekly or monthly review walks through.
+type Review struct {
+	Period      ReviewPeriod
+	Since       time.Time
+	Until       time.Time
+	Completions int
+	XPEarned    int
+	Attributes  []ReviewAttr // Most XP first
+	Stale       []StaleTask  // Oldest first
+	Behind      []BehindHabit
+	Blueprints  []BlueprintProgress // Accepted, not completed yet
+	Last        *storage.Review     // The previous review of this period kind
+	XPAvailable int                 // Review XP saving it now pays
+}
+
+// ReviewActionKind is what a review does with a stale task.
+type ReviewActionKind string
+
+const (
+	ReviewReschedule ReviewActionKind = "reschedule" // set or clear the due date
+	ReviewDrop       ReviewActionKind = "drop"       // move it to the trash
+	ReviewRerate     ReviewActionKind = "rerate"     // change its difficulty
+)
+
+// ReviewAction is a decision taken on a task during a review.
+type ReviewAction struct {
+	TaskID     int64
+	Kind       ReviewActionKind
+	Due        *time.Time // ReviewReschedule; nil clears the due date
+	Difficulty Difficulty // ReviewRerate
+}
+
+// ReviewResult is a saved review.
+type ReviewResult struct {
+	Review       storage.Review
+	LevelBefore  int
+	LevelAfter   int
+	Achievements []Achievement
+}
+
+// BuildReview gathers the report for a review of period ending at now. Reviews
+// unlock at the reviews gate level.
+func (s *Service) BuildReview(ctx context.Context, period ReviewPeriod, now time.Time) (*Review, error) {
+	p, err := s.getPlayer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.rules.CanReview(p.Level); err != nil {
+		return nil, err
+	}
+	return s.buildReview(ctx, period, now)
+}
+
+func (s *Service) buildReview(ctx context.Context, period ReviewPeriod, now time.Time) (*Review, error) {
+	since, until := period.window(now)
+	rv := &Review{Period: period, Since: since, Until: until}
+
+	all, err := s.tasks.ListAllIncludingDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*storage.Task, len(all))
+	for i := range all {
+		byID[all[i].ID] = &all[i]
+	}
+
+	comps, err := s.completions.ListInRange(ctx, since.UTC(), until.UTC())
+	if err != nil {
+		return nil, err
+	}
+	attrs := map[Attribute]*ReviewAttr{}
+	for _, c := range comps {
+		rv.Completions++
+		rv.XPEarned += c.XPAwarded
+		t := byID[c.TaskID]
+		if t == nil {
+			continue
+		}
+		for _, sh := range splitXP(c.XPAwarded, parseStoredAttribute(t.Attribute), t.Attributes) {
+			a := attrs[sh.Attr]
+			if a == nil {
+				a = &ReviewAttr{Attr: sh.Attr}
+				attrs[sh.Attr] = a
+			}
+			a.Completions++
+			a.XP += sh.Amount
+		}
+	}
+	for _, a := range attrs {
+		rv.Attributes = append(rv.Attributes, *a)
+	}
+	sort.Slice(rv.Attributes, func(i, j int) bool {
+		if rv.Attributes[i].XP != rv.Attributes[j].XP {
+			return rv.Attributes[i].XP > rv.Attributes[j].XP
+		}
+		return rv.Attributes[i].Attr < rv.Attributes[j].Attr
+	})
+
+	live, err := s.tasks.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	children := map[int64]int{}
+	for _, t := range live {
+		if t.ParentID != nil && t.Status != "done" {
+			children[*t.ParentID]++
+		}
+	}
+	staleBefore := now.AddDate(0, 0, -ReviewStaleDays)
+	for _, t := range live {
+		switch {
+		case t.IsHabit:
+			behind, err := s.habitBehind(ctx, &t, since, until)
+			if err != nil {
+				return nil, err
+			}
+			if behind != nil {
+				rv.Behind = append(rv.Behind, *behind)
+			}
+		case t.IsProject, t.Status != "pending", children[t.ID] > 0:
+		default:
+			overdue := IsOverdue(&t, now)
+			if overdue || t.CreatedAt.Before(staleBefore) {
+				rv.Stale = append(rv.Stale, StaleTask{Task: t, AgeDays: int(now.Sub(t.CreatedAt).Hours() / 24), Overdue: overdue})
+			}
+		}
+	}
+	sort.SliceStable(rv.Stale, func(i, j int) bool { return rv.Stale[i].Task.CreatedAt.Before(rv.Stale[j].Task.CreatedAt) })
+
+	active, err := s.blueprints.ListByStatus(ctx, string(BlueprintActive))
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range active {
+		prog, err := s.BlueprintProgress(ctx, b.Code)
+		if err != nil {
+			return nil, err
+		}
+		rv.Blueprints = append(rv.Blueprints, *prog)
+	}
+
+	if rv.Last, err = s.reviews.Last(ctx, string(period)); err != nil {
+		return nil, err
+	}
+	if rv.XPAvailable, err = s.reviewBonus(ctx, period, now); err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// habitBehind compares a habit's completions with its schedule over the
+// periods that ended between since and until. It returns nil when the habit
+// kept up.
+func (s *Service) habitBehind(ctx context.Context, t *storage.Task, since, until time.Time) (*BehindHabit, error) {
+	sched, err := ScheduleForTask(t)
+	if err != nil {
+		return nil, err
+	}
+	comps, err := s.completions.ListByTask(ctx, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	loc := until.Location()
+	started := sched.periodStart(sched.Anchor.In(loc))
+	b := &BehindHabit{Task: *t}
+	for p := sched.periodStart(since.In(loc)); ; p = sched.nextPeriod(p) {
+		end := sched.nextPeriod(p)
+		if end.After(until) {
+			break
+		}
+		if p.Before(started) {
+			continue
+		}
+		if t.HabitEndDate != nil && !p.Before(*t.HabitEndDate) {
+			break
+		}
+		done := 0
+		for _, c := range comps {
+			if at := c.CompletedAt.In(loc); !at.Before(p) && at.Before(end) {
+				done++
+			}
+		}
+		target := sched.target(p)
+		b.Expected += target
+		b.Done += min(done, target)
+	}
+	if b.Done >= b.Expected {
+		return nil, nil
+	}
+	return b, nil
+}
+
+// reviewBonus is the review XP a review of period saved at now pays: the
+// bonus, unless a review already took it this calendar week or month.
+func (s *Service) reviewBonus(ctx context.Context, period ReviewPeriod, now time.Time) (int, error) {
+	paid, err := s.reviews.LastPaid(ctx, string(period))
+	if err != nil {
+		return 0, err
+	}
+	if paid != nil && !period.calendarStart(paid.CreatedAt.In(now.Location())).Before(period.calendarStart(now)) {
+		return 0, nil
+	}
+	if period == ReviewMonth {
+		return s.rules.XP.MonthlyReviewBonus, nil
+	}
+	return s.rules.XP.ReviewBonus, nil
+}
+
+// SaveReview applies the decisions taken during a review, records the review
+// and pays its XP, all in one transaction. Dropped tasks go to the trash; any
+// XP their subtasks earned is kept.
+func (s *Service) SaveReview(ctx context.Context, period ReviewPeriod, actions []ReviewAction, now time.Time) (*ReviewResult, error) {
+	var res *ReviewResult
+	err := s.inTx(ctx, func(tx *Service) error {
+		var err error
+		if res, err = tx.saveReview(ctx, period, actions, now); err != nil {
+			return err
+		}
+		res.Achievements, err = tx.recordAchievements(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Service) saveReview(ctx context.Context, period ReviewPeriod, actions []ReviewAction, now time.Time) (*ReviewResult, error) {
+	rv, err := s.BuildReview(ctx, period, now)
+	if err != nil {
+		return nil, err
+	}
+	row := storage.Review{
+		Period:      string(period),
+		PeriodStart: rv.Since.UTC(),
+		PeriodEnd:   rv.Until.UTC(),
+		Completions: rv.Completions,
+		XPEarned:    rv.XPEarned,
+		CreatedAt:   now.UTC(),
+	}
+
+	for _, a := range actions {
+		switch a.Kind {
+		case ReviewReschedule:
+			patch := TaskPatch{DueDate: a.Due, ClearDue: a.Due == nil}
+			if _, err := s.updateTask(ctx, a.TaskID, patch); err != nil {
+				return nil, err
+			}
+			row.Rescheduled++
+		case ReviewDrop:
+			if _, err := s.deleteTask(ctx, a.TaskID, DeleteXPKeep); err != nil {
+				return nil, err
+			}
+			row.Dropped++
+		case ReviewRerate:
+			d := a.Difficulty
+			if _, err := s.updateTask(ctx, a.TaskID, TaskPatch{Difficulty: &d}); err != nil {
+				return nil, err
+			}
+			row.Rerated++
+		default:
+			return nil, fmt.Errorf("unknown review action %q", a.Kind)
+		}
+	}
+
+	p, err := s.getPlayer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &ReviewResult{LevelBefore: p.Level, LevelAfter: p.Level}
+	row.XPAwarded = rv.XPAvailable
+	if row.XPAwarded > 0 {
+		if err := s.recordXP(ctx, p, XPEventReview, nil, nil, []xpShare{{Amount: row.XPAwarded}}, row.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := s.players.Update(ctx, p); err != nil {
+			return nil, err
+		}
+		res.LevelAfter = p.Level
+	}
+	if row.ID, err = s.reviews.Insert(ctx, row); err != nil {
+		return nil, err
+	}
+	res.Review = row
+	return res, nil
+}
+
+// Reviews returns every saved review, oldest first.
+func (s *Service) Reviews(ctx context.Context) ([]storage.Review, error) {
+	return s.reviews.ListAll(ctx)
+}
@@ -0,0 +1,169 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReviewReportsActsAndPaysOncePerPeriod(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	// Two weeks from now the tasks created today are stale.
+	now := time.Now().AddDate(0, 0, 15)
+
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelReviews)-1)
+	var gate GateError
+	if _, err := svc.BuildReview(ctx, ReviewWeek, now); !errors.As(err, &gate) || gate.RequiredLevel != LevelReviews {
+		t.Fatalf("BuildReview below the gate err=%v, want GateError at L%d", err, LevelReviews)
+	}
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelReviews))
+
+	create := func(in CreateTaskInput) int64 {
+		t.Helper()
+		res, err := svc.CreateTask(ctx, in)
+		if err != nil {
+			t.Fatalf("CreateTask %s: %v", in.Title, err)
+		}
+		return res.TaskID
+	}
+	due := time.Now().AddDate(0, 0, 3)
+	run := create(CreateTaskInput{Title: "Run 5k", Difficulty: DifficultyMedium, Attribute: AttributeSTR})
+	taxes := create(CreateTaskInput{Title: "File taxes", Difficulty: DifficultyHard, Attribute: AttributeWIS, DueDate: &due})
+	drawer := create(CreateTaskInput{Title: "Sort drawer", Difficulty: DifficultyTrivial, Attribute: AttributeHOME})
+	novel := create(CreateTaskInput{Title: "Read a novel", Difficulty: DifficultyEasy, Attribute: AttributeINT})
+	gym := create(CreateTaskInput{Title: "Gym", Difficulty: DifficultyTrivial, Attribute: AttributeSTR, IsHabit: true, HabitInterval: HabitIntervalDaily})
+	done, err := svc.CompleteTask(ctx, run)
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if _, err := svc.CompleteTask(ctx, gym); err != nil {
+		t.Fatalf("CompleteTask habit: %v", err)
+	}
+
+	rv, err := svc.BuildReview(ctx, ReviewMonth, now)
+	if err != nil {
+		t.Fatalf("BuildReview: %v", err)
+	}
+	if rv.Completions != 2 || len(rv.Attributes) != 1 || rv.Attributes[0].Attr != AttributeSTR || rv.Attributes[0].XP < done.XPAwarded {
+		t.Fatalf("completions=%d attributes=%+v, want two STR completions", rv.Completions, rv.Attributes)
+	}
+	stale := map[int64]bool{}
+	for _, st := range rv.Stale {
+		stale[st.Task.ID] = true
+		if st.Task.ID == taxes && !st.Overdue {
+			t.Fatal("overdue task not flagged")
+		}
+	}
+	if len(stale) != 3 || !stale[taxes] || !stale[drawer] || !stale[novel] {
+		t.Fatalf("stale=%+v, want taxes, drawer and novel", rv.Stale)
+	}
+	if len(rv.Behind) != 1 || rv.Behind[0].Task.ID != gym || rv.Behind[0].Done != 1 || rv.Behind[0].Expected < 10 {
+		t.Fatalf("behind=%+v, want the gym habit", rv.Behind)
+	}
+	if rv.XPAvailable != svc.Rules().XP.MonthlyReviewBonus {
+		t.Fatalf("XPAvailable=%d, want %d", rv.XPAvailable, svc.Rules().XP.MonthlyReviewBonus)
+	}
+
+	xpBefore := rv.XPAvailable
+	p, _ := svc.PlayerRepo().GetOrCreateMain(ctx)
+	start := p.XPTotal
+	newDue := now.AddDate(0, 0, 7)
+	res, err := svc.SaveReview(ctx, ReviewMonth, []ReviewAction{
+		{TaskID: taxes, Kind: ReviewReschedule, Due: &newDue},
+		{TaskID: drawer, Kind: ReviewDrop},
+		{TaskID: novel, Kind: ReviewRerate, Difficulty: DifficultyMedium},
+	}, now)
+	if err != nil {
+		t.Fatalf("SaveReview: %v", err)
+	}
+	if r := res.Review; r.Rescheduled != 1 || r.Dropped != 1 || r.Rerated != 1 || r.XPAwarded != xpBefore || r.Completions != 2 {
+		t.Fatalf("review=%+v", r)
+	}
+	if got, _ := svc.TaskRepo().Get(ctx, taxes); got == nil || got.DueDate == nil || !got.DueDate.Equal(newDue) {
+		t.Fatalf("taxes=%+v, want it due %v", got, newDue)
+	}
+	if got, _ := svc.TaskRepo().Get(ctx, drawer); got != nil {
+		t.Fatal("dropped task still listed")
+	}
+	if got, _ := svc.TaskRepo().Get(ctx, novel); got == nil || got.Difficulty != int(DifficultyMedium) {
+		t.Fatalf("novel=%+v, want difficulty 3", got)
+	}
+	p, _ = svc.PlayerRepo().GetOrCreateMain(ctx)
+	if p.XPTotal != start+xpBefore {
+		t.Fatalf("xp=%d, want %d", p.XPTotal, start+xpBefore)
+	}
+
+	// The month's XP is paid once; the weekly review pays its own.
+	again, err := svc.SaveReview(ctx, ReviewMonth, nil, now)
+	if err != nil || again.Review.XPAwarded != 0 {
+		t.Fatalf("second monthly review=%+v, %v; want no XP", again, err)
+	}
+	if week, err := svc.SaveReview(ctx, ReviewWeek, nil, now); err != nil || week.Review.XPAwarded != svc.Rules().XP.ReviewBonus {
+		t.Fatalf("weekly review=%+v, %v", week, err)
+	}
+
+	// Review XP is in the ledger and reviews travel with exports.
+	entries, err := svc.LedgerRepo().ListAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reviewXP := 0
+	for _, e := range entries {
+		if e.Event == XPEventReview {
+			reviewXP += e.Amount
+		}
+	}
+	if reviewXP != xpBefore+svc.Rules().XP.ReviewBonus {
+		t.Fatalf("review XP in the ledger=%d, want %d", reviewXP, xpBefore+svc.Rules().XP.ReviewBonus)
+	}
+	doc, err := svc.Export(ctx)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(doc.Reviews) != 3 {
+		t.Fatalf("exported %d reviews, want 3", len(doc.Reviews))
+	}
+	dst, cleanupDst := newTestService(t)
+	defer cleanupDst()
+	if _, err := dst.Import(ctx, doc, false); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if got, err := dst.Reviews(ctx); err != nil || len(got) != 3 || got[0].XPAwarded != xpBefore {
+		t.Fatalf("imported reviews=%+v, %v", got, err)
+	}
+}
+
+func TestReviewBonusPaysOnlyTheFirstReviewOfAPeriod(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelReviews))
+
+	// A Wednesday noon, so the reviews an hour apart stay in one week.
+	wed := time.Date(2026, 3, 11, 12, 0, 0, 0, time.Local)
+	for i, want := range []int{svc.Rules().XP.ReviewBonus, 0, 0} {
+		res, err := svc.SaveReview(ctx, ReviewWeek, nil, wed.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatalf("SaveReview week #%d: %v", i+1, err)
+		}
+		if res.Review.XPAwarded != want {
+			t.Fatalf("weekly review #%d paid %d XP, want %d", i+1, res.Review.XPAwarded, want)
+		}
+	}
+	res, err := svc.SaveReview(ctx, ReviewMonth, nil, wed)
+	if err != nil {
+		t.Fatalf("SaveReview month: %v", err)
+	}
+	if want := svc.Rules().XP.MonthlyReviewBonus; res.Review.XPAwarded != want {
+		t.Fatalf("monthly review paid %d XP, want %d", res.Review.XPAwarded, want)
+	}
+	if res, err = svc.SaveReview(ctx, ReviewMonth, nil, wed.AddDate(0, 0, 7)); err != nil {
+		t.Fatalf("SaveReview month: %v", err)
+	}
+	if res.Review.XPAwarded != 0 {
+		t.Fatalf("second monthly review paid %d XP, want 0", res.Review.XPAwarded)
+	}
+}
@@ -0,0 +1,658 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"questline/internal/storage"
+)
+
+// EnvRulesPath overrides the rules file location.
+const EnvRulesPath = "QL_RULES_PATH"
+
+// Rules holds the tunable numbers of the game: the XP curve, feature gates,
+// habit decay, deadlines, streaks, HP and tracked time. A Service is built with one rule set and uses it everywhere.
+type Rules struct {
+	Preset    string        `toml:"preset" json:"preset"`
+	XP        XPRules       `toml:"xp" json:"xp"`
+	Gates     GateRules     `toml:"gates" json:"gates"`
+	Habits    HabitRules    `toml:"habits" json:"habits"`
+	Deadlines DeadlineRules `toml:"deadlines" json:"deadlines"`
+	Streaks   StreakRules   `toml:"streaks" json:"streaks"`
+	HP        HPRules       `toml:"hp" json:"hp"`
+	Time      TimeRules     `toml:"time" json:"time"`
+}
+
+type XPRules struct {
+	// Level L needs RequiredCoef * L^RequiredExponent total XP.
+	RequiredCoef     float64 `toml:"required_coef" json:"required_coef"`
+	RequiredExponent float64 `toml:"required_exponent" json:"required_exponent"`
+
+	// Task XP = TaskBase * multiplier(difficulty) * (1 + attrLevel*AttributeLevelBonusRate).
+	TaskBase                float64    `toml:"task_base" json:"task_base"`
+	DifficultyMultipliers   [5]float64 `toml:"difficulty_multipliers" json:"difficulty_multipliers"` // trivial..epic
+	AttributeLevelBonusRate float64    `toml:"attribute_level_bonus_rate" json:"attribute_level_bonus_rate"`
+
+	// ProjectBonusRate is the share of a project's child XP paid out on completion.
+	ProjectBonusRate float64 `toml:"project_bonus_rate" json:"project_bonus_rate"`
+
+	// BlueprintBonus is the flat XP paid the first time a blueprint is completed.
+	BlueprintBonus int `toml:"blueprint_bonus" json:"blueprint_bonus"`
+
+	// ReviewBonus and MonthlyReviewBonus are paid for the first review of each
+	// week and month.
+	ReviewBonus        int `toml:"review_bonus" json:"review_bonus"`
+	MonthlyReviewBonus int `toml:"monthly_review_bonus" json:"monthly_review_bonus"`
+}
+
+type GateRules struct {
+	Subtasks         int              `toml:"subtasks" json:"subtasks"`
+	Habits           int              `toml:"habits" json:"habits"`
+	Projects         int              `toml:"projects" json:"projects"`
+	DeepSubtasks     int              `toml:"deep_subtasks" json:"deep_subtasks"`
+	Reviews          int              `toml:"reviews" json:"reviews"`
+	DifficultyUnlock [5]int           `toml:"difficulty_unlock" json:"difficulty_unlock"` // level needed for trivial..epic
+	MaxActiveTasks   []ActiveTaskStep `toml:"max_active_tasks" json:"max_active_tasks"`
+}
+
+// ActiveTaskStep caps active tasks at Tasks from player level Level onwards.
+type ActiveTaskStep struct {
+	Level int `toml:"level" json:"level"`
+	Tasks int `toml:"tasks" json:"tasks"`
+}
+
+type HabitRules struct {
+	// A habit completed DecayThreshold or more times at the same difficulty in
+	// the last DecayWindowDays pays DecayFactor of its XP.
+	DecayThreshold  int     `toml:"decay_threshold" json:"decay_threshold"`
+	DecayWindowDays int     `toml:"decay_window_days" json:"decay_window_days"`
+	DecayFactor     float64 `toml:"decay_factor" json:"decay_factor"`
+}
+
+// DeadlineRules adjust the XP of tasks completed after or well before their due
+// date. All zero (the default) disables them.
+type DeadlineRules struct {
+	// LatePenaltyPerDay is the share of XP lost per started day late, up to MaxLatePenalty.
+	LatePenaltyPerDay float64 `toml:"late_penalty_per_day" json:"late_penalty_per_day"`
+	MaxLatePenalty    float64 `toml:"max_late_penalty" json:"max_late_penalty"`
+	// EarlyBonus is the share of XP added when a task is done at least
+	// EarlyBonusDays before it is due.
+	EarlyBonus     float64 `toml:"early_bonus" json:"early_bonus"`
+	EarlyBonusDays int     `toml:"early_bonus_days" json:"early_bonus_days"`
+}
+
+// StreakRules reward completing a habit in consecutive periods (days, weeks or
+// months, following its interval).
+type StreakRules struct {
+	// Each period of an ongoing streak after the first adds BonusPerPeriod to the
+	// habit's XP multiplier, up to MaxBonus.
+	BonusPerPeriod float64 `toml:"bonus_per_period" json:"bonus_per_period"`
+	MaxBonus       float64 `toml:"max_bonus" json:"max_bonus"`
+	// A freeze token is earned every FreezeEvery periods of a streak while fewer
+	// than MaxFreezes are held. Tokens are spent automatically on missed periods.
+	FreezeEvery int `toml:"freeze_every" json:"freeze_every"`
+	MaxFreezes  int `toml:"max_freezes" json:"max_freezes"`
+}
+
+// HPRules govern the player's health. Every scheduled habit occurrence that
+// passes without a completion costs DamagePerMiss HP; each full day heals
+// RegenPerDay and completions heal a little. Dropping to zero HP knocks the
+// player out: for KnockoutDays all XP is multiplied by KnockoutXPMultiplier.
+type HPRules struct {
+	Max                  int     `toml:"max" json:"max"`
+	DamagePerMiss        int     `toml:"damage_per_miss" json:"damage_per_miss"` // 0 disables damage
+	RegenPerDay          int     `toml:"regen_per_day" json:"regen_per_day"`
+	HealPerHabit         int     `toml:"heal_per_habit" json:"heal_per_habit"`
+	HealPerTask          int     `toml:"heal_per_task" json:"heal_per_task"`
+	KnockoutXPMultiplier float64 `toml:"knockout_xp_multiplier" json:"knockout_xp_multiplier"`
+	KnockoutDays         int     `toml:"knockout_days" json:"knockout_days"` // 0 disables the knockout
+}
+
+// TimeRules reward time tracked on a task (ql start/stop, ql focus) when it is
+// completed. All zero (the default) disables them.
+type TimeRules struct {
+	// Each tracked hour adds BonusPerHour to the task's XP multiplier, up to
+	// MaxBonus. Habits count the time tracked since their last completion.
+	BonusPerHour float64 `toml:"bonus_per_hour" json:"bonus_per_hour"`
+	MaxBonus     float64 `toml:"max_bonus" json:"max_bonus"`
+}
+
+// DefaultRules returns the stock rule set (the package constants).
+func DefaultRules() *Rules {
+	return &Rules{
+		Preset: "default",
+		XP: XPRules{
+			RequiredCoef:            XPRequiredCoef,
+			RequiredExponent:        1.5,
+			TaskBase:                TaskBaseXP,
+			DifficultyMultipliers:   [5]float64{1, 2, 5, 10, 25},
+			AttributeLevelBonusRate: AttributeLevelBonusRate,
+			ProjectBonusRate:        0.10,
+			BlueprintBonus:          100,
+			ReviewBonus:             50,
+			MonthlyReviewBonus:      150,
+		},
+		Gates: GateRules{
+			Subtasks:     LevelSubtasks,
+			Habits:       LevelHabits,
+			Projects:     LevelProjects,
+			DeepSubtasks: LevelDeepRecurs,
+			Reviews:      LevelReviews,
+			DifficultyUnlock: [5]int{
+				DifficultyUnlockLevels[DifficultyTrivial],
+				DifficultyUnlockLevels[DifficultyEasy],
+				DifficultyUnlockLevels[DifficultyMedium],
+				DifficultyUnlockLevels[DifficultyHard],
+				DifficultyUnlockLevels[DifficultyEpic],
+			},
+			MaxActiveTasks: []ActiveTaskStep{{Level: 0, Tasks: 3}, {Level: 2, Tasks: 5}},
+		},
+		Habits:  HabitRules{DecayThreshold: 5, DecayWindowDays: 7, DecayFactor: 0.5},
+		Streaks: StreakRules{BonusPerPeriod: 0.02, MaxBonus: 0.5, FreezeEvery: 7, MaxFreezes: 2},
+		HP: HPRules{Max: 50, DamagePerMiss: 5, RegenPerDay: 2, HealPerHabit: 2, HealPerTask: 1,
+			KnockoutXPMultiplier: 0.5, KnockoutDays: 3},
+	}
+}
+
+var rulePresets = map[string]func() *Rules{
+	"default": DefaultRules,
+	// casual: faster levels, earlier unlocks, gentler habit decay.
+	"casual": func() *Rules {
+		r := DefaultRules()
+		r.Preset = "casual"
+		r.XP.RequiredCoef = 300
+		r.XP.ProjectBonusRate = 0.15
+		r.XP.BlueprintBonus = 150
+		r.XP.ReviewBonus = 75
+		r.XP.MonthlyReviewBonus = 200
+		r.Gates = GateRules{
+			Subtasks:         2,
+			Habits:           3,
+			Projects:         5,
+			DeepSubtasks:     8,
+			Reviews:          12,
+			DifficultyUnlock: [5]int{0, 1, 3, 6, 10},
+			MaxActiveTasks:   []ActiveTaskStep{{Level: 0, Tasks: 5}, {Level: 2, Tasks: 8}},
+		}
+		r.Habits = HabitRules{DecayThreshold: 7, DecayWindowDays: 7, DecayFactor: 0.75}
+		r.Deadlines = DeadlineRules{EarlyBonus: 0.10, EarlyBonusDays: 1}
+		r.Streaks = StreakRules{BonusPerPeriod: 0.03, MaxBonus: 0.6, FreezeEvery: 5, MaxFreezes: 3}
+		r.HP = HPRules{Max: 100, DamagePerMiss: 3, RegenPerDay: 5, HealPerHabit: 3, HealPerTask: 2,
+			KnockoutXPMultiplier: 0.75, KnockoutDays: 1}
+		r.Time = TimeRules{BonusPerHour: 0.05, MaxBonus: 0.25}
+		return r
+	},
+	// hardcore: steeper curve, later unlocks, harsh decay.
+	"hardcore": func() *Rules {
+		r := DefaultRules()
+		r.Preset = "hardcore"
+		r.XP.RequiredCoef = 750
+		r.XP.RequiredExponent = 1.6
+		r.XP.TaskBase = 40
+		r.XP.AttributeLevelBonusRate = 0.03
+		r.XP.ProjectBonusRate = 0.05
+		r.XP.BlueprintBonus = 50
+		r.XP.ReviewBonus = 25
+		r.XP.MonthlyReviewBonus = 100
+		r.Gates = GateRules{
+			Subtasks:         4,
+			Habits:           7,
+			Projects:         9,
+			DeepSubtasks:     12,
+			Reviews:          18,
+			DifficultyUnlock: [5]int{0, 3, 7, 10, 15},
+			MaxActiveTasks:   []ActiveTaskStep{{Level: 0, Tasks: 2}, {Level: 3, Tasks: 4}},
+		}
+		r.Habits = HabitRules{DecayThreshold: 3, DecayWindowDays: 7, DecayFactor: 0.25}
+		r.Deadlines = DeadlineRules{LatePenaltyPerDay: 0.10, MaxLatePenalty: 0.50}
+		r.Streaks = StreakRules{BonusPerPeriod: 0.01, MaxBonus: 0.2, FreezeEvery: 14, MaxFreezes: 1}
+		r.HP = HPRules{Max: 30, DamagePerMiss: 8, RegenPerDay: 1, HealPerHabit: 1, HealPerTask: 0,
+			KnockoutXPMultiplier: 0.25, KnockoutDays: 7}
+		return r
+	},
+}
+
+// RulePresets returns the names of the built-in rule sets.
+func RulePresets() []string {
+	names := make([]string, 0, len(rulePresets))
+	for n := range rulePresets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PresetRules returns a copy of the named built-in rule set.
+func PresetRules(name string) (*Rules, error) {
+	mk, ok := rulePresets[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("unknown rules preset %q (available: %s)", name, strings.Join(RulePresets(), ", "))
+	}
+	return mk(), nil
+}
+
+// DefaultRulesPath returns ~/.config/questline/rules.toml.
+func DefaultRulesPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve home dir: %w", err)
+	}
+	return filepath.Join(home, ".config", "questline", "rules.toml"), nil
+}
+
+// ResolveRulesPath returns the rules file to load: $QL_RULES_PATH if set,
+// otherwise rules.toml or rules.json in ~/.config/questline if one exists, and
+// "" when there is none (use the default rules).
+func ResolveRulesPath() (string, error) {
+	if v := os.Getenv(EnvRulesPath); v != "" {
+		return v, nil
+	}
+	p, err := DefaultRulesPath()
+	if err != nil {
+		return "", err
+	}
+	for _, candidate := range []string{p, strings.TrimSuffix(p, ".toml") + ".json"} {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", nil
+}
+
+// LoadRulesFile reads a TOML or JSON (by extension) rules file. The file may
+// name a base preset ("preset = \"hardcore\"") and override any subset of its
+// values. An empty path returns the default rules.
+func LoadRulesFile(path string) (*Rules, error) {
+	if path == "" {
+		return DefaultRules(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rules: %w", err)
+	}
+	r, err := ParseRules(data, strings.EqualFold(filepath.Ext(path), ".json"))
+	if err != nil {
+		return nil, fmt.Errorf("rules %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// ParseRules decodes a rules document (TOML unless isJSON) on top of its preset.
+func ParseRules(data []byte, isJSON bool) (*Rules, error) {
+	decode := func(v any) error {
+		if isJSON {
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.DisallowUnknownFields()
+			return dec.Decode(v)
+		}
+		md, err := toml.Decode(string(data), v)
+		if err != nil {
+			return err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown key %q", undecoded[0].String())
+		}
+		return nil
+	}
+
+	var head struct {
+		Preset string `toml:"preset" json:"preset"`
+	}
+	if isJSON {
+		// The full decode below rejects unknown fields; this pass only wants the preset.
+		if err := json.Unmarshal(data, &head); err != nil {
+			return nil, err
+		}
+	} else if _, err := toml.Decode(string(data), &head); err != nil {
+		return nil, err
+	}
+	base := head.Preset
+	if base == "" {
+		base = "default"
+	}
+	r, err := PresetRules(base)
+	if err != nil {
+		return nil, err
+	}
+	if err := decode(r); err != nil {
+		return nil, err
+	}
+	r.Preset = base
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Validate rejects rule sets the engine cannot work with.
+func (r *Rules) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+	check(r.XP.RequiredCoef > 0, "xp.required_coef must be > 0")
+	check(r.XP.RequiredExponent > 0, "xp.required_exponent must be > 0")
+	check(r.XP.TaskBase > 0, "xp.task_base must be > 0")
+	for i, m := range r.XP.DifficultyMultipliers {
+		check(m > 0, "xp.difficulty_multipliers[%d] must be > 0", i)
+	}
+	check(r.XP.AttributeLevelBonusRate >= 0, "xp.attribute_level_bonus_rate must be >= 0")
+	check(r.XP.ProjectBonusRate >= 0, "xp.project_bonus_rate must be >= 0")
+	check(r.XP.BlueprintBonus >= 0, "xp.blueprint_bonus must be >= 0")
+	check(r.XP.ReviewBonus >= 0 && r.XP.MonthlyReviewBonus >= 0, "xp.review_bonus and xp.monthly_review_bonus must be >= 0")
+
+	g := r.Gates
+	check(g.Subtasks >= 0 && g.Habits >= 0 && g.Projects >= 0 && g.Reviews >= 0, "gate levels must be >= 0")
+	check(g.DeepSubtasks >= g.Subtasks, "gates.deep_subtasks must be >= gates.subtasks")
+	check(g.DifficultyUnlock[0] == 0, "gates.difficulty_unlock[0] must be 0 (trivial is always available)")
+	for i := 1; i < len(g.DifficultyUnlock); i++ {
+		check(g.DifficultyUnlock[i] >= g.DifficultyUnlock[i-1], "gates.difficulty_unlock must be non-decreasing")
+	}
+	check(len(g.MaxActiveTasks) > 0 && g.MaxActiveTasks[0].Level == 0, "gates.max_active_tasks must start at level 0")
+	for i, st := range g.MaxActiveTasks {
+		check(st.Tasks > 0, "gates.max_active_tasks[%d].tasks must be > 0", i)
+		check(i == 0 || st.Level > g.MaxActiveTasks[i-1].Level, "gates.max_active_tasks levels must increase")
+	}
+
+	check(r.Habits.DecayThreshold >= 1, "habits.decay_threshold must be >= 1")
+	check(r.Habits.DecayWindowDays >= 1, "habits.decay_window_days must be >= 1")
+	check(r.Habits.DecayFactor >= 0 && r.Habits.DecayFactor <= 1, "habits.decay_factor must be within 0..1")
+
+	d := r.Deadlines
+	check(d.LatePenaltyPerDay >= 0, "deadlines.late_penalty_per_day must be >= 0")
+	check(d.MaxLatePenalty >= 0 && d.MaxLatePenalty <= 1, "deadlines.max_late_penalty must be within 0..1")
+	check(d.EarlyBonus >= 0, "deadlines.early_bonus must be >= 0")
+	check(d.EarlyBonusDays >= 0, "deadlines.early_bonus_days must be >= 0")
+
+	st := r.Streaks
+	check(st.BonusPerPeriod >= 0, "streaks.bonus_per_period must be >= 0")
+	check(st.MaxBonus >= 0, "streaks.max_bonus must be >= 0")
+	check(st.FreezeEvery >= 0, "streaks.freeze_every must be >= 0 (0 disables freezes)")
+	check(st.MaxFreezes >= 0, "streaks.max_freezes must be >= 0")
+
+	hp := r.HP
+	check(hp.Max >= 1, "hp.max must be >= 1")
+	check(hp.DamagePerMiss >= 0 && hp.RegenPerDay >= 0 && hp.HealPerHabit >= 0 && hp.HealPerTask >= 0,
+		"hp.damage_per_miss, regen_per_day, heal_per_habit and heal_per_task must be >= 0")
+	check(hp.KnockoutXPMultiplier >= 0 && hp.KnockoutXPMultiplier <= 1, "hp.knockout_xp_multiplier must be within 0..1")
+	check(hp.KnockoutDays >= 0, "hp.knockout_days must be >= 0")
+
+	check(r.Time.BonusPerHour >= 0 && r.Time.MaxBonus >= 0, "time.bonus_per_hour and time.max_bonus must be >= 0")
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid rules: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Encode writes the rules as TOML.
+func (r *Rules) Encode() (string, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(r); err != nil {
+		return "", fmt.Errorf("encode rules: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// XPRequiredForLevel returns the total XP threshold required to be at the given level.
+// Level 0 requires 0 XP.
+func (r *Rules) XPRequiredForLevel(level int) int {
+	if level <= 0 {
+		return 0
+	}
+	req := r.XP.RequiredCoef * math.Pow(float64(level), r.XP.RequiredExponent)
+	// Use ceil to avoid making thresholds easier due to floating point rounding.
+	return int(math.Ceil(req))
+}
+
+// LevelForTotalXP returns the highest level L such that totalXP >= XPRequiredForLevel(L).
+func (r *Rules) LevelForTotalXP(totalXP int) int {
+	if totalXP <= 0 {
+		return 0
+	}
+
+	// Exponential search upper bound, then binary search.
+	low := 0
+	high := 1
+	for r.XPRequiredForLevel(high) <= totalXP {
+		low = high
+		high *= 2
+		if high > 1_000_000 {
+			break
+		}
+	}
+
+	for low+1 < high {
+		mid := low + (high-low)/2
+		if r.XPRequiredForLevel(mid) <= totalXP {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return low
+}
+
+// AttributeLevelForXP is the same curve as global leveling, applied to attribute-specific XP.
+func (r *Rules) AttributeLevelForXP(attributeXP int) int {
+	return r.LevelForTotalXP(attributeXP)
+}
+
+// PlayerAttrLevel returns the player's level in attr.
+func (r *Rules) PlayerAttrLevel(p *storage.Player, attr Attribute) int {
+	return r.AttributeLevelForXP(p.AttrXP(string(attr)))
+}
+
+func (r *Rules) difficultyMultiplier(d Difficulty) (float64, error) {
+	if !d.IsValid() {
+		return 0, fmt.Errorf("invalid difficulty: %d", d)
+	}
+	return r.XP.DifficultyMultipliers[d-1], nil
+}
+
+// CalculateXP computes task XP and returns an integer XP value.
+// The value is intended to be frozen at task creation time.
+func (r *Rules) CalculateXP(d Difficulty, attributeLevel int) (int, error) {
+	mult, err := r.difficultyMultiplier(d)
+	if err != nil {
+		return 0, err
+	}
+	if attributeLevel < 0 {
+		attributeLevel = 0
+	}
+
+	base := r.XP.TaskBase * mult
+	bonus := 1.0 + float64(attributeLevel)*r.XP.AttributeLevelBonusRate
+	xp := base * bonus
+	// Round to nearest integer for stable results.
+	return int(math.Round(xp)), nil
+}
+
+// DifficultyUnlockLevel returns the player level required for d.
+func (r *Rules) DifficultyUnlockLevel(d Difficulty) int {
+	if !d.IsValid() {
+		return 0
+	}
+	return r.Gates.DifficultyUnlock[d-1]
+}
+
+// MaxDifficultyForLevel returns the highest difficulty available at the given player level.
+func (r *Rules) MaxDifficultyForLevel(level int) Difficulty {
+	max := DifficultyTrivial
+	for d := DifficultyTrivial; d <= DifficultyEpic; d++ {
+		if level >= r.DifficultyUnlockLevel(d) {
+			max = d
+		}
+	}
+	return max
+}
+
+// CanUseDifficulty returns an error if the player level is too low for the requested difficulty.
+func (r *Rules) CanUseDifficulty(level int, difficulty Difficulty) error {
+	if !difficulty.IsValid() {
+		return fmt.Errorf("invalid difficulty: %d", difficulty)
+	}
+	reqLevel := r.DifficultyUnlockLevel(difficulty)
+	if level < reqLevel {
+		return DifficultyGateError{
+			Difficulty:    difficulty,
+			RequiredLevel: reqLevel,
+			CurrentLevel:  level,
+		}
+	}
+	return nil
+}
+
+// MaxActiveTasks returns the maximum number of active tasks allowed at level.
+func (r *Rules) MaxActiveTasks(level int) int {
+	max := r.Gates.MaxActiveTasks[0].Tasks
+	for _, st := range r.Gates.MaxActiveTasks {
+		if level >= st.Level {
+			max = st.Tasks
+		}
+	}
+	return max
+}
+
+// MaxSubtaskDepth returns the maximum allowed depth for subtasks (see the
+// package-level MaxSubtaskDepth).
+func (r *Rules) MaxSubtaskDepth(level int) int {
+	if level < r.Gates.Subtasks {
+		return 0
+	}
+	if level < r.Gates.DeepSubtasks {
+		return 1
+	}
+	return SubtaskDepthUnlimited
+}
+
+func (r *Rules) CanCreateHabit(level int) error {
+	if level < r.Gates.Habits {
+		return GateError{Feature: "habits", RequiredLevel: r.Gates.Habits}
+	}
+	return nil
+}
+
+func (r *Rules) CanCreateProject(level int) error {
+	if level < r.Gates.Projects {
+		return GateError{Feature: "projects", RequiredLevel: r.Gates.Projects}
+	}
+	return nil
+}
+
+func (r *Rules) CanReview(level int) error {
+	if level < r.Gates.Reviews {
+		return GateError{Feature: "reviews", RequiredLevel: r.Gates.Reviews}
+	}
+	return nil
+}
+
+func (r *Rules) CanAttachToParent(level int, requestedDepth int) error {
+	maxDepth := r.MaxSubtaskDepth(level)
+	if maxDepth == SubtaskDepthUnlimited {
+		return nil
+	}
+	if requestedDepth <= 0 {
+		return nil
+	}
+	if maxDepth == 0 {
+		return GateError{Feature: "subtasks", RequiredLevel: r.Gates.Subtasks}
+	}
+	if requestedDepth > maxDepth {
+		return fmt.Errorf("subtask depth %d exceeds max depth %d at level %d", requestedDepth, maxDepth, level)
+	}
+	return nil
+}
+
+// HabitXP applies habit decay: recent is the number of completions at the same
+// difficulty within the decay window. The result is at least 1.
+func (r *Rules) HabitXP(xp int, recent int) int {
+	if recent >= r.Habits.DecayThreshold {
+		xp = int(math.Round(float64(xp) * r.Habits.DecayFactor))
+	}
+	if xp < 1 {
+		xp = 1
+	}
+	return xp
+}
+
+// DeadlineXP adjusts the XP of a task completed at `at` for its due date. It
+// returns the adjusted XP (at least 1) and the change applied, negative for a
+// late penalty.
+func (r *Rules) DeadlineXP(xp int, due *time.Time, at time.Time) (int, int) {
+	if due == nil {
+		return xp, 0
+	}
+	d := r.Deadlines
+	rate := 0.0
+	if at.After(*due) {
+		daysLate := math.Ceil(at.Sub(*due).Hours() / 24)
+		rate = -math.Min(daysLate*d.LatePenaltyPerDay, d.MaxLatePenalty)
+	} else if d.EarlyBonus > 0 && due.Sub(at) >= time.Duration(d.EarlyBonusDays)*24*time.Hour {
+		rate = d.EarlyBonus
+	}
+	if rate == 0 {
+		return xp, 0
+	}
+	adjusted := int(math.Round(float64(xp) * (1 + rate)))
+	if adjusted < 1 {
+		adjusted = 1
+	}
+	return adjusted, adjusted - xp
+}
+
+// StreakXP applies the streak multiplier to a habit's XP for a completion that
+// brings its current streak to streak periods. It returns the adjusted XP and
+// the bonus part of it.
+func (r *Rules) StreakXP(xp, streak int) (int, int) {
+	if streak <= 1 {
+		return xp, 0
+	}
+	rate := math.Min(float64(streak-1)*r.Streaks.BonusPerPeriod, r.Streaks.MaxBonus)
+	adjusted := int(math.Round(float64(xp) * (1 + rate)))
+	return adjusted, adjusted - xp
+}
+
+// TimeXP applies the tracked-time bonus to xp for a task worked on for
+// tracked. It returns the adjusted XP and the bonus part of it.
+func (r *Rules) TimeXP(xp int, tracked time.Duration) (int, int) {
+	if tracked <= 0 || r.Time.BonusPerHour == 0 {
+		return xp, 0
+	}
+	rate := math.Min(tracked.Hours()*r.Time.BonusPerHour, r.Time.MaxBonus)
+	adjusted := int(math.Round(float64(xp) * (1 + rate)))
+	return adjusted, adjusted - xp
+}
+
+// WeakenedXP applies the knockout multiplier to xp. It returns the reduced XP
+// (at least 1 when xp is positive) and how much was lost.
+func (r *Rules) WeakenedXP(xp int) (int, int) {
+	reduced := int(math.Round(float64(xp) * r.HP.KnockoutXPMultiplier))
+	if reduced < 1 && xp > 0 {
+		reduced = 1
+	}
+	return reduced, xp - reduced
+}
+
+// EarnsFreeze reports whether reaching a streak of streak periods earns a
+// freeze token while held tokens are already held.
+func (r *Rules) EarnsFreeze(streak, held int) bool {
+	st := r.Streaks
+	return st.FreezeEvery > 0 && streak > 0 && streak%st.FreezeEvery == 0 && held < st.MaxFreezes
+}
+
+// ProjectBonus returns the completion bonus for a project whose children are
+// worth volume XP.
+func (r *Rules) ProjectBonus(volume int) int {
+	return int(math.Round(float64(volume) * r.XP.ProjectBonusRate))
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func presetRules(t *testing.T) []*Rules {
+	t.Helper()
+	var out []*Rules
+	for _, name := range RulePresets() {
+		r, err := PresetRules(name)
+		if err != nil {
+			t.Fatalf("PresetRules(%q): %v", name, err)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func TestPresetCurvesAreConsistent(t *testing.T) {
+	for _, r := range presetRules(t) {
+		t.Run(r.Preset, func(t *testing.T) {
+			if err := r.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			for l := 1; l <= 30; l++ {
+				req := r.XPRequiredForLevel(l)
+				if req <= r.XPRequiredForLevel(l-1) {
+					t.Fatalf("XPRequiredForLevel(%d)=%d not increasing", l, req)
+				}
+				if got := r.LevelForTotalXP(req); got != l {
+					t.Fatalf("LevelForTotalXP(%d)=%d, want %d", req, got, l)
+				}
+				if got := r.LevelForTotalXP(req - 1); got != l-1 {
+					t.Fatalf("LevelForTotalXP(%d)=%d, want %d", req-1, got, l-1)
+				}
+			}
+		})
+	}
+}
+
+func TestServiceAppliesRules(t *testing.T) {
+	for _, r := range presetRules(t) {
+		t.Run(r.Preset, func(t *testing.T) {
+			svc, cleanup := newTestServiceWithRules(t, r)
+			defer cleanup()
+			ctx := context.Background()
+
+			habit := CreateTaskInput{Title: "Stretch", Difficulty: DifficultyTrivial, Attribute: AttributeSTR, IsHabit: true, HabitInterval: HabitIntervalDaily}
+			setPlayerXP(t, svc, r.XPRequiredForLevel(r.Gates.Habits)-1)
+			var gate GateError
+			if _, err := svc.CreateTask(ctx, habit); !errors.As(err, &gate) || gate.RequiredLevel != r.Gates.Habits {
+				t.Fatalf("CreateTask habit below gate err=%v, want GateError at L%d", err, r.Gates.Habits)
+			}
+
+			setPlayerXP(t, svc, r.XPRequiredForLevel(r.Gates.Habits))
+			h, err := svc.CreateTask(ctx, habit)
+			if err != nil {
+				t.Fatalf("CreateTask habit at gate: %v", err)
+			}
+			task, _ := svc.TaskRepo().Get(ctx, h.TaskID)
+			wantXP, _ := r.CalculateXP(DifficultyTrivial, 0)
+			if task.XPValue != wantXP {
+				t.Fatalf("habit xp_value=%d, want %d", task.XPValue, wantXP)
+			}
+
+			for i := 0; i <= r.Habits.DecayThreshold; i++ {
+				res, err := svc.CompleteTask(ctx, h.TaskID)
+				if err != nil {
+					t.Fatalf("complete #%d: %v", i+1, err)
+				}
+				want := task.XPValue
+				if i == r.Habits.DecayThreshold {
+					want = r.HabitXP(task.XPValue, i)
+				}
+				if res.XPAwarded != want {
+					t.Fatalf("complete #%d xp=%d, want %d", i+1, res.XPAwarded, want)
+				}
+			}
+
+			// The player sits at the habit gate; the next locked difficulty must be refused.
+			if next := r.MaxDifficultyForLevel(r.Gates.Habits) + 1; next.IsValid() {
+				var diffGate DifficultyGateError
+				_, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Too hard", Difficulty: next, Attribute: AttributeSTR})
+				if !errors.As(err, &diffGate) || diffGate.RequiredLevel != r.DifficultyUnlockLevel(next) {
+					t.Fatalf("CreateTask difficulty %d err=%v, want gate at L%d", next, err, r.DifficultyUnlockLevel(next))
+				}
+			}
+		})
+	}
+}
+
+func TestParseRulesOverridesPreset(t *testing.T) {
+	r, err := ParseRules([]byte("preset = \"hardcore\"\n[gates]\nhabits = 2\n"), false)
+	if err != nil {
+		t.Fatalf("ParseRules toml: %v", err)
+	}
+	hard, _ := PresetRules("hardcore")
+	if r.Gates.Habits != 2 || r.XP.RequiredCoef != hard.XP.RequiredCoef || r.Gates.Projects != hard.Gates.Projects {
+		t.Fatalf("toml rules=%+v, want hardcore with habits=2", r)
+	}
+
+	r, err = ParseRules([]byte(`{"habits": {"decay_factor": 1}}`), true)
+	if err != nil {
+		t.Fatalf("ParseRules json: %v", err)
+	}
+	if r.Preset != "default" || r.Habits.DecayFactor != 1 || r.Habits.DecayThreshold != 5 {
+		t.Fatalf("json rules=%+v, want default with decay_factor=1", r)
+	}
+
+	if _, err := ParseRules([]byte("[xp]\ntask_bas = 3\n"), false); err == nil {
+		t.Fatalf("expected unknown key error")
+	}
+	if _, err := ParseRules([]byte("[habits]\ndecay_factor = 2\n"), false); err == nil {
+		t.Fatalf("expected validation error")
+	}
+}
@@ -0,0 +1,404 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"questline/internal/storage"
+)
+
+// ScheduleKind is the shape of a habit schedule.
+type ScheduleKind string
+
+const (
+	ScheduleEveryDays   ScheduleKind = "days"     // every N days
+	ScheduleEveryWeeks  ScheduleKind = "weeks"    // every N weeks, on the anchor's weekday
+	ScheduleEveryMonths ScheduleKind = "months"   // every N months, on the anchor's day of month
+	ScheduleWeekdays    ScheduleKind = "weekdays" // on the listed weekdays
+	ScheduleTimesPer    ScheduleKind = "times"    // N times per week or month, on any days
+	ScheduleNthWeekday  ScheduleKind = "nth"      // the Nth (or last) weekday of each month
+	ScheduleMonthDay    ScheduleKind = "monthday" // a fixed day of each month
+)
+
+// HabitSchedule is a parsed habit interval. Due dates follow the calendar: the
+// next occurrence is computed from the schedule and its anchor, not by adding
+// the interval to the completion time.
+type HabitSchedule struct {
+	Kind     ScheduleKind
+	Every    int            // days, weeks or months between occurrences (Every* kinds)
+	Weekdays []time.Weekday // ScheduleWeekdays, sorted Monday first
+	Times    int            // ScheduleTimesPer
+	Per      HabitInterval  // ScheduleTimesPer: weekly or monthly
+	Nth      int            // ScheduleNthWeekday: 1..4, or -1 for the last one
+	Weekday  time.Weekday   // ScheduleNthWeekday
+	Day      int            // ScheduleMonthDay: 1..31, clamped to short months
+	Anchor   time.Time      // start of the schedule (the habit's start date)
+}
+
+var (
+	reEvery    = regexp.MustCompile(`^every (\d+) ?(d|day|days|w|week|weeks|m|month|months)$`)
+	reTimesPer = regexp.MustCompile(`^(\d+) ?(?:x|times?)? ?(?:/|per|a|an|each) ?(week|month)$`)
+	reNth      = regexp.MustCompile(`^(first|1st|second|2nd|third|3rd|fourth|4th|last) ([a-z]+)(?: of(?: the| each| every)? month)?$`)
+	reMonthDay = regexp.MustCompile(`^(?:day (\d+)|(\d+)(?:st|nd|rd|th)?)(?: of(?: the| each| every)? month)$|^day (\d+)$`)
+)
+
+var nthWords = map[string]int{
+	"first": 1, "1st": 1, "second": 2, "2nd": 2, "third": 3, "3rd": 3, "fourth": 4, "4th": 4, "last": -1,
+}
+
+// ParseHabitSchedule parses a habit interval. Accepted forms:
+//
+//	daily, weekly, monthly, every 2 days, every 3 weeks, every 2 months
+//	mon,wed,fri (also mon/wed/fri), weekdays, weekends
+//	3x/week, 3 times per week, 2x/month
+//	first mon of month, 2nd tue, last fri of month
+//	day 15 of month, 15th of month
+func ParseHabitSchedule(input string) (HabitSchedule, error) {
+	s := strings.Join(strings.Fields(strings.ToLower(input)), " ")
+	bad := func() (HabitSchedule, error) {
+		return HabitSchedule{}, fmt.Errorf("invalid habit interval: %q (try daily, weekly, mon/wed/fri, 3x/week, every 2 days, first mon of month)", input)
+	}
+
+	switch s {
+	case "daily", "every day":
+		return HabitSchedule{Kind: ScheduleEveryDays, Every: 1}, nil
+	case "weekly", "every week":
+		return HabitSchedule{Kind: ScheduleEveryWeeks, Every: 1}, nil
+	case "monthly", "every month":
+		return HabitSchedule{Kind: ScheduleEveryMonths, Every: 1}, nil
+	case "weekdays":
+		return HabitSchedule{Kind: ScheduleWeekdays, Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}}, nil
+	case "weekends":
+		return HabitSchedule{Kind: ScheduleWeekdays, Weekdays: []time.Weekday{time.Saturday, time.Sunday}}, nil
+	}
+
+	if m := reEvery.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		sched := HabitSchedule{Every: n}
+		limit := 0
+		switch m[2][0] {
+		case 'd':
+			sched.Kind, limit = ScheduleEveryDays, 365
+		case 'w':
+			sched.Kind, limit = ScheduleEveryWeeks, 52
+		default:
+			sched.Kind, limit = ScheduleEveryMonths, 12
+		}
+		if n < 1 || n > limit {
+			return HabitSchedule{}, fmt.Errorf("invalid habit interval: %q (every 1..%d %s)", input, limit, sched.Kind)
+		}
+		return sched, nil
+	}
+
+	if m := reTimesPer.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		per, max := HabitIntervalWeekly, 7
+		if m[2] == "month" {
+			per, max = HabitIntervalMonthly, 28
+		}
+		if n < 1 || n > max {
+			return HabitSchedule{}, fmt.Errorf("invalid habit interval: %q (1..%d times per %s)", input, max, m[2])
+		}
+		return HabitSchedule{Kind: ScheduleTimesPer, Times: n, Per: per}, nil
+	}
+
+	if m := reNth.FindStringSubmatch(s); m != nil {
+		if wd, ok := weekdays[m[2]]; ok {
+			return HabitSchedule{Kind: ScheduleNthWeekday, Nth: nthWords[m[1]], Weekday: wd}, nil
+		}
+		return bad()
+	}
+
+	if m := reMonthDay.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1] + m[2] + m[3])
+		if n < 1 || n > 31 {
+			return HabitSchedule{}, fmt.Errorf("invalid habit interval: %q (day 1..31)", input)
+		}
+		return HabitSchedule{Kind: ScheduleMonthDay, Day: n}, nil
+	}
+
+	// A list of weekday names.
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '/' || r == ' ' || r == '+' })
+	seen := map[time.Weekday]bool{}
+	var days []time.Weekday
+	for _, p := range parts {
+		wd, ok := weekdays[p]
+		if !ok {
+			return bad()
+		}
+		if !seen[wd] {
+			seen[wd] = true
+			days = append(days, wd)
+		}
+	}
+	if len(days) == 0 {
+		return bad()
+	}
+	sort.Slice(days, func(i, j int) bool { return mondayFirst(days[i]) < mondayFirst(days[j]) })
+	return HabitSchedule{Kind: ScheduleWeekdays, Weekdays: days}, nil
+}
+
+// ScheduleForTask parses a habit's interval and anchors it at the habit's
+// start date (its creation time for older habits).
+func ScheduleForTask(task *storage.Task) (HabitSchedule, error) {
+	if task.HabitInterval == nil {
+		return HabitSchedule{}, fmt.Errorf("habit %d is missing interval", task.ID)
+	}
+	sched, err := ParseHabitSchedule(*task.HabitInterval)
+	if err != nil {
+		return HabitSchedule{}, err
+	}
+	sched.Anchor = task.CreatedAt
+	if task.HabitStartDate != nil {
+		sched.Anchor = *task.HabitStartDate
+	}
+	return sched, nil
+}
+
+var shortDays = [...]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// String returns the canonical form, which is what gets stored.
+func (s HabitSchedule) String() string {
+	unit := func(n int, one, many string) string {
+		if n == 1 {
+			return one
+		}
+		return fmt.Sprintf("every %d %s", n, many)
+	}
+	switch s.Kind {
+	case ScheduleEveryDays:
+		return unit(s.Every, "daily", "days")
+	case ScheduleEveryWeeks:
+		return unit(s.Every, "weekly", "weeks")
+	case ScheduleEveryMonths:
+		return unit(s.Every, "monthly", "months")
+	case ScheduleWeekdays:
+		names := make([]string, 0, len(s.Weekdays))
+		for _, d := range s.Weekdays {
+			names = append(names, shortDays[d])
+		}
+		return strings.Join(names, ",")
+	case ScheduleTimesPer:
+		per := "week"
+		if s.Per == HabitIntervalMonthly {
+			per = "month"
+		}
+		return fmt.Sprintf("%dx/%s", s.Times, per)
+	case ScheduleNthWeekday:
+		nth := map[int]string{1: "1st", 2: "2nd", 3: "3rd", 4: "4th", -1: "last"}[s.Nth]
+		return fmt.Sprintf("%s %s of month", nth, shortDays[s.Weekday])
+	case ScheduleMonthDay:
+		return fmt.Sprintf("day %d of month", s.Day)
+	}
+	return string(s.Kind)
+}
+
+// monthly reports whether the schedule's periods are months.
+func (s HabitSchedule) monthly() bool {
+	switch s.Kind {
+	case ScheduleEveryMonths, ScheduleNthWeekday, ScheduleMonthDay:
+		return true
+	case ScheduleTimesPer:
+		return s.Per == HabitIntervalMonthly
+	}
+	return false
+}
+
+func (s HabitSchedule) every() int {
+	switch s.Kind {
+	case ScheduleEveryDays, ScheduleEveryWeeks, ScheduleEveryMonths:
+		if s.Every > 0 {
+			return s.Every
+		}
+	}
+	return 1
+}
+
+// periodStart returns the start of the period containing t, in t's location.
+// Periods are N-day blocks for day schedules, Monday-to-Sunday weeks (or
+// N-week blocks) for week schedules and calendar months (or N-month blocks)
+// for month schedules; blocks are aligned to the anchor.
+func (s HabitSchedule) periodStart(t time.Time) time.Time {
+	loc := t.Location()
+	anchor := s.Anchor.In(loc)
+	n := s.every()
+	switch {
+	case s.Kind == ScheduleEveryDays:
+		idx := dayIndex(t)
+		return dayFromIndex(idx-floorMod(idx-dayIndex(anchor), n), loc)
+	case s.monthly():
+		y, m, _ := t.Date()
+		idx := y*12 + int(m) - 1
+		ay, am, _ := anchor.Date()
+		idx -= floorMod(idx-(ay*12+int(am)-1), n)
+		return time.Date(idx/12, time.Month(idx%12+1), 1, 0, 0, 0, 0, loc)
+	default:
+		monday := dayIndex(t) - mondayFirst(t.Weekday())
+		anchorMonday := dayIndex(anchor) - mondayFirst(anchor.Weekday())
+		weeks := (monday - anchorMonday) / 7
+		return dayFromIndex(monday-7*floorMod(weeks, n), loc)
+	}
+}
+
+func (s HabitSchedule) nextPeriod(p time.Time) time.Time {
+	n := s.every()
+	switch {
+	case s.Kind == ScheduleEveryDays:
+		return p.AddDate(0, 0, n)
+	case s.monthly():
+		return p.AddDate(0, n, 0)
+	default:
+		return p.AddDate(0, 0, 7*n)
+	}
+}
+
+// target is how many completions the period starting at p asks for. Weekday
+// schedules only count the scheduled days from the anchor on.
+func (s HabitSchedule) target(p time.Time) int {
+	switch s.Kind {
+	case ScheduleTimesPer:
+		return s.Times
+	case ScheduleWeekdays:
+		from := dayIndex(s.Anchor.In(p.Location()))
+		n := 0
+		for d := p; d.Before(s.nextPeriod(p)); d = d.AddDate(0, 0, 1) {
+			if dayIndex(d) >= from && s.occursOn(d) {
+				n++
+			}
+		}
+		if n == 0 {
+			return 1
+		}
+		return n
+	}
+	return 1
+}
+
+// occursOn reports whether the habit is scheduled on day. Times-per-period
+// schedules have no fixed days.
+func (s HabitSchedule) occursOn(day time.Time) bool {
+	anchor := s.Anchor.In(day.Location())
+	switch s.Kind {
+	case ScheduleEveryDays:
+		return floorMod(dayIndex(day)-dayIndex(anchor), s.every()) == 0
+	case ScheduleEveryWeeks:
+		return day.Weekday() == anchor.Weekday() && floorMod((dayIndex(day)-dayIndex(anchor))/7, s.every()) == 0
+	case ScheduleEveryMonths:
+		months := (day.Year()*12 + int(day.Month())) - (anchor.Year()*12 + int(anchor.Month()))
+		return day.Day() == clampDay(day, anchor.Day()) && floorMod(months, s.every()) == 0
+	case ScheduleWeekdays:
+		for _, wd := range s.Weekdays {
+			if day.Weekday() == wd {
+				return true
+			}
+		}
+	case ScheduleNthWeekday:
+		if day.Weekday() != s.Weekday {
+			return false
+		}
+		if s.Nth < 0 {
+			return day.AddDate(0, 0, 7).Month() != day.Month()
+		}
+		return (day.Day()-1)/7+1 == s.Nth
+	case ScheduleMonthDay:
+		return day.Day() == clampDay(day, s.Day)
+	}
+	return false
+}
+
+// FirstDue returns the first due date on or after now: the end of the first
+// scheduled day, or of the current period for times-per-period schedules.
+func (s HabitSchedule) FirstDue(now time.Time) time.Time {
+	return s.dueFrom(now, true, 0)
+}
+
+// NextDue returns the due date after a completion at now. doneInPeriod is the
+// number of completions in now's period including this one; times-per-period
+// schedules stay due in the current period until it reaches the target.
+func (s HabitSchedule) NextDue(now time.Time, doneInPeriod int) time.Time {
+	return s.dueFrom(now, false, doneInPeriod)
+}
+
+func (s HabitSchedule) dueFrom(now time.Time, inclusive bool, doneInPeriod int) time.Time {
+	if s.Kind == ScheduleTimesPer {
+		p := s.periodStart(now)
+		if doneInPeriod >= s.Times {
+			p = s.nextPeriod(p)
+		}
+		return endOfDay(s.nextPeriod(p).AddDate(0, 0, -1))
+	}
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if !inclusive {
+		day = day.AddDate(0, 0, 1)
+	}
+	// Every schedule occurs within a little over a year.
+	for i := 0; i < 800; i++ {
+		if s.occursOn(day) {
+			return endOfDay(day)
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return endOfDay(day)
+}
+
+// missed counts the occurrences from the one due at due onwards that ended
+// after after and before until: scheduled days, or whole periods for
+// times-per-period schedules.
+func (s HabitSchedule) missed(due, after, until time.Time) int {
+	loc := until.Location()
+	due = due.In(loc)
+	n := 0
+	if s.Kind == ScheduleTimesPer {
+		for p := s.periodStart(due); ; p = s.nextPeriod(p) {
+			end := endOfDay(s.nextPeriod(p).AddDate(0, 0, -1))
+			if !end.Before(until) {
+				return n
+			}
+			if end.After(after) {
+				n++
+			}
+		}
+	}
+	for d := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, loc); ; d = d.AddDate(0, 0, 1) {
+		end := endOfDay(d)
+		if !end.Before(until) {
+			return n
+		}
+		if end.After(after) && s.occursOn(d) {
+			n++
+		}
+	}
+}
+
+// mondayFirst numbers weekdays from Monday (0) to Sunday (6).
+func mondayFirst(wd time.Weekday) int {
+	return (int(wd) + 6) % 7
+}
+
+// dayIndex numbers calendar days, ignoring the time of day and DST.
+func dayIndex(t time.Time) int {
+	y, m, d := t.Date()
+	return int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+func dayFromIndex(i int, loc *time.Location) time.Time {
+	u := time.Unix(int64(i)*86400, 0).UTC()
+	return time.Date(u.Year(), u.Month(), u.Day(), 0, 0, 0, 0, loc)
+}
+
+func floorMod(a, n int) int {
+	return ((a % n) + n) % n
+}
+
+// clampDay limits a day of month to the length of t's month.
+func clampDay(t time.Time, day int) int {
+	last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	if day > last {
+		return last
+	}
+	return day
+}
@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"questline/internal/storage"
+)
+
+func TestParseHabitSchedule(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"daily", "daily"},
+		{"Every Day", "daily"},
+		{"weekly", "weekly"},
+		{"monthly", "monthly"},
+		{"every 2 days", "every 2 days"},
+		{"every 3w", "every 3 weeks"},
+		{"every 1 month", "monthly"},
+		{"fri/mon/wed", "mon,wed,fri"},
+		{"Monday, Thursday", "mon,thu"},
+		{"weekdays", "mon,tue,wed,thu,fri"},
+		{"weekends", "sat,sun"},
+		{"3x/week", "3x/week"},
+		{"3 times per week", "3x/week"},
+		{"2 times a month", "2x/month"},
+		{"first monday of the month", "1st mon of month"},
+		{"2nd tue", "2nd tue of month"},
+		{"last fri of month", "last fri of month"},
+		{"day 15 of month", "day 15 of month"},
+		{"31st of the month", "day 31 of month"},
+	}
+	for _, tt := range tests {
+		got, err := ParseHabitInterval(tt.in)
+		if err != nil {
+			t.Fatalf("ParseHabitInterval(%q): %v", tt.in, err)
+		}
+		if string(got) != tt.want {
+			t.Fatalf("ParseHabitInterval(%q)=%q, want %q", tt.in, got, tt.want)
+		}
+		// The canonical form parses to itself.
+		if again, err := ParseHabitInterval(string(got)); err != nil || again != got {
+			t.Fatalf("ParseHabitInterval(%q)=%q, %v; not canonical", got, again, err)
+		}
+	}
+
+	for _, in := range []string{"", "hourly", "every 0 days", "8x/week", "5th mon", "day 32", "mon,funday"} {
+		if _, err := ParseHabitSchedule(in); err == nil {
+			t.Fatalf("ParseHabitSchedule(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestScheduleDueDatesFollowCalendar(t *testing.T) {
+	// Anchored on Thursday 2026-10-01.
+	anchor := time.Date(2026, 10, 1, 9, 0, 0, 0, time.Local)
+	eod := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 23, 59, 59, 0, time.Local) }
+	at := func(m time.Month, d, hour int) time.Time { return time.Date(2026, m, d, hour, 0, 0, 0, time.Local) }
+
+	tests := []struct {
+		spec  string
+		now   time.Time
+		done  int
+		first time.Time
+		next  time.Time
+	}{
+		// Completing late (23:00) does not push the schedule.
+		{"daily", at(10, 16, 23), 1, eod(10, 16), eod(10, 17)},
+		{"every 2 days", at(10, 16, 8), 1, eod(10, 17), eod(10, 17)},
+		{"every 2 days", at(10, 17, 22), 1, eod(10, 17), eod(10, 19)},
+		{"weekly", at(10, 16, 8), 1, eod(10, 22), eod(10, 22)},
+		{"every 2 weeks", at(10, 16, 8), 1, eod(10, 29), eod(10, 29)},
+		{"monthly", at(10, 16, 8), 1, eod(11, 1), eod(11, 1)},
+		{"mon,wed,fri", at(10, 16, 8), 1, eod(10, 16), eod(10, 19)},
+		{"mon,wed,fri", at(10, 17, 8), 1, eod(10, 19), eod(10, 19)},
+		{"3x/week", at(10, 16, 8), 2, eod(10, 18), eod(10, 18)},
+		{"3x/week", at(10, 16, 8), 3, eod(10, 18), eod(10, 25)},
+		{"2x/month", at(10, 16, 8), 2, eod(10, 31), eod(11, 30)},
+		{"1st mon of month", at(10, 16, 8), 1, eod(11, 2), eod(11, 2)},
+		{"last fri of month", at(10, 16, 8), 1, eod(10, 30), eod(10, 30)},
+		{"last fri of month", at(10, 30, 8), 1, eod(10, 30), eod(11, 27)},
+		{"day 31 of month", at(11, 1, 8), 1, eod(11, 30), eod(11, 30)},
+	}
+	for _, tt := range tests {
+		sched, err := ParseHabitSchedule(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseHabitSchedule(%q): %v", tt.spec, err)
+		}
+		sched.Anchor = anchor
+		if got := sched.FirstDue(tt.now); !got.Equal(tt.first) {
+			t.Fatalf("%s: FirstDue(%v)=%v, want %v", tt.spec, tt.now, got, tt.first)
+		}
+		if got := sched.NextDue(tt.now, tt.done); !got.Equal(tt.next) {
+			t.Fatalf("%s: NextDue(%v, %d)=%v, want %v", tt.spec, tt.now, tt.done, got, tt.next)
+		}
+	}
+}
+
+func TestScheduleStreaksAndProgress(t *testing.T) {
+	// Friday 2026-10-16; the habit started on Monday 2026-09-28.
+	now := time.Date(2026, 10, 16, 20, 0, 0, 0, time.Local)
+	start := time.Date(2026, 9, 28, 8, 0, 0, 0, time.Local)
+	on := func(days ...int) []storage.TaskCompletion {
+		var out []storage.TaskCompletion
+		for _, d := range days {
+			out = append(out, storage.TaskCompletion{CompletedAt: time.Date(2026, 10, d, 12, 0, 0, 0, time.Local)})
+		}
+		return out
+	}
+	task := func(spec string) *storage.Task {
+		return &storage.Task{ID: 1, IsHabit: true, HabitInterval: &spec, CreatedAt: start, HabitStartDate: &start}
+	}
+
+	tests := []struct {
+		spec   string
+		comps  []storage.TaskCompletion
+		streak HabitStreak
+		sched  int // HabitProgress.Scheduled
+		period [2]int
+	}{
+		// Weeks of Sep 28, Oct 5 and Oct 12: three times each, the current week in progress.
+		{"3x/week", on(1, 2, 3, 5, 7, 9, 12, 14), HabitStreak{Current: 2, Longest: 2}, 9, [2]int{2, 3}},
+		// Mon/Wed/Fri: the week of Oct 5 misses Friday.
+		{"mon,wed,fri", on(12, 14, 16, 5, 7), HabitStreak{Current: 1, Longest: 1, DoneThisPeriod: true}, 9, [2]int{3, 3}},
+		// Every 2 days from Sep 28: blocks start Oct 14 and Oct 16.
+		{"every 2 days", on(12, 14, 16), HabitStreak{Current: 3, Longest: 3, DoneThisPeriod: true}, 10, [2]int{1, 1}},
+	}
+	for _, tt := range tests {
+		tk := task(tt.spec)
+		sched, err := ScheduleForTask(tk)
+		if err != nil {
+			t.Fatalf("ScheduleForTask(%q): %v", tt.spec, err)
+		}
+		got := ComputeStreak(sched, tt.comps, nil, now)
+		got.gap, got.runBeforeGap = nil, 0
+		if got.Current != tt.streak.Current || got.Longest != tt.streak.Longest || got.DoneThisPeriod != tt.streak.DoneThisPeriod {
+			t.Fatalf("%s: streak=%+v, want %+v", tt.spec, got, tt.streak)
+		}
+		p := GetHabitProgress(tk, tt.comps, now)
+		if p.Scheduled != tt.sched || p.PeriodDone != tt.period[0] || p.PeriodTarget != tt.period[1] {
+			t.Fatalf("%s: progress scheduled=%d period=%d/%d, want %d %d/%d", tt.spec, p.Scheduled, p.PeriodDone, p.PeriodTarget, tt.sched, tt.period[0], tt.period[1])
+		}
+	}
+}
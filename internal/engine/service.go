@@ -11,27 +11,85 @@ import (
 )
 
 type Service struct {
-	db          *sql.DB
-	players     *storage.PlayerRepo
-	tasks       *storage.TaskRepo
-	completions *storage.CompletionRepo
-	blueprints  *storage.BlueprintRepo
+	db           *sql.DB
+	tx           *sql.Tx // non-nil when the service is bound to a transaction
+	players      *storage.PlayerRepo
+	tasks        *storage.TaskRepo
+	completions  *storage.CompletionRepo
+	blueprints   *storage.BlueprintRepo
+	ledger       *storage.LedgerRepo
+	attributes   *storage.AttributeRepo
+	freezes      *storage.FreezeRepo
+	hp           *storage.HPRepo
+	achievements *storage.AchievementRepo
+	dependencies *storage.DependencyRepo
+	tags         *storage.TagRepo
+	reviews      *storage.ReviewRepo
+	sessions     *storage.SessionRepo
+	rules        *Rules
+
+	// userBlueprints are the blueprints loaded from files, after the built-in ones.
+	userBlueprints []BlueprintDef
+	// userAchievements are the achievements loaded from files, after the built-in ones.
+	userAchievements []AchievementDef
+
+	// wrapTx lets tests intercept statements run inside transactions.
+	wrapTx func(storage.DBTX) storage.DBTX
 }
 
+// NewService returns a service using the default rules.
 func NewService(db *sql.DB) *Service {
-	return &Service{
-		db:          db,
-		players:     storage.NewPlayerRepo(db),
-		tasks:       storage.NewTaskRepo(db),
-		completions: storage.NewCompletionRepo(db),
-		blueprints:  storage.NewBlueprintRepo(db),
+	return NewServiceWithRules(db, DefaultRules())
+}
+
+// NewServiceWithRules returns a service that plays by rules.
+func NewServiceWithRules(db *sql.DB, rules *Rules) *Service {
+	s := &Service{db: db, rules: rules}
+	s.bind(db)
+	return s
+}
+
+// bind points every repo at conn.
+func (s *Service) bind(conn storage.DBTX) {
+	s.players = storage.NewPlayerRepo(conn)
+	s.tasks = storage.NewTaskRepo(conn)
+	s.completions = storage.NewCompletionRepo(conn)
+	s.blueprints = storage.NewBlueprintRepo(conn)
+	s.ledger = storage.NewLedgerRepo(conn)
+	s.attributes = storage.NewAttributeRepo(conn)
+	s.freezes = storage.NewFreezeRepo(conn)
+	s.hp = storage.NewHPRepo(conn)
+	s.achievements = storage.NewAchievementRepo(conn)
+	s.dependencies = storage.NewDependencyRepo(conn)
+	s.tags = storage.NewTagRepo(conn)
+	s.reviews = storage.NewReviewRepo(conn)
+	s.sessions = storage.NewSessionRepo(conn)
+}
+
+// inTx runs fn with a copy of the service whose repos share a single transaction.
+// If the service is already bound to a transaction, fn joins it, so multi-step
+// operations that call each other still commit or roll back as one unit.
+func (s *Service) inTx(ctx context.Context, fn func(tx *Service) error) error {
+	if s.tx != nil {
+		return fn(s)
 	}
+	return storage.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		var conn storage.DBTX = tx
+		if s.wrapTx != nil {
+			conn = s.wrapTx(conn)
+		}
+		txs := &Service{db: s.db, tx: tx, rules: s.rules, userBlueprints: s.userBlueprints, userAchievements: s.userAchievements, wrapTx: s.wrapTx}
+		txs.bind(conn)
+		return fn(txs)
+	})
 }
 
 func (s *Service) PlayerRepo() *storage.PlayerRepo         { return s.players }
 func (s *Service) TaskRepo() *storage.TaskRepo             { return s.tasks }
 func (s *Service) CompletionRepo() *storage.CompletionRepo { return s.completions }
 func (s *Service) BlueprintRepo() *storage.BlueprintRepo   { return s.blueprints }
+func (s *Service) LedgerRepo() *storage.LedgerRepo         { return s.ledger }
+func (s *Service) Rules() *Rules                           { return s.rules }
 
 func normalizeTitle(title string) (string, error) {
 	t := strings.TrimSpace(title)
@@ -46,7 +104,7 @@ func (s *Service) getPlayer(ctx context.Context) (*storage.Player, error) {
 	if err != nil {
 		return nil, err
 	}
-	computed := LevelForTotalXP(p.XPTotal)
+	computed := s.rules.LevelForTotalXP(p.XPTotal)
 	if p.Level != computed {
 		p.Level = computed
 		if err := s.players.Update(ctx, p); err != nil {
@@ -57,18 +115,7 @@ func (s *Service) getPlayer(ctx context.Context) (*storage.Player, error) {
 }
 
 func playerXPForAttribute(p *storage.Player, attr Attribute) int {
-	switch attr {
-	case AttributeSTR:
-		return p.XPStr
-	case AttributeINT:
-		return p.XPInt
-	case AttributeART:
-		return p.XPArt
-	case AttributeWIS:
-		fallthrough
-	default:
-		return p.XPWis
-	}
+	return p.AttrXP(string(attr))
 }
 
 func (s *Service) countActiveLeafTasks(ctx context.Context) (int, error) {
@@ -104,7 +151,7 @@ func (s *Service) taskDepthFromRoot(ctx context.Context, id int64) (int, error)
 			return 0, err
 		}
 		if t == nil {
-			return 0, fmt.Errorf("task %d not found", cur)
+			return 0, fmt.Errorf("task %d %w", cur, ErrNotFound)
 		}
 		if t.ParentID == nil {
 			return depth, nil
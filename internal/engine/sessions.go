@@ -0,0 +1,321 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"questline/internal/storage"
+)
+
+// SessionKind is how a work session was tracked.
+type SessionKind string
+
+const (
+	SessionTimer SessionKind = "timer" // ql start / ql stop, or the board
+	SessionFocus SessionKind = "focus" // one pomodoro work interval
+)
+
+// ErrNoTimer is returned when stopping while no timer runs.
+var ErrNoTimer = errors.New("no timer is running")
+
+// TimerResult is a started or stopped work session.
+type TimerResult struct {
+	Session      storage.TaskSession
+	Task         storage.Task
+	Stopped      *storage.TaskSession // The session a start ended, if another one was running
+	Tracked      time.Duration        // Total time tracked on the task so far
+	Achievements []Achievement
+}
+
+// StartTimer starts tracking time on a task. A timer already running, on this
+// task or another, is stopped first: only one runs at a time.
+func (s *Service) StartTimer(ctx context.Context, taskID int64, kind SessionKind, now time.Time) (*TimerResult, error) {
+	var res *TimerResult
+	err := s.inTx(ctx, func(tx *Service) error {
+		task, err := tx.tasks.Get(ctx, taskID)
+		if err != nil {
+			return err
+		}
+		if task == nil {
+			return fmt.Errorf("task %d %w", taskID, ErrNotFound)
+		}
+		if task.IsProject {
+			return fmt.Errorf("task %d is a project; track time on its tasks", taskID)
+		}
+		if task.Status == "done" {
+			return fmt.Errorf("task %d is already done", taskID)
+		}
+		res = &TimerResult{Task: *task}
+		if res.Stopped, err = tx.stopTimer(ctx, now); err != nil {
+			return err
+		}
+		res.Session = storage.TaskSession{TaskID: taskID, Kind: string(kind), StartedAt: now.UTC()}
+		if res.Session.ID, err = tx.sessions.Insert(ctx, res.Session); err != nil {
+			return err
+		}
+		res.Tracked, err = tx.trackedSince(ctx, taskID, time.Time{}, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// StopTimer stops the running timer. It returns ErrNoTimer when none runs.
+func (s *Service) StopTimer(ctx context.Context, now time.Time) (*TimerResult, error) {
+	var res *TimerResult
+	err := s.inTx(ctx, func(tx *Service) error {
+		stopped, err := tx.stopTimer(ctx, now)
+		if err != nil {
+			return err
+		}
+		if stopped == nil {
+			return ErrNoTimer
+		}
+		res = &TimerResult{Session: *stopped}
+		task, err := tx.tasks.GetIncludingDeleted(ctx, stopped.TaskID)
+		if err != nil {
+			return err
+		}
+		if task != nil {
+			res.Task = *task
+		}
+		if res.Tracked, err = tx.trackedSince(ctx, stopped.TaskID, time.Time{}, now); err != nil {
+			return err
+		}
+		res.Achievements, err = tx.recordAchievements(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// stopTimer ends the running session at now and returns it, or nil when no
+// timer runs.
+func (s *Service) stopTimer(ctx context.Context, now time.Time) (*storage.TaskSession, error) {
+	running, err := s.sessions.Running(ctx)
+	if err != nil || running == nil {
+		return nil, err
+	}
+	end := now.UTC()
+	if end.Before(running.StartedAt) {
+		end = running.StartedAt
+	}
+	if err := s.sessions.Stop(ctx, running.ID, end); err != nil {
+		return nil, err
+	}
+	running.EndedAt = &end
+	return running, nil
+}
+
+// RunningTimer returns the running session, or nil.
+func (s *Service) RunningTimer(ctx context.Context) (*storage.TaskSession, error) {
+	return s.sessions.Running(ctx)
+}
+
+// TaskSessions returns every work session, oldest first.
+func (s *Service) TaskSessions(ctx context.Context) ([]storage.TaskSession, error) {
+	return s.sessions.ListAll(ctx)
+}
+
+// TrackedTime returns the time tracked on each task, counting a running timer
+// up to now.
+func (s *Service) TrackedTime(ctx context.Context, now time.Time) (map[int64]time.Duration, error) {
+	all, err := s.sessions.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := map[int64]time.Duration{}
+	for i := range all {
+		out[all[i].TaskID] += all[i].Duration(now)
+	}
+	return out, nil
+}
+
+// trackedSince returns the time tracked on a task in sessions started at or
+// after since.
+func (s *Service) trackedSince(ctx context.Context, taskID int64, since, now time.Time) (time.Duration, error) {
+	list, err := s.sessions.ListByTask(ctx, taskID)
+	if err != nil {
+		return 0, err
+	}
+	var total time.Duration
+	for i := range list {
+		if !list[i].StartedAt.Before(since) {
+			total += list[i].Duration(now)
+		}
+	}
+	return total, nil
+}
+
+// settleTimer stops the timer if it runs on task and returns the time tracked
+// towards its completion at now: everything since the last completion.
+func (s *Service) settleTimer(ctx context.Context, task *storage.Task, now time.Time) (time.Duration, error) {
+	running, err := s.sessions.Running(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if running != nil && running.TaskID == task.ID {
+		if _, err := s.stopTimer(ctx, now); err != nil {
+			return 0, err
+		}
+	}
+	var since time.Time
+	last, err := s.completions.Last(ctx, task.ID)
+	if err != nil {
+		return 0, err
+	}
+	if last != nil {
+		since = last.CompletedAt
+	}
+	return s.trackedSince(ctx, task.ID, since, now)
+}
+
+// TimeAttr is the time tracked for one attribute. Tasks crediting several
+// attributes split their time like their XP.
+type TimeAttr struct {
+	Attr     Attribute
+	Time     time.Duration
+	Sessions int
+}
+
+// TaskTime is the time tracked on one task.
+type TaskTime struct {
+	Task     storage.Task
+	Time     time.Duration
+	Sessions int
+}
+
+// TimeReport sums the time tracked between Since and Until.
+type TimeReport struct {
+	Since      time.Time // Zero for all time
+	Until      time.Time
+	Total      time.Duration
+	Focus      int        // Pomodoro focus intervals
+	Attributes []TimeAttr // Most time first
+	Tasks      []TaskTime // Most time first
+	Running    *storage.TaskSession
+}
+
+// TimeReport reports the time tracked between since and now. Sessions are
+// clipped to the span; a running timer counts up to now.
+func (s *Service) TimeReport(ctx context.Context, since, now time.Time) (*TimeReport, error) {
+	all, err := s.sessions.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.tasks.ListAllIncludingDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*storage.Task, len(tasks))
+	for i := range tasks {
+		byID[tasks[i].ID] = &tasks[i]
+	}
+
+	rep := &TimeReport{Since: since, Until: now}
+	attrs := map[Attribute]*TimeAttr{}
+	perTask := map[int64]*TaskTime{}
+	for i := range all {
+		sess := all[i]
+		if sess.EndedAt == nil {
+			rep.Running = &all[i]
+		}
+		if sess.StartedAt.Before(since) {
+			sess.StartedAt = since
+		}
+		d := sess.Duration(now)
+		t := byID[sess.TaskID]
+		if d <= 0 || t == nil {
+			continue
+		}
+		rep.Total += d
+		if sess.Kind == string(SessionFocus) {
+			rep.Focus++
+		}
+		tt := perTask[t.ID]
+		if tt == nil {
+			tt = &TaskTime{Task: *t}
+			perTask[t.ID] = tt
+		}
+		tt.Time += d
+		tt.Sessions++
+		for _, sh := range splitXP(int(d/time.Second), parseStoredAttribute(t.Attribute), t.Attributes) {
+			a := attrs[sh.Attr]
+			if a == nil {
+				a = &TimeAttr{Attr: sh.Attr}
+				attrs[sh.Attr] = a
+			}
+			a.Time += time.Duration(sh.Amount) * time.Second
+			a.Sessions++
+		}
+	}
+	for _, a := range attrs {
+		rep.Attributes = append(rep.Attributes, *a)
+	}
+	sort.Slice(rep.Attributes, func(i, j int) bool {
+		if rep.Attributes[i].Time != rep.Attributes[j].Time {
+			return rep.Attributes[i].Time > rep.Attributes[j].Time
+		}
+		return rep.Attributes[i].Attr < rep.Attributes[j].Attr
+	})
+	for _, tt := range perTask {
+		rep.Tasks = append(rep.Tasks, *tt)
+	}
+	sort.Slice(rep.Tasks, func(i, j int) bool {
+		if rep.Tasks[i].Time != rep.Tasks[j].Time {
+			return rep.Tasks[i].Time > rep.Tasks[j].Time
+		}
+		return rep.Tasks[i].Task.ID < rep.Tasks[j].Task.ID
+	})
+	return rep, nil
+}
+
+// TimeReportSince parses the span of a time report: today, week (since
+// Monday), month (since the 1st) or all.
+func TimeReportSince(span string, now time.Time) (time.Time, error) {
+	switch strings.ToLower(strings.TrimSpace(span)) {
+	case "today", "day":
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), nil
+	case "week", "":
+		return ReviewWeek.calendarStart(now), nil
+	case "month":
+		return ReviewMonth.calendarStart(now), nil
+	case "all":
+		return time.Time{}, nil
+	}
+	return time.Time{}, fmt.Errorf("unknown span %q (today, week, month or all)", span)
+}
+
+// Pomodoro is a focus rhythm: Work minutes on the task, then a Break.
+type Pomodoro struct {
+	Work  time.Duration
+	Break time.Duration
+}
+
+// ParsePomodoro parses "25/5" (minutes of work and break) or durations such
+// as "50m/10m".
+func ParsePomodoro(input string) (Pomodoro, error) {
+	work, brk, ok := strings.Cut(strings.TrimSpace(input), "/")
+	parse := func(s string) (time.Duration, error) {
+		s = strings.TrimSpace(s)
+		if n, err := strconv.Atoi(s); err == nil {
+			return time.Duration(n) * time.Minute, nil
+		}
+		return time.ParseDuration(s)
+	}
+	w, err1 := parse(work)
+	b, err2 := parse(brk)
+	if !ok || err1 != nil || err2 != nil || w <= 0 || b < 0 {
+		return Pomodoro{}, fmt.Errorf("invalid pomodoro %q (work/break minutes, e.g. 25/5)", input)
+	}
+	return Pomodoro{Work: w, Break: b}, nil
+}
@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimerTracksReportsAndPaysOnCompletion(t *testing.T) {
+	rules := DefaultRules()
+	rules.Time = TimeRules{BonusPerHour: 0.10, MaxBonus: 0.25}
+	svc, cleanup := newTestServiceWithRules(t, rules)
+	defer cleanup()
+	ctx := context.Background()
+
+	create := func(in CreateTaskInput) int64 {
+		t.Helper()
+		res, err := svc.CreateTask(ctx, in)
+		if err != nil {
+			t.Fatalf("CreateTask %s: %v", in.Title, err)
+		}
+		return res.TaskID
+	}
+	novel := create(CreateTaskInput{Title: "Read a novel", Difficulty: DifficultyTrivial, Attribute: AttributeREAD})
+	essay := create(CreateTaskInput{Title: "Write an essay", Difficulty: DifficultyTrivial, Attribute: AttributeINT,
+		Attributes: map[Attribute]int{AttributeINT: 50, AttributeREAD: 50}})
+	scratch := create(CreateTaskInput{Title: "Scratch", Difficulty: DifficultyTrivial, Attribute: AttributeINT})
+
+	// Only one timer runs: starting the essay stops the novel.
+	now := time.Now()
+	t0 := now.Add(-12 * time.Hour)
+	if _, err := svc.StartTimer(ctx, novel, SessionTimer, t0); err != nil {
+		t.Fatalf("StartTimer: %v", err)
+	}
+	res, err := svc.StartTimer(ctx, essay, SessionFocus, t0.Add(11*time.Hour))
+	if err != nil {
+		t.Fatalf("StartTimer: %v", err)
+	}
+	if res.Stopped == nil || res.Stopped.TaskID != novel || res.Stopped.Duration(now) != 11*time.Hour {
+		t.Fatalf("stopped=%+v, want the novel after 11h", res.Stopped)
+	}
+	stopped, err := svc.StopTimer(ctx, t0.Add(12*time.Hour))
+	if err != nil {
+		t.Fatalf("StopTimer: %v", err)
+	}
+	if stopped.Session.TaskID != essay || stopped.Tracked != time.Hour {
+		t.Fatalf("stopped=%+v, want the essay after 1h", stopped)
+	}
+	earned := map[string]bool{}
+	for _, a := range stopped.Achievements {
+		earned[a.ID] = true
+	}
+	if !earned["page_turner"] || earned["deep_focus"] {
+		t.Fatalf("achievements=%+v, want page_turner only", stopped.Achievements)
+	}
+	if _, err := svc.StopTimer(ctx, now); !errors.Is(err, ErrNoTimer) {
+		t.Fatalf("StopTimer with no timer err=%v, want ErrNoTimer", err)
+	}
+
+	// The essay's hour splits between INT and READ like its XP.
+	rep, err := svc.TimeReport(ctx, t0.Add(time.Hour), now)
+	if err != nil {
+		t.Fatalf("TimeReport: %v", err)
+	}
+	if rep.Total != 11*time.Hour || rep.Focus != 1 || len(rep.Tasks) != 2 || rep.Tasks[0].Task.ID != novel {
+		t.Fatalf("report=%+v", rep)
+	}
+	if len(rep.Attributes) != 2 || rep.Attributes[0].Attr != AttributeREAD || rep.Attributes[0].Time != 10*time.Hour+30*time.Minute ||
+		rep.Attributes[1].Attr != AttributeINT || rep.Attributes[1].Time != 30*time.Minute {
+		t.Fatalf("attributes=%+v, want READ 10h30m and INT 30m", rep.Attributes)
+	}
+
+	// Completing a task stops its timer and pays for the tracked time, capped.
+	if _, err := svc.StartTimer(ctx, essay, SessionTimer, now.Add(-2*time.Hour)); err != nil {
+		t.Fatalf("StartTimer: %v", err)
+	}
+	done, err := svc.CompleteTask(ctx, essay)
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	task, _ := svc.TaskRepo().Get(ctx, essay)
+	if done.Tracked < 3*time.Hour || done.TimeBonus == 0 {
+		t.Fatalf("tracked=%v bonus=%d, want 3h and a bonus", done.Tracked, done.TimeBonus)
+	}
+	if want, _ := rules.TimeXP(task.XPValue, 3*time.Hour); done.XPAwarded != want {
+		t.Fatalf("XPAwarded=%d, want %d (capped at +25%%)", done.XPAwarded, want)
+	}
+	if running, _ := svc.RunningTimer(ctx); running != nil {
+		t.Fatalf("timer still running after completion: %+v", running)
+	}
+	if _, err := svc.StartTimer(ctx, essay, SessionTimer, now); err == nil {
+		t.Fatal("started a timer on a done task")
+	}
+
+	// Trashing a task stops its timer; sessions travel with exports.
+	if _, err := svc.StartTimer(ctx, scratch, SessionTimer, now.Add(-time.Minute)); err != nil {
+		t.Fatalf("StartTimer: %v", err)
+	}
+	if _, err := svc.DeleteTask(ctx, scratch, DeleteXPAsk); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if running, _ := svc.RunningTimer(ctx); running != nil {
+		t.Fatalf("timer still running on a trashed task: %+v", running)
+	}
+	doc, err := svc.Export(ctx)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(doc.Sessions) != 4 {
+		t.Fatalf("exported %d sessions, want 4", len(doc.Sessions))
+	}
+	dst, cleanupDst := newTestService(t)
+	defer cleanupDst()
+	imported, err := dst.Import(ctx, doc, false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if tracked, _ := dst.TrackedTime(ctx, now); imported.Sessions != 4 || tracked[novel] != 11*time.Hour {
+		t.Fatalf("imported %d sessions, novel tracked %v", imported.Sessions, tracked[novel])
+	}
+}
+
+func TestParsePomodoro(t *testing.T) {
+	cases := []struct {
+		in        string
+		work, brk time.Duration
+		ok        bool
+	}{
+		{"25/5", 25 * time.Minute, 5 * time.Minute, true},
+		{"50m/10m", 50 * time.Minute, 10 * time.Minute, true},
+		{"1h/0", time.Hour, 0, true},
+		{"25", 0, 0, false},
+		{"0/5", 0, 0, false},
+		{"x/5", 0, 0, false},
+	}
+	for _, c := range cases {
+		p, err := ParsePomodoro(c.in)
+		if (err == nil) != c.ok || p.Work != c.work || p.Break != c.brk {
+			t.Errorf("ParsePomodoro(%q) = %+v, %v", c.in, p, err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"questline/internal/storage"
+)
+
+// HabitStreak summarizes a habit's run of consecutive completed periods. A
+// period is a day, week (Monday to Sunday) or month, or a block of several of
+// them for "every N" schedules, and is completed once it has as many
+// completions as the schedule asks for. The current period only breaks the
+// streak once it is over.
+type HabitStreak struct {
+	Current        int  // completed periods in the ongoing streak
+	Longest        int  // longest streak ever
+	Frozen         int  // missed periods inside the ongoing streak covered by freeze tokens
+	DoneThisPeriod bool // the current period is already completed
+
+	// gap lists the missed periods right before the current one, and
+	// runBeforeGap the streak they broke. Freeze tokens can cover them.
+	gap          []time.Time
+	runBeforeGap int
+}
+
+// ComputeStreak derives a habit's streak from its completions and the periods
+// freeze tokens cover. Periods are evaluated in now's location.
+func ComputeStreak(sched HabitSchedule, completions []storage.TaskCompletion, frozen []time.Time, now time.Time) HabitStreak {
+	loc := now.Location()
+	count := map[int64]int{}
+	var first time.Time
+	for _, c := range completions {
+		p := sched.periodStart(c.CompletedAt.In(loc))
+		count[p.Unix()]++
+		if first.IsZero() || p.Before(first) {
+			first = p
+		}
+	}
+	var st HabitStreak
+	if first.IsZero() {
+		return st
+	}
+	covered := map[int64]bool{}
+	for _, f := range frozen {
+		covered[sched.periodStart(f.In(loc)).Unix()] = true
+	}
+	done := func(p time.Time) bool { return count[p.Unix()] >= sched.target(p) }
+
+	cur := sched.periodStart(now)
+	for p := first; !p.After(cur); p = sched.nextPeriod(p) {
+		switch {
+		case done(p):
+			st.Current++
+			st.gap = nil
+			if st.Current > st.Longest {
+				st.Longest = st.Current
+			}
+		case covered[p.Unix()]:
+			st.Frozen++
+		case p.Equal(cur):
+			// Still open: the streak is not broken yet.
+		default:
+			if st.Current > 0 {
+				st.runBeforeGap = st.Current
+			}
+			st.gap = append(st.gap, p)
+			st.Current = 0
+			st.Frozen = 0
+		}
+	}
+	st.DoneThisPeriod = done(cur)
+	if st.Current > 0 {
+		st.runBeforeGap = 0
+	}
+	return st
+}
+
+// habitStreak loads a habit's completions and covered periods and computes its streak.
+func (s *Service) habitStreak(ctx context.Context, task *storage.Task, now time.Time) (HabitStreak, error) {
+	if !task.IsHabit || task.HabitInterval == nil {
+		return HabitStreak{}, nil
+	}
+	sched, comps, frozen, err := s.streakInputs(ctx, task)
+	if err != nil {
+		return HabitStreak{}, err
+	}
+	return ComputeStreak(sched, comps, frozen, now), nil
+}
+
+func (s *Service) streakInputs(ctx context.Context, task *storage.Task) (HabitSchedule, []storage.TaskCompletion, []time.Time, error) {
+	sched, err := ScheduleForTask(task)
+	if err != nil {
+		return HabitSchedule{}, nil, nil, err
+	}
+	comps, err := s.completions.ListByTask(ctx, task.ID)
+	if err != nil {
+		return HabitSchedule{}, nil, nil, err
+	}
+	frozen, err := s.freezes.CoveredPeriods(ctx, task.ID)
+	if err != nil {
+		return HabitSchedule{}, nil, nil, err
+	}
+	return sched, comps, frozen, nil
+}
+
+// HabitStreaks returns the streak of every habit, keyed by task ID.
+func (s *Service) HabitStreaks(ctx context.Context) (map[int64]HabitStreak, error) {
+	tasks, err := s.tasks.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := map[int64]HabitStreak{}
+	for i := range tasks {
+		if !tasks[i].IsHabit {
+			continue
+		}
+		st, err := s.habitStreak(ctx, &tasks[i], now)
+		if err != nil {
+			return nil, fmt.Errorf("streak for habit %d: %w", tasks[i].ID, err)
+		}
+		out[tasks[i].ID] = st
+	}
+	return out, nil
+}
+
+// FreezeTokens returns how many unused freeze tokens the player holds.
+func (s *Service) FreezeTokens(ctx context.Context) (int, error) {
+	return s.freezes.Available(ctx)
+}
+
+// streakPlan is what a habit completion does to its streak.
+type streakPlan struct {
+	sched        HabitSchedule
+	completes    bool        // the completion completes the current period
+	streak       int         // streak including the new completion
+	freezeOn     []time.Time // missed periods to cover with tokens
+	held         int         // tokens held before the completion
+	doneInPeriod int         // completions in the current period, this one included
+}
+
+// planStreak works out the streak a habit reaches by being completed at now,
+// spending freeze tokens on the periods missed since its last streak when
+// there are enough of them.
+func (s *Service) planStreak(ctx context.Context, task *storage.Task, now time.Time) (streakPlan, error) {
+	var plan streakPlan
+	sched, comps, frozen, err := s.streakInputs(ctx, task)
+	if err != nil {
+		return plan, err
+	}
+	plan.sched = sched
+
+	cur := sched.periodStart(now)
+	plan.doneInPeriod = 1
+	for _, c := range comps {
+		if sched.periodStart(c.CompletedAt.In(now.Location())).Equal(cur) {
+			plan.doneInPeriod++
+		}
+	}
+
+	before := ComputeStreak(sched, comps, frozen, now)
+	after := ComputeStreak(sched, append(comps, storage.TaskCompletion{CompletedAt: now}), frozen, now)
+	plan.streak = before.Current
+	plan.completes = after.DoneThisPeriod && !before.DoneThisPeriod
+	if !plan.completes {
+		return plan, nil
+	}
+
+	plan.held, err = s.freezes.Available(ctx)
+	if err != nil {
+		return plan, err
+	}
+	plan.streak = after.Current
+	if before.runBeforeGap > 0 && len(before.gap) > 0 && len(before.gap) <= plan.held {
+		plan.freezeOn = before.gap
+		plan.streak = before.runBeforeGap + 1
+	}
+	return plan, nil
+}
+
+// applyStreak spends the planned freeze tokens and awards a new one when the
+// streak reaches a multiple of the freeze interval. It reports whether a token
+// was earned.
+func (s *Service) applyStreak(ctx context.Context, task *storage.Task, compID int64, plan streakPlan, now time.Time) (bool, error) {
+	sort.Slice(plan.freezeOn, func(i, j int) bool { return plan.freezeOn[i].Before(plan.freezeOn[j]) })
+	for _, p := range plan.freezeOn {
+		ok, err := s.freezes.Use(ctx, task.ID, compID, p.UTC(), now)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, fmt.Errorf("no freeze token left for habit %d", task.ID)
+		}
+	}
+	if !plan.completes || !s.rules.EarnsFreeze(plan.streak, plan.held-len(plan.freezeOn)) {
+		return false, nil
+	}
+	if _, err := s.freezes.Earn(ctx, task.ID, compID, now); err != nil {
+		return false, err
+	}
+	return true, nil
+}
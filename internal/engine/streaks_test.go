@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"questline/internal/storage"
+)
+
+func TestComputeStreak(t *testing.T) {
+	// Friday 2026-10-16, 20:00.
+	now := time.Date(2026, 10, 16, 20, 0, 0, 0, time.Local)
+	daysAgo := func(days ...int) []storage.TaskCompletion {
+		var out []storage.TaskCompletion
+		for _, d := range days {
+			out = append(out, storage.TaskCompletion{CompletedAt: now.AddDate(0, 0, -d)})
+		}
+		return out
+	}
+	day := func(d int) time.Time { return endOfDay(now.AddDate(0, 0, -d)) }
+	sched := func(spec string) HabitSchedule {
+		s, err := ParseHabitSchedule(spec)
+		if err != nil {
+			t.Fatalf("ParseHabitSchedule(%q): %v", spec, err)
+		}
+		s.Anchor = now.AddDate(0, -3, 0)
+		return s
+	}
+	daily, weekly, monthly := sched("daily"), sched("weekly"), sched("monthly")
+
+	tests := []struct {
+		name     string
+		schedule HabitSchedule
+		comps    []storage.TaskCompletion
+		frozen   []time.Time
+		want     HabitStreak
+	}{
+		{"none", daily, nil, nil, HabitStreak{}},
+		{"today only", daily, daysAgo(0), nil, HabitStreak{Current: 1, Longest: 1, DoneThisPeriod: true}},
+		{"open today keeps streak", daily, daysAgo(1, 2, 3), nil, HabitStreak{Current: 3, Longest: 3}},
+		{"twice a day counts once", daily, daysAgo(0, 0, 1), nil, HabitStreak{Current: 2, Longest: 2, DoneThisPeriod: true}},
+		{"missed day breaks", daily, daysAgo(0, 2, 3, 4, 5), nil, HabitStreak{Current: 1, Longest: 4, DoneThisPeriod: true}},
+		{"freeze bridges", daily, daysAgo(0, 2, 3), []time.Time{day(1)}, HabitStreak{Current: 3, Longest: 3, Frozen: 1, DoneThisPeriod: true}},
+		{"weekly", weekly, daysAgo(1, 5, 12), nil, HabitStreak{Current: 3, Longest: 3, DoneThisPeriod: true}},
+		{"weekly gap", weekly, daysAgo(0, 14), nil, HabitStreak{Current: 1, Longest: 1, DoneThisPeriod: true}},
+		{"monthly", monthly, daysAgo(20, 50), nil, HabitStreak{Current: 2, Longest: 2}},
+	}
+	for _, tt := range tests {
+		got := ComputeStreak(tt.schedule, tt.comps, tt.frozen, now)
+		got.gap, got.runBeforeGap = nil, 0
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: ComputeStreak=%+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStreakBonusAndFreezeTokens(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	rules := svc.Rules()
+
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelHabits))
+	h, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Read", Difficulty: DifficultyTrivial, Attribute: AttributeREAD, IsHabit: true, HabitInterval: HabitIntervalDaily})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	habit, _ := svc.TaskRepo().Get(ctx, h.TaskID)
+
+	// Six days in a row; today makes seven and earns a token.
+	now := time.Now()
+	for d := 6; d >= 1; d-- {
+		if _, err := svc.completions.Insert(ctx, h.TaskID, now.AddDate(0, 0, -d).UTC(), habit.Difficulty, habit.XPValue); err != nil {
+			t.Fatalf("insert completion: %v", err)
+		}
+	}
+	res, err := svc.CompleteTask(ctx, h.TaskID)
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	wantXP, wantBonus := rules.StreakXP(rules.HabitXP(habit.XPValue, 6), 7)
+	if res.Streak != 7 || res.XPAwarded != wantXP || res.StreakBonus != wantBonus || !res.FreezeEarned {
+		t.Fatalf("result=%+v, want streak 7, xp %d, bonus %d, freeze earned", res, wantXP, wantBonus)
+	}
+
+	// A second completion the same day neither extends the streak nor pays a bonus.
+	res, err = svc.CompleteTask(ctx, h.TaskID)
+	if err != nil {
+		t.Fatalf("CompleteTask again: %v", err)
+	}
+	if res.Streak != 7 || res.StreakBonus != 0 || res.FreezeEarned {
+		t.Fatalf("same-day result=%+v", res)
+	}
+	if n, _ := svc.FreezeTokens(ctx); n != 1 {
+		t.Fatalf("tokens=%d, want 1", n)
+	}
+
+	// A second habit that missed yesterday keeps its streak by spending the token.
+	h2, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Walk", Difficulty: DifficultyTrivial, Attribute: AttributeOUT, IsHabit: true, HabitInterval: HabitIntervalDaily})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	for _, d := range []int{4, 3, 2} {
+		if _, err := svc.completions.Insert(ctx, h2.TaskID, now.AddDate(0, 0, -d).UTC(), habit.Difficulty, habit.XPValue); err != nil {
+			t.Fatalf("insert completion: %v", err)
+		}
+	}
+	res, err = svc.CompleteTask(ctx, h2.TaskID)
+	if err != nil {
+		t.Fatalf("CompleteTask h2: %v", err)
+	}
+	if res.Streak != 4 || res.FreezesUsed != 1 {
+		t.Fatalf("h2 result=%+v, want streak 4 using 1 freeze", res)
+	}
+	streaks, err := svc.HabitStreaks(ctx)
+	if err != nil {
+		t.Fatalf("HabitStreaks: %v", err)
+	}
+	if st := streaks[h2.TaskID]; st.Current != 4 || st.Frozen != 1 {
+		t.Fatalf("h2 streak=%+v, want 4 with 1 frozen", st)
+	}
+
+	// Restoring the completion gives the token back and breaks the streak again.
+	if _, err := svc.RestoreTask(ctx, h2.TaskID); err != nil {
+		t.Fatalf("RestoreTask: %v", err)
+	}
+	if n, _ := svc.FreezeTokens(ctx); n != 1 {
+		t.Fatalf("tokens after restore=%d, want 1", n)
+	}
+	streaks, _ = svc.HabitStreaks(ctx)
+	if st := streaks[h2.TaskID]; st.Current != 0 || st.Longest != 3 {
+		t.Fatalf("h2 streak after restore=%+v, want 0 (longest 3)", st)
+	}
+}
@@ -0,0 +1,223 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// NormalizeTag lowercases a tag and drops a leading "#". Tags are made of
+// letters, digits and - _ / . (e.g. "work", "home/garden").
+func NormalizeTag(input string) (string, error) {
+	tag := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(input), "#"))
+	if tag == "" {
+		return "", fmt.Errorf("tag is empty")
+	}
+	if len(tag) > 32 {
+		return "", fmt.Errorf("tag %q is longer than 32 characters", tag)
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_/.", r) {
+			return "", fmt.Errorf("tag %q: use letters, digits and - _ / . only", input)
+		}
+	}
+	return tag, nil
+}
+
+// ParseTags parses a comma- or space-separated list of tags, e.g. "work,urgent".
+// Duplicates are dropped.
+func ParseTags(input string) ([]string, error) {
+	var out []string
+	seen := map[string]bool{}
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		tag, err := NormalizeTag(field)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	return out, nil
+}
+
+// TaskTags are the tags set on a task and the ones it inherits from the tasks
+// above it, each sorted.
+type TaskTags struct {
+	Own       []string
+	Inherited []string
+}
+
+// All returns own and inherited tags together, sorted.
+func (t TaskTags) All() []string {
+	out := append(append([]string(nil), t.Own...), t.Inherited...)
+	sort.Strings(out)
+	return out
+}
+
+// Has reports whether the task has tag, set or inherited.
+func (t TaskTags) Has(tag string) bool {
+	for _, list := range [][]string{t.Own, t.Inherited} {
+		for _, have := range list {
+			if have == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TaskTags returns the tags of every task not in the trash, keyed by task ID.
+// Tasks without any are left out.
+func (s *Service) TaskTags(ctx context.Context) (map[int64]TaskTags, error) {
+	tasks, err := s.tasks.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	own, err := s.tags.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[int64]*int64, len(tasks))
+	for _, t := range tasks {
+		parents[t.ID] = t.ParentID
+	}
+
+	out := map[int64]TaskTags{}
+	for _, t := range tasks {
+		tags := TaskTags{Own: own[t.ID]}
+		seen := map[string]bool{}
+		for _, tag := range tags.Own {
+			seen[tag] = true
+		}
+		visited := map[int64]bool{t.ID: true}
+		for p := t.ParentID; p != nil && !visited[*p]; p = parents[*p] {
+			visited[*p] = true
+			for _, tag := range own[*p] {
+				if !seen[tag] {
+					seen[tag] = true
+					tags.Inherited = append(tags.Inherited, tag)
+				}
+			}
+		}
+		sort.Strings(tags.Inherited)
+		if len(tags.Own)+len(tags.Inherited) > 0 {
+			out[t.ID] = tags
+		}
+	}
+	return out, nil
+}
+
+// AddTags tags a task; its subtasks inherit the tags. It returns the tags that
+// were new.
+func (s *Service) AddTags(ctx context.Context, id int64, tags []string) ([]string, error) {
+	var added []string
+	err := s.inTx(ctx, func(tx *Service) error {
+		var err error
+		added, err = tx.addTags(ctx, id, tags)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+func (s *Service) addTags(ctx context.Context, id int64, tags []string) ([]string, error) {
+	task, err := s.tasks.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, fmt.Errorf("task %d %w", id, ErrNotFound)
+	}
+	var added []string
+	for _, input := range tags {
+		tag, err := NormalizeTag(input)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := s.tags.Add(ctx, id, tag)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			added = append(added, tag)
+		}
+	}
+	return added, nil
+}
+
+// RemoveTags takes tags off a task. A tag the task only inherits has to be
+// removed from the task it was set on.
+func (s *Service) RemoveTags(ctx context.Context, id int64, tags []string) error {
+	return s.inTx(ctx, func(tx *Service) error {
+		task, err := tx.tasks.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if task == nil {
+			return fmt.Errorf("task %d %w", id, ErrNotFound)
+		}
+		for _, input := range tags {
+			tag, err := NormalizeTag(input)
+			if err != nil {
+				return err
+			}
+			ok, err := tx.tags.Remove(ctx, id, tag)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("task %d has no tag %q of its own", id, tag)
+			}
+		}
+		return nil
+	})
+}
+
+// TagCount is a tag and how many tasks carry it.
+type TagCount struct {
+	Tag   string
+	Tasks int // Tasks it is set on
+	Open  int // Tasks not done that have it, set or inherited
+}
+
+// TagCounts returns every tag in use on tasks not in the trash, sorted by name.
+func (s *Service) TagCounts(ctx context.Context) ([]TagCount, error) {
+	tags, err := s.TaskTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.tasks.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]*TagCount{}
+	count := func(tag string) *TagCount {
+		if counts[tag] == nil {
+			counts[tag] = &TagCount{Tag: tag}
+		}
+		return counts[tag]
+	}
+	for _, t := range tasks {
+		tt := tags[t.ID]
+		for _, tag := range tt.Own {
+			count(tag).Tasks++
+		}
+		if t.Status != "done" {
+			for _, tag := range tt.All() {
+				count(tag).Open++
+			}
+		}
+	}
+	out := make([]TagCount, 0, len(counts))
+	for _, c := range counts {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Tag < out[j].Tag })
+	return out, nil
+}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTagsInheritFilterAndExport(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelDeepRecurs))
+
+	if got, err := ParseTags("Work, #urgent work home/garden"); err != nil || !reflect.DeepEqual(got, []string{"work", "urgent", "home/garden"}) {
+		t.Fatalf("ParseTags=%v, %v", got, err)
+	}
+	if _, err := ParseTags("work,to do!"); err == nil {
+		t.Fatal("invalid tag accepted")
+	}
+
+	// The achievement counts completed tasks tagged work, set or inherited.
+	workhorse := AchievementDef{ID: "workhorse", Name: "Workhorse", When: []AchievementCond{{Kind: CondTasksDone, Tag: "Work", Min: 2}}}
+	if err := workhorse.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if errs := svc.AddUserAchievements([]AchievementDef{workhorse}); len(errs) != 0 {
+		t.Fatalf("AddUserAchievements: %v", errs)
+	}
+	if err := (&AchievementDef{ID: "x", Name: "X", When: []AchievementCond{{Kind: CondLevel, Tag: "work"}}}).validate(); err == nil {
+		t.Fatal("tag filter on a level condition accepted")
+	}
+
+	proj, err := svc.CreateProject(ctx, CreateProjectInput{Title: "Launch", Attribute: AttributeCAREER, Tags: []string{"work"}})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	create := func(title string, parent *int64, tags ...string) int64 {
+		t.Helper()
+		res, err := svc.CreateTask(ctx, CreateTaskInput{Title: title, Difficulty: DifficultyTrivial, Attribute: AttributeCAREER, ParentID: parent, Tags: tags})
+		if err != nil {
+			t.Fatalf("CreateTask %s: %v", title, err)
+		}
+		return res.TaskID
+	}
+	draft := create("Draft post", &proj.TaskID, "writing")
+	review := create("Review", &draft)
+	call := create("Call client", &proj.TaskID)
+	other := create("Water plants", nil, "home")
+
+	tags, err := svc.TaskTags(ctx)
+	if err != nil {
+		t.Fatalf("TaskTags: %v", err)
+	}
+	want := map[int64]TaskTags{
+		proj.TaskID: {Own: []string{"work"}},
+		draft:       {Own: []string{"writing"}, Inherited: []string{"work"}},
+		review:      {Inherited: []string{"work", "writing"}},
+		call:        {Inherited: []string{"work"}},
+		other:       {Own: []string{"home"}},
+	}
+	if !reflect.DeepEqual(tags, want) {
+		t.Fatalf("tags=%+v, want %+v", tags, want)
+	}
+
+	added, err := svc.AddTags(ctx, review, []string{"writing", "Urgent"})
+	if err != nil || !reflect.DeepEqual(added, []string{"writing", "urgent"}) {
+		t.Fatalf("AddTags=%v, %v", added, err)
+	}
+	if err := svc.RemoveTags(ctx, review, []string{"work"}); err == nil {
+		t.Fatal("removing an inherited tag succeeded")
+	}
+	if err := svc.RemoveTags(ctx, review, []string{"writing"}); err != nil {
+		t.Fatalf("RemoveTags: %v", err)
+	}
+
+	reg, _ := svc.Attributes(ctx)
+	f, err := ParseTaskFilter("tag:work -kind:project", reg, time.Now())
+	if err != nil {
+		t.Fatalf("ParseTaskFilter: %v", err)
+	}
+	if got, err := svc.FilterTasks(ctx, f); err != nil || len(got) != 3 || got[0].ID != draft || got[2].ID != call {
+		t.Fatalf("tag:work matched %+v, %v", got, err)
+	}
+
+	earned := func() bool {
+		achievements, err := GetAchievementsForPlayer(ctx, svc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range achievements {
+			if a.ID == "workhorse" {
+				return a.Earned
+			}
+		}
+		t.Fatal("workhorse not defined")
+		return false
+	}
+	for _, id := range []int64{other, review} {
+		if _, err := svc.CompleteTask(ctx, id); err != nil {
+			t.Fatalf("CompleteTask: %v", err)
+		}
+	}
+	if earned() {
+		t.Fatal("workhorse earned with one work task done")
+	}
+	if _, err := svc.CompleteTask(ctx, call); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if !earned() {
+		t.Fatal("workhorse not earned with two work tasks done")
+	}
+
+	// Exports carry each task's own tags.
+	doc, err := svc.Export(ctx)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	dst, cleanupDst := newTestService(t)
+	defer cleanupDst()
+	if _, err := dst.Import(ctx, doc, false); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	back, err := dst.TaskTags(ctx)
+	if err != nil {
+		t.Fatalf("TaskTags after import: %v", err)
+	}
+	if !reflect.DeepEqual(back[review], TaskTags{Own: []string{"urgent"}, Inherited: []string{"work", "writing"}}) {
+		t.Fatalf("imported tags=%+v", back[review])
+	}
+}
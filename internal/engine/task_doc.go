@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"questline/internal/storage"
+)
+
+// TaskDoc is the YAML form of a task that ql edit opens in $EDITOR:
+//
+//	title: Week 2 - Volume
+//	description: three sessions
+//	difficulty: 3
+//	attributes: STR:70,OUT:30   # or a single code
+//	parent: 12                  # omit for a top-level task
+//	due: 2026-11-01             # or 2026-11-01 18:00, fri, +3d
+//	interval: mon,wed,fri       # habits only, like duration and goal
+//	duration: 30d
+//	goal: 12
+//
+// Fields that don't apply to the task are left out, and removing a line
+// clears the field.
+type TaskDoc struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description,omitempty"`
+	Difficulty  int    `yaml:"difficulty,omitempty"`
+	Attributes  string `yaml:"attributes"`
+	Parent      int64  `yaml:"parent,omitempty"`
+	Due         string `yaml:"due,omitempty"`
+	Interval    string `yaml:"interval,omitempty"`
+	Duration    string `yaml:"duration,omitempty"`
+	Goal        int    `yaml:"goal,omitempty"`
+}
+
+// NewTaskDoc describes t, with times in loc.
+func NewTaskDoc(t *storage.Task, loc *time.Location) TaskDoc {
+	d := TaskDoc{Title: t.Title, Attributes: formatWeights(t.Attribute, t.Attributes)}
+	if t.Description != nil {
+		d.Description = *t.Description
+	}
+	if !t.IsProject {
+		d.Difficulty = t.Difficulty
+	}
+	if t.ParentID != nil {
+		d.Parent = *t.ParentID
+	}
+	if t.DueDate != nil {
+		due := t.DueDate.In(loc)
+		if due.Equal(endOfDay(due)) {
+			d.Due = due.Format("2006-01-02")
+		} else {
+			d.Due = due.Format("2006-01-02 15:04")
+		}
+	}
+	if t.IsHabit {
+		if t.HabitInterval != nil {
+			d.Interval = *t.HabitInterval
+		}
+		if t.HabitEndDate != nil {
+			start := t.CreatedAt
+			if t.HabitStartDate != nil {
+				start = *t.HabitStartDate
+			}
+			d.Duration = fmt.Sprintf("%dd", int(t.HabitEndDate.Sub(start).Round(24*time.Hour)/(24*time.Hour)))
+		}
+		if t.HabitGoal != nil {
+			d.Goal = *t.HabitGoal
+		}
+	}
+	return d
+}
+
+// formatWeights renders attributes the way --attr takes them: the primary
+// attribute first, then the others by code.
+func formatWeights(primary string, weights map[string]int) string {
+	if len(weights) == 0 {
+		return primary
+	}
+	codes := make([]string, 0, len(weights))
+	for code := range weights {
+		if code != primary {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	if _, ok := weights[primary]; ok {
+		codes = append([]string{primary}, codes...)
+	}
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		parts[i] = fmt.Sprintf("%s:%d", code, weights[code])
+	}
+	return strings.Join(parts, ",")
+}
+
+// YAML encodes the document.
+func (d TaskDoc) YAML() ([]byte, error) {
+	return yaml.Marshal(d)
+}
+
+// ParseTaskDoc decodes an edited document, rejecting unknown keys.
+func ParseTaskDoc(data []byte) (TaskDoc, error) {
+	var d TaskDoc
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&d); err != nil {
+		if err == io.EOF {
+			return d, errors.New("empty document")
+		}
+		return d, err
+	}
+	return d, nil
+}
+
+// Patch returns the changes from orig (the document the task was opened
+// with) to d. Relative due dates are read against now.
+func (d TaskDoc) Patch(reg *AttributeRegistry, orig TaskDoc, now time.Time) (TaskPatch, error) {
+	var p TaskPatch
+	if d.Title != orig.Title {
+		p.Title = &d.Title
+	}
+	if d.Description != orig.Description {
+		p.Description = &d.Description
+	}
+	if d.Difficulty != orig.Difficulty {
+		diff := Difficulty(d.Difficulty)
+		p.Difficulty = &diff
+	}
+	if d.Attributes != orig.Attributes {
+		primary, weights, err := reg.ParseWeightsStrict(d.Attributes)
+		if err != nil {
+			return p, err
+		}
+		p.Attribute = &primary
+		p.Attributes = weights
+	}
+	if d.Parent != orig.Parent {
+		p.ParentID = &d.Parent
+	}
+	if d.Due != orig.Due {
+		if strings.TrimSpace(d.Due) == "" {
+			p.ClearDue = true
+		} else {
+			due, err := ParseDueDate(d.Due, now)
+			if err != nil {
+				return p, err
+			}
+			p.DueDate = &due
+		}
+	}
+	if d.Interval != orig.Interval {
+		interval := HabitInterval(d.Interval)
+		p.HabitInterval = &interval
+	}
+	if d.Duration != orig.Duration {
+		var dur time.Duration
+		if strings.TrimSpace(d.Duration) != "" {
+			var err error
+			if dur, err = ParseDuration(strings.TrimSpace(d.Duration)); err != nil {
+				return p, err
+			}
+		}
+		p.HabitDuration = &dur
+	}
+	if d.Goal != orig.Goal {
+		p.HabitGoal = &d.Goal
+	}
+	return p, nil
+}
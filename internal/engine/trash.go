@@ -0,0 +1,430 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"questline/internal/storage"
+)
+
+// DeleteXP says what happens to the XP earned by the tasks being deleted.
+type DeleteXP int
+
+const (
+	DeleteXPAsk    DeleteXP = iota // Refuse with an XPChoiceError if they earned any
+	DeleteXPKeep                   // The player keeps it
+	DeleteXPRevoke                 // It is taken back (and given back on undelete)
+)
+
+// XPChoiceError is returned when deleting tasks that earned XP without saying
+// whether the player keeps it.
+type XPChoiceError struct {
+	TaskID int64
+	XP     int
+}
+
+func (e XPChoiceError) Error() string {
+	return fmt.Sprintf("task %d earned %d XP; choose whether to keep or revoke it", e.TaskID, e.XP)
+}
+
+type DeleteResult struct {
+	TaskID      int64
+	Deleted     []int64 // The task and its subtasks
+	XPEarned    int     // XP their completions had earned
+	XPRevoked   int
+	LevelBefore int
+	LevelAfter  int
+}
+
+// DeleteTask moves a task and its whole subtree to the trash. Their
+// completions stay; with DeleteXPRevoke the XP they earned is reversed in the
+// ledger.
+func (s *Service) DeleteTask(ctx context.Context, id int64, xp DeleteXP) (*DeleteResult, error) {
+	var res *DeleteResult
+	err := s.inTx(ctx, func(tx *Service) error {
+		var err error
+		res, err = tx.deleteTask(ctx, id, xp)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Service) deleteTask(ctx context.Context, id int64, xp DeleteXP) (*DeleteResult, error) {
+	task, err := s.tasks.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, fmt.Errorf("task %d %w", id, ErrNotFound)
+	}
+	p, err := s.getPlayer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &DeleteResult{TaskID: id, LevelBefore: p.Level, LevelAfter: p.Level}
+
+	subtree := []storage.Task{*task}
+	for i := 0; i < len(subtree); i++ {
+		children, err := s.tasks.ListChildren(ctx, subtree[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		subtree = append(subtree, children...)
+	}
+
+	type earned struct {
+		task   storage.Task
+		compID int64
+		shares []xpShare
+	}
+	var earnings []earned
+	for _, t := range subtree {
+		res.Deleted = append(res.Deleted, t.ID)
+		comps, err := s.completions.ListByTask(ctx, t.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range comps {
+			shares, err := s.completionNet(ctx, &t, c, nil)
+			if err != nil {
+				return nil, err
+			}
+			for _, sh := range shares {
+				res.XPEarned += sh.Amount
+			}
+			earnings = append(earnings, earned{task: t, compID: c.ID, shares: shares})
+		}
+	}
+	if res.XPEarned != 0 && xp == DeleteXPAsk {
+		return nil, XPChoiceError{TaskID: id, XP: res.XPEarned}
+	}
+
+	now := time.Now().UTC()
+	if xp == DeleteXPRevoke {
+		for _, e := range earnings {
+			var reversal []xpShare
+			for _, sh := range e.shares {
+				reversal = append(reversal, xpShare{Attr: sh.Attr, Amount: -sh.Amount})
+				res.XPRevoked += sh.Amount
+			}
+			if err := s.recordXP(ctx, p, XPEventTrash, &e.task.ID, &e.compID, reversal, now); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.players.Update(ctx, p); err != nil {
+			return nil, err
+		}
+		res.LevelAfter = p.Level
+	}
+
+	if err := s.tasks.SetDeleted(ctx, res.Deleted, &now); err != nil {
+		return nil, err
+	}
+	// A timer running on a trashed task stops with it.
+	running, err := s.sessions.Running(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, tid := range res.Deleted {
+		if running != nil && running.TaskID == tid {
+			if _, err := s.stopTimer(ctx, now); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return res, nil
+}
+
+// completionNet returns what a completion nets in the ledger per attribute,
+// counting only the given events (all of them when events is nil).
+// Completions from before the ledger fall back to splitting the awarded XP.
+func (s *Service) completionNet(ctx context.Context, task *storage.Task, c storage.TaskCompletion, events map[string]bool) ([]xpShare, error) {
+	entries, err := s.ledger.ListByCompletion(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		if events != nil {
+			return nil, nil
+		}
+		return splitXP(c.XPAwarded, parseStoredAttribute(task.Attribute), task.Attributes), nil
+	}
+	byAttr := map[string]int{}
+	for _, e := range entries {
+		if events == nil || events[e.Event] {
+			byAttr[e.Attribute] += e.Amount
+		}
+	}
+	codes := make([]string, 0, len(byAttr))
+	for code, amount := range byAttr {
+		if amount != 0 {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	out := make([]xpShare, len(codes))
+	for i, code := range codes {
+		out[i] = xpShare{Attr: Attribute(code), Amount: byAttr[code]}
+	}
+	return out, nil
+}
+
+// TrashItem is a task deleted together with its subtasks.
+type TrashItem struct {
+	Task      storage.Task
+	Subtasks  []int64 // Trashed with it
+	XPRevoked int     // XP taken back when it was deleted
+}
+
+// Trash lists what was deleted, newest first. Subtasks deleted with their
+// parent are listed under it.
+func (s *Service) Trash(ctx context.Context) ([]TrashItem, error) {
+	deleted, err := s.tasks.ListDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*storage.Task, len(deleted))
+	for i := range deleted {
+		byID[deleted[i].ID] = &deleted[i]
+	}
+
+	var out []TrashItem
+	for _, t := range deleted {
+		if parent := byID[ptrID(t.ParentID)]; parent != nil && parent.DeletedAt.Equal(*t.DeletedAt) {
+			continue
+		}
+		batch, err := s.trashBatch(ctx, &t)
+		if err != nil {
+			return nil, err
+		}
+		item := TrashItem{Task: t}
+		for _, b := range batch {
+			if b.ID != t.ID {
+				item.Subtasks = append(item.Subtasks, b.ID)
+			}
+			revoked, err := s.revokedXP(ctx, &b)
+			if err != nil {
+				return nil, err
+			}
+			for _, sh := range revoked {
+				item.XPRevoked -= sh.Amount
+			}
+		}
+		out = append(out, item)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Task.DeletedAt.After(*out[j].Task.DeletedAt) })
+	return out, nil
+}
+
+func ptrID(id *int64) int64 {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// trashBatch returns t and the subtasks deleted together with it.
+func (s *Service) trashBatch(ctx context.Context, t *storage.Task) ([]storage.Task, error) {
+	batch := []storage.Task{*t}
+	for i := 0; i < len(batch); i++ {
+		children, err := s.tasks.ListChildrenIncludingDeleted(ctx, batch[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range children {
+			if c.DeletedAt != nil && c.DeletedAt.Equal(*t.DeletedAt) {
+				batch = append(batch, c)
+			}
+		}
+	}
+	return batch, nil
+}
+
+// revokedXP returns the XP still revoked from a trashed task's completions,
+// as negative shares.
+func (s *Service) revokedXP(ctx context.Context, t *storage.Task) ([]xpShare, error) {
+	comps, err := s.completions.ListByTask(ctx, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	var out []xpShare
+	for _, c := range comps {
+		shares, err := s.completionNet(ctx, t, c, map[string]bool{XPEventTrash: true, XPEventUntrash: true})
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, shares...)
+	}
+	return out, nil
+}
+
+type UndeleteResult struct {
+	TaskID      int64
+	Restored    []int64 // The task and the subtasks deleted with it
+	XPRestored  int
+	LevelBefore int
+	LevelAfter  int
+}
+
+// UndeleteTask takes a task out of the trash together with the subtasks that
+// were deleted with it, and gives back any XP the deletion revoked. A subtask
+// can't come back while its parent is still in the trash.
+func (s *Service) UndeleteTask(ctx context.Context, id int64) (*UndeleteResult, error) {
+	var res *UndeleteResult
+	err := s.inTx(ctx, func(tx *Service) error {
+		var err error
+		res, err = tx.undeleteTask(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *Service) undeleteTask(ctx context.Context, id int64) (*UndeleteResult, error) {
+	task, err := s.tasks.GetIncludingDeleted(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, fmt.Errorf("task %d %w", id, ErrNotFound)
+	}
+	if task.DeletedAt == nil {
+		return nil, fmt.Errorf("task %d is not in the trash", id)
+	}
+	if task.ParentID != nil {
+		parent, err := s.tasks.GetIncludingDeleted(ctx, *task.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent != nil && parent.DeletedAt != nil {
+			return nil, fmt.Errorf("task %d is inside deleted task %d; restore that instead", id, parent.ID)
+		}
+	}
+
+	p, err := s.getPlayer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &UndeleteResult{TaskID: id, LevelBefore: p.Level}
+
+	batch, err := s.trashBatch(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	for _, t := range batch {
+		res.Restored = append(res.Restored, t.ID)
+		comps, err := s.completions.ListByTask(ctx, t.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range comps {
+			revoked, err := s.completionNet(ctx, &t, c, map[string]bool{XPEventTrash: true, XPEventUntrash: true})
+			if err != nil {
+				return nil, err
+			}
+			var back []xpShare
+			for _, sh := range revoked {
+				back = append(back, xpShare{Attr: sh.Attr, Amount: -sh.Amount})
+				res.XPRestored -= sh.Amount
+			}
+			if err := s.recordXP(ctx, p, XPEventUntrash, &t.ID, &c.ID, back, now); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if res.XPRestored != 0 {
+		if err := s.players.Update(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+	res.LevelAfter = p.Level
+
+	if err := s.tasks.SetDeleted(ctx, res.Restored, nil); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// PurgeTrash removes the tasks deleted before cutoff for good, with their
+// completions. The XP ledger keeps its entries, so the player's XP is
+// unchanged. It returns the number of tasks removed.
+func (s *Service) PurgeTrash(ctx context.Context, cutoff time.Time) (int, error) {
+	var n int
+	err := s.inTx(ctx, func(tx *Service) error {
+		deleted, err := tx.tasks.ListDeleted(ctx)
+		if err != nil {
+			return err
+		}
+		parents := map[int64]*int64{}
+		var purge []int64
+		runs := map[string]int64{} // Latest purged run of each blueprint
+		for _, t := range deleted {
+			parents[t.ID] = t.ParentID
+			if t.DeletedAt.Before(cutoff) {
+				purge = append(purge, t.ID)
+				if t.BlueprintCode != nil && t.ID > runs[*t.BlueprintCode] {
+					runs[*t.BlueprintCode] = t.ID
+				}
+			}
+		}
+		// Children go first: their rows reference the parent.
+		depth := func(id int64) int {
+			d := 0
+			for p := parents[id]; p != nil; p = parents[*p] {
+				d++
+			}
+			return d
+		}
+		sort.SliceStable(purge, func(i, j int) bool { return depth(purge[i]) > depth(purge[j]) })
+		for _, id := range purge {
+			if err := tx.tasks.Purge(ctx, id); err != nil {
+				return err
+			}
+		}
+		n = len(purge)
+		return tx.releaseBlueprints(ctx, runs)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// releaseBlueprints makes the active blueprints whose latest run was purged
+// available again, so they can be accepted anew. runs maps each blueprint code
+// to the latest purged task created from it.
+func (s *Service) releaseBlueprints(ctx context.Context, runs map[string]int64) error {
+	if len(runs) == 0 {
+		return nil
+	}
+	all, err := s.tasks.ListAllIncludingDeleted(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range all {
+		if code := all[i].BlueprintCode; code != nil && all[i].ID > runs[*code] {
+			delete(runs, *code)
+		}
+	}
+	for code := range runs {
+		b, err := s.blueprints.Get(ctx, code)
+		if err != nil {
+			return err
+		}
+		if b == nil || b.Status != string(BlueprintActive) {
+			continue
+		}
+		if err := s.blueprints.Upsert(ctx, storage.Blueprint{Code: code, Status: string(BlueprintAvailable)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTrashDeleteUndeleteAndPurge(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelDeepRecurs))
+
+	create := func(title string, parent *int64) int64 {
+		t.Helper()
+		res, err := svc.CreateTask(ctx, CreateTaskInput{Title: title, Difficulty: DifficultyTrivial, Attribute: AttributeSTR, ParentID: parent})
+		if err != nil {
+			t.Fatalf("CreateTask %s: %v", title, err)
+		}
+		return res.TaskID
+	}
+	a := create("Renovate", nil)
+	b := create("Kitchen", &a)
+	c := create("Paint walls", &b)
+	other := create("Unrelated", nil)
+	if err := svc.AddDependency(ctx, other, c); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+	done, err := svc.CompleteTask(ctx, c)
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	xp := func() int {
+		p, err := svc.PlayerRepo().GetOrCreateMain(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.XPTotal
+	}
+	start := xp()
+
+	// Deleting tasks that earned XP needs a decision.
+	var choice XPChoiceError
+	if _, err := svc.DeleteTask(ctx, a, DeleteXPAsk); !errors.As(err, &choice) || choice.XP != done.XPAwarded {
+		t.Fatalf("delete err=%v, want an XP choice for %d XP", err, done.XPAwarded)
+	}
+	res, err := svc.DeleteTask(ctx, a, DeleteXPRevoke)
+	if err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if !reflect.DeepEqual(res.Deleted, []int64{a, b, c}) || res.XPRevoked != done.XPAwarded || xp() != start-done.XPAwarded {
+		t.Fatalf("delete=%+v xp=%d, want the whole subtree and %d XP revoked", res, xp(), done.XPAwarded)
+	}
+	for _, id := range []int64{a, b, c} {
+		if got, _ := svc.TaskRepo().Get(ctx, id); got != nil {
+			t.Fatalf("task %d still listed after delete", id)
+		}
+	}
+	if blockers, _ := svc.OpenBlockers(ctx, other); len(blockers) != 0 {
+		t.Fatalf("blockers=%v, a trashed task shouldn't block", blockers)
+	}
+
+	trash, err := svc.Trash(ctx)
+	if err != nil || len(trash) != 1 || trash[0].Task.ID != a || !reflect.DeepEqual(trash[0].Subtasks, []int64{b, c}) || trash[0].XPRevoked != done.XPAwarded {
+		t.Fatalf("trash=%+v, %v", trash, err)
+	}
+
+	// Subtasks come back with their parent, and so does the XP.
+	if _, err := svc.UndeleteTask(ctx, b); err == nil {
+		t.Fatal("restoring a subtask of a trashed task succeeded")
+	}
+	und, err := svc.UndeleteTask(ctx, a)
+	if err != nil {
+		t.Fatalf("UndeleteTask: %v", err)
+	}
+	if !reflect.DeepEqual(und.Restored, []int64{a, b, c}) || und.XPRestored != done.XPAwarded || xp() != start {
+		t.Fatalf("undelete=%+v xp=%d, want everything back", und, xp())
+	}
+
+	// Kept XP stays through delete and purge; the ledger still adds up.
+	if _, err := svc.DeleteTask(ctx, b, DeleteXPKeep); err != nil {
+		t.Fatalf("DeleteTask keep: %v", err)
+	}
+	if xp() != start {
+		t.Fatalf("xp=%d after keeping, want %d", xp(), start)
+	}
+	before, err := svc.RebuildPlayer(ctx)
+	if err != nil {
+		t.Fatalf("RebuildPlayer: %v", err)
+	}
+	if n, err := svc.PurgeTrash(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("purge of older tasks removed %d, %v", n, err)
+	}
+	if n, err := svc.PurgeTrash(ctx, time.Now().Add(time.Second)); err != nil || n != 2 {
+		t.Fatalf("purge removed %d, %v; want 2", n, err)
+	}
+	if got, _ := svc.TaskRepo().GetIncludingDeleted(ctx, c); got != nil {
+		t.Fatal("purged task still stored")
+	}
+	if rb, err := svc.RebuildPlayer(ctx); err != nil || rb.XPAfter != before.XPAfter || rb.Entries != before.Entries {
+		t.Fatalf("rebuild after purge=%+v, %v; want %+v", rb, err, before)
+	}
+
+	// The export stays consistent after a purge, and carries trashed tasks.
+	if _, err := svc.DeleteTask(ctx, other, DeleteXPAsk); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	doc, err := svc.Export(ctx)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	dst, cleanupDst := newTestService(t)
+	defer cleanupDst()
+	if _, err := dst.Import(ctx, doc, false); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if trash, err := dst.Trash(ctx); err != nil || len(trash) != 1 || trash[0].Task.ID != other {
+		t.Fatalf("imported trash=%+v, %v", trash, err)
+	}
+}
+
+func TestPurgingABlueprintRunMakesItAvailableAgain(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	setPlayerXP(t, svc, XPRequiredForLevel(5))
+
+	run, err := svc.AcceptBlueprint(ctx, "career_resume")
+	if err != nil {
+		t.Fatalf("AcceptBlueprint: %v", err)
+	}
+	if _, err := svc.DeleteTask(ctx, run.TaskID, DeleteXPAsk); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	// In the trash the run can still come back, so the blueprint stays active.
+	if _, err := svc.AcceptBlueprint(ctx, "career_resume"); err == nil {
+		t.Fatal("accepted a blueprint whose run is in the trash")
+	}
+	if n, err := svc.PurgeTrash(ctx, time.Now().Add(time.Minute)); err != nil || n != 1 {
+		t.Fatalf("PurgeTrash n=%d err=%v, want 1", n, err)
+	}
+	if b, _ := svc.BlueprintRepo().Get(ctx, "career_resume"); b.Status != string(BlueprintAvailable) {
+		t.Fatalf("status after purge=%s, want available", b.Status)
+	}
+	again, err := svc.AcceptBlueprint(ctx, "career_resume")
+	if err != nil {
+		t.Fatalf("accept after purge: %v", err)
+	}
+	if prog, _ := svc.BlueprintProgress(ctx, "career_resume"); prog.Status != BlueprintActive || prog.TaskID != again.TaskID {
+		t.Fatalf("progress=%+v, want the new run active", prog)
+	}
+}
@@ -0,0 +1,191 @@
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"questline/internal/storage"
+)
+
+var errInjected = errors.New("injected failure")
+
+// failingConn fails the first Exec whose query contains match, after skipping
+// the first skip matching statements.
+type failingConn struct {
+	storage.DBTX
+	match string
+	skip  *int
+}
+
+func (c failingConn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if strings.Contains(query, c.match) {
+		if *c.skip == 0 {
+			return nil, errInjected
+		}
+		*c.skip--
+	}
+	return c.DBTX.ExecContext(ctx, query, args...)
+}
+
+// failOnExec makes the (skip+1)-th transactional statement containing match fail.
+func failOnExec(svc *Service, match string, skip int) {
+	svc.wrapTx = func(conn storage.DBTX) storage.DBTX {
+		n := skip
+		return failingConn{DBTX: conn, match: match, skip: &n}
+	}
+}
+
+func countRows(t *testing.T, svc *Service, table string) int {
+	t.Helper()
+	var n int
+	if err := svc.db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+		t.Fatalf("count %s: %v", table, err)
+	}
+	return n
+}
+
+func TestCompleteTaskRollsBackOnFailure(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	res, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Buy groceries", Difficulty: DifficultyTrivial, Attribute: AttributeWIS})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	failOnExec(svc, "INSERT INTO task_completions", 0)
+	if _, err := svc.CompleteTask(ctx, res.TaskID); !errors.Is(err, errInjected) {
+		t.Fatalf("CompleteTask err=%v, want injected failure", err)
+	}
+
+	task, err := svc.TaskRepo().Get(ctx, res.TaskID)
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	if task.Status != "pending" {
+		t.Fatalf("task status=%q, want pending", task.Status)
+	}
+	p, err := svc.PlayerRepo().GetOrCreateMain(ctx)
+	if err != nil {
+		t.Fatalf("get player: %v", err)
+	}
+	if p.XPTotal != 0 {
+		t.Fatalf("player xp=%d, want 0", p.XPTotal)
+	}
+	if n := countRows(t, svc, "task_completions"); n != 0 {
+		t.Fatalf("completions=%d, want 0", n)
+	}
+
+	svc.wrapTx = nil
+	if _, err := svc.CompleteTask(ctx, res.TaskID); err != nil {
+		t.Fatalf("CompleteTask after rollback: %v", err)
+	}
+}
+
+func TestRestoreTaskRollsBackOnFailure(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	res, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Walk", Difficulty: DifficultyTrivial, Attribute: AttributeSTR})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	done, err := svc.CompleteTask(ctx, res.TaskID)
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+
+	failOnExec(svc, "UPDATE tasks SET status = 'pending'", 0)
+	if _, err := svc.RestoreTask(ctx, res.TaskID); !errors.Is(err, errInjected) {
+		t.Fatalf("RestoreTask err=%v, want injected failure", err)
+	}
+
+	task, _ := svc.TaskRepo().Get(ctx, res.TaskID)
+	if task.Status != "done" {
+		t.Fatalf("task status=%q, want done", task.Status)
+	}
+	p, _ := svc.PlayerRepo().GetOrCreateMain(ctx)
+	if p.XPTotal != done.XPAwarded {
+		t.Fatalf("player xp=%d, want %d", p.XPTotal, done.XPAwarded)
+	}
+	if n := countRows(t, svc, "task_completions"); n != 1 {
+		t.Fatalf("completions=%d, want 1", n)
+	}
+}
+
+func TestCreateTaskRollsBackProjectActivation(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelProjects))
+	proj, err := svc.CreateProject(ctx, CreateProjectInput{Title: "Learn Go", Attribute: AttributeINT})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+
+	failOnExec(svc, "UPDATE tasks SET status = ?", 0)
+	pid := proj.TaskID
+	if _, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Read the tour", Difficulty: DifficultyTrivial, Attribute: AttributeINT, ParentID: &pid}); !errors.Is(err, errInjected) {
+		t.Fatalf("CreateTask err=%v, want injected failure", err)
+	}
+
+	if n := countRows(t, svc, "tasks"); n != 1 {
+		t.Fatalf("tasks=%d, want only the project", n)
+	}
+	p, _ := svc.TaskRepo().Get(ctx, pid)
+	if p.Status != "planning" {
+		t.Fatalf("project status=%q, want planning", p.Status)
+	}
+}
+
+func TestAcceptBlueprintRollsBackChildren(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	p, err := svc.PlayerRepo().GetOrCreateMain(ctx)
+	if err != nil {
+		t.Fatalf("get player: %v", err)
+	}
+	p.XPTotal = XPRequiredForLevel(DifficultyUnlockLevels[DifficultyHard])
+	p.XP["INT"] = XPRequiredForLevel(2)
+	if err := svc.PlayerRepo().Update(ctx, p); err != nil {
+		t.Fatalf("update player: %v", err)
+	}
+
+	// int_course spawns a project and four children; fail on the third child.
+	failOnExec(svc, "INSERT INTO tasks", 3)
+	if _, err := svc.AcceptBlueprint(ctx, "int_course"); !errors.Is(err, errInjected) {
+		t.Fatalf("AcceptBlueprint err=%v, want injected failure", err)
+	}
+
+	if n := countRows(t, svc, "tasks"); n != 0 {
+		t.Fatalf("tasks=%d, want 0", n)
+	}
+	b, err := svc.BlueprintRepo().Get(ctx, "int_course")
+	if err != nil {
+		t.Fatalf("get blueprint: %v", err)
+	}
+	if b != nil && b.Status == string(BlueprintActive) {
+		t.Fatalf("blueprint status=%q after rollback", b.Status)
+	}
+
+	svc.wrapTx = nil
+	res, err := svc.AcceptBlueprint(ctx, "int_course")
+	if err != nil {
+		t.Fatalf("AcceptBlueprint after rollback: %v", err)
+	}
+	kids, err := svc.TaskRepo().ListChildren(ctx, res.TaskID)
+	if err != nil {
+		t.Fatalf("list children: %v", err)
+	}
+	if len(kids) != 4 {
+		t.Fatalf("children=%d, want 4", len(kids))
+	}
+}
@@ -17,12 +17,14 @@ const (
 	AttributeCAREER Attribute = "CAREER" // Career & Finance
 )
 
-// AllAttributes returns all valid attributes in display order.
+// AllAttributes returns the built-in attributes in display order.
+// Custom attributes live in the database registry (see Service.Attributes).
 var AllAttributes = []Attribute{
 	AttributeSTR, AttributeINT, AttributeWIS, AttributeART,
 	AttributeHOME, AttributeOUT, AttributeREAD, AttributeCINEMA, AttributeCAREER,
 }
 
+// IsValid reports whether a is a built-in attribute.
 func (a Attribute) IsValid() bool {
 	switch a {
 	case AttributeSTR, AttributeINT, AttributeWIS, AttributeART,
@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"questline/internal/storage"
+)
+
+// UnlockCondKind is what an unlock condition checks.
+type UnlockCondKind string
+
+const (
+	UnlockLevel     UnlockCondKind = "level"     // player level >= Min
+	UnlockAttr      UnlockCondKind = "attr"      // attribute level >= Min
+	UnlockProject   UnlockCondKind = "project"   // a project titled Name is done
+	UnlockBlueprint UnlockCondKind = "blueprint" // blueprint Name has been completed
+)
+
+// UnlockCond is one declarative blueprint unlock requirement.
+type UnlockCond struct {
+	Kind UnlockCondKind
+	Attr Attribute // UnlockAttr
+	Min  int       // UnlockLevel, UnlockAttr
+	Name string    // UnlockProject: project title; UnlockBlueprint: blueprint code
+}
+
+var (
+	reUnlockLevel     = regexp.MustCompile(`^level\s*(?:>=|≥)\s*(\d+)$`)
+	reUnlockAttr      = regexp.MustCompile(`^(?:attr\s+)?([a-z][a-z0-9_]*)\s*(?:>=|≥)\s*(\d+)$`)
+	reUnlockProject   = regexp.MustCompile(`^(?:completed project\s+(?:"([^"]+)"|'([^']+)')|project\s+(?:"([^"]+)"|'([^']+)')\s+completed)$`)
+	reUnlockBlueprint = regexp.MustCompile(`^(?:completed blueprint\s+([a-z0-9_\-]+)|blueprint\s+([a-z0-9_\-]+)\s+completed)$`)
+	reUnlockAnd       = regexp.MustCompile(`(?i)\s+and\s+|\s*&&\s*`)
+)
+
+// ParseUnlockExpr parses an unlock expression: one or more conditions joined
+// by "and" (or "&&"), all of which must hold. Conditions:
+//
+//	level >= 5
+//	STR >= 2              (also "attr STR >= 2"; attribute levels)
+//	project "Gym Program" completed   (also: completed project "Gym Program")
+//	blueprint str_gym completed       (also: completed blueprint str_gym)
+func ParseUnlockExpr(expr string) ([]UnlockCond, error) {
+	var out []UnlockCond
+	for _, part := range reUnlockAnd.Split(strings.TrimSpace(expr), -1) {
+		c, err := parseUnlockCond(part)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func parseUnlockCond(input string) (UnlockCond, error) {
+	s := strings.Join(strings.Fields(input), " ")
+	lower := strings.ToLower(s)
+	if m := reUnlockLevel.FindStringSubmatch(lower); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return UnlockCond{Kind: UnlockLevel, Min: n}, nil
+	}
+	if m := reUnlockAttr.FindStringSubmatch(lower); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return UnlockCond{Kind: UnlockAttr, Attr: Attribute(strings.ToUpper(m[1])), Min: n}, nil
+	}
+	// Match titles case-insensitively but keep them as written.
+	if m := reUnlockProject.FindStringSubmatchIndex(lower); m != nil {
+		for g := 1; g <= 4; g++ {
+			if m[2*g] >= 0 {
+				return UnlockCond{Kind: UnlockProject, Name: s[m[2*g]:m[2*g+1]]}, nil
+			}
+		}
+	}
+	if m := reUnlockBlueprint.FindStringSubmatch(lower); m != nil {
+		return UnlockCond{Kind: UnlockBlueprint, Name: m[1] + m[2]}, nil
+	}
+	return UnlockCond{}, fmt.Errorf("invalid unlock condition %q (try level >= 5, STR >= 2, project \"Title\" completed, blueprint code completed)", input)
+}
+
+// String returns the condition in expression form.
+func (c UnlockCond) String() string {
+	switch c.Kind {
+	case UnlockLevel:
+		return fmt.Sprintf("level >= %d", c.Min)
+	case UnlockAttr:
+		return fmt.Sprintf("%s >= %d", c.Attr, c.Min)
+	case UnlockProject:
+		return fmt.Sprintf("project %q completed", c.Name)
+	case UnlockBlueprint:
+		return fmt.Sprintf("blueprint %s completed", c.Name)
+	}
+	return string(c.Kind)
+}
+
+// unlockCondMet reports whether the player meets c.
+func (s *Service) unlockCondMet(ctx context.Context, p *storage.Player, c UnlockCond) (bool, error) {
+	switch c.Kind {
+	case UnlockLevel:
+		return p.Level >= c.Min, nil
+	case UnlockAttr:
+		return s.rules.PlayerAttrLevel(p, c.Attr) >= c.Min, nil
+	case UnlockProject:
+		return s.tasks.HasCompletedProjectTitle(ctx, c.Name)
+	case UnlockBlueprint:
+		b, err := s.blueprints.Get(ctx, c.Name)
+		if err != nil {
+			return false, err
+		}
+		return b != nil && (b.Status == string(BlueprintCompleted) || b.CompletedAt != nil), nil
+	}
+	return false, fmt.Errorf("unknown unlock condition %q", c.Kind)
+}
+
+// unlockMet reports whether the player meets every condition.
+func (s *Service) unlockMet(ctx context.Context, p *storage.Player, conds []UnlockCond) (bool, error) {
+	for _, c := range conds {
+		ok, err := s.unlockCondMet(ctx, p, c)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// BlueprintRequirement is one unlock requirement of a blueprint and whether
+// the player meets it.
+type BlueprintRequirement struct {
+	Text string
+	Met  bool
+}
+
+// blueprintRequirements lists the unlock requirements of def: the feature gate
+// of its kind, MinLevel, AttrReqs and Requires.
+func (s *Service) blueprintRequirements(ctx context.Context, p *storage.Player, def *BlueprintDef) ([]BlueprintRequirement, error) {
+	var reqs []BlueprintRequirement
+	gate := 0
+	switch def.Kind {
+	case BlueprintKindHabit:
+		gate = s.rules.Gates.Habits
+		reqs = append(reqs, BlueprintRequirement{Text: fmt.Sprintf("level >= %d (habits)", gate), Met: p.Level >= gate})
+	case BlueprintKindProject:
+		gate = s.rules.Gates.Projects
+		reqs = append(reqs, BlueprintRequirement{Text: fmt.Sprintf("level >= %d (projects)", gate), Met: p.Level >= gate})
+	}
+	if def.MinLevel > gate {
+		reqs = append(reqs, BlueprintRequirement{Text: fmt.Sprintf("level >= %d", def.MinLevel), Met: p.Level >= def.MinLevel})
+	}
+	for _, r := range def.AttrReqs {
+		c := UnlockCond{Kind: UnlockAttr, Attr: r.Attr, Min: r.MinLevel}
+		reqs = append(reqs, BlueprintRequirement{Text: c.String(), Met: s.rules.CheckUnlockReqs(p, r)})
+	}
+	for _, c := range def.Requires {
+		ok, err := s.unlockCondMet(ctx, p, c)
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, BlueprintRequirement{Text: c.String(), Met: ok})
+	}
+	return reqs, nil
+}
+
+// requirementsMet reports whether every requirement is met.
+func requirementsMet(reqs []BlueprintRequirement) bool {
+	for _, r := range reqs {
+		if !r.Met {
+			return false
+		}
+	}
+	return true
+}
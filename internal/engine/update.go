@@ -3,40 +3,378 @@ package engine
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
+
+	"questline/internal/storage"
 )
 
-// UpdateTaskDifficulty updates a task/habit difficulty and recalculates xp_value.
-// For habits, raising difficulty implicitly resets diminishing returns because the
-// decay rule only triggers for repeated completions at the same difficulty.
-func (s *Service) UpdateTaskDifficulty(ctx context.Context, id int64, newDifficulty Difficulty) error {
-	if !newDifficulty.IsValid() {
-		return fmt.Errorf("invalid difficulty: %d", newDifficulty)
-	}
+// TaskPatch lists the fields UpdateTask changes; nil fields are left alone.
+type TaskPatch struct {
+	Title       *string
+	Description *string // "" clears it
+	Difficulty  *Difficulty
+	// Attribute and Attributes replace the primary attribute and the weights
+	// together; Attributes is nil for a single attribute.
+	Attribute  *Attribute
+	Attributes map[Attribute]int
+	ParentID   *int64     // 0 moves the task to the top level
+	DueDate    *time.Time // see ClearDue
+	ClearDue   bool
 
-	p, err := s.getPlayer(ctx)
-	if err != nil {
+	// Habits only.
+	HabitInterval *HabitInterval
+	HabitDuration *time.Duration // From the habit's start; 0 makes it ongoing
+	HabitGoal     *int           // 0 removes the goal
+}
+
+// UpdateResult reports what UpdateTask changed.
+type UpdateResult struct {
+	TaskID           int64
+	Changed          []string // Names of the changed fields, e.g. "title", "parent"
+	XPBefore         int
+	XPAfter          int
+	ProjectActivated bool // The new parent project left the planning state
+}
+
+// UpdateTask applies a patch to a task in one transaction. Changing the
+// difficulty or attribute recalculates its XP value at the current attribute
+// level, so both are refused on done tasks (restore them first). Moving a task
+// goes through the same gates as creating a subtask, for the deepest task it
+// carries along, and may not put a task under itself or make tasks wait for
+// each other. A new habit schedule moves the due date to its first occurrence.
+func (s *Service) UpdateTask(ctx context.Context, id int64, patch TaskPatch) (*UpdateResult, error) {
+	var res *UpdateResult
+	err := s.inTx(ctx, func(tx *Service) error {
+		var err error
+		res, err = tx.updateTask(ctx, id, patch)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return res, nil
+}
 
+func (s *Service) updateTask(ctx context.Context, id int64, patch TaskPatch) (*UpdateResult, error) {
 	t, err := s.tasks.Get(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if t == nil {
-		return fmt.Errorf("task %d not found", id)
+		return nil, fmt.Errorf("task %d %w", id, ErrNotFound)
 	}
-	if t.IsProject {
-		return fmt.Errorf("cannot set difficulty on a project")
+	p, err := s.getPlayer(ctx)
+	if err != nil {
+		return nil, err
 	}
+	res := &UpdateResult{TaskID: id, XPBefore: t.XPValue, XPAfter: t.XPValue}
+	changed := func(field string) { res.Changed = append(res.Changed, field) }
 
-	attr := parseStoredAttribute(t.Attribute)
-	attrXP := playerXPForAttribute(p, attr)
-	attrLevel := AttributeLevelForXP(attrXP)
+	if patch.Title != nil {
+		title, err := normalizeTitle(*patch.Title)
+		if err != nil {
+			return nil, err
+		}
+		if title != t.Title {
+			t.Title = title
+			changed("title")
+		}
+	}
 
-	xpValue, err := CalculateXP(newDifficulty, attrLevel)
+	if patch.Description != nil {
+		var desc *string
+		if d := strings.TrimSpace(*patch.Description); d != "" {
+			desc = &d
+		}
+		if !equalPtr(desc, t.Description) {
+			t.Description = desc
+			changed("description")
+		}
+	}
+
+	recalc := false
+	if patch.Difficulty != nil && int(*patch.Difficulty) != t.Difficulty {
+		if t.IsProject {
+			return nil, fmt.Errorf("cannot set difficulty on a project")
+		}
+		if err := s.rules.CanUseDifficulty(p.Level, *patch.Difficulty); err != nil {
+			return nil, err
+		}
+		t.Difficulty = int(*patch.Difficulty)
+		recalc = true
+		changed("difficulty")
+	}
+
+	if patch.Attribute != nil {
+		reg, err := s.Attributes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		attrs := map[string]int{}
+		for a, w := range patch.Attributes {
+			if !reg.Has(a) {
+				return nil, fmt.Errorf("unknown attribute %q", a)
+			}
+			attrs[string(a)] = w
+		}
+		if !reg.Has(*patch.Attribute) {
+			return nil, fmt.Errorf("unknown attribute %q", *patch.Attribute)
+		}
+		if len(attrs) == 0 {
+			attrs = nil
+		}
+		if string(*patch.Attribute) != t.Attribute || !equalWeights(attrs, t.Attributes) {
+			t.Attribute = string(*patch.Attribute)
+			t.Attributes = attrs
+			recalc = !t.IsProject
+			changed("attributes")
+		}
+	}
+
+	if recalc {
+		if t.Status == "done" {
+			return nil, fmt.Errorf("task %d is done; restore it before changing its difficulty or attributes", id)
+		}
+		attrLevel := s.rules.AttributeLevelForXP(playerXPForAttribute(p, parseStoredAttribute(t.Attribute)))
+		xpValue, err := s.rules.CalculateXP(Difficulty(t.Difficulty), attrLevel)
+		if err != nil {
+			return nil, err
+		}
+		t.XPValue = xpValue
+		res.XPAfter = xpValue
+	}
+
+	var activate *storage.Task
+	if patch.ParentID != nil {
+		var parentID *int64
+		if *patch.ParentID != 0 {
+			v := *patch.ParentID
+			parentID = &v
+		}
+		if !equalPtr(parentID, t.ParentID) {
+			if parentID != nil {
+				if activate, err = s.checkReparent(ctx, p, t, *parentID); err != nil {
+					return nil, err
+				}
+			}
+			t.ParentID = parentID
+			changed("parent")
+		}
+	}
+
+	if patch.ClearDue || patch.DueDate != nil {
+		var due *time.Time
+		if !patch.ClearDue {
+			v := patch.DueDate.UTC()
+			due = &v
+		}
+		if t.IsHabit && due == nil {
+			return nil, fmt.Errorf("habit %d needs a due date; change its schedule instead", id)
+		}
+		if !equalTime(due, t.DueDate) {
+			t.DueDate = due
+			changed("due")
+		}
+	}
+
+	if patch.HabitInterval != nil || patch.HabitDuration != nil || patch.HabitGoal != nil {
+		if !t.IsHabit {
+			return nil, fmt.Errorf("task %d is not a habit", id)
+		}
+	}
+	if patch.HabitInterval != nil {
+		sched, err := ParseHabitSchedule(string(*patch.HabitInterval))
+		if err != nil {
+			return nil, err
+		}
+		interval := sched.String()
+		if t.HabitInterval == nil || interval != *t.HabitInterval {
+			t.HabitInterval = &interval
+			changed("interval")
+			if patch.DueDate == nil {
+				due := sched.FirstDue(time.Now()).UTC()
+				t.DueDate = &due
+			}
+		}
+	}
+	if patch.HabitDuration != nil {
+		var end *time.Time
+		if *patch.HabitDuration > 0 {
+			start := t.CreatedAt
+			if t.HabitStartDate != nil {
+				start = *t.HabitStartDate
+			}
+			v := start.Add(*patch.HabitDuration)
+			end = &v
+		}
+		if !equalTime(end, t.HabitEndDate) {
+			t.HabitEndDate = end
+			changed("duration")
+		}
+	}
+	if patch.HabitGoal != nil {
+		if *patch.HabitGoal < 0 {
+			return nil, fmt.Errorf("goal must not be negative")
+		}
+		var goal *int
+		if *patch.HabitGoal > 0 {
+			v := *patch.HabitGoal
+			goal = &v
+		}
+		if !equalPtr(goal, t.HabitGoal) {
+			t.HabitGoal = goal
+			changed("goal")
+		}
+	}
+
+	if len(res.Changed) == 0 {
+		return res, nil
+	}
+	if err := s.tasks.Update(ctx, *t); err != nil {
+		return nil, err
+	}
+	if activate != nil {
+		if err := s.tasks.UpdateStatus(ctx, activate.ID, "active"); err != nil {
+			return nil, err
+		}
+		res.ProjectActivated = true
+	}
+	return res, nil
+}
+
+// checkParentAccepts reports why parent can't take subtasks: habits must stay
+// leaves to be completed, and finished tasks take no new work.
+func checkParentAccepts(parent *storage.Task) error {
+	if parent.IsHabit {
+		return fmt.Errorf("task %d is a habit; habits can't have subtasks", parent.ID)
+	}
+	if parent.Status == "done" {
+		return fmt.Errorf("task %d is already done", parent.ID)
+	}
+	return nil
+}
+
+// checkReparent validates moving t under parentID and returns the parent if
+// it is a planning project the move activates.
+func (s *Service) checkReparent(ctx context.Context, p *storage.Player, t *storage.Task, parentID int64) (*storage.Task, error) {
+	parent, err := s.tasks.Get(ctx, parentID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if parent == nil {
+		return nil, fmt.Errorf("parent task %d %w", parentID, ErrNotFound)
+	}
+	if err := checkParentAccepts(parent); err != nil {
+		return nil, err
+	}
+	for cur := parent; ; {
+		if cur.ID == t.ID {
+			return nil, fmt.Errorf("cannot move task %d under itself or one of its subtasks", t.ID)
+		}
+		if cur.ParentID == nil {
+			break
+		}
+		if cur, err = s.tasks.Get(ctx, *cur.ParentID); err != nil {
+			return nil, err
+		}
+		if cur == nil {
+			break
+		}
+	}
+
+	depth, err := s.taskDepthFromRoot(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	height, err := s.subtreeHeight(ctx, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.rules.CanAttachToParent(p.Level, depth+1+height); err != nil {
+		return nil, err
+	}
+
+	// The new parent waits for the task, which must not already wait for it.
+	waits, err := s.waitGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if t.ParentID != nil {
+		waits[*t.ParentID] = removeID(waits[*t.ParentID], t.ID)
+	}
+	if path := findPath(waits, t.ID, parentID); path != nil {
+		return nil, CycleError{Path: append([]int64{parentID}, path...)}
+	}
+
+	if parent.IsProject && parent.Status == "planning" {
+		return parent, nil
+	}
+	return nil, nil
+}
+
+// subtreeHeight returns how many levels of subtasks sit below id (0 for a leaf).
+func (s *Service) subtreeHeight(ctx context.Context, id int64) (int, error) {
+	children, err := s.tasks.ListChildren(ctx, id)
+	if err != nil {
+		return 0, err
 	}
+	height := 0
+	for _, c := range children {
+		h, err := s.subtreeHeight(ctx, c.ID)
+		if err != nil {
+			return 0, err
+		}
+		if h+1 > height {
+			height = h + 1
+		}
+	}
+	return height, nil
+}
+
+// UpdateTaskDifficulty updates a task/habit difficulty and recalculates xp_value.
+// For habits, raising difficulty implicitly resets diminishing returns because the
+// decay rule only triggers for repeated completions at the same difficulty.
+func (s *Service) UpdateTaskDifficulty(ctx context.Context, id int64, newDifficulty Difficulty) error {
+	if !newDifficulty.IsValid() {
+		return fmt.Errorf("invalid difficulty: %d", newDifficulty)
+	}
+	_, err := s.UpdateTask(ctx, id, TaskPatch{Difficulty: &newDifficulty})
+	return err
+}
 
-	return s.tasks.UpdateDifficultyAndXP(ctx, id, int(newDifficulty), xpValue)
+func removeID(ids []int64, id int64) []int64 {
+	out := ids[:0:0]
+	for _, v := range ids {
+		if v != id {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func equalWeights(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,241 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdateTaskFieldsAndXP(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelHabits))
+
+	task, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Read", Difficulty: DifficultyTrivial, Attribute: AttributeINT})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	before, _ := svc.TaskRepo().Get(ctx, task.TaskID)
+
+	title, desc, diff := "Read two chapters", "  the blue book ", DifficultyEasy
+	attr := AttributeART
+	due := time.Date(2026, 11, 1, 18, 0, 0, 0, time.UTC)
+	res, err := svc.UpdateTask(ctx, task.TaskID, TaskPatch{
+		Title:       &title,
+		Description: &desc,
+		Difficulty:  &diff,
+		Attribute:   &attr,
+		Attributes:  map[Attribute]int{AttributeART: 60, AttributeINT: 40},
+		DueDate:     &due,
+	})
+	if err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if want := []string{"title", "description", "difficulty", "attributes", "due"}; !reflect.DeepEqual(res.Changed, want) {
+		t.Fatalf("changed=%v, want %v", res.Changed, want)
+	}
+	want, _ := svc.Rules().CalculateXP(DifficultyEasy, 0)
+	if res.XPBefore != before.XPValue || res.XPAfter != want {
+		t.Fatalf("xp %d → %d, want %d → %d", res.XPBefore, res.XPAfter, before.XPValue, want)
+	}
+	got, _ := svc.TaskRepo().Get(ctx, task.TaskID)
+	if got.Title != title || *got.Description != "the blue book" || got.Attribute != "ART" || got.Attributes["INT"] != 40 || !got.DueDate.Equal(due) || got.XPValue != want {
+		t.Fatalf("task=%+v", got)
+	}
+
+	// Re-applying the same values changes nothing.
+	if res, err := svc.UpdateTask(ctx, task.TaskID, TaskPatch{Title: &title, DueDate: &due}); err != nil || len(res.Changed) != 0 {
+		t.Fatalf("no-op update changed=%v, %v", res.Changed, err)
+	}
+	if _, err := svc.UpdateTask(ctx, task.TaskID, TaskPatch{ClearDue: true}); err != nil {
+		t.Fatalf("clear due: %v", err)
+	}
+	if got, _ := svc.TaskRepo().Get(ctx, task.TaskID); got.DueDate != nil {
+		t.Fatalf("due=%v after clearing", got.DueDate)
+	}
+
+	// Gated difficulties, habit fields on tasks and done tasks are refused.
+	epic := DifficultyEpic
+	if _, err := svc.UpdateTask(ctx, task.TaskID, TaskPatch{Difficulty: &epic}); !errors.As(err, new(DifficultyGateError)) {
+		t.Fatalf("epic err=%v, want a gate error", err)
+	}
+	goal := 3
+	if _, err := svc.UpdateTask(ctx, task.TaskID, TaskPatch{HabitGoal: &goal}); err == nil {
+		t.Fatal("setting a goal on a task succeeded")
+	}
+	if _, err := svc.CompleteTask(ctx, task.TaskID); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if _, err := svc.UpdateTask(ctx, task.TaskID, TaskPatch{Difficulty: new(Difficulty)}); err == nil {
+		t.Fatal("changing the difficulty of a done task succeeded")
+	}
+
+	// Habits: a new schedule moves the due date; duration and goal can be cleared.
+	habit, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Gym", Difficulty: DifficultyTrivial, Attribute: AttributeSTR, IsHabit: true, HabitInterval: HabitIntervalDaily})
+	if err != nil {
+		t.Fatalf("CreateTask habit: %v", err)
+	}
+	interval := HabitInterval("3x/week")
+	dur := 30 * 24 * time.Hour
+	if _, err := svc.UpdateTask(ctx, habit.TaskID, TaskPatch{HabitInterval: &interval, HabitDuration: &dur, HabitGoal: &goal}); err != nil {
+		t.Fatalf("UpdateTask habit: %v", err)
+	}
+	h, _ := svc.TaskRepo().Get(ctx, habit.TaskID)
+	sched, _ := ParseHabitSchedule("3x/week")
+	if *h.HabitInterval != "3x/week" || !h.DueDate.Equal(sched.FirstDue(time.Now()).UTC()) || h.HabitEndDate.Sub(*h.HabitStartDate) != dur || *h.HabitGoal != 3 {
+		t.Fatalf("habit=%+v", h)
+	}
+	zero := 0
+	if _, err := svc.UpdateTask(ctx, habit.TaskID, TaskPatch{HabitDuration: new(time.Duration), HabitGoal: &zero}); err != nil {
+		t.Fatalf("clear habit duration/goal: %v", err)
+	}
+	if h, _ := svc.TaskRepo().Get(ctx, habit.TaskID); h.HabitEndDate != nil || h.HabitGoal != nil {
+		t.Fatalf("habit end=%v goal=%v, want both cleared", h.HabitEndDate, h.HabitGoal)
+	}
+}
+
+func TestUpdateTaskReparent(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelSubtasks))
+
+	create := func(title string, parent *int64) int64 {
+		t.Helper()
+		res, err := svc.CreateTask(ctx, CreateTaskInput{Title: title, Difficulty: DifficultyTrivial, Attribute: AttributeSTR, ParentID: parent})
+		if err != nil {
+			t.Fatalf("CreateTask %s: %v", title, err)
+		}
+		return res.TaskID
+	}
+	a := create("A", nil)
+	b := create("B", nil)
+	c := create("C", &b)
+
+	// Only one level of subtasks is unlocked: B carries C along.
+	if _, err := svc.UpdateTask(ctx, b, TaskPatch{ParentID: &a}); err == nil || !strings.Contains(err.Error(), "depth") {
+		t.Fatalf("move B under A err=%v, want a depth error", err)
+	}
+	if _, err := svc.UpdateTask(ctx, b, TaskPatch{ParentID: &c}); err == nil {
+		t.Fatal("moving B under its own subtask succeeded")
+	}
+	res, err := svc.UpdateTask(ctx, c, TaskPatch{ParentID: &a})
+	if err != nil || !reflect.DeepEqual(res.Changed, []string{"parent"}) {
+		t.Fatalf("move C under A: %v, %v", res, err)
+	}
+	top := int64(0)
+	if _, err := svc.UpdateTask(ctx, c, TaskPatch{ParentID: &top}); err != nil {
+		t.Fatalf("move C to the top level: %v", err)
+	}
+	if got, _ := svc.TaskRepo().Get(ctx, c); got.ParentID != nil {
+		t.Fatalf("parent=%v, want none", *got.ParentID)
+	}
+
+	// Moving into a planning project activates it; a project can't hold a
+	// task it has to wait for.
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelProjects))
+	proj, err := svc.CreateProject(ctx, CreateProjectInput{Title: "Move house", Attribute: AttributeHOME})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	if err := svc.AddDependency(ctx, a, proj.TaskID); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+	if _, err := svc.UpdateTask(ctx, a, TaskPatch{ParentID: &proj.TaskID}); !errors.As(err, new(CycleError)) {
+		t.Fatalf("move A into the project it waits for err=%v, want a cycle", err)
+	}
+	res, err = svc.UpdateTask(ctx, c, TaskPatch{ParentID: &proj.TaskID})
+	if err != nil || !res.ProjectActivated {
+		t.Fatalf("move C into the project: %+v, %v; want it activated", res, err)
+	}
+}
+
+func TestHabitsAndDoneTasksTakeNoSubtasks(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelDeepRecurs))
+
+	create := func(in CreateTaskInput) int64 {
+		t.Helper()
+		in.Difficulty, in.Attribute = DifficultyTrivial, AttributeSTR
+		res, err := svc.CreateTask(ctx, in)
+		if err != nil {
+			t.Fatalf("CreateTask %s: %v", in.Title, err)
+		}
+		return res.TaskID
+	}
+	habit := create(CreateTaskInput{Title: "Stretch", IsHabit: true, HabitInterval: "daily"})
+	done := create(CreateTaskInput{Title: "Buy mat"})
+	if _, err := svc.CompleteTask(ctx, done); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	task := create(CreateTaskInput{Title: "Warm up"})
+
+	for _, parent := range []int64{habit, done} {
+		if _, err := svc.UpdateTask(ctx, task, TaskPatch{ParentID: &parent}); err == nil {
+			t.Errorf("moving a task under #%d succeeded", parent)
+		}
+		p := parent
+		if _, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Cool down", Difficulty: DifficultyTrivial, Attribute: AttributeSTR, ParentID: &p}); err == nil {
+			t.Errorf("adding a subtask to #%d succeeded", parent)
+		}
+	}
+	// The habit is still a leaf and can be completed.
+	if _, err := svc.CompleteTask(ctx, habit); err != nil {
+		t.Fatalf("CompleteTask(habit): %v", err)
+	}
+}
+
+func TestTaskDocRoundTrip(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelHabits))
+
+	due := time.Date(2026, 11, 1, 23, 59, 59, 0, time.UTC)
+	res, err := svc.CreateTask(ctx, CreateTaskInput{Title: "Draw", Difficulty: DifficultyEasy, Attribute: AttributeART, Attributes: map[Attribute]int{AttributeART: 70, AttributeWIS: 30}, DueDate: &due})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	task, _ := svc.TaskRepo().Get(ctx, res.TaskID)
+	reg, _ := svc.Attributes(ctx)
+
+	orig := NewTaskDoc(task, time.UTC)
+	if want := (TaskDoc{Title: "Draw", Difficulty: 2, Attributes: "ART:70,WIS:30", Due: "2026-11-01"}); orig != want {
+		t.Fatalf("doc=%+v, want %+v", orig, want)
+	}
+	data, err := orig.YAML()
+	if err != nil {
+		t.Fatalf("YAML: %v", err)
+	}
+	back, err := ParseTaskDoc(data)
+	if err != nil || back != orig {
+		t.Fatalf("round trip=%+v, %v; want %+v", back, err, orig)
+	}
+	if p, err := back.Patch(reg, orig, time.Now()); err != nil || !reflect.DeepEqual(p, TaskPatch{}) {
+		t.Fatalf("unchanged doc patch=%+v, %v", p, err)
+	}
+
+	edited, err := ParseTaskDoc([]byte("title: Draw daily\ndifficulty: 2\nattributes: art\n"))
+	if err != nil {
+		t.Fatalf("ParseTaskDoc: %v", err)
+	}
+	p, err := edited.Patch(reg, orig, time.Now())
+	if err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	if *p.Title != "Draw daily" || *p.Attribute != AttributeART || p.Attributes != nil || !p.ClearDue || p.Difficulty != nil {
+		t.Fatalf("patch=%+v", p)
+	}
+	if _, err := ParseTaskDoc([]byte("title: x\nprio: 1\n")); err == nil {
+		t.Fatal("unknown key accepted")
+	}
+	if _, err := (TaskDoc{Title: "Draw", Attributes: "ART:50,nope:50"}).Patch(reg, orig, time.Now()); err == nil {
+		t.Fatal("unknown attribute accepted")
+	}
+}
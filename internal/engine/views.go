@@ -0,0 +1,767 @@
+package engine
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"questline/internal/storage"
+)
+
+// ViewVersion is the version of the views below, the documents ql --output
+// prints for scripts. Fields are only added within a version; see
+// docs/OUTPUT.md for the schema. All times are UTC.
+const ViewVersion = 1
+
+// TaskListView is the task tree printed by ql list.
+type TaskListView struct {
+	Version int        `json:"version"`
+	Tasks   []TaskView `json:"tasks"` // Top-level tasks; subtasks nest under them
+}
+
+type TaskView struct {
+	ID            int64          `json:"id"`
+	ParentID      *int64         `json:"parent_id,omitempty"`
+	Title         string         `json:"title"`
+	Description   string         `json:"description,omitempty"`
+	Kind          string         `json:"kind"` // task, habit or project
+	Status        string         `json:"status"`
+	Difficulty    int            `json:"difficulty"`
+	Attribute     string         `json:"attribute"`
+	Attributes    map[string]int `json:"attributes,omitempty"`
+	XPValue       int            `json:"xp_value"`
+	CreatedAt     time.Time      `json:"created_at"`
+	CompletedAt   *time.Time     `json:"completed_at,omitempty"`
+	DueDate       *time.Time     `json:"due_date,omitempty"`
+	Overdue       bool           `json:"overdue"`
+	Tags          []string       `json:"tags"`
+	InheritedTags []string       `json:"inherited_tags"`
+	BlockedBy     []int64        `json:"blocked_by"` // Unfinished tasks it waits for
+	Habit         *HabitView     `json:"habit,omitempty"`
+	Subtasks      []TaskView     `json:"subtasks"`
+}
+
+type HabitView struct {
+	Interval       string     `json:"interval"`
+	Streak         int        `json:"streak"`
+	LongestStreak  int        `json:"longest_streak"`
+	DoneThisPeriod bool       `json:"done_this_period"`
+	PeriodDone     int        `json:"period_done"`
+	PeriodTarget   int        `json:"period_target"`
+	Completions    int        `json:"completions"`
+	Goal           *int       `json:"goal,omitempty"`
+	EndDate        *time.Time `json:"end_date,omitempty"`
+}
+
+// TaskViews returns a view of each of tasks, in the same order and without
+// subtasks, the way ql search lists them.
+func (s *Service) TaskViews(ctx context.Context, tasks []storage.Task, now time.Time) ([]TaskView, error) {
+	streaks, err := s.HabitStreaks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := s.BlockedTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.TaskTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]TaskView, 0, len(tasks))
+	for i := range tasks {
+		t := &tasks[i]
+		v := newTaskView(t, now)
+		if tt, ok := tags[t.ID]; ok {
+			v.Tags = append(v.Tags, tt.Own...)
+			v.InheritedTags = append(v.InheritedTags, tt.Inherited...)
+		}
+		v.BlockedBy = append(v.BlockedBy, blocked[t.ID]...)
+		if t.IsHabit {
+			comps, err := s.completions.ListByTask(ctx, t.ID)
+			if err != nil {
+				return nil, err
+			}
+			p := GetHabitProgress(t, comps, now)
+			st := streaks[t.ID]
+			v.Habit = &HabitView{
+				Streak:         st.Current,
+				LongestStreak:  st.Longest,
+				DoneThisPeriod: st.DoneThisPeriod,
+				PeriodDone:     p.PeriodDone,
+				PeriodTarget:   p.PeriodTarget,
+				Completions:    p.Completions,
+				Goal:           t.HabitGoal,
+				EndDate:        utcTime(t.HabitEndDate),
+			}
+			if t.HabitInterval != nil {
+				v.Habit.Interval = *t.HabitInterval
+			}
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// TaskTree returns tasks as a tree, the way ql list shows them: subtasks
+// under their parent and siblings in the order they can be done in. A subtask
+// whose parent is not among tasks is listed at the top level.
+func (s *Service) TaskTree(ctx context.Context, tasks []storage.Task, now time.Time) ([]TaskView, error) {
+	flat, err := s.TaskViews(ctx, tasks, now)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := s.Dependencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	views := make(map[int64]*TaskView, len(flat))
+	for i := range flat {
+		views[flat[i].ID] = &flat[i]
+	}
+
+	var roots []int64
+	children := map[int64][]int64{}
+	for i := range tasks {
+		t := &tasks[i]
+		if t.ParentID == nil || views[*t.ParentID] == nil {
+			roots = append(roots, t.ID)
+			continue
+		}
+		children[*t.ParentID] = append(children[*t.ParentID], t.ID)
+	}
+	var build func(ids []int64) []TaskView
+	build = func(ids []int64) []TaskView {
+		out := []TaskView{}
+		for _, id := range OrderByDependencies(ids, deps) {
+			v := *views[id]
+			v.Subtasks = build(children[id])
+			out = append(out, v)
+		}
+		return out
+	}
+	return build(roots), nil
+}
+
+func newTaskView(t *storage.Task, now time.Time) TaskView {
+	v := TaskView{
+		ID:            t.ID,
+		ParentID:      t.ParentID,
+		Title:         t.Title,
+		Kind:          taskKind(t),
+		Status:        t.Status,
+		Difficulty:    t.Difficulty,
+		Attribute:     string(parseStoredAttribute(t.Attribute)),
+		Attributes:    t.Attributes,
+		XPValue:       t.XPValue,
+		CreatedAt:     t.CreatedAt.UTC(),
+		CompletedAt:   utcTime(t.CompletedAt),
+		DueDate:       utcTime(t.DueDate),
+		Overdue:       IsOverdue(t, now),
+		Tags:          []string{},
+		InheritedTags: []string{},
+		BlockedBy:     []int64{},
+		Subtasks:      []TaskView{},
+	}
+	if t.Description != nil {
+		v.Description = *t.Description
+	}
+	return v
+}
+
+// taskKind returns task, habit or project.
+func taskKind(t *storage.Task) string {
+	switch {
+	case t.IsProject:
+		return "project"
+	case t.IsHabit:
+		return "habit"
+	}
+	return "task"
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// StatusView is the player summary printed by ql status.
+type StatusView struct {
+	Version    int             `json:"version"`
+	Player     PlayerView      `json:"player"`
+	Gates      GatesView       `json:"gates"`
+	Blueprints []BlueprintView `json:"blueprints"`
+	Overdue    []int64         `json:"overdue"` // Open tasks past their due date, earliest first
+	Timer      *SessionView    `json:"timer"`   // The running timer, null when none runs
+}
+
+type PlayerView struct {
+	Level         int             `json:"level"`
+	XPTotal       int             `json:"xp_total"`
+	XPNextLevel   int             `json:"xp_next_level"` // Total XP the next level needs
+	HP            int             `json:"hp"`
+	HPMax         int             `json:"hp_max"`
+	WeakenedUntil *time.Time      `json:"weakened_until,omitempty"` // Set while knocked out
+	FreezeTokens  int             `json:"freeze_tokens"`
+	Attributes    []AttributeView `json:"attributes"`
+}
+
+type AttributeView struct {
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+	Icon  string `json:"icon,omitempty"`
+	XP    int    `json:"xp"`
+	Level int    `json:"level"`
+}
+
+type GatesView struct {
+	MaxActiveTasks      int  `json:"max_active_tasks"`
+	ActiveTasks         int  `json:"active_tasks"`
+	MaxSubtaskDepth     int  `json:"max_subtask_depth"` // 0 while subtasks are locked, -1 for unlimited
+	MaxDifficulty       int  `json:"max_difficulty"`
+	NextDifficultyLevel int  `json:"next_difficulty_level,omitempty"` // Level unlocking the next difficulty
+	Habits              bool `json:"habits"`
+	Projects            bool `json:"projects"`
+	Reviews             bool `json:"reviews"`
+}
+
+type BlueprintView struct {
+	Code        string     `json:"code"`
+	Kind        string     `json:"kind"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	Status      string     `json:"status"` // locked, available, active or completed
+	Repeatable  bool       `json:"repeatable"`
+	CanAccept   bool       `json:"can_accept"`
+	XP          int        `json:"xp"`    // XP of one completion of every task it creates
+	Bonus       int        `json:"bonus"` // Blueprint bonus still to earn
+	TaskID      *int64     `json:"task_id,omitempty"`
+	Done        int        `json:"done"`
+	Total       int        `json:"total"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+type SessionView struct {
+	ID        int64      `json:"id"`
+	TaskID    int64      `json:"task_id"`
+	Kind      string     `json:"kind"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Seconds   int64      `json:"seconds"`
+}
+
+// StatusView returns the player, gates and blueprints as ql status shows them.
+func (s *Service) StatusView(ctx context.Context, now time.Time) (*StatusView, error) {
+	player, err := s.PlayerView(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gates, err := s.GatesView(ctx)
+	if err != nil {
+		return nil, err
+	}
+	blueprints, err := s.BlueprintViews(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v := &StatusView{Version: ViewVersion, Player: *player, Gates: *gates, Blueprints: blueprints, Overdue: []int64{}}
+
+	all, err := s.tasks.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var overdue []storage.Task
+	for i := range all {
+		if IsOverdue(&all[i], now) {
+			overdue = append(overdue, all[i])
+		}
+	}
+	sort.SliceStable(overdue, func(i, j int) bool { return overdue[i].DueDate.Before(*overdue[j].DueDate) })
+	for _, t := range overdue {
+		v.Overdue = append(v.Overdue, t.ID)
+	}
+
+	running, err := s.sessions.Running(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.Timer = newSessionView(running, now)
+	return v, nil
+}
+
+// newSessionView returns the view of a timer session, nil for none.
+func newSessionView(ts *storage.TaskSession, now time.Time) *SessionView {
+	if ts == nil {
+		return nil
+	}
+	return &SessionView{
+		ID:        ts.ID,
+		TaskID:    ts.TaskID,
+		Kind:      ts.Kind,
+		StartedAt: ts.StartedAt.UTC(),
+		EndedAt:   utcTime(ts.EndedAt),
+		Seconds:   int64(ts.Duration(now) / time.Second),
+	}
+}
+
+// PlayerView returns the player's level, XP, HP and attribute levels.
+func (s *Service) PlayerView(ctx context.Context) (*PlayerView, error) {
+	p, err := s.getPlayer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	level := s.rules.LevelForTotalXP(p.XPTotal)
+	v := &PlayerView{Level: level, XPTotal: p.XPTotal, XPNextLevel: s.rules.XPRequiredForLevel(level + 1), Attributes: []AttributeView{}}
+	hp, err := s.HP(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	v.HP, v.HPMax, v.WeakenedUntil = hp.HP, hp.Max, utcTime(hp.WeakenedUntil)
+	if v.FreezeTokens, err = s.FreezeTokens(ctx); err != nil {
+		return nil, err
+	}
+	reg, err := s.Attributes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range reg.All() {
+		xp := p.AttrXP(string(a.Code))
+		v.Attributes = append(v.Attributes, AttributeView{Code: string(a.Code), Name: a.Name, Icon: a.Icon, XP: xp, Level: s.rules.AttributeLevelForXP(xp)})
+	}
+	return v, nil
+}
+
+// GatesView returns what the player's level unlocks.
+func (s *Service) GatesView(ctx context.Context) (*GatesView, error) {
+	p, err := s.getPlayer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	level := s.rules.LevelForTotalXP(p.XPTotal)
+	maxDiff := s.rules.MaxDifficultyForLevel(level)
+	v := &GatesView{
+		MaxActiveTasks:  s.rules.MaxActiveTasks(level),
+		MaxSubtaskDepth: s.rules.MaxSubtaskDepth(level),
+		MaxDifficulty:   int(maxDiff),
+		Habits:          level >= s.rules.Gates.Habits,
+		Projects:        level >= s.rules.Gates.Projects,
+		Reviews:         level >= s.rules.Gates.Reviews,
+	}
+	if maxDiff < DifficultyEpic {
+		v.NextDifficultyLevel = s.rules.DifficultyUnlockLevel(maxDiff + 1)
+	}
+	all, err := s.tasks.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range all {
+		if !all[i].IsProject && (all[i].Status == "pending" || all[i].Status == "active") {
+			v.ActiveTasks++
+		}
+	}
+	return v, nil
+}
+
+// BlueprintViews returns every blueprint with its status and progress.
+func (s *Service) BlueprintViews(ctx context.Context) ([]BlueprintView, error) {
+	infos, err := s.Blueprints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := []BlueprintView{}
+	for i := range infos {
+		out = append(out, NewBlueprintView(&infos[i]))
+	}
+	return out, nil
+}
+
+// NewBlueprintView returns the view of one blueprint.
+func NewBlueprintView(b *BlueprintInfo) BlueprintView {
+	v := BlueprintView{
+		Code:        b.Def.Code,
+		Kind:        string(b.Def.Kind),
+		Title:       b.Def.Title,
+		Description: b.Def.Description,
+		Status:      string(b.Status),
+		Repeatable:  b.Def.Repeatable,
+		CanAccept:   b.CanAccept(),
+		XP:          b.XP,
+		Bonus:       b.Bonus,
+	}
+	if p := b.Progress; p != nil {
+		if p.TaskID != 0 {
+			id := p.TaskID
+			v.TaskID = &id
+		}
+		v.Done, v.Total, v.CompletedAt = p.Done, p.Total, utcTime(p.CompletedAt)
+	}
+	return v
+}
+
+type AchievementView struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Icon        string `json:"icon,omitempty"`
+	Description string `json:"description"`
+}
+
+func newAchievementViews(earned []Achievement) []AchievementView {
+	out := []AchievementView{}
+	for _, a := range earned {
+		out = append(out, AchievementView{ID: a.ID, Name: a.Name, Icon: a.Icon, Description: a.Description})
+	}
+	return out
+}
+
+// CompleteView is the outcome of completing a task (ql do).
+type CompleteView struct {
+	Version            int               `json:"version"`
+	TaskID             int64             `json:"task_id"`
+	XPAwarded          int               `json:"xp_awarded"`
+	LevelBefore        int               `json:"level_before"`
+	LevelAfter         int               `json:"level_after"`
+	LevelUp            bool              `json:"level_up"`
+	DeadlineDelta      int               `json:"deadline_delta"` // Early bonus (> 0) or late penalty (< 0)
+	TrackedSeconds     int64             `json:"tracked_seconds"`
+	TimeBonus          int               `json:"time_bonus"`
+	Streak             int               `json:"streak"`
+	StreakBonus        int               `json:"streak_bonus"`
+	FreezesUsed        int               `json:"freezes_used"`
+	FreezeEarned       bool              `json:"freeze_earned"`
+	HPHealed           int               `json:"hp_healed"`
+	WeakenedLoss       int               `json:"weakened_loss"`
+	HabitCompleted     bool              `json:"habit_completed"`
+	ProjectBonus       bool              `json:"project_bonus"`
+	BlueprintCompleted string            `json:"blueprint_completed,omitempty"`
+	BlueprintBonus     int               `json:"blueprint_bonus"`
+	Achievements       []AchievementView `json:"achievements"`
+}
+
+func NewCompleteView(res *CompleteResult) CompleteView {
+	return CompleteView{
+		Version:            ViewVersion,
+		TaskID:             res.TaskID,
+		XPAwarded:          res.XPAwarded,
+		LevelBefore:        res.LevelBefore,
+		LevelAfter:         res.LevelAfter,
+		LevelUp:            res.LevelUp,
+		DeadlineDelta:      res.DeadlineDelta,
+		TrackedSeconds:     int64(res.Tracked / time.Second),
+		TimeBonus:          res.TimeBonus,
+		Streak:             res.Streak,
+		StreakBonus:        res.StreakBonus,
+		FreezesUsed:        res.FreezesUsed,
+		FreezeEarned:       res.FreezeEarned,
+		HPHealed:           res.HPHealed,
+		WeakenedLoss:       res.WeakenedLoss,
+		HabitCompleted:     res.HabitCompleted,
+		ProjectBonus:       res.ProjectBonus,
+		BlueprintCompleted: res.BlueprintCompleted,
+		BlueprintBonus:     res.BlueprintBonus,
+		Achievements:       newAchievementViews(res.Achievements),
+	}
+}
+
+// RestoreView is the outcome of undoing a completion (ql restore).
+type RestoreView struct {
+	Version     int   `json:"version"`
+	TaskID      int64 `json:"task_id"`
+	XPDeducted  int   `json:"xp_deducted"`
+	LevelBefore int   `json:"level_before"`
+	LevelAfter  int   `json:"level_after"`
+	LevelDown   bool  `json:"level_down"`
+}
+
+func NewRestoreView(res *RestoreResult) RestoreView {
+	return RestoreView{
+		Version:     ViewVersion,
+		TaskID:      res.TaskID,
+		XPDeducted:  res.XPDeducted,
+		LevelBefore: res.LevelBefore,
+		LevelAfter:  res.LevelAfter,
+		LevelDown:   res.LevelDown,
+	}
+}
+
+// CreateView is the outcome of adding a task (ql add) or accepting a
+// blueprint (ql accept).
+type CreateView struct {
+	Version          int               `json:"version"`
+	TaskID           int64             `json:"task_id"`
+	ProjectActivated bool              `json:"project_activated"`
+	Achievements     []AchievementView `json:"achievements"`
+}
+
+func NewCreateView(res *CreateResult) CreateView {
+	return CreateView{
+		Version:          ViewVersion,
+		TaskID:           res.TaskID,
+		ProjectActivated: res.ProjectActivated,
+		Achievements:     newAchievementViews(res.Achievements),
+	}
+}
+
+// ErrorView describes a failed command; Code is one of the ErrCode*
+// constants.
+type ErrorView struct {
+	Version int       `json:"version"`
+	Error   ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func NewErrorView(code string, err error) ErrorView {
+	return ErrorView{Version: ViewVersion, Error: ErrorBody{Code: code, Message: err.Error()}}
+}
+
+// DeleteView is the outcome of moving a task to the trash.
+type DeleteView struct {
+	Version     int     `json:"version"`
+	TaskID      int64   `json:"task_id"`
+	Deleted     []int64 `json:"deleted"` // The task and its subtasks
+	XPRevoked   int     `json:"xp_revoked"`
+	LevelBefore int     `json:"level_before"`
+	LevelAfter  int     `json:"level_after"`
+}
+
+func NewDeleteView(res *DeleteResult) DeleteView {
+	return DeleteView{
+		Version:     ViewVersion,
+		TaskID:      res.TaskID,
+		Deleted:     append([]int64{}, res.Deleted...),
+		XPRevoked:   res.XPRevoked,
+		LevelBefore: res.LevelBefore,
+		LevelAfter:  res.LevelAfter,
+	}
+}
+
+// AchievementStatusView is an achievement and whether the player earned it.
+type AchievementStatusView struct {
+	AchievementView
+	Earned   bool       `json:"earned"`
+	EarnedAt *time.Time `json:"earned_at,omitempty"`
+}
+
+// AchievementViews returns every achievement, earned or not.
+func (s *Service) AchievementViews(ctx context.Context) ([]AchievementStatusView, error) {
+	all, err := GetAchievementsForPlayer(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	out := []AchievementStatusView{}
+	for _, a := range all {
+		out = append(out, AchievementStatusView{
+			AchievementView: AchievementView{ID: a.ID, Name: a.Name, Icon: a.Icon, Description: a.Description},
+			Earned:          a.Earned,
+			EarnedAt:        utcTime(a.EarnedAt),
+		})
+	}
+	return out, nil
+}
+
+// BlueprintListView is the blueprint list printed by ql blueprint list.
+type BlueprintListView struct {
+	Version    int             `json:"version"`
+	Blueprints []BlueprintView `json:"blueprints"`
+}
+
+// AchievementListView is the achievement list printed by ql achievements.
+type AchievementListView struct {
+	Version      int                     `json:"version"`
+	Achievements []AchievementStatusView `json:"achievements"`
+}
+
+// TagListView is the tag list printed by ql tag list.
+type TagListView struct {
+	Version int       `json:"version"`
+	Tags    []TagView `json:"tags"`
+}
+
+type TagView struct {
+	Tag   string `json:"tag"`
+	Tasks int    `json:"tasks"` // Tasks it is set on
+	Open  int    `json:"open"`  // Tasks not done that have it, set or inherited
+}
+
+func NewTagListView(counts []TagCount) TagListView {
+	v := TagListView{Version: ViewVersion, Tags: []TagView{}}
+	for _, c := range counts {
+		v.Tags = append(v.Tags, TagView{Tag: c.Tag, Tasks: c.Tasks, Open: c.Open})
+	}
+	return v
+}
+
+// TrashView is the trash printed by ql trash list, newest first.
+type TrashView struct {
+	Version int             `json:"version"`
+	Tasks   []TrashItemView `json:"tasks"`
+}
+
+type TrashItemView struct {
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	Kind      string    `json:"kind"`
+	Status    string    `json:"status"`
+	DeletedAt time.Time `json:"deleted_at"`
+	Subtasks  []int64   `json:"subtasks"` // Trashed with it
+	XPRevoked int       `json:"xp_revoked"`
+}
+
+func NewTrashView(items []TrashItem) TrashView {
+	v := TrashView{Version: ViewVersion, Tasks: []TrashItemView{}}
+	for _, it := range items {
+		v.Tasks = append(v.Tasks, TrashItemView{
+			ID:        it.Task.ID,
+			Title:     it.Task.Title,
+			Kind:      taskKind(&it.Task),
+			Status:    it.Task.Status,
+			DeletedAt: it.Task.DeletedAt.UTC(),
+			Subtasks:  append([]int64{}, it.Subtasks...),
+			XPRevoked: it.XPRevoked,
+		})
+	}
+	return v
+}
+
+// TimeReportView is the time report printed by ql time.
+type TimeReportView struct {
+	Version    int            `json:"version"`
+	Since      *time.Time     `json:"since,omitempty"` // Omitted for all time
+	Until      time.Time      `json:"until"`
+	Seconds    int64          `json:"seconds"`
+	Focus      int            `json:"focus"` // Pomodoro focus intervals
+	Attributes []TimeAttrView `json:"attributes"`
+	Tasks      []TaskTimeView `json:"tasks"`
+	Timer      *SessionView   `json:"timer"` // The running timer, null when none runs
+}
+
+type TimeAttrView struct {
+	Code    string `json:"code"`
+	Seconds int64  `json:"seconds"`
+}
+
+type TaskTimeView struct {
+	TaskID   int64  `json:"task_id"`
+	Title    string `json:"title"`
+	Seconds  int64  `json:"seconds"`
+	Sessions int    `json:"sessions"`
+}
+
+func NewTimeReportView(rep *TimeReport, now time.Time) TimeReportView {
+	v := TimeReportView{
+		Version:    ViewVersion,
+		Until:      rep.Until.UTC(),
+		Seconds:    int64(rep.Total / time.Second),
+		Focus:      rep.Focus,
+		Attributes: []TimeAttrView{},
+		Tasks:      []TaskTimeView{},
+		Timer:      newSessionView(rep.Running, now),
+	}
+	if !rep.Since.IsZero() {
+		v.Since = utcTime(&rep.Since)
+	}
+	for _, a := range rep.Attributes {
+		v.Attributes = append(v.Attributes, TimeAttrView{Code: string(a.Attr), Seconds: int64(a.Time / time.Second)})
+	}
+	for _, t := range rep.Tasks {
+		v.Tasks = append(v.Tasks, TaskTimeView{TaskID: t.Task.ID, Title: t.Task.Title, Seconds: int64(t.Time / time.Second), Sessions: t.Sessions})
+	}
+	return v
+}
+
+// ReviewView is the report of ql review, without the answers about stale
+// tasks.
+type ReviewView struct {
+	Version      int                   `json:"version"`
+	Period       string                `json:"period"` // week or month
+	Since        time.Time             `json:"since"`
+	Until        time.Time             `json:"until"`
+	Completions  int                   `json:"completions"`
+	XPEarned     int                   `json:"xp_earned"`
+	Attributes   []ReviewAttrView      `json:"attributes"`
+	Behind       []BehindHabitView     `json:"behind"`
+	Blueprints   []ReviewBlueprintView `json:"blueprints"` // Accepted, not completed yet
+	Stale        []StaleTaskView       `json:"stale"`
+	LastReviewAt *time.Time            `json:"last_review_at,omitempty"`
+	XPAvailable  int                   `json:"xp_available"` // Review XP saving it now pays
+}
+
+type ReviewAttrView struct {
+	Code        string `json:"code"`
+	Completions int    `json:"completions"`
+	XP          int    `json:"xp"`
+}
+
+type BehindHabitView struct {
+	TaskID   int64  `json:"task_id"`
+	Title    string `json:"title"`
+	Done     int    `json:"done"`
+	Expected int    `json:"expected"`
+}
+
+type ReviewBlueprintView struct {
+	Code   string `json:"code"`
+	TaskID *int64 `json:"task_id,omitempty"`
+	Done   int    `json:"done"`
+	Total  int    `json:"total"`
+}
+
+type StaleTaskView struct {
+	TaskID     int64      `json:"task_id"`
+	Title      string     `json:"title"`
+	Difficulty int        `json:"difficulty"`
+	AgeDays    int        `json:"age_days"`
+	DueDate    *time.Time `json:"due_date,omitempty"`
+	Overdue    bool       `json:"overdue"`
+}
+
+func NewReviewView(rv *Review) ReviewView {
+	v := ReviewView{
+		Version:     ViewVersion,
+		Period:      string(rv.Period),
+		Since:       rv.Since.UTC(),
+		Until:       rv.Until.UTC(),
+		Completions: rv.Completions,
+		XPEarned:    rv.XPEarned,
+		Attributes:  []ReviewAttrView{},
+		Behind:      []BehindHabitView{},
+		Blueprints:  []ReviewBlueprintView{},
+		Stale:       []StaleTaskView{},
+		XPAvailable: rv.XPAvailable,
+	}
+	if rv.Last != nil {
+		v.LastReviewAt = utcTime(&rv.Last.CreatedAt)
+	}
+	for _, a := range rv.Attributes {
+		v.Attributes = append(v.Attributes, ReviewAttrView{Code: string(a.Attr), Completions: a.Completions, XP: a.XP})
+	}
+	for _, h := range rv.Behind {
+		v.Behind = append(v.Behind, BehindHabitView{TaskID: h.Task.ID, Title: h.Task.Title, Done: h.Done, Expected: h.Expected})
+	}
+	for _, b := range rv.Blueprints {
+		bv := ReviewBlueprintView{Code: b.Code, Done: b.Done, Total: b.Total}
+		if b.TaskID != 0 {
+			id := b.TaskID
+			bv.TaskID = &id
+		}
+		v.Blueprints = append(v.Blueprints, bv)
+	}
+	for _, st := range rv.Stale {
+		v.Stale = append(v.Stale, StaleTaskView{
+			TaskID:     st.Task.ID,
+			Title:      st.Task.Title,
+			Difficulty: st.Task.Difficulty,
+			AgeDays:    st.AgeDays,
+			DueDate:    utcTime(st.Task.DueDate),
+			Overdue:    st.Overdue,
+		})
+	}
+	return v
+}
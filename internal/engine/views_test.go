@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"questline/internal/storage"
+)
+
+func TestViewsDescribeTasksStatusAndErrors(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelDeepRecurs))
+
+	now := time.Now()
+	yesterday := now.Add(-24 * time.Hour)
+	create := func(in CreateTaskInput) int64 {
+		t.Helper()
+		if in.Difficulty == 0 {
+			in.Difficulty = DifficultyTrivial
+		}
+		res, err := svc.CreateTask(ctx, in)
+		if err != nil {
+			t.Fatalf("CreateTask %s: %v", in.Title, err)
+		}
+		return res.TaskID
+	}
+	trip := create(CreateTaskInput{Title: "Plan trip", Attribute: AttributeOUT, Tags: []string{"travel"}})
+	book := create(CreateTaskInput{Title: "Book hotel", Attribute: AttributeOUT, ParentID: &trip, DueDate: &yesterday})
+	pack := create(CreateTaskInput{Title: "Pack", Attribute: AttributeOUT, ParentID: &trip, After: []int64{book}})
+	stretch := create(CreateTaskInput{Title: "Stretch", Attribute: AttributeSTR, IsHabit: true, HabitInterval: "daily"})
+	if _, err := svc.StartTimer(ctx, pack, SessionTimer, now.Add(-time.Minute)); err != nil {
+		t.Fatalf("StartTimer: %v", err)
+	}
+
+	all, err := svc.TaskRepo().ListAll(ctx)
+	if err != nil {
+		t.Fatalf("ListAll: %v", err)
+	}
+	tree, err := svc.TaskTree(ctx, all, now)
+	if err != nil {
+		t.Fatalf("TaskTree: %v", err)
+	}
+	if len(tree) != 2 || tree[0].ID != trip || tree[1].ID != stretch {
+		t.Fatalf("roots=%+v, want the trip and the habit", tree)
+	}
+	subs := tree[0].Subtasks
+	if len(subs) != 2 || subs[0].ID != book || !subs[0].Overdue || subs[1].ID != pack ||
+		fmt.Sprint(subs[1].BlockedBy) != fmt.Sprint([]int64{book}) || fmt.Sprint(subs[1].InheritedTags) != "[travel]" {
+		t.Fatalf("subtasks=%+v", subs)
+	}
+	if h := tree[1].Habit; tree[1].Kind != "habit" || h == nil || h.Interval != "daily" || h.PeriodTarget != 1 {
+		t.Fatalf("habit=%+v", tree[1])
+	}
+	// A subtask without its parent is listed at the top level.
+	if only, err := svc.TaskTree(ctx, all[2:3], now); err != nil || len(only) != 1 || only[0].ID != pack {
+		t.Fatalf("TaskTree(pack)=%+v, %v", only, err)
+	}
+
+	st, err := svc.StatusView(ctx, now)
+	if err != nil {
+		t.Fatalf("StatusView: %v", err)
+	}
+	if st.Player.Level != LevelDeepRecurs || !st.Gates.Habits || st.Gates.ActiveTasks != 4 ||
+		fmt.Sprint(st.Overdue) != fmt.Sprint([]int64{book}) || st.Timer == nil || st.Timer.TaskID != pack || len(st.Blueprints) == 0 {
+		t.Fatalf("status=%+v", st)
+	}
+
+	res, err := svc.CompleteTask(ctx, book)
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	data, err := json.Marshal(NewCompleteView(res))
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	for _, key := range []string{`"version":1`, `"task_id":`, `"xp_awarded":`, `"deadline_delta":`, `"achievements":[`} {
+		if !strings.Contains(string(data), key) {
+			t.Fatalf("complete view %s lacks %s", data, key)
+		}
+	}
+
+	_, notFound := svc.CompleteTask(ctx, 999)
+	fresh, cleanupFresh := newTestService(t)
+	defer cleanupFresh()
+	_, locked := fresh.CreateTask(ctx, CreateTaskInput{Title: "Too soon", Difficulty: DifficultyTrivial, Attribute: AttributeSTR, IsHabit: true, HabitInterval: "daily"})
+	codes := []struct {
+		err  error
+		want string
+	}{
+		{notFound, ErrCodeNotFound},
+		{locked, ErrCodeLocked},
+		{BlockedError{TaskID: pack, BlockedBy: []int64{book}}, ErrCodeConflict},
+		{fmt.Errorf("stop: %w", ErrNoTimer), ErrCodeConflict},
+		{errors.New("disk full"), ErrCodeError},
+	}
+	for _, c := range codes {
+		if got := ErrorCode(c.err); got != c.want {
+			t.Errorf("ErrorCode(%v)=%s, want %s", c.err, got, c.want)
+		}
+	}
+}
+
+func TestListViewsForSearchTimeTagsAndTrash(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	setPlayerXP(t, svc, XPRequiredForLevel(LevelDeepRecurs))
+
+	now := time.Now()
+	create := func(title string, parent *int64, tags ...string) int64 {
+		t.Helper()
+		res, err := svc.CreateTask(ctx, CreateTaskInput{Title: title, Difficulty: DifficultyTrivial, Attribute: AttributeINT, ParentID: parent, Tags: tags})
+		if err != nil {
+			t.Fatalf("CreateTask %s: %v", title, err)
+		}
+		return res.TaskID
+	}
+	report := create("Write report", nil, "work")
+	draft := create("Draft report", &report)
+	old := create("Old notes", nil, "work")
+
+	// Search lists its matches flat, in the order given.
+	sub, err := svc.TaskRepo().Get(ctx, draft)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	top, err := svc.TaskRepo().Get(ctx, report)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	flat, err := svc.TaskViews(ctx, []storage.Task{*sub, *top}, now)
+	if err != nil {
+		t.Fatalf("TaskViews: %v", err)
+	}
+	if len(flat) != 2 || flat[0].ID != draft || flat[1].ID != report || len(flat[1].Subtasks) != 0 ||
+		fmt.Sprint(flat[0].InheritedTags) != "[work]" {
+		t.Fatalf("TaskViews=%+v", flat)
+	}
+
+	if _, err := svc.StartTimer(ctx, report, SessionTimer, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("StartTimer: %v", err)
+	}
+	if _, err := svc.StopTimer(ctx, now.Add(-30*time.Minute)); err != nil {
+		t.Fatalf("StopTimer: %v", err)
+	}
+	rep, err := svc.TimeReport(ctx, time.Time{}, now)
+	if err != nil {
+		t.Fatalf("TimeReport: %v", err)
+	}
+	tv := NewTimeReportView(rep, now)
+	if tv.Since != nil || tv.Seconds != 1800 || len(tv.Tasks) != 1 || tv.Tasks[0].TaskID != report ||
+		len(tv.Attributes) != 1 || tv.Attributes[0].Code != "INT" || tv.Timer != nil {
+		t.Fatalf("time view=%+v", tv)
+	}
+
+	if _, err := svc.DeleteTask(ctx, old, DeleteXPAsk); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	counts, err := svc.TagCounts(ctx)
+	if err != nil {
+		t.Fatalf("TagCounts: %v", err)
+	}
+	if v := NewTagListView(counts); len(v.Tags) != 1 || v.Tags[0] != (TagView{Tag: "work", Tasks: 1, Open: 2}) {
+		t.Fatalf("tag view=%+v", v)
+	}
+	items, err := svc.Trash(ctx)
+	if err != nil {
+		t.Fatalf("Trash: %v", err)
+	}
+	if v := NewTrashView(items); len(v.Tasks) != 1 || v.Tasks[0].ID != old || v.Tasks[0].Kind != "task" || v.Tasks[0].Subtasks == nil {
+		t.Fatalf("trash view=%+v", v)
+	}
+}
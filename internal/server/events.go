@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Event types of the /events stream.
+const (
+	eventCompletion = "completion" // A CompleteView
+	eventLevelUp    = "level_up"   // A levelUpView
+)
+
+// pingInterval is how often an idle stream gets a comment, so proxies and
+// clients don't take it for dead.
+const pingInterval = 30 * time.Second
+
+// levelUpView is the data of a level_up event.
+type levelUpView struct {
+	Version     int   `json:"version"`
+	TaskID      int64 `json:"task_id"` // The task whose completion did it
+	LevelBefore int   `json:"level_before"`
+	LevelAfter  int   `json:"level_after"`
+}
+
+type event struct {
+	id   int64
+	typ  string
+	data []byte
+}
+
+// broker fans events out to the open streams. A stream that falls behind
+// loses events rather than holding up the request that caused them.
+type broker struct {
+	mu     sync.Mutex
+	nextID int64
+	subs   map[chan event]struct{}
+}
+
+func newBroker() *broker {
+	return &broker{subs: map[chan event]struct{}{}}
+}
+
+func (b *broker) subscribe() chan event {
+	ch := make(chan event, 16)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *broker) unsubscribe(ch chan event) {
+	b.mu.Lock()
+	delete(b.subs, ch)
+	b.mu.Unlock()
+}
+
+func (b *broker) publish(typ string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	e := event{id: b.nextID, typ: typ, data: data}
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// serveEvents streams events as Server-Sent Events until the client goes
+// away or the server shuts down.
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	ch := s.events.subscribe()
+	defer s.events.unsubscribe(ch)
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	// The comment tells clients the stream is open and subscribed.
+	fmt.Fprint(w, ": questline events\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ping.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case e := <-ch:
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.id, e.typ, e.data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
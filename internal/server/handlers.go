@@ -0,0 +1,413 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"questline/internal/engine"
+	"questline/internal/storage"
+)
+
+// The documents of the endpoints that return a part of ql status, or a
+// single task, carry a version like every other view.
+type (
+	taskDoc struct {
+		Version int             `json:"version"`
+		Task    engine.TaskView `json:"task"`
+	}
+	playerDoc struct {
+		Version int               `json:"version"`
+		Player  engine.PlayerView `json:"player"`
+	}
+	gatesDoc struct {
+		Version int              `json:"version"`
+		Gates   engine.GatesView `json:"gates"`
+	}
+)
+
+func (s *Server) listTasks(ctx context.Context, r *http.Request) (int, any, error) {
+	reg, err := s.svc.Attributes(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	now := time.Now()
+	filter, err := engine.ParseTaskFilter(r.URL.Query().Get("filter"), reg, now)
+	if err != nil {
+		return 0, nil, badRequest(err)
+	}
+	tasks, err := s.svc.FilterTasks(ctx, filter)
+	if err != nil {
+		return 0, nil, err
+	}
+	tree, err := s.svc.TaskTree(ctx, tasks, now)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, engine.TaskListView{Version: engine.ViewVersion, Tasks: tree}, nil
+}
+
+// createBody is the request of POST /tasks. It takes what ql add takes:
+// either a title and fields, or text with quick-add tokens.
+type createBody struct {
+	Title      string   `json:"title"`
+	Text       string   `json:"text"`
+	Project    bool     `json:"project"`
+	Difficulty int      `json:"difficulty"`
+	Attributes string   `json:"attributes"`
+	ParentID   *int64   `json:"parent_id"`
+	Due        string   `json:"due"`
+	Interval   string   `json:"interval"` // Makes it a habit
+	Duration   string   `json:"duration"`
+	Goal       int      `json:"goal"`
+	Tags       []string `json:"tags"`
+	After      []int64  `json:"after"`
+}
+
+func (s *Server) createTask(ctx context.Context, r *http.Request) (int, any, error) {
+	var b createBody
+	if err := decodeBody(r, &b); err != nil {
+		return 0, nil, err
+	}
+	reg, err := s.svc.Attributes(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	now := time.Now()
+
+	q := engine.QuickAdd{Title: b.Title}
+	if b.Text != "" {
+		if b.Title != "" {
+			return 0, nil, badRequest(errors.New("give a title or text, not both"))
+		}
+		if q, err = engine.ParseQuickAdd(b.Text, reg, now); err != nil {
+			return 0, nil, badRequest(err)
+		}
+	}
+	for _, c := range []struct {
+		token, field bool
+		name         string
+	}{
+		{q.Difficulty != 0, b.Difficulty != 0, "difficulty"},
+		{q.Attribute != "", b.Attributes != "", "attributes"},
+		{q.ParentID != nil, b.ParentID != nil, "parent_id"},
+		{q.DueDate != nil, b.Due != "", "due"},
+		{q.Interval != "", b.Interval != "", "interval"},
+	} {
+		if c.token && c.field {
+			return 0, nil, badRequest(fmt.Errorf("text and %s both set the same field; use one", c.name))
+		}
+	}
+
+	in := q.TaskInput()
+	if b.Attributes != "" {
+		if in.Attribute, in.Attributes, err = reg.ParseWeightsStrict(b.Attributes); err != nil {
+			return 0, nil, badRequest(err)
+		}
+	}
+	if in.Tags, err = engine.ParseTags(strings.Join(append(b.Tags, q.Tags...), ",")); err != nil {
+		return 0, nil, badRequest(err)
+	}
+	in.After = b.After
+
+	if b.Project {
+		if q.Difficulty != 0 || q.ParentID != nil || q.DueDate != nil || q.Interval != "" ||
+			b.Difficulty != 0 || b.ParentID != nil || b.Due != "" || b.Interval != "" || b.Duration != "" || b.Goal != 0 {
+			return 0, nil, badRequest(errors.New("projects take only a title, attributes, tags and after"))
+		}
+		res, err := s.svc.CreateProject(ctx, engine.CreateProjectInput{
+			Title:      in.Title,
+			Attribute:  in.Attribute,
+			Attributes: in.Attributes,
+			After:      in.After,
+			Tags:       in.Tags,
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusCreated, engine.NewCreateView(res), nil
+	}
+
+	if b.Difficulty != 0 {
+		in.Difficulty = engine.Difficulty(b.Difficulty)
+	}
+	if b.ParentID != nil {
+		in.ParentID = b.ParentID
+	}
+	if b.Due != "" {
+		due, err := engine.ParseDueDate(b.Due, now)
+		if err != nil {
+			return 0, nil, badRequest(err)
+		}
+		in.DueDate = &due
+	}
+	if b.Interval != "" {
+		if in.HabitInterval, err = engine.ParseHabitInterval(b.Interval); err != nil {
+			return 0, nil, badRequest(err)
+		}
+		in.IsHabit = true
+	}
+	if b.Duration != "" || b.Goal != 0 {
+		if !in.IsHabit {
+			return 0, nil, badRequest(errors.New("duration and goal need an interval"))
+		}
+		if b.Duration != "" {
+			dur, err := engine.ParseDuration(b.Duration)
+			if err != nil {
+				return 0, nil, badRequest(fmt.Errorf("invalid duration: %w", err))
+			}
+			in.HabitDuration = &dur
+		}
+		if b.Goal > 0 {
+			in.HabitGoal = &b.Goal
+		}
+	}
+	res, err := s.svc.CreateTask(ctx, in)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, engine.NewCreateView(res), nil
+}
+
+func (s *Server) getTask(ctx context.Context, r *http.Request) (int, any, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	v, err := s.taskView(ctx, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, taskDoc{Version: engine.ViewVersion, Task: *v}, nil
+}
+
+// taskView describes task id with its subtasks.
+func (s *Server) taskView(ctx context.Context, id int64) (*engine.TaskView, error) {
+	all, err := s.svc.TaskRepo().ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	in := map[int64]bool{}
+	for i := range all {
+		if all[i].ID == id {
+			in[id] = true
+		}
+	}
+	if !in[id] {
+		return nil, fmt.Errorf("task %d %w", id, engine.ErrNotFound)
+	}
+	for added := true; added; {
+		added = false
+		for i := range all {
+			t := &all[i]
+			if !in[t.ID] && t.ParentID != nil && in[*t.ParentID] {
+				in[t.ID] = true
+				added = true
+			}
+		}
+	}
+	var subtree []storage.Task
+	for i := range all {
+		if in[all[i].ID] {
+			subtree = append(subtree, all[i])
+		}
+	}
+	tree, err := s.svc.TaskTree(ctx, subtree, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for i := range tree {
+		if tree[i].ID == id {
+			return &tree[i], nil
+		}
+	}
+	return nil, fmt.Errorf("task %d %w", id, engine.ErrNotFound)
+}
+
+// patchBody is the request of PATCH /tasks/{id}: the fields of the document
+// ql edit opens, each optional. An empty due, duration or description clears
+// it, goal 0 removes the goal and parent_id 0 moves the task to the top level.
+type patchBody struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Difficulty  *int    `json:"difficulty"`
+	Attributes  *string `json:"attributes"`
+	ParentID    *int64  `json:"parent_id"`
+	Due         *string `json:"due"`
+	Interval    *string `json:"interval"`
+	Duration    *string `json:"duration"`
+	Goal        *int    `json:"goal"`
+}
+
+func (s *Server) updateTask(ctx context.Context, r *http.Request) (int, any, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	var b patchBody
+	if err := decodeBody(r, &b); err != nil {
+		return 0, nil, err
+	}
+	task, err := s.svc.TaskRepo().Get(ctx, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	if task == nil {
+		return 0, nil, fmt.Errorf("task %d %w", id, engine.ErrNotFound)
+	}
+	reg, err := s.svc.Attributes(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	now := time.Now()
+	orig := engine.NewTaskDoc(task, now.Location())
+	doc := orig
+	setField(&doc.Title, b.Title)
+	setField(&doc.Description, b.Description)
+	setField(&doc.Difficulty, b.Difficulty)
+	setField(&doc.Attributes, b.Attributes)
+	setField(&doc.Parent, b.ParentID)
+	setField(&doc.Due, b.Due)
+	setField(&doc.Interval, b.Interval)
+	setField(&doc.Duration, b.Duration)
+	setField(&doc.Goal, b.Goal)
+	patch, err := doc.Patch(reg, orig, now)
+	if err != nil {
+		return 0, nil, badRequest(err)
+	}
+	if _, err := s.svc.UpdateTask(ctx, id, patch); err != nil {
+		return 0, nil, err
+	}
+	v, err := s.taskView(ctx, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, taskDoc{Version: engine.ViewVersion, Task: *v}, nil
+}
+
+func (s *Server) deleteTask(ctx context.Context, r *http.Request) (int, any, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	xp := engine.DeleteXPAsk
+	switch r.URL.Query().Get("xp") {
+	case "":
+	case "keep":
+		xp = engine.DeleteXPKeep
+	case "revoke":
+		xp = engine.DeleteXPRevoke
+	default:
+		return 0, nil, badRequest(errors.New("xp must be keep or revoke"))
+	}
+	res, err := s.svc.DeleteTask(ctx, id, xp)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, engine.NewDeleteView(res), nil
+}
+
+func (s *Server) completeTask(ctx context.Context, r *http.Request) (int, any, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	res, err := s.svc.CompleteTask(ctx, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	v := engine.NewCompleteView(res)
+	s.events.publish(eventCompletion, v)
+	if res.LevelUp {
+		s.events.publish(eventLevelUp, levelUpView{
+			Version:     engine.ViewVersion,
+			TaskID:      res.TaskID,
+			LevelBefore: res.LevelBefore,
+			LevelAfter:  res.LevelAfter,
+		})
+	}
+	return http.StatusOK, v, nil
+}
+
+func (s *Server) restoreTask(ctx context.Context, r *http.Request) (int, any, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	res, err := s.svc.RestoreTask(ctx, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, engine.NewRestoreView(res), nil
+}
+
+func (s *Server) player(ctx context.Context, r *http.Request) (int, any, error) {
+	v, err := s.svc.PlayerView(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, playerDoc{Version: engine.ViewVersion, Player: *v}, nil
+}
+
+func (s *Server) gates(ctx context.Context, r *http.Request) (int, any, error) {
+	v, err := s.svc.GatesView(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, gatesDoc{Version: engine.ViewVersion, Gates: *v}, nil
+}
+
+func (s *Server) blueprints(ctx context.Context, r *http.Request) (int, any, error) {
+	v, err := s.svc.BlueprintViews(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, engine.BlueprintListView{Version: engine.ViewVersion, Blueprints: v}, nil
+}
+
+func (s *Server) acceptBlueprint(ctx context.Context, r *http.Request) (int, any, error) {
+	res, err := s.svc.AcceptBlueprint(ctx, r.PathValue("code"))
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, engine.NewCreateView(res), nil
+}
+
+func (s *Server) achievements(ctx context.Context, r *http.Request) (int, any, error) {
+	v, err := s.svc.AchievementViews(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, engine.AchievementListView{Version: engine.ViewVersion, Achievements: v}, nil
+}
+
+// setField sets *dst to the value of a field the request gave.
+func setField[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func pathID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return 0, badRequest(errors.New("id must be an integer"))
+	}
+	return id, nil
+}
+
+// decodeBody reads a JSON request body into v, rejecting unknown fields.
+func decodeBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest(fmt.Errorf("invalid request body: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Package server exposes the engine over a local HTTP/JSON API, the one
+// ql serve runs. Responses are the engine's views (see docs/OUTPUT.md);
+// docs/API.md and the OpenAPI document served at /api/v1/openapi.yaml
+// describe the endpoints.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"questline/internal/engine"
+)
+
+// Prefix is the path every endpoint lives under.
+const Prefix = "/api/v1"
+
+// Error codes of the API, next to the engine's ErrCode* codes.
+const (
+	errCodeUnauthorized = "unauthorized"
+	errCodeBadRequest   = "bad_request"
+)
+
+// statusCodes are the HTTP statuses of failed requests by error code.
+var statusCodes = map[string]int{
+	engine.ErrCodeError:    http.StatusUnprocessableEntity,
+	engine.ErrCodeNotFound: http.StatusNotFound,
+	engine.ErrCodeLocked:   http.StatusForbidden,
+	engine.ErrCodeConflict: http.StatusConflict,
+	errCodeUnauthorized:    http.StatusUnauthorized,
+	errCodeBadRequest:      http.StatusBadRequest,
+}
+
+//go:embed openapi.yaml
+var openAPI []byte
+
+// Server serves the API for one Service. Requests take turns with it, like
+// commands run one after another.
+type Server struct {
+	svc    *engine.Service
+	token  string
+	mux    *http.ServeMux
+	mu     sync.Mutex
+	events *broker
+}
+
+// New returns a server for svc that accepts requests bearing token.
+func New(svc *engine.Service, token string) *Server {
+	s := &Server{svc: svc, token: token, mux: http.NewServeMux(), events: newBroker()}
+	for _, rt := range s.routes() {
+		s.mux.HandleFunc(rt.method+" "+Prefix+rt.path, s.handle(rt.fn))
+	}
+	s.mux.HandleFunc("GET "+Prefix+"/events", s.authorized(s.serveEvents))
+	s.mux.HandleFunc("GET "+Prefix+"/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPI)
+	})
+	s.mux.HandleFunc(Prefix+"/", s.authorized(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, noEndpoint(r))
+	}))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// apiFunc handles a request, returning the HTTP status and the view to send.
+type apiFunc func(ctx context.Context, r *http.Request) (int, any, error)
+
+type route struct {
+	method string
+	path   string // Below Prefix, in ServeMux pattern syntax
+	fn     apiFunc
+}
+
+func (s *Server) routes() []route {
+	return []route{
+		{"GET", "/tasks", s.listTasks},
+		{"POST", "/tasks", s.createTask},
+		{"GET", "/tasks/{id}", s.getTask},
+		{"PATCH", "/tasks/{id}", s.updateTask},
+		{"DELETE", "/tasks/{id}", s.deleteTask},
+		{"POST", "/tasks/{id}/complete", s.completeTask},
+		{"POST", "/tasks/{id}/restore", s.restoreTask},
+		{"GET", "/player", s.player},
+		{"GET", "/gates", s.gates},
+		{"GET", "/blueprints", s.blueprints},
+		{"POST", "/blueprints/{code}/accept", s.acceptBlueprint},
+		{"GET", "/achievements", s.achievements},
+	}
+}
+
+// handle authorizes the request and runs fn on its own with the Service.
+// Requests that change something first apply the HP damage due, like ql
+// commands do; GET requests never write.
+func (s *Server) handle(fn apiFunc) http.HandlerFunc {
+	return s.authorized(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		status, v, err := func() (int, any, error) {
+			if r.Method != http.MethodGet {
+				if _, err := s.svc.EvaluateHP(r.Context()); err != nil {
+					return 0, nil, err
+				}
+			}
+			return fn(r.Context(), r)
+		}()
+		s.mu.Unlock()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, status, v)
+	})
+}
+
+// authorized lets requests through that carry the token as a bearer token,
+// or, for event streams opened by browsers, as the token query parameter.
+func (s *Server) authorized(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok && strings.HasSuffix(r.URL.Path, "/events") {
+			got = r.URL.Query().Get("token")
+		}
+		if got == "" || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="questline"`)
+			writeError(w, apiError{errCodeUnauthorized, errors.New("missing or wrong token")})
+			return
+		}
+		h(w, r)
+	}
+}
+
+// apiError is an error with an API error code rather than an engine one.
+type apiError struct {
+	code string
+	err  error
+}
+
+func (e apiError) Error() string { return e.err.Error() }
+func (e apiError) Unwrap() error { return e.err }
+
+func badRequest(err error) error {
+	return apiError{errCodeBadRequest, err}
+}
+
+func noEndpoint(r *http.Request) error {
+	return apiError{engine.ErrCodeNotFound, errors.New("no endpoint " + r.Method + " " + r.URL.Path)}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := engine.ErrorCode(err)
+	var api apiError
+	if errors.As(err, &api) {
+		code = api.code
+	}
+	writeJSON(w, statusCodes[code], engine.NewErrorView(code, err))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
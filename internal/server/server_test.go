@@ -0,0 +1,239 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"questline/internal/engine"
+	"questline/internal/storage"
+)
+
+const testToken = "secret"
+
+func newTestServer(t *testing.T) (*httptest.Server, *engine.Service) {
+	t.Helper()
+	ctx := context.Background()
+	db, err := storage.Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	svc := engine.NewService(db)
+	ts := httptest.NewServer(New(svc, testToken))
+	t.Cleanup(ts.Close)
+	return ts, svc
+}
+
+// call sends a request with the test token and decodes the JSON response
+// into out, when given.
+func call(t *testing.T, ts *httptest.Server, method, path string, body any, out any) int {
+	t.Helper()
+	var rd io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rd = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, ts.URL+Prefix+path, rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decode: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+type sseEvent struct{ typ, data string }
+
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var e sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if e.typ != "" {
+				return e
+			}
+		case strings.HasPrefix(line, "event: "):
+			e.typ = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestServerTasksPlayerAndEvents(t *testing.T) {
+	ts, svc := newTestServer(t)
+	ctx := context.Background()
+
+	// Everything but the OpenAPI document needs the token.
+	for _, header := range []string{"", "Bearer wrong"} {
+		req, _ := http.NewRequest("GET", ts.URL+Prefix+"/player", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ev engine.ErrorView
+		json.NewDecoder(resp.Body).Decode(&ev)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized || ev.Error.Code != "unauthorized" {
+			t.Fatalf("%q: status=%d error=%+v, want 401 unauthorized", header, resp.StatusCode, ev.Error)
+		}
+	}
+	if resp, err := ts.Client().Get(ts.URL + Prefix + "/openapi.yaml"); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("openapi.yaml: %v %v", resp, err)
+	}
+
+	// Open the event stream before anything happens.
+	streamCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(streamCtx, "GET", ts.URL+Prefix+"/events?token="+testToken, nil)
+	stream, err := ts.Client().Do(req)
+	if err != nil || stream.StatusCode != http.StatusOK {
+		t.Fatalf("events: %v %v", stream, err)
+	}
+	defer stream.Body.Close()
+	events := bufio.NewReader(stream.Body)
+	if line, err := events.ReadString('\n'); err != nil || !strings.HasPrefix(line, ":") {
+		t.Fatalf("events opened with %q, %v", line, err)
+	}
+
+	var created engine.CreateView
+	if code := call(t, ts, "POST", "/tasks", map[string]any{"text": "Write report @int #work"}, &created); code != http.StatusCreated || created.TaskID == 0 {
+		t.Fatalf("create: status=%d view=%+v", code, created)
+	}
+	id := created.TaskID
+	path := "/tasks/" + strconv.FormatInt(id, 10)
+
+	var list engine.TaskListView
+	if code := call(t, ts, "GET", "/tasks?filter=tag:work", nil, &list); code != http.StatusOK || len(list.Tasks) != 1 ||
+		list.Tasks[0].Attribute != "INT" || list.Tasks[0].Tags[0] != "work" {
+		t.Fatalf("list: status=%d view=%+v", code, list)
+	}
+
+	var got taskDoc
+	if code := call(t, ts, "PATCH", path, map[string]any{"title": "Write the report", "due": "tomorrow"}, &got); code != http.StatusOK ||
+		got.Task.Title != "Write the report" || got.Task.DueDate == nil || got.Task.Attribute != "INT" {
+		t.Fatalf("patch: status=%d view=%+v", code, got.Task)
+	}
+
+	// Errors carry the codes of ql --output.
+	var ev engine.ErrorView
+	for _, c := range []struct {
+		method, path string
+		body         any
+		status       int
+		code         string
+	}{
+		{"PATCH", path, map[string]any{"colour": "red"}, http.StatusBadRequest, "bad_request"},
+		{"POST", "/tasks", map[string]any{"title": "Hard", "difficulty": 5}, http.StatusForbidden, "locked"},
+		{"POST", "/tasks", map[string]any{"title": "Typo", "attributes": "strr"}, http.StatusBadRequest, "bad_request"},
+		{"POST", "/tasks", map[string]any{"title": "Typo", "attributes": "str:-5,int:0"}, http.StatusBadRequest, "bad_request"},
+		{"PATCH", path, map[string]any{"attributes": "strr"}, http.StatusBadRequest, "bad_request"},
+		{"GET", "/tasks/999", nil, http.StatusNotFound, "not_found"},
+		{"GET", "/tasks/abc", nil, http.StatusBadRequest, "bad_request"},
+		{"POST", "/blueprints/nope/accept", nil, http.StatusNotFound, "not_found"},
+		{"GET", "/nowhere", nil, http.StatusNotFound, "not_found"},
+	} {
+		ev = engine.ErrorView{}
+		if code := call(t, ts, c.method, c.path, c.body, &ev); code != c.status || ev.Error.Code != c.code {
+			t.Errorf("%s %s: status=%d error=%+v, want %d %s", c.method, c.path, code, ev.Error, c.status, c.code)
+		}
+	}
+
+	// Completing announces the completion and the level-up on the stream.
+	p, err := svc.PlayerRepo().GetOrCreateMain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.XPTotal = engine.XPRequiredForLevel(1) - 1
+	if err := svc.PlayerRepo().Update(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	var done engine.CompleteView
+	if code := call(t, ts, "POST", path+"/complete", nil, &done); code != http.StatusOK || !done.LevelUp {
+		t.Fatalf("complete: status=%d view=%+v", code, done)
+	}
+	if e := readEvent(t, events); e.typ != eventCompletion || !strings.Contains(e.data, `"xp_awarded"`) {
+		t.Fatalf("first event %+v, want a completion", e)
+	}
+	var up levelUpView
+	if e := readEvent(t, events); e.typ != eventLevelUp || json.Unmarshal([]byte(e.data), &up) != nil || up.LevelAfter != 1 || up.TaskID != id {
+		t.Fatalf("second event %+v, want a level-up to 1", e)
+	}
+
+	var player playerDoc
+	if code := call(t, ts, "GET", "/player", nil, &player); code != http.StatusOK || player.Player.Level != 1 {
+		t.Fatalf("player: status=%d view=%+v", code, player)
+	}
+	var achievements engine.AchievementListView
+	if code := call(t, ts, "GET", "/achievements", nil, &achievements); code != http.StatusOK || len(achievements.Achievements) == 0 {
+		t.Fatalf("achievements: status=%d view=%+v", code, achievements)
+	}
+	for _, path := range []string{"/gates", "/blueprints"} {
+		if code := call(t, ts, "GET", path, nil, nil); code != http.StatusOK {
+			t.Fatalf("%s: status=%d", path, code)
+		}
+	}
+
+	// Deleting a task that earned XP needs a choice, as with ql delete.
+	ev = engine.ErrorView{}
+	if code := call(t, ts, "DELETE", path, nil, &ev); code != http.StatusConflict || ev.Error.Code != "conflict" {
+		t.Fatalf("delete without xp: status=%d error=%+v", code, ev.Error)
+	}
+	var restored engine.RestoreView
+	if code := call(t, ts, "POST", path+"/restore", nil, &restored); code != http.StatusOK || !restored.LevelDown {
+		t.Fatalf("restore: status=%d view=%+v", code, restored)
+	}
+	var deleted engine.DeleteView
+	if code := call(t, ts, "DELETE", path+"?xp=keep", nil, &deleted); code != http.StatusOK || len(deleted.Deleted) != 1 {
+		t.Fatalf("delete: status=%d view=%+v", code, deleted)
+	}
+}
+
+func TestOpenAPIDescribesEveryEndpoint(t *testing.T) {
+	var doc struct {
+		Paths map[string]map[string]any `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(openAPI, &doc); err != nil {
+		t.Fatalf("parse openapi.yaml: %v", err)
+	}
+	routes := (&Server{}).routes()
+	routes = append(routes, route{method: "GET", path: "/events"}, route{method: "GET", path: "/openapi.yaml"})
+	for _, rt := range routes {
+		if _, ok := doc.Paths[rt.path][strings.ToLower(rt.method)]; !ok {
+			t.Errorf("openapi.yaml doesn't describe %s %s", rt.method, rt.path)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+type AchievementRepo struct {
+	db DBTX
+}
+
+func NewAchievementRepo(db DBTX) *AchievementRepo {
+	return &AchievementRepo{db: db}
+}
+
+// Insert records an earned achievement. It reports false if the player had
+// already earned it; the first timestamp is kept.
+func (r *AchievementRepo) Insert(ctx context.Context, a EarnedAchievement) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO achievements (player_key, id, earned_at) VALUES (?, ?, ?)
+	`, a.PlayerKey, a.ID, a.EarnedAt)
+	if err != nil {
+		return false, fmt.Errorf("achievement insert: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("achievement rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ListByPlayer returns a player's earned achievements, oldest first.
+func (r *AchievementRepo) ListByPlayer(ctx context.Context, playerKey string) ([]EarnedAchievement, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT player_key, id, earned_at FROM achievements
+		WHERE player_key = ?
+		ORDER BY earned_at ASC, id ASC
+	`, playerKey)
+	if err != nil {
+		return nil, fmt.Errorf("achievement list: %w", err)
+	}
+	defer rows.Close()
+
+	var out []EarnedAchievement
+	for rows.Next() {
+		var a EarnedAchievement
+		if err := rows.Scan(&a.PlayerKey, &a.ID, &a.EarnedAt); err != nil {
+			return nil, fmt.Errorf("achievement scan: %w", err)
+		}
+		out = append(out, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("achievement rows: %w", err)
+	}
+	return out, nil
+}
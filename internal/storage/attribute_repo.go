@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+type AttributeRepo struct {
+	db DBTX
+}
+
+func NewAttributeRepo(db DBTX) *AttributeRepo {
+	return &AttributeRepo{db: db}
+}
+
+// ListAll returns every registered attribute in display order.
+func (r *AttributeRepo) ListAll(ctx context.Context) ([]Attribute, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT code, name, icon, aliases, color, sort_order, builtin
+		FROM attributes
+		ORDER BY sort_order ASC, code ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("attribute list: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Attribute
+	for rows.Next() {
+		var a Attribute
+		var aliases string
+		var builtin int
+		if err := rows.Scan(&a.Code, &a.Name, &a.Icon, &aliases, &a.Color, &a.SortOrder, &builtin); err != nil {
+			return nil, fmt.Errorf("attribute scan: %w", err)
+		}
+		if aliases != "" {
+			a.Aliases = strings.Split(aliases, ",")
+		}
+		a.Builtin = builtin != 0
+		out = append(out, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("attribute rows: %w", err)
+	}
+	return out, nil
+}
+
+func (r *AttributeRepo) Insert(ctx context.Context, a Attribute) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO attributes (code, name, icon, aliases, color, sort_order, builtin)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, a.Code, a.Name, a.Icon, strings.Join(a.Aliases, ","), a.Color, a.SortOrder, boolToInt(a.Builtin))
+	if err != nil {
+		return fmt.Errorf("attribute insert: %w", err)
+	}
+	return nil
+}
+
+// MaxSortOrder returns the highest sort_order in use (0 when empty).
+func (r *AttributeRepo) MaxSortOrder(ctx context.Context) (int, error) {
+	var v sql.NullInt64
+	if err := r.db.QueryRowContext(ctx, `SELECT MAX(sort_order) FROM attributes`).Scan(&v); err != nil {
+		return 0, fmt.Errorf("attribute max sort order: %w", err)
+	}
+	return int(v.Int64), nil
+}
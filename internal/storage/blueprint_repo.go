@@ -7,22 +7,37 @@ import (
 )
 
 type BlueprintRepo struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewBlueprintRepo(db *sql.DB) *BlueprintRepo {
+func NewBlueprintRepo(db DBTX) *BlueprintRepo {
 	return &BlueprintRepo{db: db}
 }
 
 func (r *BlueprintRepo) Get(ctx context.Context, code string) (*Blueprint, error) {
-	row := r.db.QueryRowContext(ctx, `SELECT code, status FROM blueprints WHERE code = ?`, code)
-	var b Blueprint
-	if err := row.Scan(&b.Code, &b.Status); err != nil {
+	row := r.db.QueryRowContext(ctx, `SELECT code, status, completed_at, reward_completion_id FROM blueprints WHERE code = ?`, code)
+	b, err := scanBlueprint(row)
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("blueprint get: %w", err)
 	}
+	return b, nil
+}
+
+func scanBlueprint(row scanner) (*Blueprint, error) {
+	var b Blueprint
+	var completedAt sql.NullTime
+	var reward sql.NullInt64
+	if err := row.Scan(&b.Code, &b.Status, &completedAt, &reward); err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		v := completedAt.Time
+		b.CompletedAt = &v
+	}
+	b.RewardCompletionID = nullInt64Ptr(reward)
 	return &b, nil
 }
 
@@ -37,8 +52,24 @@ func (r *BlueprintRepo) Upsert(ctx context.Context, b Blueprint) error {
 	return nil
 }
 
+// SetCompletion writes a blueprint's status together with its completion time
+// and the completion that paid its bonus. Upsert leaves both untouched.
+func (r *BlueprintRepo) SetCompletion(ctx context.Context, b Blueprint) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO blueprints (code, status, completed_at, reward_completion_id) VALUES (?, ?, ?, ?)
+		ON CONFLICT(code) DO UPDATE SET
+			status = excluded.status,
+			completed_at = excluded.completed_at,
+			reward_completion_id = excluded.reward_completion_id
+	`, b.Code, b.Status, b.CompletedAt, b.RewardCompletionID)
+	if err != nil {
+		return fmt.Errorf("blueprint set completion: %w", err)
+	}
+	return nil
+}
+
 func (r *BlueprintRepo) ListByStatus(ctx context.Context, status string) ([]Blueprint, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT code, status FROM blueprints WHERE status = ? ORDER BY code ASC`, status)
+	rows, err := r.db.QueryContext(ctx, `SELECT code, status, completed_at, reward_completion_id FROM blueprints WHERE status = ? ORDER BY code ASC`, status)
 	if err != nil {
 		return nil, fmt.Errorf("blueprint list: %w", err)
 	}
@@ -46,11 +77,11 @@ func (r *BlueprintRepo) ListByStatus(ctx context.Context, status string) ([]Blue
 
 	var out []Blueprint
 	for rows.Next() {
-		var b Blueprint
-		if err := rows.Scan(&b.Code, &b.Status); err != nil {
+		b, err := scanBlueprint(rows)
+		if err != nil {
 			return nil, fmt.Errorf("blueprint scan: %w", err)
 		}
-		out = append(out, b)
+		out = append(out, *b)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("blueprint rows: %w", err)
@@ -59,7 +90,7 @@ func (r *BlueprintRepo) ListByStatus(ctx context.Context, status string) ([]Blue
 }
 
 func (r *BlueprintRepo) ListAll(ctx context.Context) ([]Blueprint, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT code, status FROM blueprints ORDER BY code ASC`)
+	rows, err := r.db.QueryContext(ctx, `SELECT code, status, completed_at, reward_completion_id FROM blueprints ORDER BY code ASC`)
 	if err != nil {
 		return nil, fmt.Errorf("blueprint list all: %w", err)
 	}
@@ -67,11 +98,11 @@ func (r *BlueprintRepo) ListAll(ctx context.Context) ([]Blueprint, error) {
 
 	var out []Blueprint
 	for rows.Next() {
-		var b Blueprint
-		if err := rows.Scan(&b.Code, &b.Status); err != nil {
+		b, err := scanBlueprint(rows)
+		if err != nil {
 			return nil, fmt.Errorf("blueprint scan: %w", err)
 		}
-		out = append(out, b)
+		out = append(out, *b)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("blueprint rows: %w", err)
@@ -8,10 +8,10 @@ import (
 )
 
 type CompletionRepo struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewCompletionRepo(db *sql.DB) *CompletionRepo {
+func NewCompletionRepo(db DBTX) *CompletionRepo {
 	return &CompletionRepo{db: db}
 }
 
@@ -30,6 +30,22 @@ func (r *CompletionRepo) Insert(ctx context.Context, taskID int64, completedAt t
 	return id, nil
 }
 
+// InsertFull writes a completion row, keeping c.ID when non-zero. Used by import.
+func (r *CompletionRepo) InsertFull(ctx context.Context, c TaskCompletion) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO task_completions (id, task_id, completed_at, difficulty, xp_awarded)
+		VALUES (?, ?, ?, ?, ?)
+	`, nullID(c.ID), c.TaskID, c.CompletedAt, c.Difficulty, c.XPAwarded)
+	if err != nil {
+		return 0, fmt.Errorf("completion insert: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("completion last insert id: %w", err)
+	}
+	return id, nil
+}
+
 func (r *CompletionRepo) CountSince(ctx context.Context, taskID int64, since time.Time) (int, error) {
 	row := r.db.QueryRowContext(ctx, `
 		SELECT COUNT(*)
@@ -99,6 +115,32 @@ func (r *CompletionRepo) ListByTask(ctx context.Context, taskID int64) ([]TaskCo
 	return out, nil
 }
 
+// ListAll returns every completion in ID order.
+func (r *CompletionRepo) ListAll(ctx context.Context) ([]TaskCompletion, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, task_id, completed_at, difficulty, xp_awarded
+		FROM task_completions
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("completion list all: %w", err)
+	}
+	defer rows.Close()
+
+	var out []TaskCompletion
+	for rows.Next() {
+		var tc TaskCompletion
+		if err := rows.Scan(&tc.ID, &tc.TaskID, &tc.CompletedAt, &tc.Difficulty, &tc.XPAwarded); err != nil {
+			return nil, fmt.Errorf("completion scan: %w", err)
+		}
+		out = append(out, tc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("completion rows: %w", err)
+	}
+	return out, nil
+}
+
 // ListInRange returns all completions between since and until (inclusive).
 func (r *CompletionRepo) ListInRange(ctx context.Context, since, until time.Time) ([]TaskCompletion, error) {
 	rows, err := r.db.QueryContext(ctx, `
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	_ "modernc.org/sqlite"
 )
@@ -34,21 +35,33 @@ func ResolveDBPath() (string, error) {
 
 // Open opens (and creates if missing) the SQLite database and runs migrations.
 func Open(ctx context.Context, path string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite", path)
+	db, err := Connect(ctx, path)
 	if err != nil {
-		return nil, fmt.Errorf("open sqlite: %w", err)
+		return nil, err
 	}
-	if err := db.PingContext(ctx); err != nil {
+	if err := Migrate(ctx, db); err != nil {
 		_ = db.Close()
-		return nil, fmt.Errorf("ping sqlite: %w", err)
+		return nil, err
 	}
-	if _, err := db.ExecContext(ctx, "PRAGMA foreign_keys = ON;"); err != nil {
-		_ = db.Close()
-		return nil, fmt.Errorf("enable foreign_keys pragma: %w", err)
+	return db, nil
+}
+
+// Connect opens the SQLite database without running migrations.
+// Use it for tooling that inspects schema state; everything else should use Open.
+func Connect(ctx context.Context, path string) (*sql.DB, error) {
+	// The pragma goes in the DSN so every pooled connection enforces foreign
+	// keys, not just the first one.
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
 	}
-	if err := Migrate(ctx, db); err != nil {
+	db, err := sql.Open("sqlite", path+sep+"_pragma=foreign_keys(1)")
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
 		_ = db.Close()
-		return nil, err
+		return nil, fmt.Errorf("ping sqlite: %w", err)
 	}
 	return db, nil
 }
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+type DependencyRepo struct {
+	db DBTX
+}
+
+func NewDependencyRepo(db DBTX) *DependencyRepo {
+	return &DependencyRepo{db: db}
+}
+
+// Insert records that d.TaskID is blocked by d.BlockedBy. It reports false if
+// the dependency already existed.
+func (r *DependencyRepo) Insert(ctx context.Context, d TaskDependency) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO task_dependencies (task_id, blocked_by, created_at) VALUES (?, ?, ?)
+	`, d.TaskID, d.BlockedBy, d.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("dependency insert: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("dependency rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Delete removes a dependency. It reports false if there was none.
+func (r *DependencyRepo) Delete(ctx context.Context, taskID, blockedBy int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM task_dependencies WHERE task_id = ? AND blocked_by = ?`, taskID, blockedBy)
+	if err != nil {
+		return false, fmt.Errorf("dependency delete: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("dependency rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ListAll returns every dependency, ordered by task and blocker.
+func (r *DependencyRepo) ListAll(ctx context.Context) ([]TaskDependency, error) {
+	return r.list(ctx, `SELECT task_id, blocked_by, created_at FROM task_dependencies ORDER BY task_id ASC, blocked_by ASC`)
+}
+
+// ListBlockers returns the dependencies of taskID: the tasks it waits for.
+func (r *DependencyRepo) ListBlockers(ctx context.Context, taskID int64) ([]TaskDependency, error) {
+	return r.list(ctx, `SELECT task_id, blocked_by, created_at FROM task_dependencies WHERE task_id = ? ORDER BY blocked_by ASC`, taskID)
+}
+
+func (r *DependencyRepo) list(ctx context.Context, query string, args ...any) ([]TaskDependency, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("dependency list: %w", err)
+	}
+	defer rows.Close()
+
+	var out []TaskDependency
+	for rows.Next() {
+		var d TaskDependency
+		if err := rows.Scan(&d.TaskID, &d.BlockedBy, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("dependency scan: %w", err)
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("dependency rows: %w", err)
+	}
+	return out, nil
+}
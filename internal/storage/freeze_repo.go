@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type FreezeRepo struct {
+	db DBTX
+}
+
+func NewFreezeRepo(db DBTX) *FreezeRepo {
+	return &FreezeRepo{db: db}
+}
+
+// Earn adds an unused freeze token earned by a habit completion.
+func (r *FreezeRepo) Earn(ctx context.Context, taskID, completionID int64, at time.Time) (int64, error) {
+	return r.Insert(ctx, StreakFreeze{EarnedTaskID: &taskID, EarnedCompletionID: &completionID, EarnedAt: at})
+}
+
+// Insert writes a token row, keeping f.ID when non-zero. Used by import.
+func (r *FreezeRepo) Insert(ctx context.Context, f StreakFreeze) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO streak_freezes (id, earned_task_id, earned_completion_id, earned_at, used_task_id, used_completion_id, covered_period, used_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, nullID(f.ID), f.EarnedTaskID, f.EarnedCompletionID, f.EarnedAt, f.UsedTaskID, f.UsedCompletionID, f.CoveredPeriod, f.UsedAt)
+	if err != nil {
+		return 0, fmt.Errorf("freeze insert: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("freeze last insert id: %w", err)
+	}
+	return id, nil
+}
+
+// Available counts the unused tokens.
+func (r *FreezeRepo) Available(ctx context.Context) (int, error) {
+	var n int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM streak_freezes WHERE used_at IS NULL`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("freeze available: %w", err)
+	}
+	return n, nil
+}
+
+// Use spends the oldest unused token on the missed period starting at period.
+// It returns false when no token is left.
+func (r *FreezeRepo) Use(ctx context.Context, taskID, completionID int64, period, at time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE streak_freezes
+		SET used_task_id = ?, used_completion_id = ?, covered_period = ?, used_at = ?
+		WHERE id = (SELECT id FROM streak_freezes WHERE used_at IS NULL ORDER BY id ASC LIMIT 1)
+	`, taskID, completionID, period, at)
+	if err != nil {
+		return false, fmt.Errorf("freeze use: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("freeze use rows: %w", err)
+	}
+	return n > 0, nil
+}
+
+// CoveredPeriods returns the start of every period a token covers for a habit.
+func (r *FreezeRepo) CoveredPeriods(ctx context.Context, taskID int64) ([]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT covered_period FROM streak_freezes
+		WHERE used_task_id = ? AND covered_period IS NOT NULL
+		ORDER BY covered_period ASC
+	`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("freeze covered periods: %w", err)
+	}
+	defer rows.Close()
+
+	var out []time.Time
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, fmt.Errorf("freeze covered scan: %w", err)
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("freeze covered rows: %w", err)
+	}
+	return out, nil
+}
+
+// RevertCompletion undoes what a completion did to the tokens: unused tokens it
+// earned are removed and tokens it spent become unused again. It returns how
+// many tokens were removed and how many were returned.
+func (r *FreezeRepo) RevertCompletion(ctx context.Context, completionID int64) (removed, returned int, err error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM streak_freezes WHERE earned_completion_id = ? AND used_at IS NULL`, completionID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("freeze revert earned: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	removed = int(n)
+
+	// A token that was already spent elsewhere stays spent.
+	if _, err := r.db.ExecContext(ctx, `UPDATE streak_freezes SET earned_completion_id = NULL WHERE earned_completion_id = ?`, completionID); err != nil {
+		return 0, 0, fmt.Errorf("freeze revert earned: %w", err)
+	}
+
+	res, err = r.db.ExecContext(ctx, `
+		UPDATE streak_freezes
+		SET used_task_id = NULL, used_completion_id = NULL, covered_period = NULL, used_at = NULL
+		WHERE used_completion_id = ?
+	`, completionID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("freeze revert used: %w", err)
+	}
+	n, _ = res.RowsAffected()
+	returned = int(n)
+	return removed, returned, nil
+}
+
+// ListAll returns every token, oldest first.
+func (r *FreezeRepo) ListAll(ctx context.Context) ([]StreakFreeze, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, earned_task_id, earned_completion_id, earned_at, used_task_id, used_completion_id, covered_period, used_at
+		FROM streak_freezes ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("freeze list: %w", err)
+	}
+	defer rows.Close()
+
+	var out []StreakFreeze
+	for rows.Next() {
+		var f StreakFreeze
+		var earnedTask, earnedComp, usedTask, usedComp sql.NullInt64
+		var covered, usedAt sql.NullTime
+		if err := rows.Scan(&f.ID, &earnedTask, &earnedComp, &f.EarnedAt, &usedTask, &usedComp, &covered, &usedAt); err != nil {
+			return nil, fmt.Errorf("freeze scan: %w", err)
+		}
+		f.EarnedTaskID = nullInt64Ptr(earnedTask)
+		f.EarnedCompletionID = nullInt64Ptr(earnedComp)
+		f.UsedTaskID = nullInt64Ptr(usedTask)
+		f.UsedCompletionID = nullInt64Ptr(usedComp)
+		if covered.Valid {
+			v := covered.Time
+			f.CoveredPeriod = &v
+		}
+		if usedAt.Valid {
+			v := usedAt.Time
+			f.UsedAt = &v
+		}
+		out = append(out, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("freeze rows: %w", err)
+	}
+	return out, nil
+}
+
+func nullInt64Ptr(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	n := v.Int64
+	return &n
+}
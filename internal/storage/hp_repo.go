@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type HPRepo struct {
+	db DBTX
+}
+
+func NewHPRepo(db DBTX) *HPRepo {
+	return &HPRepo{db: db}
+}
+
+// Insert appends an HP log entry.
+func (r *HPRepo) Insert(ctx context.Context, e HPEntry) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO hp_log (player_key, task_id, reason, delta, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, e.PlayerKey, e.TaskID, e.Reason, e.Delta, e.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("hp log insert: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("hp log last insert id: %w", err)
+	}
+	return id, nil
+}
+
+// Recent returns a player's latest entries, newest first.
+func (r *HPRepo) Recent(ctx context.Context, playerKey string, limit int) ([]HPEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, player_key, task_id, reason, delta, created_at
+		FROM hp_log WHERE player_key = ?
+		ORDER BY id DESC LIMIT ?
+	`, playerKey, limit)
+	if err != nil {
+		return nil, fmt.Errorf("hp log list: %w", err)
+	}
+	defer rows.Close()
+
+	var out []HPEntry
+	for rows.Next() {
+		var e HPEntry
+		var taskID sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.PlayerKey, &taskID, &e.Reason, &e.Delta, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("hp log scan: %w", err)
+		}
+		e.TaskID = nullInt64Ptr(taskID)
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("hp log rows: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type LedgerRepo struct {
+	db DBTX
+}
+
+func NewLedgerRepo(db DBTX) *LedgerRepo {
+	return &LedgerRepo{db: db}
+}
+
+// Insert appends an entry. e.ID is kept when non-zero (import), otherwise the
+// next ID is assigned.
+func (r *LedgerRepo) Insert(ctx context.Context, e XPEntry) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO xp_ledger (id, player_key, event, task_id, completion_id, attribute, amount, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, nullID(e.ID), e.PlayerKey, e.Event, e.TaskID, e.CompletionID, e.Attribute, e.Amount, e.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("ledger insert: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("ledger last insert id: %w", err)
+	}
+	return id, nil
+}
+
+// ListByCompletion returns the entries recorded for a completion, oldest first.
+func (r *LedgerRepo) ListByCompletion(ctx context.Context, completionID int64) ([]XPEntry, error) {
+	return r.list(ctx, `WHERE completion_id = ? ORDER BY id ASC`, completionID)
+}
+
+// ListAll returns every ledger entry, oldest first.
+func (r *LedgerRepo) ListAll(ctx context.Context) ([]XPEntry, error) {
+	return r.list(ctx, `ORDER BY id ASC`)
+}
+
+// Totals sums the ledger for a player: the grand total and the per-attribute totals.
+// Entries with an empty attribute only count toward the grand total.
+func (r *LedgerRepo) Totals(ctx context.Context, playerKey string) (total int, byAttr map[string]int, entries int, err error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT attribute, SUM(amount), COUNT(*)
+		FROM xp_ledger
+		WHERE player_key = ?
+		GROUP BY attribute
+	`, playerKey)
+	if err != nil {
+		return 0, nil, 0, fmt.Errorf("ledger totals: %w", err)
+	}
+	defer rows.Close()
+
+	byAttr = map[string]int{}
+	for rows.Next() {
+		var attr string
+		var sum, n int
+		if err := rows.Scan(&attr, &sum, &n); err != nil {
+			return 0, nil, 0, fmt.Errorf("ledger totals scan: %w", err)
+		}
+		total += sum
+		entries += n
+		if attr != "" {
+			byAttr[attr] = sum
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, 0, fmt.Errorf("ledger totals rows: %w", err)
+	}
+	return total, byAttr, entries, nil
+}
+
+func (r *LedgerRepo) list(ctx context.Context, where string, args ...any) ([]XPEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, player_key, event, task_id, completion_id, attribute, amount, created_at
+		FROM xp_ledger `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ledger list: %w", err)
+	}
+	defer rows.Close()
+
+	var out []XPEntry
+	for rows.Next() {
+		var e XPEntry
+		var taskID, compID sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.PlayerKey, &e.Event, &taskID, &compID, &e.Attribute, &e.Amount, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ledger scan: %w", err)
+		}
+		if taskID.Valid {
+			v := taskID.Int64
+			e.TaskID = &v
+		}
+		if compID.Valid {
+			v := compID.Int64
+			e.CompletionID = &v
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ledger rows: %w", err)
+	}
+	return out, nil
+}
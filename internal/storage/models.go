@@ -6,17 +6,32 @@ type Player struct {
 	Key     string
 	Level   int
 	XPTotal int
-	// Original 4 attributes
-	XPStr int
-	XPInt int
-	XPWis int
-	XPArt int
-	// New 5 attributes
-	XPHome   int
-	XPOut    int
-	XPRead   int
-	XPCinema int
-	XPCareer int
+	XP      map[string]int // XP per attribute code (e.g. "STR"), from player_attributes
+
+	// Health. HP is nil until it is first evaluated (a full pool).
+	HP            *int
+	HPCheckedAt   *time.Time // when missed habits and rest were last applied
+	WeakenedUntil *time.Time // set while the player is knocked out
+
+	// AchievementsSince is when achievements started being announced; nil for
+	// players from before they were recorded.
+	AchievementsSince *time.Time
+}
+
+// AttrXP returns the player's XP for an attribute code (0 if none yet).
+func (p *Player) AttrXP(code string) int {
+	return p.XP[code]
+}
+
+// Attribute is a registered attribute track (built-in or user-defined).
+type Attribute struct {
+	Code      string
+	Name      string
+	Icon      string
+	Aliases   []string
+	Color     string
+	SortOrder int
+	Builtin   bool
 }
 
 type Task struct {
@@ -39,11 +54,15 @@ type Task struct {
 	HabitStartDate *time.Time // When the habit challenge started
 	HabitEndDate   *time.Time // When the habit challenge ends (nil = forever)
 	HabitGoal      *int       // Target completions to finish the habit (nil = ongoing)
+	BlueprintCode  *string    // Blueprint this task was created from (root of the instance only)
+	DeletedAt      *time.Time // When the task was moved to the trash (nil = live)
 }
 
 type Blueprint struct {
-	Code   string
-	Status string
+	Code               string
+	Status             string
+	CompletedAt        *time.Time
+	RewardCompletionID *int64 // completion that paid the one-time bonus
 }
 
 type TaskCompletion struct {
@@ -53,3 +72,91 @@ type TaskCompletion struct {
 	Difficulty  int
 	XPAwarded   int
 }
+
+// XPEntry is one row of the append-only XP ledger: the XP one attribute gained
+// (or lost) in a single event. An empty Attribute only affects the total.
+type XPEntry struct {
+	ID           int64
+	PlayerKey    string
+	Event        string
+	TaskID       *int64
+	CompletionID *int64
+	Attribute    string
+	Amount       int
+	CreatedAt    time.Time
+}
+
+// StreakFreeze is a freeze token. It is earned by a habit completion and, once
+// used, covers one missed period of a habit so its streak survives.
+type StreakFreeze struct {
+	ID                 int64
+	EarnedTaskID       *int64
+	EarnedCompletionID *int64
+	EarnedAt           time.Time
+	UsedTaskID         *int64
+	UsedCompletionID   *int64
+	CoveredPeriod      *time.Time // start of the missed period the token covers
+	UsedAt             *time.Time
+}
+
+// EarnedAchievement records when a player first earned an achievement.
+type EarnedAchievement struct {
+	PlayerKey string
+	ID        string
+	EarnedAt  time.Time
+}
+
+// HPEntry is one change to the player's HP: damage from a missed habit, rest,
+// healing from a completion or a knockout.
+type HPEntry struct {
+	ID        int64
+	PlayerKey string
+	TaskID    *int64
+	Reason    string
+	Delta     int
+	CreatedAt time.Time
+}
+
+// Review is a saved weekly or monthly review of [PeriodStart, PeriodEnd).
+type Review struct {
+	ID          int64
+	Period      string // week or month
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Completions int // Completions in the period
+	XPEarned    int // XP those completions earned
+	Rescheduled int
+	Dropped     int
+	Rerated     int
+	XPAwarded   int // Review XP; 0 when the period had been reviewed already
+	CreatedAt   time.Time
+}
+
+// TaskSession is a stretch of time spent on a task, tracked with a timer or a
+// pomodoro focus interval. EndedAt is nil while it runs.
+type TaskSession struct {
+	ID        int64
+	TaskID    int64
+	Kind      string // timer or focus
+	StartedAt time.Time
+	EndedAt   *time.Time
+}
+
+// Duration returns how long the session lasted, up to now while it runs.
+func (s *TaskSession) Duration(now time.Time) time.Duration {
+	end := now
+	if s.EndedAt != nil {
+		end = *s.EndedAt
+	}
+	if end.Before(s.StartedAt) {
+		return 0
+	}
+	return end.Sub(s.StartedAt)
+}
+
+// TaskDependency says TaskID cannot be completed before BlockedBy is done.
+type TaskDependency struct {
+	TaskID    int64
+	BlockedBy int64
+	CreatedAt time.Time
+}
@@ -4,33 +4,65 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 )
 
 const MainPlayerKey = "main_user"
 
 type PlayerRepo struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewPlayerRepo(db *sql.DB) *PlayerRepo {
+func NewPlayerRepo(db DBTX) *PlayerRepo {
 	return &PlayerRepo{db: db}
 }
 
 func (r *PlayerRepo) Get(ctx context.Context, key string) (*Player, error) {
-	row := r.db.QueryRowContext(ctx, `
-		SELECT key, level, xp_total, xp_str, xp_int, xp_wis, xp_art,
-		       xp_home, xp_out, xp_read, xp_cinema, xp_career
-		FROM player WHERE key = ?`, key)
+	row := r.db.QueryRowContext(ctx, `SELECT key, level, xp_total, hp, hp_checked_at, weakened_until, achievements_since FROM player WHERE key = ?`, key)
 
 	var p Player
-	if err := row.Scan(&p.Key, &p.Level, &p.XPTotal,
-		&p.XPStr, &p.XPInt, &p.XPWis, &p.XPArt,
-		&p.XPHome, &p.XPOut, &p.XPRead, &p.XPCinema, &p.XPCareer); err != nil {
+	var hp sql.NullInt64
+	var checked, weakened, since sql.NullTime
+	if err := row.Scan(&p.Key, &p.Level, &p.XPTotal, &hp, &checked, &weakened, &since); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("player get: %w", err)
 	}
+	if hp.Valid {
+		v := int(hp.Int64)
+		p.HP = &v
+	}
+	if checked.Valid {
+		v := checked.Time
+		p.HPCheckedAt = &v
+	}
+	if weakened.Valid {
+		v := weakened.Time
+		p.WeakenedUntil = &v
+	}
+	if since.Valid {
+		v := since.Time
+		p.AchievementsSince = &v
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT attribute, xp FROM player_attributes WHERE player_key = ?`, key)
+	if err != nil {
+		return nil, fmt.Errorf("player attributes: %w", err)
+	}
+	defer rows.Close()
+	p.XP = map[string]int{}
+	for rows.Next() {
+		var code string
+		var xp int
+		if err := rows.Scan(&code, &xp); err != nil {
+			return nil, fmt.Errorf("player attributes scan: %w", err)
+		}
+		p.XP[code] = xp
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("player attributes rows: %w", err)
+	}
 	return &p, nil
 }
 
@@ -43,22 +75,28 @@ func (r *PlayerRepo) GetOrCreateMain(ctx context.Context) (*Player, error) {
 		return p, nil
 	}
 
-	if _, err := r.db.ExecContext(ctx, `INSERT INTO player (key) VALUES (?)`, MainPlayerKey); err != nil {
+	// A new player announces every achievement it earns.
+	if _, err := r.db.ExecContext(ctx, `INSERT INTO player (key, achievements_since) VALUES (?, ?)`, MainPlayerKey, time.Now().UTC()); err != nil {
 		return nil, fmt.Errorf("player insert: %w", err)
 	}
 	return r.Get(ctx, MainPlayerKey)
 }
 
+// Update writes the player row and every attribute in p.XP.
 func (r *PlayerRepo) Update(ctx context.Context, p *Player) error {
-	_, err := r.db.ExecContext(ctx, `
-		UPDATE player
-		SET level = ?, xp_total = ?, xp_str = ?, xp_int = ?, xp_wis = ?, xp_art = ?,
-		    xp_home = ?, xp_out = ?, xp_read = ?, xp_cinema = ?, xp_career = ?
-		WHERE key = ?
-	`, p.Level, p.XPTotal, p.XPStr, p.XPInt, p.XPWis, p.XPArt,
-		p.XPHome, p.XPOut, p.XPRead, p.XPCinema, p.XPCareer, p.Key)
+	_, err := r.db.ExecContext(ctx, `UPDATE player SET level = ?, xp_total = ?, hp = ?, hp_checked_at = ?, weakened_until = ?, achievements_since = ? WHERE key = ?`,
+		p.Level, p.XPTotal, p.HP, p.HPCheckedAt, p.WeakenedUntil, p.AchievementsSince, p.Key)
 	if err != nil {
 		return fmt.Errorf("player update: %w", err)
 	}
+	for code, xp := range p.XP {
+		_, err := r.db.ExecContext(ctx, `
+			INSERT INTO player_attributes (player_key, attribute, xp) VALUES (?, ?, ?)
+			ON CONFLICT(player_key, attribute) DO UPDATE SET xp = excluded.xp
+		`, p.Key, code, xp)
+		if err != nil {
+			return fmt.Errorf("player attribute update: %w", err)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+type ReviewRepo struct {
+	db DBTX
+}
+
+func NewReviewRepo(db DBTX) *ReviewRepo {
+	return &ReviewRepo{db: db}
+}
+
+const reviewColumns = `id, period, period_start, period_end, completions, xp_earned, rescheduled, dropped, rerated, xp_awarded, created_at`
+
+// Insert writes a review, keeping rv.ID when non-zero (import).
+func (r *ReviewRepo) Insert(ctx context.Context, rv Review) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO reviews (`+reviewColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, nullID(rv.ID), rv.Period, rv.PeriodStart, rv.PeriodEnd, rv.Completions, rv.XPEarned, rv.Rescheduled, rv.Dropped, rv.Rerated, rv.XPAwarded, rv.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("review insert: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("review last insert id: %w", err)
+	}
+	return id, nil
+}
+
+// Last returns the most recent review of a period kind, or nil if there is none.
+func (r *ReviewRepo) Last(ctx context.Context, period string) (*Review, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+reviewColumns+` FROM reviews WHERE period = ? ORDER BY period_start DESC, id DESC LIMIT 1`, period)
+	rv, err := scanReview(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("review last: %w", err)
+	}
+	return rv, nil
+}
+
+// LastPaid returns the most recent review of a period kind that paid XP, or
+// nil if there is none.
+func (r *ReviewRepo) LastPaid(ctx context.Context, period string) (*Review, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+reviewColumns+` FROM reviews WHERE period = ? AND xp_awarded > 0 ORDER BY created_at DESC, id DESC LIMIT 1`, period)
+	rv, err := scanReview(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("review last paid: %w", err)
+	}
+	return rv, nil
+}
+
+// ListAll returns every review, oldest first.
+func (r *ReviewRepo) ListAll(ctx context.Context) ([]Review, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+reviewColumns+` FROM reviews ORDER BY id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("review list: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Review
+	for rows.Next() {
+		rv, err := scanReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("review scan: %w", err)
+		}
+		out = append(out, *rv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("review rows: %w", err)
+	}
+	return out, nil
+}
+
+func scanReview(row scanner) (*Review, error) {
+	var rv Review
+	if err := row.Scan(&rv.ID, &rv.Period, &rv.PeriodStart, &rv.PeriodEnd, &rv.Completions, &rv.XPEarned, &rv.Rescheduled, &rv.Dropped, &rv.Rerated, &rv.XPAwarded, &rv.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &rv, nil
+}
@@ -4,11 +4,208 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strings"
+	"time"
 )
 
+// Migration is a single numbered schema change.
+// Each migration runs exactly once, inside its own transaction.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, tx *sql.Tx) error
+}
+
+// migrations is the ordered migration registry.
+// Append new steps at the end; never renumber or edit a step that has shipped.
+var migrations = []Migration{
+	{Version: 1, Name: "baseline schema", Up: migrateBaseline},
+	{Version: 2, Name: "xp ledger", Up: migrateXPLedger},
+	{Version: 3, Name: "attribute registry", Up: migrateAttributeRegistry},
+	{Version: 4, Name: "streak freezes", Up: migrateStreakFreezes},
+	{Version: 5, Name: "player hp", Up: migratePlayerHP},
+	{Version: 6, Name: "blueprint instances", Up: migrateBlueprintInstances},
+	{Version: 7, Name: "achievements", Up: migrateAchievements},
+	{Version: 8, Name: "task dependencies", Up: migrateTaskDependencies},
+	{Version: 9, Name: "task trash", Up: migrateTaskTrash},
+	{Version: 10, Name: "task search", Up: migrateTaskSearch},
+	{Version: 11, Name: "task tags", Up: migrateTaskTags},
+	{Version: 12, Name: "reviews", Up: migrateReviews},
+	{Version: 13, Name: "task sessions", Up: migrateTaskSessions},
+	{Version: 14, Name: "single running session", Up: migrateSingleRunningSession},
+	{Version: 15, Name: "orphan rows", Up: migrateOrphanRows},
+}
+
+// LatestSchemaVersion is the schema version this binary migrates databases to.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaTooNewError is returned when a database was migrated by a newer binary.
+type SchemaTooNewError struct {
+	DBVersion int
+	Supported int
+}
+
+func (e SchemaTooNewError) Error() string {
+	return fmt.Sprintf("database schema version %d is newer than this binary supports (%d); upgrade questline", e.DBVersion, e.Supported)
+}
+
+// MigrationState reports whether a registered migration has been applied.
+type MigrationState struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time // nil when pending
+}
+
+// Migrate applies every pending migration in order.
+// It refuses to touch a database whose version is newer than LatestSchemaVersion.
 func Migrate(ctx context.Context, db *sql.DB) error {
-	stmts := []string{
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	);`); err != nil {
+		return fmt.Errorf("migrate: create schema_version: %w", err)
+	}
+
+	current, err := currentSchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	if current > LatestSchemaVersion() {
+		return SchemaTooNewError{DBVersion: current, Supported: LatestSchemaVersion()}
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		err := WithTx(ctx, db, func(tx *sql.Tx) error {
+			if err := m.Up(ctx, tx); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)`, m.Version, m.Name, time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migrate %03d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// MigrationStatus lists every registered migration with its applied time.
+// It does not modify the database.
+func MigrationStatus(ctx context.Context, db *sql.DB) ([]MigrationState, error) {
+	applied := map[int]time.Time{}
+
+	exists, err := tableExists(ctx, db, "schema_version")
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		rows, err := db.QueryContext(ctx, `SELECT version, applied_at FROM schema_version`)
+		if err != nil {
+			return nil, fmt.Errorf("migration status: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var v int
+			var at time.Time
+			if err := rows.Scan(&v, &at); err != nil {
+				return nil, fmt.Errorf("migration status scan: %w", err)
+			}
+			applied[v] = at
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("migration status rows: %w", err)
+		}
+	}
+
+	out := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		st := MigrationState{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			v := at
+			st.AppliedAt = &v
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// CurrentSchemaVersion returns the highest applied migration version (0 for a fresh DB).
+func CurrentSchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	exists, err := tableExists(ctx, db, "schema_version")
+	if err != nil || !exists {
+		return 0, err
+	}
+	return currentSchemaVersion(ctx, db)
+}
+
+func currentSchemaVersion(ctx context.Context, db DBTX) (int, error) {
+	var v sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_version`).Scan(&v); err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	return int(v.Int64), nil
+}
+
+func tableExists(ctx context.Context, db DBTX, name string) (bool, error) {
+	var one int
+	err := db.QueryRowContext(ctx, `SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("check table %s: %w", name, err)
+	}
+	return true, nil
+}
+
+func columnExists(ctx context.Context, db DBTX, table, column string) (bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return false, fmt.Errorf("table info %s: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, fmt.Errorf("table info scan: %w", err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+func addColumnIfMissing(ctx context.Context, tx *sql.Tx, table, column, decl string) error {
+	ok, err := columnExists(ctx, tx, table, column)
+	if err != nil || ok {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s;`, table, column, decl)); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+func execAll(ctx context.Context, tx *sql.Tx, stmts ...string) error {
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateBaseline creates the pre-versioning schema. Databases created before
+// schema_version existed may be missing later columns, so those are added only
+// when absent.
+func migrateBaseline(ctx context.Context, tx *sql.Tx) error {
+	err := execAll(ctx, tx,
 		`CREATE TABLE IF NOT EXISTS player (
 			key TEXT PRIMARY KEY,
 			level INTEGER DEFAULT 1,
@@ -61,35 +258,348 @@ func Migrate(ctx context.Context, db *sql.DB) error {
 		`CREATE INDEX IF NOT EXISTS idx_tasks_parent_id ON tasks(parent_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);`,
 		`CREATE INDEX IF NOT EXISTS idx_task_completions_task_id_completed_at ON task_completions(task_id, completed_at);`,
+	)
+	if err != nil {
+		return err
 	}
 
-	for _, stmt := range stmts {
-		if _, err := db.ExecContext(ctx, stmt); err != nil {
-			return fmt.Errorf("migrate: %w", err)
+	columns := []struct{ table, column, decl string }{
+		// Add new attribute columns to existing player tables
+		{"player", "xp_home", "INTEGER DEFAULT 0"},
+		{"player", "xp_out", "INTEGER DEFAULT 0"},
+		{"player", "xp_read", "INTEGER DEFAULT 0"},
+		{"player", "xp_cinema", "INTEGER DEFAULT 0"},
+		{"player", "xp_career", "INTEGER DEFAULT 0"},
+		// Multi-attribute support for tasks
+		{"tasks", "attributes", "TEXT"},
+		// Habit duration fields
+		{"tasks", "habit_start_date", "DATETIME"},
+		{"tasks", "habit_end_date", "DATETIME"},
+		{"tasks", "habit_goal", "INTEGER"},
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(ctx, tx, c.table, c.column, c.decl); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	// Add new attribute columns to existing player tables (ignore if already exists)
-	alterStmts := []string{
-		`ALTER TABLE player ADD COLUMN xp_home INTEGER DEFAULT 0;`,
-		`ALTER TABLE player ADD COLUMN xp_out INTEGER DEFAULT 0;`,
-		`ALTER TABLE player ADD COLUMN xp_read INTEGER DEFAULT 0;`,
-		`ALTER TABLE player ADD COLUMN xp_cinema INTEGER DEFAULT 0;`,
-		`ALTER TABLE player ADD COLUMN xp_career INTEGER DEFAULT 0;`,
-		// Multi-attribute support for tasks
-		`ALTER TABLE tasks ADD COLUMN attributes TEXT;`,
-		// Habit duration fields
-		`ALTER TABLE tasks ADD COLUMN habit_start_date DATETIME;`,
-		`ALTER TABLE tasks ADD COLUMN habit_end_date DATETIME;`,
-		`ALTER TABLE tasks ADD COLUMN habit_goal INTEGER;`,
+// migrateXPLedger creates the append-only XP ledger and seeds it with one
+// adjustment per attribute, so rebuilding from the ledger reproduces the
+// counters the player already has.
+func migrateXPLedger(ctx context.Context, tx *sql.Tx) error {
+	err := execAll(ctx, tx,
+		`CREATE TABLE xp_ledger (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			player_key TEXT NOT NULL,
+			event TEXT NOT NULL,
+			task_id INTEGER,
+			completion_id INTEGER,
+			attribute TEXT NOT NULL DEFAULT '',
+			amount INTEGER NOT NULL,
+			created_at DATETIME NOT NULL
+		);`,
+		`CREATE INDEX idx_xp_ledger_completion_id ON xp_ledger(completion_id);`,
+		`CREATE INDEX idx_xp_ledger_player_key ON xp_ledger(player_key);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT key, xp_total, xp_str, xp_int, xp_wis, xp_art,
+		       xp_home, xp_out, xp_read, xp_cinema, xp_career
+		FROM player`)
+	if err != nil {
+		return err
+	}
+	type legacyPlayer struct {
+		key   string
+		total int
+		xp    [9]int // same order as codes below
+	}
+	codes := [9]string{"STR", "INT", "WIS", "ART", "HOME", "OUT", "READ", "CINEMA", "CAREER"}
+	var players []legacyPlayer
+	for rows.Next() {
+		var p legacyPlayer
+		if err := rows.Scan(&p.key, &p.total,
+			&p.xp[0], &p.xp[1], &p.xp[2], &p.xp[3],
+			&p.xp[4], &p.xp[5], &p.xp[6], &p.xp[7], &p.xp[8]); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		players = append(players, p)
 	}
-	for _, stmt := range alterStmts {
-		_, err := db.ExecContext(ctx, stmt)
-		if err != nil && !strings.Contains(err.Error(), "duplicate column") {
-			// Ignore "duplicate column" errors - column already exists
-			return fmt.Errorf("migrate alter: %w", err)
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, p := range players {
+		sum := 0
+		for i, xp := range p.xp {
+			if xp == 0 {
+				continue
+			}
+			sum += xp
+			if _, err := tx.ExecContext(ctx, `INSERT INTO xp_ledger (player_key, event, attribute, amount, created_at) VALUES (?, 'adjustment', ?, ?, ?)`, p.key, codes[i], xp, now); err != nil {
+				return err
+			}
 		}
+		// Older restores deducted attributes with integer division, so the total
+		// can disagree with the attribute sum. Keep the difference as a total-only row.
+		if diff := p.total - sum; diff != 0 {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO xp_ledger (player_key, event, attribute, amount, created_at) VALUES (?, 'adjustment', '', ?, ?)`, p.key, diff, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateAttributeRegistry moves attributes from hard-coded player columns to
+// data: an attributes table seeded with the built-in tracks, and per-player XP
+// in player_attributes. The old xp_* columns are dropped.
+func migrateAttributeRegistry(ctx context.Context, tx *sql.Tx) error {
+	err := execAll(ctx, tx,
+		`CREATE TABLE attributes (
+			code TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			icon TEXT NOT NULL DEFAULT '',
+			aliases TEXT NOT NULL DEFAULT '',
+			color TEXT NOT NULL DEFAULT '',
+			sort_order INTEGER NOT NULL DEFAULT 0,
+			builtin INTEGER NOT NULL DEFAULT 0
+		);`,
+		`CREATE TABLE player_attributes (
+			player_key TEXT NOT NULL,
+			attribute TEXT NOT NULL,
+			xp INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (player_key, attribute),
+			FOREIGN KEY(player_key) REFERENCES player(key)
+		);`,
+	)
+	if err != nil {
+		return err
 	}
 
+	builtins := []struct{ code, column, name, icon, aliases, color string }{
+		{"STR", "xp_str", "Strength", "💪", "", "196"},
+		{"INT", "xp_int", "Intelligence", "🧠", "", "51"},
+		{"WIS", "xp_wis", "Wisdom", "🧘", "", "141"},
+		{"ART", "xp_art", "Art", "🎨", "", "201"},
+		{"HOME", "xp_home", "Home", "🏠", "", "214"},
+		{"OUT", "xp_out", "Outdoors", "🌲", "outdoors", "34"},
+		{"READ", "xp_read", "Reading", "📚", "reading", "180"},
+		{"CINEMA", "xp_cinema", "Cinema", "🎬", "movies,film", "135"},
+		{"CAREER", "xp_career", "Career", "💼", "finance,work", "39"},
+	}
+	for i, b := range builtins {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO attributes (code, name, icon, aliases, color, sort_order, builtin)
+			VALUES (?, ?, ?, ?, ?, ?, 1)
+		`, b.code, b.name, b.icon, b.aliases, b.color, (i+1)*10); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO player_attributes (player_key, attribute, xp)
+			SELECT key, ?, %s FROM player WHERE %s != 0
+		`, b.column, b.column), b.code); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE player DROP COLUMN %s;`, b.column)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// migrateStreakFreezes adds the freeze tokens habits earn and spend to keep a
+// streak alive across a missed period.
+func migrateStreakFreezes(ctx context.Context, tx *sql.Tx) error {
+	return execAll(ctx, tx,
+		`CREATE TABLE streak_freezes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			earned_task_id INTEGER,
+			earned_completion_id INTEGER,
+			earned_at DATETIME NOT NULL,
+			used_task_id INTEGER,
+			used_completion_id INTEGER,
+			covered_period DATETIME,
+			used_at DATETIME
+		);`,
+		`CREATE INDEX idx_streak_freezes_used_task_id ON streak_freezes(used_task_id);`,
+	)
+}
+
+// migratePlayerHP adds the player's health pool and the log of what changed it.
+func migratePlayerHP(ctx context.Context, tx *sql.Tx) error {
+	return execAll(ctx, tx,
+		`ALTER TABLE player ADD COLUMN hp INTEGER;`,
+		`ALTER TABLE player ADD COLUMN hp_checked_at DATETIME;`,
+		`ALTER TABLE player ADD COLUMN weakened_until DATETIME;`,
+		`CREATE TABLE hp_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			player_key TEXT NOT NULL,
+			task_id INTEGER,
+			reason TEXT NOT NULL,
+			delta INTEGER NOT NULL,
+			created_at DATETIME NOT NULL
+		);`,
+		`CREATE INDEX idx_hp_log_player_key ON hp_log(player_key);`,
+	)
+}
+
+// migrateBlueprintInstances links tasks to the blueprint they were created
+// from and records when a blueprint was completed and which completion paid
+// its one-time bonus.
+func migrateBlueprintInstances(ctx context.Context, tx *sql.Tx) error {
+	return execAll(ctx, tx,
+		`ALTER TABLE tasks ADD COLUMN blueprint_code TEXT;`,
+		`CREATE INDEX idx_tasks_blueprint_code ON tasks(blueprint_code);`,
+		`ALTER TABLE blueprints ADD COLUMN completed_at DATETIME;`,
+		`ALTER TABLE blueprints ADD COLUMN reward_completion_id INTEGER;`,
+	)
+}
+
+// migrateAchievements records when each achievement was first earned. Players
+// that existed before have no achievements_since; their badges are recorded
+// silently on the next check instead of being announced all at once.
+func migrateAchievements(ctx context.Context, tx *sql.Tx) error {
+	return execAll(ctx, tx,
+		`CREATE TABLE achievements (
+			player_key TEXT NOT NULL,
+			id TEXT NOT NULL,
+			earned_at DATETIME NOT NULL,
+			PRIMARY KEY (player_key, id)
+		);`,
+		`ALTER TABLE player ADD COLUMN achievements_since DATETIME;`,
+	)
+}
+
+// migrateTaskDependencies adds task dependencies: a task can't be completed
+// before the tasks it is blocked by are done.
+func migrateTaskDependencies(ctx context.Context, tx *sql.Tx) error {
+	return execAll(ctx, tx,
+		`CREATE TABLE task_dependencies (
+			task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+			blocked_by INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (task_id, blocked_by),
+			CHECK (task_id != blocked_by)
+		);`,
+		`CREATE INDEX idx_task_dependencies_blocked_by ON task_dependencies(blocked_by);`,
+	)
+}
+
+// migrateTaskTrash lets tasks be soft-deleted: a trashed task keeps its row,
+// subtree and completions until it is purged.
+func migrateTaskTrash(ctx context.Context, tx *sql.Tx) error {
+	return execAll(ctx, tx,
+		`ALTER TABLE tasks ADD COLUMN deleted_at DATETIME;`,
+		`CREATE INDEX idx_tasks_deleted_at ON tasks(deleted_at);`,
+	)
+}
+
+// migrateTaskSearch adds a full-text index over task titles and descriptions.
+// Triggers keep it in sync with the tasks table; it indexes trashed tasks too,
+// searches filter them out.
+func migrateTaskSearch(ctx context.Context, tx *sql.Tx) error {
+	return execAll(ctx, tx,
+		`CREATE VIRTUAL TABLE tasks_fts USING fts5(
+			title, description,
+			content = 'tasks', content_rowid = 'id',
+			tokenize = 'unicode61 remove_diacritics 2'
+		);`,
+		`CREATE TRIGGER tasks_fts_insert AFTER INSERT ON tasks BEGIN
+			INSERT INTO tasks_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+		END;`,
+		`CREATE TRIGGER tasks_fts_delete AFTER DELETE ON tasks BEGIN
+			INSERT INTO tasks_fts (tasks_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+		END;`,
+		`CREATE TRIGGER tasks_fts_update AFTER UPDATE OF title, description ON tasks BEGIN
+			INSERT INTO tasks_fts (tasks_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+			INSERT INTO tasks_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+		END;`,
+		`INSERT INTO tasks_fts (tasks_fts) VALUES ('rebuild');`,
+	)
+}
+
+// migrateTaskTags adds free-form tags. Subtasks inherit their ancestors' tags;
+// only the ones set on a task are stored.
+func migrateTaskTags(ctx context.Context, tx *sql.Tx) error {
+	return execAll(ctx, tx,
+		`CREATE TABLE task_tags (
+			task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+			tag TEXT NOT NULL,
+			PRIMARY KEY (task_id, tag)
+		);`,
+		`CREATE INDEX idx_task_tags_tag ON task_tags(tag);`,
+	)
+}
+
+// migrateReviews adds the weekly and monthly review log. Each row records what a
+// review saw and did; its XP is in the ledger as a "review" event.
+func migrateReviews(ctx context.Context, tx *sql.Tx) error {
+	return execAll(ctx, tx,
+		`CREATE TABLE reviews (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			period TEXT NOT NULL,
+			period_start DATETIME NOT NULL,
+			period_end DATETIME NOT NULL,
+			completions INTEGER NOT NULL DEFAULT 0,
+			xp_earned INTEGER NOT NULL DEFAULT 0,
+			rescheduled INTEGER NOT NULL DEFAULT 0,
+			dropped INTEGER NOT NULL DEFAULT 0,
+			rerated INTEGER NOT NULL DEFAULT 0,
+			xp_awarded INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL
+		);`,
+		`CREATE INDEX idx_reviews_period ON reviews(period, period_start);`,
+	)
+}
+
+// migrateTaskSessions adds time tracking. A session with no ended_at is the
+// running timer; there is at most one.
+func migrateTaskSessions(ctx context.Context, tx *sql.Tx) error {
+	return execAll(ctx, tx,
+		`CREATE TABLE task_sessions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+			kind TEXT NOT NULL DEFAULT 'timer',
+			started_at DATETIME NOT NULL,
+			ended_at DATETIME
+		);`,
+		`CREATE INDEX idx_task_sessions_task ON task_sessions(task_id, started_at);`,
+	)
+}
+
+// migrateSingleRunningSession lets the database enforce the single running
+// timer. Any extra open sessions are ended where the next session started, as
+// starting a timer would have done; the latest one keeps running.
+func migrateSingleRunningSession(ctx context.Context, tx *sql.Tx) error {
+	return execAll(ctx, tx,
+		`UPDATE task_sessions SET ended_at = COALESCE(
+			(SELECT MIN(n.started_at) FROM task_sessions n WHERE n.started_at > task_sessions.started_at),
+			started_at)
+		WHERE ended_at IS NULL AND id != (
+			SELECT id FROM task_sessions WHERE ended_at IS NULL ORDER BY started_at DESC, id DESC LIMIT 1);`,
+		`CREATE UNIQUE INDEX idx_task_sessions_running ON task_sessions((ended_at IS NULL)) WHERE ended_at IS NULL;`,
+	)
+}
+
+// migrateOrphanRows clears what older deletes left behind before foreign keys
+// were enforced on every connection: subtasks of deleted tasks move to the top
+// level, and rows pointing at deleted tasks go. Otherwise the next update of
+// such a row fails its foreign key check.
+func migrateOrphanRows(ctx context.Context, tx *sql.Tx) error {
+	return execAll(ctx, tx,
+		`UPDATE tasks SET parent_id = NULL WHERE parent_id IS NOT NULL AND parent_id NOT IN (SELECT id FROM tasks);`,
+		`DELETE FROM task_completions WHERE task_id NOT IN (SELECT id FROM tasks);`,
+		`DELETE FROM task_dependencies WHERE task_id NOT IN (SELECT id FROM tasks) OR blocked_by NOT IN (SELECT id FROM tasks);`,
+		`DELETE FROM task_tags WHERE task_id NOT IN (SELECT id FROM tasks);`,
+		`DELETE FROM task_sessions WHERE task_id NOT IN (SELECT id FROM tasks);`,
+		`DELETE FROM player_attributes WHERE player_key NOT IN (SELECT key FROM player);`,
+	)
+}
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMigrateIsIdempotentAndRefusesNewerSchema(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := Open(ctx, path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	v, err := CurrentSchemaVersion(ctx, db)
+	if err != nil {
+		t.Fatalf("version: %v", err)
+	}
+	if v != LatestSchemaVersion() {
+		t.Fatalf("version=%d, want %d", v, LatestSchemaVersion())
+	}
+
+	future := LatestSchemaVersion() + 1
+	if _, err := db.ExecContext(ctx, `INSERT INTO schema_version (version, name, applied_at) VALUES (?, 'future', CURRENT_TIMESTAMP)`, future); err != nil {
+		t.Fatalf("insert future version: %v", err)
+	}
+	_ = db.Close()
+
+	_, err = Open(ctx, path)
+	var tooNew SchemaTooNewError
+	if !errors.As(err, &tooNew) {
+		t.Fatalf("open err=%v, want SchemaTooNewError", err)
+	}
+	if tooNew.DBVersion != future {
+		t.Fatalf("DBVersion=%d, want %d", tooNew.DBVersion, future)
+	}
+}
+
+func TestEveryConnectionEnforcesForeignKeys(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	// Hold two connections at once so the pool has to open a second one.
+	for i := 0; i < 2; i++ {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("conn: %v", err)
+		}
+		defer conn.Close()
+		var on int
+		if err := conn.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&on); err != nil {
+			t.Fatalf("pragma: %v", err)
+		}
+		if on != 1 {
+			t.Fatalf("connection %d has foreign_keys=%d, want 1", i+1, on)
+		}
+	}
+}
+
+func TestOnlyOneSessionRuns(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	// Go back to before the index, when two timers could be left running.
+	for _, q := range []string{
+		`DROP INDEX idx_task_sessions_running`,
+		`DELETE FROM schema_version WHERE version >= 14`,
+	} {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	taskID, err := NewTaskRepo(db).Insert(ctx, TaskInsert{Title: "Write", Status: "pending", Difficulty: 1, Attribute: "INT", XPValue: 50})
+	if err != nil {
+		t.Fatalf("insert task: %v", err)
+	}
+	sessions := NewSessionRepo(db)
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{start, start.Add(time.Hour)} {
+		if _, err := sessions.Insert(ctx, TaskSession{TaskID: taskID, Kind: "timer", StartedAt: at}); err != nil {
+			t.Fatalf("insert session: %v", err)
+		}
+	}
+
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	all, err := sessions.ListAll(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(all) != 2 || all[0].EndedAt == nil || !all[0].EndedAt.Equal(start.Add(time.Hour)) || all[1].EndedAt != nil {
+		t.Fatalf("sessions=%+v, want the first ended where the second started", all)
+	}
+	if _, err := sessions.Insert(ctx, TaskSession{TaskID: taskID, Kind: "timer", StartedAt: start.Add(2 * time.Hour)}); err == nil {
+		t.Fatal("a second running session was inserted")
+	}
+}
+
+func TestMigrateClearsOrphanRows(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	// Older deletes removed only direct children, leaving subtasks and
+	// completions that point at tasks that are gone.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	for _, q := range []string{
+		`PRAGMA foreign_keys = OFF`,
+		`INSERT INTO tasks (id, parent_id, title, attribute, xp_value) VALUES (1, NULL, 'Kept', 'STR', 50), (2, 99, 'Orphan', 'STR', 50), (3, 2, 'Its child', 'STR', 50)`,
+		`INSERT INTO task_completions (task_id, completed_at, difficulty, xp_awarded) VALUES (1, CURRENT_TIMESTAMP, 1, 50), (98, CURRENT_TIMESTAMP, 1, 50)`,
+		`INSERT INTO task_tags (task_id, tag) VALUES (2, 'home'), (98, 'home')`,
+		`INSERT INTO task_dependencies (task_id, blocked_by, created_at) VALUES (2, 98, CURRENT_TIMESTAMP)`,
+		`INSERT INTO task_sessions (task_id, started_at, ended_at) VALUES (98, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+		`DELETE FROM schema_version WHERE version >= 15`,
+		`PRAGMA foreign_keys = ON`,
+	} {
+		if _, err := conn.ExecContext(ctx, q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	_ = conn.Close()
+
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	rows, err := db.QueryContext(ctx, `PRAGMA foreign_key_check`)
+	if err != nil {
+		t.Fatalf("foreign_key_check: %v", err)
+	}
+	var violations []string
+	for rows.Next() {
+		var table string
+		var rowid, parent, fkid sql.NullString
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		violations = append(violations, table+" row "+rowid.String)
+	}
+	rows.Close()
+	if len(violations) > 0 {
+		t.Fatalf("foreign key violations after migrating: %v", violations)
+	}
+
+	var parent sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT parent_id FROM tasks WHERE id = 2`).Scan(&parent); err != nil || parent.Valid {
+		t.Fatalf("orphan parent_id=%v, %v; want it at the top level", parent, err)
+	}
+	var completions, tags int
+	if err := db.QueryRowContext(ctx, `SELECT (SELECT COUNT(*) FROM task_completions), (SELECT COUNT(*) FROM task_tags)`).Scan(&completions, &tags); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if completions != 1 || tags != 1 {
+		t.Fatalf("completions=%d tags=%d, want only the rows of existing tasks", completions, tags)
+	}
+	// The moved task and its child can be changed again.
+	if _, err := db.ExecContext(ctx, `UPDATE tasks SET title = 'x' WHERE id IN (2, 3)`); err != nil {
+		t.Fatalf("update after migrating: %v", err)
+	}
+}
+
+func TestConnectKeepsAQueryStringInThePath(t *testing.T) {
+	ctx := context.Background()
+	db, err := Connect(ctx, filepath.Join(t.TempDir(), "test.db")+"?_pragma=busy_timeout(1234)")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+	var fk, timeout int
+	if err := db.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&fk); err != nil {
+		t.Fatalf("foreign_keys: %v", err)
+	}
+	if err := db.QueryRowContext(ctx, `PRAGMA busy_timeout`).Scan(&timeout); err != nil {
+		t.Fatalf("busy_timeout: %v", err)
+	}
+	if fk != 1 || timeout != 1234 {
+		t.Fatalf("foreign_keys=%d busy_timeout=%d, want 1 and 1234", fk, timeout)
+	}
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type SessionRepo struct {
+	db DBTX
+}
+
+func NewSessionRepo(db DBTX) *SessionRepo {
+	return &SessionRepo{db: db}
+}
+
+const sessionColumns = `id, task_id, kind, started_at, ended_at`
+
+// Insert writes a session, keeping s.ID when non-zero (import).
+func (r *SessionRepo) Insert(ctx context.Context, s TaskSession) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO task_sessions (`+sessionColumns+`)
+		VALUES (?, ?, ?, ?, ?)
+	`, nullID(s.ID), s.TaskID, s.Kind, s.StartedAt, s.EndedAt)
+	if err != nil {
+		return 0, fmt.Errorf("session insert: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("session last insert id: %w", err)
+	}
+	return id, nil
+}
+
+// Running returns the session without an end, or nil if no timer runs. The
+// schema allows only one.
+func (r *SessionRepo) Running(ctx context.Context) (*TaskSession, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM task_sessions WHERE ended_at IS NULL`)
+	s, err := scanSession(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("session running: %w", err)
+	}
+	return s, nil
+}
+
+// Stop ends a session at endedAt.
+func (r *SessionRepo) Stop(ctx context.Context, id int64, endedAt time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE task_sessions SET ended_at = ? WHERE id = ?`, endedAt, id); err != nil {
+		return fmt.Errorf("session stop: %w", err)
+	}
+	return nil
+}
+
+// ListByTask returns a task's sessions, oldest first.
+func (r *SessionRepo) ListByTask(ctx context.Context, taskID int64) ([]TaskSession, error) {
+	return r.list(ctx, `SELECT `+sessionColumns+` FROM task_sessions WHERE task_id = ? ORDER BY started_at ASC, id ASC`, taskID)
+}
+
+// ListAll returns every session, oldest first.
+func (r *SessionRepo) ListAll(ctx context.Context) ([]TaskSession, error) {
+	return r.list(ctx, `SELECT `+sessionColumns+` FROM task_sessions ORDER BY started_at ASC, id ASC`)
+}
+
+func (r *SessionRepo) list(ctx context.Context, query string, args ...any) ([]TaskSession, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("session list: %w", err)
+	}
+	defer rows.Close()
+
+	var out []TaskSession
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("session scan: %w", err)
+		}
+		out = append(out, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("session rows: %w", err)
+	}
+	return out, nil
+}
+
+func scanSession(row scanner) (*TaskSession, error) {
+	var s TaskSession
+	var ended sql.NullTime
+	if err := row.Scan(&s.ID, &s.TaskID, &s.Kind, &s.StartedAt, &ended); err != nil {
+		return nil, err
+	}
+	if ended.Valid {
+		s.EndedAt = &ended.Time
+	}
+	return &s, nil
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+type TagRepo struct {
+	db DBTX
+}
+
+func NewTagRepo(db DBTX) *TagRepo {
+	return &TagRepo{db: db}
+}
+
+// Add tags a task. It reports false if the task already had the tag.
+func (r *TagRepo) Add(ctx context.Context, taskID int64, tag string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT OR IGNORE INTO task_tags (task_id, tag) VALUES (?, ?)`, taskID, tag)
+	if err != nil {
+		return false, fmt.Errorf("tag insert: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("tag rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Remove takes a tag off a task. It reports false if the task didn't have it.
+func (r *TagRepo) Remove(ctx context.Context, taskID int64, tag string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM task_tags WHERE task_id = ? AND tag = ?`, taskID, tag)
+	if err != nil {
+		return false, fmt.Errorf("tag delete: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("tag rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ListByTask returns the tags set on a task, sorted.
+func (r *TagRepo) ListByTask(ctx context.Context, taskID int64) ([]string, error) {
+	byTask, err := r.list(ctx, `SELECT task_id, tag FROM task_tags WHERE task_id = ? ORDER BY tag ASC`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return byTask[taskID], nil
+}
+
+// ListAll returns the tags set on every task (trashed ones included), sorted
+// per task.
+func (r *TagRepo) ListAll(ctx context.Context) (map[int64][]string, error) {
+	return r.list(ctx, `SELECT task_id, tag FROM task_tags ORDER BY task_id ASC, tag ASC`)
+}
+
+func (r *TagRepo) list(ctx context.Context, query string, args ...any) (map[int64][]string, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("tag list: %w", err)
+	}
+	defer rows.Close()
+
+	out := map[int64][]string{}
+	for rows.Next() {
+		var id int64
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return nil, fmt.Errorf("tag scan: %w", err)
+		}
+		out[id] = append(out[id], tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("tag rows: %w", err)
+	}
+	return out, nil
+}
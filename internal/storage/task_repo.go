@@ -9,10 +9,10 @@ import (
 )
 
 type TaskRepo struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewTaskRepo(db *sql.DB) *TaskRepo {
+func NewTaskRepo(db DBTX) *TaskRepo {
 	return &TaskRepo{db: db}
 }
 
@@ -64,11 +64,56 @@ func (r *TaskRepo) Insert(ctx context.Context, in TaskInsert) (int64, error) {
 	return id, nil
 }
 
+// InsertFull writes a complete task row, including timestamps. t.ID is kept when
+// non-zero, otherwise a new ID is assigned. Used by import.
+func (r *TaskRepo) InsertFull(ctx context.Context, t Task) (int64, error) {
+	var attrsJSON *string
+	if len(t.Attributes) > 0 {
+		data, err := json.Marshal(t.Attributes)
+		if err != nil {
+			return 0, fmt.Errorf("marshal attributes: %w", err)
+		}
+		s := string(data)
+		attrsJSON = &s
+	}
+
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO tasks (
+			id, parent_id, title, description,
+			status, created_at, completed_at, due_date,
+			difficulty, attribute, attributes, xp_value,
+			is_project, is_habit, habit_interval,
+			habit_start_date, habit_end_date, habit_goal, blueprint_code, deleted_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, nullID(t.ID), t.ParentID, t.Title, t.Description, t.Status, t.CreatedAt, t.CompletedAt, t.DueDate, t.Difficulty, t.Attribute, attrsJSON, t.XPValue, boolToInt(t.IsProject), boolToInt(t.IsHabit), t.HabitInterval, t.HabitStartDate, t.HabitEndDate, t.HabitGoal, t.BlueprintCode, t.DeletedAt)
+	if err != nil {
+		return 0, fmt.Errorf("task insert: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("task last insert id: %w", err)
+	}
+	return id, nil
+}
+
 func (r *TaskRepo) Get(ctx context.Context, id int64) (*Task, error) {
 	row := r.db.QueryRowContext(ctx, `
 		SELECT id, parent_id, title, description, status, created_at, completed_at, due_date,
 			difficulty, attribute, attributes, xp_value, is_project, is_habit, habit_interval,
-			habit_start_date, habit_end_date, habit_goal
+			habit_start_date, habit_end_date, habit_goal, blueprint_code, deleted_at
+		FROM tasks
+		WHERE id = ? AND deleted_at IS NULL
+	`, id)
+
+	return scanTaskRow(row)
+}
+
+// GetIncludingDeleted is Get that also finds tasks in the trash.
+func (r *TaskRepo) GetIncludingDeleted(ctx context.Context, id int64) (*Task, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, parent_id, title, description, status, created_at, completed_at, due_date,
+			difficulty, attribute, attributes, xp_value, is_project, is_habit, habit_interval,
+			habit_start_date, habit_end_date, habit_goal, blueprint_code, deleted_at
 		FROM tasks
 		WHERE id = ?
 	`, id)
@@ -76,14 +121,29 @@ func (r *TaskRepo) Get(ctx context.Context, id int64) (*Task, error) {
 	return scanTaskRow(row)
 }
 
+// ListAll returns the tasks that are not in the trash.
 func (r *TaskRepo) ListAll(ctx context.Context) ([]Task, error) {
+	return r.list(ctx, `WHERE deleted_at IS NULL`)
+}
+
+// ListAllIncludingDeleted returns every task, trashed ones included.
+func (r *TaskRepo) ListAllIncludingDeleted(ctx context.Context) ([]Task, error) {
+	return r.list(ctx, ``)
+}
+
+// ListDeleted returns the tasks in the trash.
+func (r *TaskRepo) ListDeleted(ctx context.Context) ([]Task, error) {
+	return r.list(ctx, `WHERE deleted_at IS NOT NULL`)
+}
+
+func (r *TaskRepo) list(ctx context.Context, where string, args ...any) ([]Task, error) {
 	rows, err := r.db.QueryContext(ctx, `
 		SELECT id, parent_id, title, description, status, created_at, completed_at, due_date,
 			difficulty, attribute, attributes, xp_value, is_project, is_habit, habit_interval,
-			habit_start_date, habit_end_date, habit_goal
-		FROM tasks
+			habit_start_date, habit_end_date, habit_goal, blueprint_code, deleted_at
+		FROM tasks `+where+`
 		ORDER BY id ASC
-	`)
+	`, args...)
 	if err != nil {
 		return nil, fmt.Errorf("task list: %w", err)
 	}
@@ -107,9 +167,9 @@ func (r *TaskRepo) ListChildren(ctx context.Context, parentID int64) ([]Task, er
 	rows, err := r.db.QueryContext(ctx, `
 		SELECT id, parent_id, title, description, status, created_at, completed_at, due_date,
 			difficulty, attribute, attributes, xp_value, is_project, is_habit, habit_interval,
-			habit_start_date, habit_end_date, habit_goal
+			habit_start_date, habit_end_date, habit_goal, blueprint_code, deleted_at
 		FROM tasks
-		WHERE parent_id = ?
+		WHERE parent_id = ? AND deleted_at IS NULL
 		ORDER BY id ASC
 	`, parentID)
 	if err != nil {
@@ -151,6 +211,80 @@ func (r *TaskRepo) UpdateHabitAfterCompletion(ctx context.Context, id int64, com
 	return nil
 }
 
+// SetBlueprintCode links a task to the blueprint it was created from.
+func (r *TaskRepo) SetBlueprintCode(ctx context.Context, id int64, code string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE tasks SET blueprint_code = ? WHERE id = ?`, code, id)
+	if err != nil {
+		return fmt.Errorf("task set blueprint code: %w", err)
+	}
+	return nil
+}
+
+// ListByBlueprint returns the tasks created from a blueprint, oldest first.
+func (r *TaskRepo) ListByBlueprint(ctx context.Context, code string) ([]Task, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, parent_id, title, description, status, created_at, completed_at, due_date,
+			difficulty, attribute, attributes, xp_value, is_project, is_habit, habit_interval,
+			habit_start_date, habit_end_date, habit_goal, blueprint_code, deleted_at
+		FROM tasks
+		WHERE blueprint_code = ? AND deleted_at IS NULL
+		ORDER BY id ASC
+	`, code)
+	if err != nil {
+		return nil, fmt.Errorf("task list by blueprint: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Task
+	for rows.Next() {
+		t, err := scanTaskRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("task list by blueprint rows: %w", err)
+	}
+	return out, nil
+}
+
+// UpdateDueDate moves a task's due date (nil clears it).
+func (r *TaskRepo) UpdateDueDate(ctx context.Context, id int64, due *time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE tasks SET due_date = ? WHERE id = ?`, due, id)
+	if err != nil {
+		return fmt.Errorf("task update due date: %w", err)
+	}
+	return nil
+}
+
+// Update writes a task's editable fields: parent, title, description, due
+// date, difficulty, attributes, XP value and habit interval, end date and goal.
+// Status, timestamps and the blueprint link are left alone.
+func (r *TaskRepo) Update(ctx context.Context, t Task) error {
+	var attrsJSON *string
+	if len(t.Attributes) > 0 {
+		data, err := json.Marshal(t.Attributes)
+		if err != nil {
+			return fmt.Errorf("marshal attributes: %w", err)
+		}
+		s := string(data)
+		attrsJSON = &s
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE tasks
+		SET parent_id = ?, title = ?, description = ?, due_date = ?,
+			difficulty = ?, attribute = ?, attributes = ?, xp_value = ?,
+			habit_interval = ?, habit_end_date = ?, habit_goal = ?
+		WHERE id = ?
+	`, t.ParentID, t.Title, t.Description, t.DueDate, t.Difficulty, t.Attribute, attrsJSON, t.XPValue, t.HabitInterval, t.HabitEndDate, t.HabitGoal, t.ID)
+	if err != nil {
+		return fmt.Errorf("task update: %w", err)
+	}
+	return nil
+}
+
 func (r *TaskRepo) UpdateDifficultyAndXP(ctx context.Context, id int64, difficulty int, xpValue int) error {
 	_, err := r.db.ExecContext(ctx, `UPDATE tasks SET difficulty = ?, xp_value = ? WHERE id = ?`, difficulty, xpValue, id)
 	if err != nil {
@@ -172,7 +306,7 @@ func (r *TaskRepo) HasCompletedProjectTitle(ctx context.Context, title string) (
 	row := r.db.QueryRowContext(ctx, `
 		SELECT 1
 		FROM tasks
-		WHERE is_project = 1 AND status = 'done' AND title = ?
+		WHERE is_project = 1 AND status = 'done' AND title = ? COLLATE NOCASE AND deleted_at IS NULL
 		LIMIT 1
 	`, title)
 	var one int
@@ -193,21 +327,77 @@ func (r *TaskRepo) UpdateStatus(ctx context.Context, id int64, status string) er
 	return nil
 }
 
-func (r *TaskRepo) Delete(ctx context.Context, id int64) error {
-	// First delete any child tasks
-	_, err := r.db.ExecContext(ctx, `DELETE FROM tasks WHERE parent_id = ?`, id)
+// ListChildrenIncludingDeleted returns every child of parentID,
+// trashed ones included.
+func (r *TaskRepo) ListChildrenIncludingDeleted(ctx context.Context, parentID int64) ([]Task, error) {
+	return r.list(ctx, `WHERE parent_id = ?`, parentID)
+}
+
+// Search returns the IDs of the tasks (not in the trash) whose title or
+// description match an FTS5 query, best matches first.
+func (r *TaskRepo) Search(ctx context.Context, match string) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.id
+		FROM tasks_fts
+		JOIN tasks t ON t.id = tasks_fts.rowid
+		WHERE tasks_fts MATCH ? AND t.deleted_at IS NULL
+		ORDER BY tasks_fts.rank, t.id
+	`, match)
 	if err != nil {
-		return fmt.Errorf("task delete children: %w", err)
+		return nil, fmt.Errorf("task search: %w", err)
 	}
-	// Delete completions for this task
-	_, err = r.db.ExecContext(ctx, `DELETE FROM task_completions WHERE task_id = ?`, id)
-	if err != nil {
-		return fmt.Errorf("task delete completions: %w", err)
+	defer rows.Close()
+
+	var out []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("task search scan: %w", err)
+		}
+		out = append(out, id)
 	}
-	// Delete the task itself
-	_, err = r.db.ExecContext(ctx, `DELETE FROM tasks WHERE id = ?`, id)
-	if err != nil {
-		return fmt.Errorf("task delete: %w", err)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("task search rows: %w", err)
+	}
+	return out, nil
+}
+
+// SetDeleted moves tasks to the trash at the given time, or takes them out of
+// it when at is nil.
+func (r *TaskRepo) SetDeleted(ctx context.Context, ids []int64, at *time.Time) error {
+	for _, id := range ids {
+		if _, err := r.db.ExecContext(ctx, `UPDATE tasks SET deleted_at = ? WHERE id = ?`, at, id); err != nil {
+			return fmt.Errorf("task set deleted: %w", err)
+		}
+	}
+	return nil
+}
+
+// Purge removes a task for good, with its completions, dependencies, tags and
+// timer sessions.
+// Ledger, freeze, HP and blueprint rows keep their amounts but lose the
+// reference, so the player state still rebuilds from the ledger. Children must
+// be purged first.
+func (r *TaskRepo) Purge(ctx context.Context, id int64) error {
+	stmts := []string{
+		`UPDATE xp_ledger SET completion_id = NULL WHERE completion_id IN (SELECT id FROM task_completions WHERE task_id = ?1)`,
+		`UPDATE xp_ledger SET task_id = NULL WHERE task_id = ?1`,
+		`UPDATE streak_freezes SET earned_completion_id = NULL WHERE earned_completion_id IN (SELECT id FROM task_completions WHERE task_id = ?1)`,
+		`UPDATE streak_freezes SET used_completion_id = NULL WHERE used_completion_id IN (SELECT id FROM task_completions WHERE task_id = ?1)`,
+		`UPDATE streak_freezes SET earned_task_id = NULL WHERE earned_task_id = ?1`,
+		`UPDATE streak_freezes SET used_task_id = NULL WHERE used_task_id = ?1`,
+		`UPDATE blueprints SET reward_completion_id = NULL WHERE reward_completion_id IN (SELECT id FROM task_completions WHERE task_id = ?1)`,
+		`UPDATE hp_log SET task_id = NULL WHERE task_id = ?1`,
+		`DELETE FROM task_dependencies WHERE task_id = ?1 OR blocked_by = ?1`,
+		`DELETE FROM task_tags WHERE task_id = ?1`,
+		`DELETE FROM task_sessions WHERE task_id = ?1`,
+		`DELETE FROM task_completions WHERE task_id = ?1`,
+		`DELETE FROM tasks WHERE id = ?1`,
+	}
+	for _, stmt := range stmts {
+		if _, err := r.db.ExecContext(ctx, stmt, id); err != nil {
+			return fmt.Errorf("task purge: %w", err)
+		}
 	}
 	return nil
 }
@@ -219,6 +409,14 @@ func boolToInt(v bool) int {
 	return 0
 }
 
+// nullID maps a zero ID to NULL so SQLite assigns the next one.
+func nullID(id int64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
 type scanner interface {
 	Scan(dest ...any) error
 }
@@ -243,12 +441,14 @@ func scanTaskRow(row scanner) (*Task, error) {
 		habitStartDate sql.NullTime
 		habitEndDate   sql.NullTime
 		habitGoal      sql.NullInt64
+		blueprintCode  sql.NullString
+		deletedAt      sql.NullTime
 	)
 
 	if err := row.Scan(
 		&id, &parent, &title, &description, &status, &createdAt, &completedAt, &dueDate,
 		&difficulty, &attribute, &attributesRaw, &xpValue, &isProject, &isHabit, &habitInterval,
-		&habitStartDate, &habitEndDate, &habitGoal,
+		&habitStartDate, &habitEndDate, &habitGoal, &blueprintCode, &deletedAt,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -297,6 +497,18 @@ func scanTaskRow(row scanner) (*Task, error) {
 		hGoal = &v
 	}
 
+	var bpCode *string
+	if blueprintCode.Valid {
+		v := blueprintCode.String
+		bpCode = &v
+	}
+
+	var deleted *time.Time
+	if deletedAt.Valid {
+		v := deletedAt.Time
+		deleted = &v
+	}
+
 	// Parse attributes JSON
 	var attrs map[string]int
 	if attributesRaw.Valid && attributesRaw.String != "" {
@@ -324,6 +536,8 @@ func scanTaskRow(row scanner) (*Task, error) {
 		HabitStartDate: hStart,
 		HabitEndDate:   hEnd,
 		HabitGoal:      hGoal,
+		BlueprintCode:  bpCode,
+		DeletedAt:      deleted,
 	}, nil
 }
 
@@ -6,7 +6,16 @@ import (
 	"fmt"
 )
 
+// DBTX is the query surface shared by *sql.DB and *sql.Tx.
+// Repos are built on top of it so the same code runs inside or outside a transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 // WithTx runs fn inside a SQL transaction.
+// The transaction is committed when fn returns nil and rolled back otherwise.
 func WithTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
@@ -21,7 +30,6 @@ func WithTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
 	}()
 
 	if err := fn(tx); err != nil {
-		_ = tx.Rollback()
 		return err
 	}
 	if err := tx.Commit(); err != nil {
@@ -0,0 +1,149 @@
+package storage_test
+
+// An external test package, so the upgraded player can be rebuilt through the
+// engine.
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"questline/internal/engine"
+	"questline/internal/storage"
+)
+
+// baselineSchema is the schema of databases created before schema_version
+// existed, with attribute XP in player columns.
+var baselineSchema = []string{
+	`CREATE TABLE player (
+		key TEXT PRIMARY KEY,
+		level INTEGER DEFAULT 1,
+		xp_total INTEGER DEFAULT 0,
+		xp_str INTEGER DEFAULT 0,
+		xp_int INTEGER DEFAULT 0,
+		xp_wis INTEGER DEFAULT 0,
+		xp_art INTEGER DEFAULT 0,
+		xp_home INTEGER DEFAULT 0,
+		xp_out INTEGER DEFAULT 0,
+		xp_read INTEGER DEFAULT 0,
+		xp_cinema INTEGER DEFAULT 0,
+		xp_career INTEGER DEFAULT 0
+	);`,
+	`CREATE TABLE tasks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		parent_id INTEGER NULL,
+		title TEXT NOT NULL,
+		description TEXT,
+		status TEXT DEFAULT 'pending',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		completed_at DATETIME,
+		due_date DATETIME,
+		difficulty INTEGER DEFAULT 1,
+		attribute TEXT NOT NULL,
+		attributes TEXT,
+		xp_value INTEGER NOT NULL,
+		is_project INTEGER DEFAULT 0,
+		is_habit INTEGER DEFAULT 0,
+		habit_interval TEXT,
+		habit_start_date DATETIME,
+		habit_end_date DATETIME,
+		habit_goal INTEGER,
+		FOREIGN KEY(parent_id) REFERENCES tasks(id)
+	);`,
+	`CREATE TABLE blueprints (
+		code TEXT PRIMARY KEY,
+		status TEXT DEFAULT 'locked'
+	);`,
+	`CREATE TABLE task_completions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL,
+		completed_at DATETIME NOT NULL,
+		difficulty INTEGER NOT NULL,
+		xp_awarded INTEGER NOT NULL,
+		FOREIGN KEY(task_id) REFERENCES tasks(id)
+	);`,
+	`CREATE INDEX idx_tasks_parent_id ON tasks(parent_id);`,
+	`CREATE INDEX idx_tasks_status ON tasks(status);`,
+	`CREATE INDEX idx_task_completions_task_id_completed_at ON task_completions(task_id, completed_at);`,
+}
+
+func TestMigrateUpgradesABaselineDatabase(t *testing.T) {
+	ctx := context.Background()
+	db, err := storage.Connect(ctx, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+
+	// The total is 10 more than the attributes add up to, as older restores
+	// could leave it.
+	want := map[string]int{"STR": 200, "INT": 150, "READ": 100}
+	const total = 460
+	rows := []string{
+		fmt.Sprintf(`INSERT INTO player (key, level, xp_total, xp_str, xp_int, xp_read) VALUES ('%s', 3, %d, 200, 150, 100)`, storage.MainPlayerKey, total),
+		`INSERT INTO tasks (title, status, attribute, xp_value, completed_at) VALUES ('Run 5k', 'done', 'STR', 200, CURRENT_TIMESTAMP)`,
+		`INSERT INTO task_completions (task_id, completed_at, difficulty, xp_awarded) VALUES (1, CURRENT_TIMESTAMP, 3, 200)`,
+		`INSERT INTO blueprints (code, status) VALUES ('str_walk', 'available')`,
+	}
+	for _, q := range append(baselineSchema, rows...) {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	if err := storage.Migrate(ctx, db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if v, err := storage.CurrentSchemaVersion(ctx, db); err != nil || v != storage.LatestSchemaVersion() {
+		t.Fatalf("version=%d, %v; want %d", v, err, storage.LatestSchemaVersion())
+	}
+
+	// player_attributes and the ledger both carry each attribute's XP.
+	got := map[string]int{}
+	attrRows, err := db.QueryContext(ctx, `SELECT attribute, xp FROM player_attributes WHERE player_key = ?`, storage.MainPlayerKey)
+	if err != nil {
+		t.Fatalf("player_attributes: %v", err)
+	}
+	for attrRows.Next() {
+		var code string
+		var xp int
+		if err := attrRows.Scan(&code, &xp); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		got[code] = xp
+	}
+	attrRows.Close()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("player_attributes=%v, want %v", got, want)
+	}
+	ledgerTotal, byAttr, _, err := storage.NewLedgerRepo(db).Totals(ctx, storage.MainPlayerKey)
+	if err != nil {
+		t.Fatalf("ledger totals: %v", err)
+	}
+	if ledgerTotal != total || fmt.Sprint(byAttr) != fmt.Sprint(want) {
+		t.Fatalf("ledger total=%d by attribute=%v, want %d and %v", ledgerTotal, byAttr, total, want)
+	}
+
+	// Rebuilding from the ledger lands on the same counters.
+	svc := engine.NewService(db)
+	res, err := svc.RebuildPlayer(ctx)
+	if err != nil {
+		t.Fatalf("RebuildPlayer: %v", err)
+	}
+	if res.XPBefore != total || res.XPAfter != total {
+		t.Fatalf("rebuild XP %d → %d, want %d", res.XPBefore, res.XPAfter, total)
+	}
+	p, err := svc.PlayerRepo().GetOrCreateMain(ctx)
+	if err != nil {
+		t.Fatalf("player: %v", err)
+	}
+	for code, xp := range want {
+		if p.XP[code] != xp {
+			t.Errorf("%s XP=%d after rebuild, want %d", code, p.XP[code], xp)
+		}
+	}
+	if task, err := svc.TaskRepo().Get(ctx, 1); err != nil || task == nil || task.Status != "done" {
+		t.Fatalf("task after upgrade=%+v, %v", task, err)
+	}
+}
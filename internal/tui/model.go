@@ -2,6 +2,7 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -34,6 +35,7 @@ type boardModel struct {
 	height int
 
 	player *storage.Player
+	attrs  []engine.AttributeDef
 	tasks  []storage.Task
 
 	// XP history for graphs
@@ -43,6 +45,39 @@ type boardModel struct {
 	// Achievements
 	achievements []engine.Achievement
 
+	// Habit streaks by task ID and unused freeze tokens
+	streaks      map[int64]engine.HabitStreak
+	freezeTokens int
+
+	// Player health
+	hp *engine.HPStatus
+
+	// Unfinished tasks each blocked task waits for
+	blocked map[int64][]int64
+
+	// Tags by task ID
+	tags map[int64]engine.TaskTags
+
+	// Running timer (nil when none) and time tracked by task ID; ticking is
+	// set while a timerTickMsg is scheduled.
+	timer   *storage.TaskSession
+	tracked map[int64]time.Duration
+	ticking bool
+
+	// Quest log filter (see engine.TaskFilter) and the tasks it matches;
+	// matches is nil when there is no filter.
+	filter      string
+	matches     map[int64]bool
+	filtering   bool
+	filterInput string
+
+	// Quick-add prompt (see engine.ParseQuickAdd)
+	adding   bool
+	addInput string
+
+	// Open review screen, nil on the board
+	review *reviewScreen
+
 	expanded map[int64]bool
 	selected int
 	focus    panelFocus
@@ -60,6 +95,7 @@ type boardModel struct {
 	// Confirmation state
 	confirmDelete bool
 	deleteTaskID  int64
+	deleteXP      int // XP the task earned, once asked whether to keep it
 }
 
 type keyMap struct {
@@ -68,7 +104,11 @@ type keyMap struct {
 	Toggle   key.Binding
 	Complete key.Binding
 	Delete   key.Binding
+	Add      key.Binding
 	Refresh  key.Binding
+	Filter   key.Binding
+	Review   key.Binding
+	Timer    key.Binding
 	Tab      key.Binding
 	Help     key.Binding
 	Quit     key.Binding
@@ -81,17 +121,26 @@ func (k keyMap) ShortHelp() []key.Binding {
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.Toggle},
-		{k.Complete, k.Delete, k.Refresh, k.Tab},
+		{k.Add, k.Complete, k.Delete, k.Timer, k.Refresh, k.Filter, k.Review, k.Tab},
 		{k.Help, k.Quit},
 	}
 }
 
 type loadedMsg struct {
 	player       *storage.Player
+	attributes   []engine.AttributeDef
 	tasks        []storage.Task
 	weeklyXP     []int
 	monthlyXP    []int
 	achievements []engine.Achievement
+	streaks      map[int64]engine.HabitStreak
+	freezeTokens int
+	hp           *engine.HPStatus
+	blocked      map[int64][]int64
+	tags         map[int64]engine.TaskTags
+	timer        *storage.TaskSession
+	tracked      map[int64]time.Duration
+	matches      map[int64]bool
 	err          error
 }
 
@@ -101,8 +150,22 @@ type completedMsg struct {
 	err error
 }
 
+type createdMsg struct {
+	res *engine.CreateResult
+	err error
+}
+
+type timerMsg struct {
+	res     *engine.TimerResult
+	started bool
+	err     error
+}
+
+type timerTickMsg struct{}
+
 type deletedMsg struct {
 	id  int64
+	res *engine.DeleteResult
 	err error
 }
 
@@ -125,7 +188,11 @@ func newBoardModel(ctx context.Context, svc *engine.Service) boardModel {
 			Toggle:   key.NewBinding(key.WithKeys("enter"), key.WithHelp("⏎", "expand")),
 			Complete: key.NewBinding(key.WithKeys("c", "space"), key.WithHelp("c/␣", "complete")),
 			Delete:   key.NewBinding(key.WithKeys("d", "backspace"), key.WithHelp("d/⌫", "delete")),
+			Add:      key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "add")),
 			Refresh:  key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "refresh")),
+			Filter:   key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter")),
+			Review:   key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "review")),
+			Timer:    key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "start/stop timer")),
 			Tab:      key.NewBinding(key.WithKeys("tab"), key.WithHelp("⇥", "switch panel")),
 			Help:     key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "help")),
 			Quit:     key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
@@ -147,6 +214,10 @@ func (m boardModel) loadCmd() tea.Cmd {
 		if err != nil {
 			return loadedMsg{err: err}
 		}
+		reg, err := m.svc.Attributes(m.ctx)
+		if err != nil {
+			return loadedMsg{err: err}
+		}
 
 		// Load XP history for graphs
 		now := time.Now()
@@ -178,7 +249,56 @@ func (m boardModel) loadCmd() tea.Cmd {
 		// Load achievements
 		achievements, _ := engine.GetAchievementsForPlayer(m.ctx, m.svc)
 
-		return loadedMsg{player: p, tasks: tasks, weeklyXP: weeklyXP, monthlyXP: monthlyXP, achievements: achievements}
+		streaks, err := m.svc.HabitStreaks(m.ctx)
+		if err != nil {
+			return loadedMsg{err: err}
+		}
+		tokens, err := m.svc.FreezeTokens(m.ctx)
+		if err != nil {
+			return loadedMsg{err: err}
+		}
+
+		hp, err := m.svc.HP(m.ctx, 0)
+		if err != nil {
+			return loadedMsg{err: err}
+		}
+
+		blocked, err := m.svc.BlockedTasks(m.ctx)
+		if err != nil {
+			return loadedMsg{err: err}
+		}
+
+		tags, err := m.svc.TaskTags(m.ctx)
+		if err != nil {
+			return loadedMsg{err: err}
+		}
+
+		timer, err := m.svc.RunningTimer(m.ctx)
+		if err != nil {
+			return loadedMsg{err: err}
+		}
+		tracked, err := m.svc.TrackedTime(m.ctx, now)
+		if err != nil {
+			return loadedMsg{err: err}
+		}
+
+		var matches map[int64]bool
+		if m.filter != "" {
+			f, err := engine.ParseTaskFilter(m.filter, reg, now)
+			if err != nil {
+				return loadedMsg{err: err}
+			}
+			found, err := m.svc.FilterTasks(m.ctx, f)
+			if err != nil {
+				return loadedMsg{err: err}
+			}
+			matches = make(map[int64]bool, len(found))
+			for _, t := range found {
+				matches[t.ID] = true
+			}
+		}
+
+		return loadedMsg{player: p, attributes: reg.All(), tasks: tasks, weeklyXP: weeklyXP, monthlyXP: monthlyXP, achievements: achievements, streaks: streaks, freezeTokens: tokens, hp: hp, blocked: blocked, tags: tags, timer: timer, tracked: tracked, matches: matches}
 	}
 }
 
@@ -189,13 +309,37 @@ func (m boardModel) completeCmd(id int64) tea.Cmd {
 	}
 }
 
-func (m boardModel) deleteCmd(id int64) tea.Cmd {
+func (m boardModel) createCmd(in engine.CreateTaskInput) tea.Cmd {
+	return func() tea.Msg {
+		res, err := m.svc.CreateTask(m.ctx, in)
+		return createdMsg{res: res, err: err}
+	}
+}
+
+func (m boardModel) deleteCmd(id int64, xp engine.DeleteXP) tea.Cmd {
+	return func() tea.Msg {
+		res, err := m.svc.DeleteTask(m.ctx, id, xp)
+		return deletedMsg{id: id, res: res, err: err}
+	}
+}
+
+// timerCmd starts the timer on a task, or stops the running one when id is 0.
+func (m boardModel) timerCmd(id int64) tea.Cmd {
 	return func() tea.Msg {
-		err := m.svc.TaskRepo().Delete(m.ctx, id)
-		return deletedMsg{id: id, err: err}
+		if id == 0 {
+			res, err := m.svc.StopTimer(m.ctx, time.Now())
+			return timerMsg{res: res, err: err}
+		}
+		res, err := m.svc.StartTimer(m.ctx, id, engine.SessionTimer, time.Now())
+		return timerMsg{res: res, started: true, err: err}
 	}
 }
 
+// tickTimer redraws the running timer every second.
+func tickTimer() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return timerTickMsg{} })
+}
+
 func (m boardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
@@ -216,19 +360,63 @@ func (m boardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		m.player = msg.player
+		m.attrs = msg.attributes
 		m.tasks = msg.tasks
 		m.weeklyXP = msg.weeklyXP
 		m.monthlyXP = msg.monthlyXP
 		m.achievements = msg.achievements
-		// Default-expand roots that have children.
-		children := indexChildren(m.tasks)
-		for _, t := range m.tasks {
-			if t.ParentID == nil && len(children[t.ID]) > 0 {
+		m.streaks = msg.streaks
+		m.freezeTokens = msg.freezeTokens
+		m.hp = msg.hp
+		m.blocked = msg.blocked
+		m.tags = msg.tags
+		m.timer = msg.timer
+		m.tracked = msg.tracked
+		m.matches = msg.matches
+		// Default-expand roots that have children; everything when filtered.
+		shown := m.questTasks()
+		children := indexChildren(shown)
+		for _, t := range shown {
+			if (t.ParentID == nil || m.matches != nil) && len(children[t.ID]) > 0 {
 				m.expanded[t.ID] = true
 			}
 		}
 		m.lastLog = fmt.Sprintf("Data loaded @ %s", time.Now().Format("15:04:05"))
+		if m.timer != nil && !m.ticking {
+			m.ticking = true
+			return m, tickTimer()
+		}
 		return m, nil
+	case timerTickMsg:
+		if m.timer == nil {
+			m.ticking = false
+			return m, nil
+		}
+		return m, tickTimer()
+	case timerMsg:
+		if msg.err != nil {
+			m.lastLog = "ERROR: " + msg.err.Error()
+			return m, nil
+		}
+		if msg.started {
+			m.lastLog = fmt.Sprintf("%s Timer started on #%d", ui.IconTimer, msg.res.Task.ID)
+		} else {
+			m.lastLog = fmt.Sprintf("%s Timer stopped on #%d after %s", ui.IconTimer, msg.res.Session.TaskID, ui.Duration(msg.res.Session.Duration(time.Now())))
+			for _, a := range msg.res.Achievements {
+				m.lastLog += fmt.Sprintf(" ◆ BADGE %s %s", a.Icon, a.Name)
+			}
+		}
+		return m, m.loadCmd()
+	case createdMsg:
+		if msg.err != nil {
+			m.lastLog = "ERROR: " + msg.err.Error()
+			return m, nil
+		}
+		m.lastLog = fmt.Sprintf("+ Task #%d added", msg.res.TaskID)
+		for _, a := range msg.res.Achievements {
+			m.lastLog += fmt.Sprintf(" ◆ BADGE %s %s", a.Icon, a.Name)
+		}
+		return m, m.loadCmd()
 	case completedMsg:
 		if msg.err != nil {
 			m.lastLog = "ERROR: " + msg.err.Error()
@@ -238,36 +426,125 @@ func (m boardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.res.LevelAfter > msg.res.LevelBefore {
 			levelMsg = fmt.Sprintf(" ▲▲▲ LEVEL UP! %d → %d ▲▲▲", msg.res.LevelBefore, msg.res.LevelAfter)
 		}
-		m.lastLog = fmt.Sprintf("✓ Task #%d complete: +%d XP%s", msg.res.TaskID, msg.res.XPAwarded, levelMsg)
+		streakMsg := ""
+		if msg.res.Streak > 1 {
+			streakMsg = fmt.Sprintf(" %s%d", ui.IconFire, msg.res.Streak)
+		}
+		if msg.res.FreezeEarned {
+			streakMsg += " +" + ui.IconFreeze
+		}
+		if msg.res.HPHealed > 0 {
+			streakMsg += fmt.Sprintf(" +%d HP", msg.res.HPHealed)
+		}
+		if msg.res.Tracked > 0 {
+			streakMsg += fmt.Sprintf(" %s %s", ui.IconTimer, ui.Duration(msg.res.Tracked))
+		}
+		if msg.res.BlueprintCompleted != "" {
+			streakMsg += fmt.Sprintf(" %s %s done +%d XP", ui.IconScroll, msg.res.BlueprintCompleted, msg.res.BlueprintBonus)
+		}
+		for _, a := range msg.res.Achievements {
+			levelMsg += fmt.Sprintf(" ◆ BADGE %s %s", a.Icon, a.Name)
+		}
+		m.lastLog = fmt.Sprintf("✓ Task #%d complete: +%d XP%s%s", msg.res.TaskID, msg.res.XPAwarded, streakMsg, levelMsg)
 		return m, m.loadCmd()
 	case deletedMsg:
+		var choice engine.XPChoiceError
+		if errors.As(msg.err, &choice) {
+			m.deleteXP = choice.XP
+			m.lastLog = fmt.Sprintf("Task #%d earned %d XP: (k)eep or (r)evoke it? (n to cancel)", msg.id, choice.XP)
+			return m, nil
+		}
 		m.confirmDelete = false
 		m.deleteTaskID = 0
+		m.deleteXP = 0
 		if msg.err != nil {
 			m.lastLog = "ERROR: " + msg.err.Error()
 			return m, nil
 		}
-		m.lastLog = fmt.Sprintf("✗ Task #%d deleted", msg.id)
+		m.lastLog = fmt.Sprintf("✗ Task #%d moved to the trash", msg.id)
+		if n := len(msg.res.Deleted) - 1; n > 0 {
+			m.lastLog += fmt.Sprintf(" with %d subtasks", n)
+		}
+		if msg.res.XPRevoked > 0 {
+			m.lastLog += fmt.Sprintf(" (-%d XP)", msg.res.XPRevoked)
+		}
+		return m, m.loadCmd()
+	case reviewLoadedMsg:
+		if msg.err != nil {
+			m.lastLog = "ERROR: " + msg.err.Error()
+			return m, nil
+		}
+		m.review = &reviewScreen{review: msg.review, actions: map[int64]engine.ReviewAction{}}
+		m.lastLog = fmt.Sprintf("Reviewing the past %s: %d stale tasks", msg.review.Period, len(msg.review.Stale))
+		return m, nil
+	case reviewSavedMsg:
+		if msg.err != nil {
+			m.lastLog = "ERROR: " + msg.err.Error()
+			return m, nil
+		}
+		m.review = nil
+		rv := msg.res.Review
+		m.lastLog = fmt.Sprintf("✓ Review saved: %d rescheduled, %d dropped, %d re-rated", rv.Rescheduled, rv.Dropped, rv.Rerated)
+		if rv.XPAwarded > 0 {
+			m.lastLog += fmt.Sprintf(" +%d XP", rv.XPAwarded)
+		}
+		if msg.res.LevelAfter > msg.res.LevelBefore {
+			m.lastLog += fmt.Sprintf(" ▲▲▲ LEVEL UP! %d → %d ▲▲▲", msg.res.LevelBefore, msg.res.LevelAfter)
+		}
+		for _, a := range msg.res.Achievements {
+			m.lastLog += fmt.Sprintf(" ◆ BADGE %s %s", a.Icon, a.Name)
+		}
 		return m, m.loadCmd()
 	case tea.KeyMsg:
 		// Handle confirmation mode
 		if m.confirmDelete {
-			switch msg.String() {
-			case "y", "Y":
+			key := msg.String()
+			switch {
+			case m.deleteXP == 0 && (key == "y" || key == "Y"):
 				m.lastLog = fmt.Sprintf("Deleting task #%d...", m.deleteTaskID)
-				return m, m.deleteCmd(m.deleteTaskID)
-			case "n", "N", "escape":
+				return m, m.deleteCmd(m.deleteTaskID, engine.DeleteXPAsk)
+			case m.deleteXP != 0 && (key == "k" || key == "K"):
+				m.lastLog = fmt.Sprintf("Deleting task #%d, keeping its XP...", m.deleteTaskID)
+				return m, m.deleteCmd(m.deleteTaskID, engine.DeleteXPKeep)
+			case m.deleteXP != 0 && (key == "r" || key == "R"):
+				m.lastLog = fmt.Sprintf("Deleting task #%d, revoking its XP...", m.deleteTaskID)
+				return m, m.deleteCmd(m.deleteTaskID, engine.DeleteXPRevoke)
+			case key == "n" || key == "N" || key == "esc":
 				m.confirmDelete = false
 				m.deleteTaskID = 0
+				m.deleteXP = 0
 				m.lastLog = "Delete cancelled"
 				return m, nil
 			}
 			return m, nil
 		}
 
+		if m.filtering {
+			return m.updateFilter(msg)
+		}
+		if m.adding {
+			return m.updateAdd(msg)
+		}
+		if m.review != nil {
+			return m.updateReview(msg)
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "q":
 			return m, tea.Quit
+		case "/":
+			m.filtering = true
+			m.filterInput = m.filter
+			m.lastLog = "Filter: e.g. status:open attr:str due<fri milk (⏎ apply, esc cancel)"
+			return m, nil
+		case "a":
+			m.adding = true
+			m.addInput = ""
+			m.lastLog = "Add: e.g. Run 5k !3 @str #fitness ^12 due:sat every:mon,thu (⏎ add, esc cancel)"
+			return m, nil
+		case "v":
+			m.lastLog = "Loading weekly review..."
+			return m, m.reviewCmd(engine.ReviewWeek)
 		case "?":
 			m.showHelp = !m.showHelp
 			return m, nil
@@ -326,6 +603,18 @@ func (m boardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.lastLog = fmt.Sprintf("Completing task #%d...", t.ID)
 			return m, m.completeCmd(t.ID)
+		case "t":
+			lines := m.questLines()
+			if m.selected < 0 || m.selected >= len(lines) {
+				return m, nil
+			}
+			line := lines[m.selected]
+			if m.timer != nil && m.timer.TaskID == line.id {
+				m.lastLog = "Stopping timer..."
+				return m, m.timerCmd(0)
+			}
+			m.lastLog = fmt.Sprintf("Starting timer on #%d...", line.id)
+			return m, m.timerCmd(line.id)
 		case "d", "backspace":
 			lines := m.questLines()
 			if m.selected < 0 || m.selected >= len(lines) {
@@ -347,6 +636,95 @@ func (m boardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// updateFilter edits the quest log filter while it is being typed.
+func (m boardModel) updateFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		query := strings.TrimSpace(m.filterInput)
+		if _, err := engine.ParseTaskFilter(query, engine.NewAttributeRegistry(m.attrs), time.Now()); err != nil {
+			m.lastLog = "ERROR: " + err.Error()
+			return m, nil
+		}
+		m.filtering = false
+		m.filter = query
+		m.selected = 0
+		if query == "" {
+			m.matches = nil
+			m.lastLog = "Filter cleared"
+			return m, nil
+		}
+		m.lastLog = "Filtering: " + query
+		return m, m.loadCmd()
+	case tea.KeyEsc:
+		m.filtering = false
+		m.lastLog = "Filter unchanged"
+	case tea.KeyBackspace:
+		if r := []rune(m.filterInput); len(r) > 0 {
+			m.filterInput = string(r[:len(r)-1])
+		}
+	case tea.KeyCtrlU:
+		m.filterInput = ""
+	case tea.KeySpace:
+		m.filterInput += " "
+	case tea.KeyRunes:
+		m.filterInput += string(msg.Runes)
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// updateAdd edits the quick-add prompt while it is being typed.
+func (m boardModel) updateAdd(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		q, err := engine.ParseQuickAdd(m.addInput, engine.NewAttributeRegistry(m.attrs), time.Now())
+		if err != nil {
+			m.lastLog = "ERROR: " + err.Error()
+			return m, nil
+		}
+		m.adding = false
+		m.lastLog = fmt.Sprintf("Adding %q...", q.Title)
+		return m, m.createCmd(q.TaskInput())
+	case tea.KeyEsc:
+		m.adding = false
+		m.lastLog = "Add cancelled"
+	case tea.KeyBackspace:
+		if r := []rune(m.addInput); len(r) > 0 {
+			m.addInput = string(r[:len(r)-1])
+		}
+	case tea.KeyCtrlU:
+		m.addInput = ""
+	case tea.KeySpace:
+		m.addInput += " "
+	case tea.KeyRunes:
+		m.addInput += string(msg.Runes)
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// questTasks returns the tasks shown in the quest log: all of them, or the
+// ones matching the filter, with subtasks of filtered-out parents moved to the
+// top level.
+func (m boardModel) questTasks() []storage.Task {
+	if m.matches == nil {
+		return m.tasks
+	}
+	var out []storage.Task
+	for _, t := range m.tasks {
+		if !m.matches[t.ID] {
+			continue
+		}
+		if t.ParentID != nil && !m.matches[*t.ParentID] {
+			t.ParentID = nil
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
 type questLine struct {
 	id          int64
 	depth       int
@@ -356,19 +734,23 @@ type questLine struct {
 	isHabit     bool
 	hasChildren bool
 	expanded    bool
+	overdue     bool
+	blocked     bool
+	tags        []string // Set on the task; inherited ones aren't shown
 }
 
 func (m boardModel) questLines() []questLine {
-	if len(m.tasks) == 0 {
+	tasks := m.questTasks()
+	if len(tasks) == 0 {
 		return nil
 	}
-	children := indexChildren(m.tasks)
-	roots := rootIDs(m.tasks)
+	children := indexChildren(tasks)
+	roots := rootIDs(tasks)
 
 	var out []questLine
 	var walk func(id int64, depth int)
 	walk = func(id int64, depth int) {
-		t := findTask(m.tasks, id)
+		t := findTask(tasks, id)
 		if t == nil {
 			return
 		}
@@ -382,6 +764,9 @@ func (m boardModel) questLines() []questLine {
 			isHabit:     t.IsHabit,
 			hasChildren: len(kids) > 0,
 			expanded:    m.expanded[id],
+			overdue:     engine.IsOverdue(t, time.Now()),
+			blocked:     len(m.blocked[id]) > 0,
+			tags:        m.tags[id].Own,
 		}
 		out = append(out, q)
 		if len(kids) == 0 {
@@ -483,9 +868,10 @@ func (m boardModel) renderHeader(w int) string {
 		return title + " " + ui.Terminal.Render(m.spinner.View()+" LOADING...")
 	}
 
-	lvl := engine.LevelForTotalXP(m.player.XPTotal)
-	curXP := m.player.XPTotal - engine.XPRequiredForLevel(lvl)
-	needXP := engine.XPRequiredForLevel(lvl+1) - engine.XPRequiredForLevel(lvl)
+	rules := m.svc.Rules()
+	lvl := rules.LevelForTotalXP(m.player.XPTotal)
+	curXP := m.player.XPTotal - rules.XPRequiredForLevel(lvl)
+	needXP := rules.XPRequiredForLevel(lvl+1) - rules.XPRequiredForLevel(lvl)
 
 	// Build header line
 	title := ui.Gold.Render("▓▓▓ QUESTLINE ▓▓▓")
@@ -507,6 +893,16 @@ func (m boardModel) renderHeader(w int) string {
 		barW = 30
 	}
 	bar := progressBarRetro(curXP, needXP, barW)
+	if m.hp != nil {
+		hp := ui.Terminal.Render(fmt.Sprintf("%d/%d", m.hp.HP, m.hp.Max))
+		if m.hp.HP*4 <= m.hp.Max {
+			hp = ui.Bad.Render(fmt.Sprintf("%d/%d", m.hp.HP, m.hp.Max))
+		}
+		if m.hp.WeakenedUntil != nil {
+			hp += " " + ui.Bad.Render("KO")
+		}
+		stats += "  " + ui.TerminalDim.Render("HP") + " " + hp
+	}
 
 	// Compose header
 	left := title
@@ -541,25 +937,12 @@ func (m boardModel) renderSidebar(w, h int) string {
 		barW = 8
 	}
 
-	// All 9 attributes
-	attrs := []struct {
-		icon string
-		name string
-		xp   int
-	}{
-		{"💪", "STR", m.player.XPStr},
-		{"🧠", "INT", m.player.XPInt},
-		{"🧘", "WIS", m.player.XPWis},
-		{"🎨", "ART", m.player.XPArt},
-		{"🏠", "HOME", m.player.XPHome},
-		{"🌲", "OUT", m.player.XPOut},
-		{"📚", "READ", m.player.XPRead},
-		{"🎬", "CINE", m.player.XPCinema},
-		{"💼", "WORK", m.player.XPCareer},
-	}
-
-	for _, a := range attrs {
-		lines = append(lines, renderAttrRetro(a.icon, a.name, a.xp, barW))
+	for _, a := range m.attrs {
+		label := string(a.Code)
+		if len(label) > 4 {
+			label = label[:4]
+		}
+		lines = append(lines, renderAttrRetro(m.svc.Rules(), a.Icon, label, a.Color, m.player.AttrXP(string(a.Code)), barW))
 	}
 
 	// Stats section
@@ -568,11 +951,15 @@ func (m boardModel) renderSidebar(w, h int) string {
 	lines = append(lines, "")
 
 	// Count tasks
-	pending, done, habits := 0, 0, 0
+	pending, done, habits, overdue := 0, 0, 0, 0
+	now := time.Now()
 	for _, t := range m.tasks {
 		if t.IsHabit {
 			habits++
 		}
+		if engine.IsOverdue(&t, now) {
+			overdue++
+		}
 		switch t.Status {
 		case "pending", "active", "planning":
 			pending++
@@ -583,6 +970,33 @@ func (m boardModel) renderSidebar(w, h int) string {
 	lines = append(lines, fmt.Sprintf("%s %d", ui.TerminalDim.Render("Active:"), pending))
 	lines = append(lines, fmt.Sprintf("%s %d", ui.TerminalDim.Render("Done:  "), done))
 	lines = append(lines, fmt.Sprintf("%s %d", ui.TerminalDim.Render("Habits:"), habits))
+	if overdue > 0 {
+		lines = append(lines, fmt.Sprintf("%s %s", ui.TerminalDim.Render("Overdue:"), ui.Bad.Render(fmt.Sprint(overdue))))
+	}
+
+	// Streaks section: the longest running habit streaks
+	if len(m.streaks) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, ui.Gold.Render("◆ STREAKS ◆"))
+		var running []storage.Task
+		for _, t := range m.tasks {
+			if m.streaks[t.ID].Current > 0 {
+				running = append(running, t)
+			}
+		}
+		sort.SliceStable(running, func(i, j int) bool { return m.streaks[running[i].ID].Current > m.streaks[running[j].ID].Current })
+		if len(running) > 3 {
+			running = running[:3]
+		}
+		for _, t := range running {
+			st := m.streaks[t.ID]
+			lines = append(lines, fmt.Sprintf("%s %s", ui.StreakChip(st.Current, st.DoneThisPeriod), truncate(t.Title, w-8)))
+		}
+		if len(running) == 0 {
+			lines = append(lines, ui.TerminalDim.Render("(none running)"))
+		}
+		lines = append(lines, ui.TerminalDim.Render(fmt.Sprintf("%s %d freeze tokens", ui.IconFreeze, m.freezeTokens)))
+	}
 
 	// XP Graph section (weekly)
 	lines = append(lines, "")
@@ -602,7 +1016,13 @@ func (m boardModel) renderSidebar(w, h int) string {
 	earnedCount := 0
 	totalCount := len(m.achievements)
 	recentBadges := ""
-	for _, a := range m.achievements {
+	// Newest badges first; ones earned before badges were recorded go last.
+	badges := append([]engine.Achievement(nil), m.achievements...)
+	sort.SliceStable(badges, func(i, j int) bool {
+		ti, tj := badges[i].EarnedAt, badges[j].EarnedAt
+		return ti != nil && (tj == nil || ti.After(*tj))
+	})
+	for _, a := range badges {
 		if a.Earned {
 			earnedCount++
 			if len(recentBadges) < barW {
@@ -623,7 +1043,11 @@ func (m boardModel) renderSidebar(w, h int) string {
 		lines = append(lines, ui.TerminalDim.Render("↑↓/jk  navigate"))
 		lines = append(lines, ui.TerminalDim.Render("⏎      expand"))
 		lines = append(lines, ui.TerminalDim.Render("c/␣    complete"))
+		lines = append(lines, ui.TerminalDim.Render("a      add"))
 		lines = append(lines, ui.TerminalDim.Render("d/⌫    delete"))
+		lines = append(lines, ui.TerminalDim.Render("t      timer"))
+		lines = append(lines, ui.TerminalDim.Render("/      filter"))
+		lines = append(lines, ui.TerminalDim.Render("v      review"))
 		lines = append(lines, ui.TerminalDim.Render("r      refresh"))
 		lines = append(lines, ui.TerminalDim.Render("?      toggle help"))
 		lines = append(lines, ui.TerminalDim.Render("q      quit"))
@@ -638,6 +1062,9 @@ func (m boardModel) renderMain(w, h int) string {
 	if m.loading {
 		return ui.Gold.Render("◆ LOADING ◆") + "\n\n" + ui.Terminal.Render(m.spinner.View()+" fetching data...")
 	}
+	if m.review != nil {
+		return m.renderReview(w, h)
+	}
 
 	var out []string
 
@@ -650,16 +1077,33 @@ func (m boardModel) renderMain(w, h int) string {
 		for _, t := range focus {
 			icon := kindIconRetro(t.IsProject, t.IsHabit)
 			xpStr := ui.TerminalDim.Render(fmt.Sprintf("+%dXP", t.XPValue))
-			out = append(out, fmt.Sprintf("  %s #%d %s %s", icon, t.ID, truncate(t.Title, w-20), xpStr))
+			line := fmt.Sprintf("  %s #%d %s %s", icon, t.ID, truncate(t.Title, w-32), xpStr)
+			if t.DueDate != nil && !t.IsHabit {
+				line += " " + ui.DueChip(*t.DueDate, time.Now())
+			}
+			out = append(out, line)
 		}
 	}
 
 	out = append(out, "")
-	out = append(out, ui.Gold.Render("◆ QUEST LOG ◆"))
+	header := ui.Gold.Render("◆ QUEST LOG ◆")
+	switch {
+	case m.adding:
+		header += " " + ui.Terminal.Render("+"+m.addInput+"█")
+	case m.filtering:
+		header += " " + ui.Terminal.Render("/"+m.filterInput+"█")
+	case m.filter != "":
+		header += " " + ui.TerminalDim.Render(truncate("/"+m.filter, w-18))
+	}
+	out = append(out, header)
 
 	lines := m.questLines()
 	if len(lines) == 0 {
-		out = append(out, ui.TerminalDim.Render("  (empty)"))
+		if m.matches != nil {
+			out = append(out, ui.TerminalDim.Render("  (no matches)"))
+		} else {
+			out = append(out, ui.TerminalDim.Render("  (empty)"))
+		}
 		return strings.Join(out, "\n")
 	}
 
@@ -702,6 +1146,21 @@ func (m boardModel) renderMain(w, h int) string {
 		title := truncate(ql.title, w-ql.depth*2-15)
 
 		row := fmt.Sprintf("%s%s%s %s %s", indent, fold, icon, title, statusIcon)
+		if ql.overdue {
+			row += " " + ui.Bad.Render("⏰")
+		}
+		if ql.blocked {
+			row += " " + ui.Warn.Render(ui.IconBlocked)
+		}
+		for _, tag := range ql.tags {
+			row += " " + ui.TagChip(tag, false)
+		}
+		switch {
+		case m.timer != nil && m.timer.TaskID == ql.id:
+			row += " " + ui.Gold.Render(ui.IconTimer+" "+ui.Clock(m.timer.Duration(time.Now())))
+		case i == m.selected && m.tracked[ql.id] > 0:
+			row += " " + ui.TerminalDim.Render(ui.IconTimer+" "+ui.Duration(m.tracked[ql.id]))
+		}
 
 		if i == m.selected {
 			// Highlight selected row
@@ -744,6 +1203,10 @@ func (m boardModel) focusTasks(n int) []storage.Task {
 		if len(children[t.ID]) > 0 {
 			continue
 		}
+		// Blocked tasks can't be done yet.
+		if len(m.blocked[t.ID]) > 0 {
+			continue
+		}
 		switch t.Status {
 		case "pending", "active":
 			leaf = append(leaf, t)
@@ -780,12 +1243,12 @@ func renderAttr(label string, xp int) string {
 	return fmt.Sprintf("%s %s %s", label, ui.Muted.Render(fmt.Sprintf("L%d", lvl)), bar)
 }
 
-func renderAttrRetro(icon, name string, xp, barW int) string {
-	lvl := engine.AttributeLevelForXP(xp)
-	cur := engine.XPRequiredForLevel(lvl)
-	next := engine.XPRequiredForLevel(lvl + 1)
+func renderAttrRetro(rules *engine.Rules, icon, name, color string, xp, barW int) string {
+	lvl := rules.AttributeLevelForXP(xp)
+	cur := rules.XPRequiredForLevel(lvl)
+	next := rules.XPRequiredForLevel(lvl + 1)
 	bar := progressBarRetro(xp-cur, next-cur, barW)
-	return fmt.Sprintf("%s %s %s %s", icon, ui.TerminalDim.Render(fmt.Sprintf("%-4s", name)), ui.Terminal.Render(fmt.Sprintf("L%d", lvl)), bar)
+	return fmt.Sprintf("%s %s %s %s", icon, ui.AttrStyle(color).Render(fmt.Sprintf("%-4s", name)), ui.Terminal.Render(fmt.Sprintf("L%d", lvl)), bar)
 }
 
 func progressBarStyled(value int, total int, width int) string {
@@ -0,0 +1,235 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"questline/internal/engine"
+	"questline/internal/ui"
+)
+
+// reviewScreen is the board's review mode: the report of a weekly or monthly
+// review and the decision taken on each stale task.
+type reviewScreen struct {
+	review   *engine.Review
+	actions  map[int64]engine.ReviewAction // By task ID; missing means keep
+	selected int                           // Index into review.Stale
+
+	// Due date being typed for the selected task
+	editingDue bool
+	dueInput   string
+}
+
+type reviewLoadedMsg struct {
+	review *engine.Review
+	err    error
+}
+
+type reviewSavedMsg struct {
+	res *engine.ReviewResult
+	err error
+}
+
+func (m boardModel) reviewCmd(period engine.ReviewPeriod) tea.Cmd {
+	return func() tea.Msg {
+		rv, err := m.svc.BuildReview(m.ctx, period, time.Now())
+		return reviewLoadedMsg{review: rv, err: err}
+	}
+}
+
+func (m boardModel) saveReviewCmd(period engine.ReviewPeriod, actions []engine.ReviewAction) tea.Cmd {
+	return func() tea.Msg {
+		res, err := m.svc.SaveReview(m.ctx, period, actions, time.Now())
+		return reviewSavedMsg{res: res, err: err}
+	}
+}
+
+// updateReview handles keys while the review screen is open.
+func (m boardModel) updateReview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := m.review
+	if r.editingDue {
+		return m.updateReviewDue(msg)
+	}
+	var stale *engine.StaleTask
+	if r.selected < len(r.review.Stale) {
+		stale = &r.review.Stale[r.selected]
+	}
+
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q":
+		m.review = nil
+		m.lastLog = "Review closed without saving"
+		return m, nil
+	case "tab":
+		period := engine.ReviewMonth
+		if r.review.Period == engine.ReviewMonth {
+			period = engine.ReviewWeek
+		}
+		m.lastLog = fmt.Sprintf("Loading %sly review...", period)
+		return m, m.reviewCmd(period)
+	case "up", "k":
+		if r.selected > 0 {
+			r.selected--
+		}
+	case "down", "j":
+		if r.selected < len(r.review.Stale)-1 {
+			r.selected++
+		}
+	case "enter":
+		var actions []engine.ReviewAction
+		for _, st := range r.review.Stale {
+			if a, ok := r.actions[st.Task.ID]; ok {
+				actions = append(actions, a)
+			}
+		}
+		m.lastLog = "Saving review..."
+		return m, m.saveReviewCmd(r.review.Period, actions)
+	case "x", " ":
+		if stale != nil {
+			delete(r.actions, stale.Task.ID)
+		}
+	case "d":
+		if stale != nil {
+			r.actions[stale.Task.ID] = engine.ReviewAction{TaskID: stale.Task.ID, Kind: engine.ReviewDrop}
+		}
+	case "s":
+		if stale != nil {
+			r.editingDue = true
+			r.dueInput = ""
+			m.lastLog = "Due: today, fri, +3d, 2026-11-01 or none (⏎ set, esc cancel)"
+		}
+	case "1", "2", "3", "4", "5":
+		if stale != nil {
+			d := engine.Difficulty(msg.String()[0] - '0')
+			if err := m.svc.Rules().CanUseDifficulty(m.player.Level, d); err != nil {
+				m.lastLog = "ERROR: " + err.Error()
+				return m, nil
+			}
+			r.actions[stale.Task.ID] = engine.ReviewAction{TaskID: stale.Task.ID, Kind: engine.ReviewRerate, Difficulty: d}
+		}
+	}
+	return m, nil
+}
+
+// updateReviewDue edits the due date of the selected stale task.
+func (m boardModel) updateReviewDue(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := m.review
+	switch msg.Type {
+	case tea.KeyEnter:
+		st := r.review.Stale[r.selected]
+		a := engine.ReviewAction{TaskID: st.Task.ID, Kind: engine.ReviewReschedule}
+		if v := strings.TrimSpace(r.dueInput); !strings.EqualFold(v, "none") {
+			due, err := engine.ParseDueDate(v, time.Now())
+			if err != nil {
+				m.lastLog = "ERROR: " + err.Error()
+				return m, nil
+			}
+			a.Due = &due
+		}
+		r.actions[st.Task.ID] = a
+		r.editingDue = false
+		m.lastLog = fmt.Sprintf("Task #%d will be rescheduled", st.Task.ID)
+	case tea.KeyEsc:
+		r.editingDue = false
+		m.lastLog = "Due date unchanged"
+	case tea.KeyBackspace:
+		if s := []rune(r.dueInput); len(s) > 0 {
+			r.dueInput = string(s[:len(s)-1])
+		}
+	case tea.KeySpace:
+		r.dueInput += " "
+	case tea.KeyRunes:
+		r.dueInput += string(msg.Runes)
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// renderReview draws the review screen in the main panel.
+func (m boardModel) renderReview(w, h int) string {
+	rv := m.review.review
+	title := "WEEKLY REVIEW"
+	if rv.Period == engine.ReviewMonth {
+		title = "MONTHLY REVIEW"
+	}
+	out := []string{
+		ui.Gold.Render("◆ "+title+" ◆") + " " + ui.TerminalDim.Render(fmt.Sprintf("%s – %s", rv.Since.Format("Mon 2 Jan"), rv.Until.Format("Mon 2 Jan"))),
+		"",
+		ui.Terminal.Render(fmt.Sprintf("%d completions · %d XP", rv.Completions, rv.XPEarned)),
+	}
+
+	reg := engine.NewAttributeRegistry(m.attrs)
+	for _, a := range rv.Attributes {
+		def := reg.Def(a.Attr)
+		out = append(out, fmt.Sprintf("  %s %s %s", def.Icon, ui.AttrStyle(def.Color).Render(fmt.Sprintf("%-5s", def.Code)), ui.TerminalDim.Render(fmt.Sprintf("%d XP (%d)", a.XP, a.Completions))))
+	}
+
+	if len(rv.Behind) > 0 {
+		out = append(out, "", ui.Gold.Render("◆ HABITS BEHIND ◆"))
+		for _, b := range rv.Behind {
+			out = append(out, fmt.Sprintf("  ○ #%d %s %s", b.Task.ID, truncate(b.Task.Title, w-20), ui.Warn.Render(fmt.Sprintf("%d/%d", b.Done, b.Expected))))
+		}
+	}
+
+	if len(rv.Blueprints) > 0 {
+		out = append(out, "", ui.Gold.Render("◆ BLUEPRINTS ◆"))
+		for _, b := range rv.Blueprints {
+			progress := fmt.Sprintf("%d done", b.Done)
+			if b.Total > 0 {
+				progress = fmt.Sprintf("%d/%d", b.Done, b.Total)
+			}
+			out = append(out, fmt.Sprintf("  %s %s %s", ui.IconScroll, truncate(b.Code, w-20), ui.TerminalDim.Render(progress)))
+		}
+	}
+
+	out = append(out, "", ui.Gold.Render("◆ STALE TASKS ◆"))
+	if len(rv.Stale) == 0 {
+		out = append(out, ui.TerminalDim.Render("  (none)"))
+	}
+	for i, st := range rv.Stale {
+		decision := ui.TerminalDim.Render("keep")
+		if a, ok := m.review.actions[st.Task.ID]; ok {
+			switch a.Kind {
+			case engine.ReviewDrop:
+				decision = ui.Bad.Render("drop")
+			case engine.ReviewRerate:
+				decision = ui.Gold.Render(fmt.Sprintf("diff %d→%d", st.Task.Difficulty, a.Difficulty))
+			case engine.ReviewReschedule:
+				decision = ui.Terminal.Render("due none")
+				if a.Due != nil {
+					decision = ui.Terminal.Render("due " + a.Due.Local().Format("Mon 2 Jan"))
+				}
+			}
+		}
+		row := fmt.Sprintf("  #%d %s %s %s", st.Task.ID, truncate(st.Task.Title, w-36), ui.TerminalDim.Render(fmt.Sprintf("%dd", st.AgeDays)), decision)
+		if st.Overdue {
+			row += " " + ui.Bad.Render("⏰")
+		}
+		if i == m.review.selected {
+			row = ui.SelectedRow.Render(row)
+		}
+		out = append(out, row)
+	}
+	if m.review.editingDue {
+		out = append(out, ui.Terminal.Render("  due: "+m.review.dueInput+"█"))
+	}
+
+	out = append(out, "")
+	reward := fmt.Sprintf("⏎ save (+%d XP)", rv.XPAvailable)
+	if rv.XPAvailable == 0 {
+		reward = "⏎ save (XP already earned)"
+	}
+	out = append(out, ui.TerminalDim.Render(reward+" · d drop · s reschedule · 1-5 re-rate · x keep · ⇥ week/month · esc close"))
+
+	// Keep the selected stale task on screen.
+	if len(out) > h && h > 0 {
+		out = out[len(out)-h:]
+	}
+	return strings.Join(out, "\n")
+}
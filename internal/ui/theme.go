@@ -2,7 +2,9 @@ package ui
 
 import (
 	"fmt"
+	"hash/fnv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -24,6 +26,17 @@ const (
 	IconLoop    = "🔁"
 	IconScroll  = "📜"
 	IconUndo    = "↩️"
+	IconFire    = "🔥"
+	IconFreeze  = "🧊"
+	IconHeart   = "❤️"
+	IconBlocked = "⛓️"
+	IconEdit    = "✏️"
+	IconTrash   = "🗑️"
+	IconSearch  = "🔍"
+	IconTag     = "🏷️"
+	IconReview  = "📋"
+	IconTimer   = "⏱️"
+	IconTomato  = "🍅"
 )
 
 // Retro terminal colors (phosphor green/amber CRT aesthetic)
@@ -121,6 +134,100 @@ func StatusText(status string) string {
 	}
 }
 
+// AttrStyle returns the style for an attribute label in its registry color,
+// falling back to the dim terminal green when no color is set.
+func AttrStyle(color string) lipgloss.Style {
+	if strings.TrimSpace(color) == "" {
+		return TerminalDim
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(color))
+}
+
+// DueChip renders a compact due-date label relative to now: "overdue 2d",
+// "due today", "due tomorrow", "due Fri" or "due Fri 24 Oct".
+func DueChip(due, now time.Time) string {
+	due = due.In(now.Location())
+	if now.After(due) {
+		if days := int(now.Sub(due).Hours() / 24); days > 0 {
+			return Bad.Render(fmt.Sprintf("overdue %dd", days))
+		}
+		return Bad.Render("overdue")
+	}
+	switch due.Format("2006-01-02") {
+	case now.Format("2006-01-02"):
+		return Warn.Render("due today")
+	case now.AddDate(0, 0, 1).Format("2006-01-02"):
+		return Warn.Render("due tomorrow")
+	}
+	if due.Sub(now) < 6*24*time.Hour {
+		return Muted.Render("due " + due.Format("Mon"))
+	}
+	return Muted.Render("due " + due.Format("Mon 2 Jan"))
+}
+
+// StreakChip renders a habit streak: gold once the current period is done,
+// a warning while it is still open. Empty for no streak.
+func StreakChip(current int, doneThisPeriod bool) string {
+	if current <= 0 {
+		return ""
+	}
+	label := fmt.Sprintf("%s %d", IconFire, current)
+	if doneThisPeriod {
+		return Gold.Render(label)
+	}
+	return Warn.Render(label)
+}
+
+// HPChip renders the player's HP, turning from good to warning to bad as it drops.
+func HPChip(hp, max int) string {
+	label := fmt.Sprintf("%s %d/%d", IconHeart, hp, max)
+	switch {
+	case hp*2 > max:
+		return Good.Render(label)
+	case hp*4 > max:
+		return Warn.Render(label)
+	}
+	return Bad.Render(label)
+}
+
+// Duration renders tracked time compactly: "42s", "25m", "3h05m".
+func Duration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// Clock renders a running or remaining time as "04:59" or "1:02:03".
+func Clock(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	secs := int(d.Round(time.Second).Seconds())
+	if secs >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+	}
+	return fmt.Sprintf("%02d:%02d", secs/60, secs%60)
+}
+
+// tagColors are the colors tag chips cycle through.
+var tagColors = []lipgloss.Color{"39", "170", "208", "114", "221", "75", "204", "150"}
+
+// TagChip renders a tag as "#tag" in a color picked from its name, so a tag
+// looks the same everywhere. Inherited tags are dimmed.
+func TagChip(tag string, inherited bool) string {
+	if inherited {
+		return Dim.Render("#" + tag)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(tag))
+	return lipgloss.NewStyle().Foreground(tagColors[h.Sum32()%uint32(len(tagColors))]).Render("#" + tag)
+}
+
 func KindIcon(isProject bool, isHabit bool) string {
 	if isProject {
 		return IconBox